@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// apiPrefix is the root of the REST API served by ko serve.
+const apiPrefix = "/api/v1"
+
+// Page sizes for list endpoints.
+const (
+	apiDefaultLimit = 50
+	apiMaxLimit     = 500
+)
+
+// apiMaxBody caps the size of a JSON request body.
+const apiMaxBody = 1 << 20
+
+// apiMu serialises API writes so concurrent requests cannot lose each
+// other's edits to the same ticket file.
+var apiMu sync.Mutex
+
+// apiError is a failed API request. It is written as
+// {"error": {"code": ..., "message": ...}} with Status as the HTTP status.
+type apiError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string { return e.Message }
+
+func errNotFound(format string, args ...interface{}) *apiError {
+	return &apiError{http.StatusNotFound, "not_found", fmt.Sprintf(format, args...)}
+}
+
+func errBadRequest(format string, args ...interface{}) *apiError {
+	return &apiError{http.StatusBadRequest, "bad_request", fmt.Sprintf(format, args...)}
+}
+
+func errInvalidField(field, format string, args ...interface{}) *apiError {
+	return &apiError{http.StatusUnprocessableEntity, "invalid_field", field + ": " + fmt.Sprintf(format, args...)}
+}
+
+func errConflict(format string, args ...interface{}) *apiError {
+	return &apiError{http.StatusConflict, "conflict", fmt.Sprintf(format, args...)}
+}
+
+// apiHandler serves one method of one route. It returns the HTTP status
+// and the value to encode, or an error; errors that are not *apiError are
+// reported as 500 internal.
+type apiHandler func(r *http.Request) (int, interface{}, error)
+
+// apiRoute dispatches on the request method, answering 405 with the
+// allowed methods for anything else.
+func apiRoute(methods map[string]apiHandler) http.HandlerFunc {
+	var allowed []string
+	for m := range methods {
+		allowed = append(allowed, m)
+	}
+	sort.Strings(allowed)
+	return func(w http.ResponseWriter, r *http.Request) {
+		h, ok := methods[r.Method]
+		if !ok {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeAPIError(w, &apiError{http.StatusMethodNotAllowed, "method_not_allowed",
+				fmt.Sprintf("%s is not supported on %s", r.Method, r.URL.Path)})
+			return
+		}
+		status, v, err := h(r)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if v != nil {
+			json.NewEncoder(w).Encode(v)
+		}
+	}
+}
+
+// writeAPIError writes err in the API error envelope.
+func writeAPIError(w http.ResponseWriter, err error) {
+	var ae *apiError
+	if !errors.As(err, &ae) {
+		fmt.Fprintf(os.Stderr, "ko serve: api: %v\n", err)
+		ae = &apiError{http.StatusInternalServerError, "internal", err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(ae.Status)
+	json.NewEncoder(w).Encode(map[string]*apiError{"error": ae})
+}
+
+// registerAPI adds the /api/v1 routes to mux.
+func registerAPI(mux *http.ServeMux) {
+	tickets := apiPrefix + "/projects/{tag}/tickets"
+	ticket := tickets + "/{id}"
+	mux.HandleFunc(apiPrefix+"/projects", apiRoute(map[string]apiHandler{
+		http.MethodGet: apiListProjects,
+	}))
+	mux.HandleFunc(tickets, apiRoute(map[string]apiHandler{
+		http.MethodGet:  apiListTickets,
+		http.MethodPost: apiCreateTicket,
+	}))
+	mux.HandleFunc(apiPrefix+"/projects/{tag}/templates", apiRoute(map[string]apiHandler{
+		http.MethodGet: apiListTemplates,
+	}))
+	mux.HandleFunc(ticket, apiRoute(map[string]apiHandler{
+		http.MethodGet:   apiGetTicket,
+		http.MethodPatch: apiPatchTicket,
+	}))
+	mux.HandleFunc(ticket+"/notes", apiRoute(map[string]apiHandler{
+		http.MethodGet:  apiListNotes,
+		http.MethodPost: apiAddNote,
+	}))
+	mux.HandleFunc(ticket+"/deps", apiRoute(map[string]apiHandler{
+		http.MethodGet:  apiListDeps,
+		http.MethodPost: apiAddDep,
+	}))
+	mux.HandleFunc(ticket+"/deps/{dep}", apiRoute(map[string]apiHandler{
+		http.MethodDelete: apiRemoveDep,
+	}))
+	mux.HandleFunc(ticket+"/builds", apiRoute(map[string]apiHandler{
+		http.MethodGet: apiListBuilds,
+	}))
+	mux.HandleFunc(ticket+"/history", apiRoute(map[string]apiHandler{
+		http.MethodGet: apiListHistory,
+	}))
+	mux.HandleFunc(apiPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, errNotFound("no such endpoint: %s", r.URL.Path))
+	})
+}
+
+// apiPageParams is the window a list request asks for.
+type apiPageParams struct {
+	Offset int
+	Limit  int
+}
+
+// apiPage is the body of every list response. NextCursor is set when more
+// items follow; pass it back as ?cursor= to get them.
+type apiPage struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// parsePageParams reads ?limit= and ?cursor=. Cursors are opaque to
+// clients; they encode the offset of the next page.
+func parsePageParams(q url.Values) (apiPageParams, error) {
+	p := apiPageParams{Limit: apiDefaultLimit}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > apiMaxLimit {
+			return p, errInvalidField("limit", "must be 1-%d", apiMaxLimit)
+		}
+		p.Limit = n
+	}
+	if c := q.Get("cursor"); c != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(c)
+		n, convErr := strconv.Atoi(strings.TrimPrefix(string(raw), "o:"))
+		if err != nil || convErr != nil || !strings.HasPrefix(string(raw), "o:") || n < 0 {
+			return p, errInvalidField("cursor", "malformed cursor")
+		}
+		p.Offset = n
+	}
+	return p, nil
+}
+
+// pageOf slices the window p out of items. items may be the full list or
+// any prefix of it longer than p.Offset+p.Limit.
+func pageOf[T any](items []T, p apiPageParams) apiPage {
+	start := min(p.Offset, len(items))
+	end := min(start+p.Limit, len(items))
+	page := apiPage{Items: append([]T{}, items[start:end]...)}
+	if end < len(items) {
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(end)))
+	}
+	return page
+}
+
+// decodeAPIBody decodes a JSON request body into v, rejecting unknown
+// fields so typos do not silently do nothing.
+func decodeAPIBody(r *http.Request, v interface{}) error {
+	body, err := readLimited(r, apiMaxBody)
+	if err != nil {
+		return errBadRequest("%v", err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(body)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errBadRequest("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// apiProjectEntry is one registered project.
+type apiProjectEntry struct {
+	Tag    string `json:"tag"`
+	Path   string `json:"path"`
+	Prefix string `json:"prefix,omitempty"`
+	Hidden bool   `json:"hidden,omitempty"`
+}
+
+func apiListProjects(r *http.Request) (int, interface{}, error) {
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		return 0, nil, err
+	}
+	reg, err := LoadRegistry(RegistryPath())
+	if err != nil {
+		return 0, nil, err
+	}
+	tok := requestToken(r)
+	projects := []apiProjectEntry{}
+	for tag, path := range reg.Projects {
+		if tok != nil && len(tok.Projects) > 0 && !contains(tok.Projects, tag) {
+			continue
+		}
+		projects = append(projects, apiProjectEntry{tag, path, reg.Prefixes[tag], reg.Hidden[tag]})
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Tag < projects[j].Tag })
+	return http.StatusOK, pageOf(projects, page), nil
+}
+
+// apiProject resolves the {tag} path segment to a tickets directory and
+// the registry prefix of the project.
+func apiProject(r *http.Request) (ticketsDir, prefix string, err error) {
+	tag := strings.TrimPrefix(r.PathValue("tag"), "#")
+	reg, err := LoadRegistry(RegistryPath())
+	if err != nil {
+		return "", "", err
+	}
+	projectPath, ok := reg.Projects[tag]
+	if !ok {
+		return "", "", errNotFound("unknown project '%s'", tag)
+	}
+	return resolveTicketsDir(projectPath), reg.Prefixes[tag], nil
+}
+
+// apiTicket resolves the {tag} and {id} path segments and loads the
+// ticket. Partial IDs are accepted, but only within the project.
+func apiTicket(r *http.Request) (string, *Ticket, error) {
+	ticketsDir, _, err := apiProject(r)
+	if err != nil {
+		return "", nil, err
+	}
+	t, err := apiLoadTicket(ticketsDir, r.PathValue("id"))
+	return ticketsDir, t, err
+}
+
+// apiLoadTicket resolves partial to a ticket in ticketsDir.
+func apiLoadTicket(ticketsDir, partial string) (*Ticket, error) {
+	id, err := ResolveID(ticketsDir, partial)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "ambiguous") {
+			return nil, errBadRequest("%v", err)
+		}
+		return nil, errNotFound("%v", err)
+	}
+	db := getShadowDB()
+	if db == nil || !db.TicketInDir(ticketsDir, id) {
+		return nil, errNotFound("ticket '%s' not found in this project", partial)
+	}
+	return LoadTicket(ticketsDir, id)
+}
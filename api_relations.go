@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// apiNote is one entry of a ticket's ## Notes section.
+type apiNote struct {
+	Timestamp string `json:"ts"`
+	Author    string `json:"author,omitempty"`
+	Text      string `json:"text"`
+}
+
+// apiNotes converts the notes parsed from a ticket body.
+func apiNotes(body string) []apiNote {
+	notes := []apiNote{}
+	for _, n := range parseNotes(body) {
+		notes = append(notes, apiNote{n.notedAt, n.author, n.body})
+	}
+	return notes
+}
+
+func apiListNotes(r *http.Request) (int, interface{}, error) {
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		return 0, nil, err
+	}
+	_, t, err := apiTicket(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, pageOf(apiNotes(t.Body), page), nil
+}
+
+func apiAddNote(r *http.Request) (int, interface{}, error) {
+	var in struct {
+		Text string `json:"text"`
+	}
+	if err := decodeAPIBody(r, &in); err != nil {
+		return 0, nil, err
+	}
+	if strings.TrimSpace(in.Text) == "" {
+		return 0, nil, errInvalidField("text", "must not be empty")
+	}
+
+	apiMu.Lock()
+	defer apiMu.Unlock()
+	ticketsDir, t, err := apiTicket(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	AddNote(t, strings.TrimSpace(in.Text))
+	if err := SaveTicket(ticketsDir, t); err != nil {
+		return 0, nil, err
+	}
+	EmitMutationEvent(ticketsDir, t.ID, "note", nil)
+	notes := apiNotes(t.Body)
+	return http.StatusCreated, notes[len(notes)-1], nil
+}
+
+// apiDep is one dependency of a ticket. Status is "missing" when the
+// dependency no longer exists.
+type apiDep struct {
+	ID     string `json:"id"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status"`
+}
+
+func apiListDeps(r *http.Request) (int, interface{}, error) {
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		return 0, nil, err
+	}
+	ticketsDir, t, err := apiTicket(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	deps := []apiDep{}
+	for _, id := range t.Deps {
+		d := apiDep{ID: id, Status: "missing"}
+		if dep, err := LoadTicket(ticketsDir, id); err == nil {
+			d.Title, d.Status = dep.Title, dep.Status
+		}
+		deps = append(deps, d)
+	}
+	return http.StatusOK, pageOf(deps, page), nil
+}
+
+func apiAddDep(r *http.Request) (int, interface{}, error) {
+	var in struct {
+		ID string `json:"id"`
+	}
+	if err := decodeAPIBody(r, &in); err != nil {
+		return 0, nil, err
+	}
+	if in.ID == "" {
+		return 0, nil, errInvalidField("id", "is required")
+	}
+
+	apiMu.Lock()
+	defer apiMu.Unlock()
+	ticketsDir, t, err := apiTicket(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Dependencies may cross projects, as with ko dep.
+	_, depID, err := ResolveTicket(ticketsDir, in.ID)
+	if err != nil {
+		return 0, nil, errInvalidField("id", "%v", err)
+	}
+	if depID == t.ID {
+		return 0, nil, errInvalidField("id", "a ticket cannot depend on itself")
+	}
+	if contains(t.Deps, depID) {
+		return 0, nil, errConflict("%s already depends on %s", t.ID, depID)
+	}
+	t.Deps = append(t.Deps, depID)
+	if err := SaveTicket(ticketsDir, t); err != nil {
+		return 0, nil, err
+	}
+	EmitMutationEvent(ticketsDir, t.ID, "dep", map[string]interface{}{
+		"dep": depID,
+	})
+	return http.StatusCreated, apiTicketDetail(ticketsDir, t), nil
+}
+
+func apiRemoveDep(r *http.Request) (int, interface{}, error) {
+	apiMu.Lock()
+	defer apiMu.Unlock()
+	ticketsDir, t, err := apiTicket(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	depID := r.PathValue("dep")
+	var kept []string
+	for _, d := range t.Deps {
+		if d != depID {
+			kept = append(kept, d)
+		}
+	}
+	if len(kept) == len(t.Deps) {
+		return 0, nil, errNotFound("dependency %s -> %s not found", t.ID, depID)
+	}
+	t.Deps = kept
+	if err := SaveTicket(ticketsDir, t); err != nil {
+		return 0, nil, err
+	}
+	EmitMutationEvent(ticketsDir, t.ID, "undep", map[string]interface{}{
+		"dep": depID,
+	})
+	return http.StatusOK, apiTicketDetail(ticketsDir, t), nil
+}
+
+// apiBuild is one build of a ticket, newest first.
+type apiBuild struct {
+	Workflow    string `json:"workflow"`
+	StartedAt   string `json:"started_at"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	Outcome     string `json:"outcome,omitempty"`
+	Duration    string `json:"duration,omitempty"`
+}
+
+func apiListBuilds(r *http.Request) (int, interface{}, error) {
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		return 0, nil, err
+	}
+	_, t, err := apiTicket(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Fetch one past the page so pageOf can tell whether more follow.
+	entries, err := getShadowDB().QueryTicketBuilds(t.ID, page.Offset+page.Limit+1)
+	if err != nil {
+		return 0, nil, err
+	}
+	builds := []apiBuild{}
+	for _, b := range entries {
+		builds = append(builds, apiBuild{
+			Workflow:    b.Workflow,
+			StartedAt:   b.StartedAt,
+			CompletedAt: b.CompletedAt.String,
+			Outcome:     b.Outcome.String,
+			Duration:    b.Duration,
+		})
+	}
+	return http.StatusOK, pageOf(builds, page), nil
+}
+
+// apiEvent is one mutation event of a ticket, newest first.
+type apiEvent struct {
+	Timestamp string          `json:"ts"`
+	Event     string          `json:"event"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+func apiListHistory(r *http.Request) (int, interface{}, error) {
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		return 0, nil, err
+	}
+	_, t, err := apiTicket(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	entries, err := getShadowDB().QueryTicketMutations(t.ID, page.Offset+page.Limit+1)
+	if err != nil {
+		return 0, nil, err
+	}
+	events := []apiEvent{}
+	for _, m := range entries {
+		e := apiEvent{Timestamp: m.OccurredAt, Event: m.EventType}
+		if m.Payload.Valid && json.Valid([]byte(m.Payload.String)) && m.Payload.String != "null" {
+			e.Data = json.RawMessage(m.Payload.String)
+		}
+		events = append(events, e)
+	}
+	return http.StatusOK, pageOf(events, page), nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAPINotesAndDeps(t *testing.T) {
+	_, do := apiTestServer(t)
+
+	_, a := do(http.MethodPost, "/api/v1/projects/api/tickets", `{"title": "A"}`)
+	_, b := do(http.MethodPost, "/api/v1/projects/api/tickets", `{"title": "B"}`)
+	aPath := "/api/v1/projects/api/tickets/" + a["id"].(string)
+	bID := b["id"].(string)
+
+	code, out := do(http.MethodPost, aPath+"/notes", `{"text": "first look"}`)
+	if code != http.StatusCreated || out["text"] != "first look" || out["ts"] == "" {
+		t.Fatalf("add note = %d %v", code, out)
+	}
+	do(http.MethodPost, aPath+"/notes", `{"text": "ko: second"}`)
+	if code, _ := do(http.MethodPost, aPath+"/notes", `{"text": "  "}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("empty note = %d", code)
+	}
+	_, out = do(http.MethodGet, aPath+"/notes", "")
+	notes, _ := out["items"].([]interface{})
+	if len(notes) != 2 || notes[1].(map[string]interface{})["author"] != "ko" || notes[1].(map[string]interface{})["text"] != "second" {
+		t.Errorf("notes = %v", out)
+	}
+
+	code, out = do(http.MethodPost, aPath+"/deps", `{"id": "`+bID+`"}`)
+	if code != http.StatusCreated || len(out["blockers"].([]interface{})) != 1 {
+		t.Fatalf("add dep = %d %v", code, out)
+	}
+	if code, out := do(http.MethodPost, aPath+"/deps", `{"id": "`+bID+`"}`); code != http.StatusConflict || apiErrorCode(out) != "conflict" {
+		t.Errorf("duplicate dep = %d %v", code, out)
+	}
+	if code, _ := do(http.MethodPost, aPath+"/deps", `{"id": "`+a["id"].(string)+`"}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("self dep = %d", code)
+	}
+	_, out = do(http.MethodGet, aPath+"/deps", "")
+	deps, _ := out["items"].([]interface{})
+	if len(deps) != 1 || deps[0].(map[string]interface{})["title"] != "B" || deps[0].(map[string]interface{})["status"] != "open" {
+		t.Errorf("deps = %v", out)
+	}
+	if code, _ := do(http.MethodDelete, aPath+"/deps/"+bID, ""); code != http.StatusOK {
+		t.Errorf("remove dep = %d", code)
+	}
+	if code, out := do(http.MethodDelete, aPath+"/deps/"+bID, ""); code != http.StatusNotFound {
+		t.Errorf("remove missing dep = %d %v", code, out)
+	}
+
+	_, out = do(http.MethodGet, aPath+"/history?limit=2", "")
+	events, _ := out["items"].([]interface{})
+	if len(events) != 2 || out["next_cursor"] == nil {
+		t.Errorf("history = %v", out)
+	}
+	code, out = do(http.MethodGet, aPath+"/builds", "")
+	if builds, ok := out["items"].([]interface{}); code != http.StatusOK || !ok || len(builds) != 0 {
+		t.Errorf("builds = %d %v", code, out)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// apiTestServer registers a project tagged "api" with prefix "ap" and
+// returns a function that sends requests to the API.
+func apiTestServer(t *testing.T) (ticketsDir string, do func(method, path, body string) (int, map[string]interface{})) {
+	t.Helper()
+	cleanup := setupTestDB(t)
+	t.Cleanup(cleanup)
+
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	projectDir := t.TempDir()
+	ticketsDir = filepath.Join(projectDir, ".ko", "tickets")
+	os.MkdirAll(ticketsDir, 0755)
+	os.MkdirAll(filepath.Join(configHome, "knockout"), 0755)
+	os.WriteFile(filepath.Join(configHome, "knockout", "projects.yml"),
+		[]byte("projects:\n  api:\n    path: "+projectDir+"\n    prefix: ap\n"), 0644)
+
+	mux := http.NewServeMux()
+	registerAPI(mux)
+	return ticketsDir, func(method, path, body string) (int, map[string]interface{}) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		var out map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &out)
+		return w.Code, out
+	}
+}
+
+// apiErrorCode returns the error code of an API error response.
+func apiErrorCode(out map[string]interface{}) string {
+	e, _ := out["error"].(map[string]interface{})
+	code, _ := e["code"].(string)
+	return code
+}
+
+func TestPageOf(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	p := pageOf(items, apiPageParams{Limit: 2})
+	if got := p.Items.([]int); len(got) != 2 || got[0] != 1 || p.NextCursor == "" {
+		t.Fatalf("first page = %+v", p)
+	}
+	next, err := parsePageParams(url.Values{"cursor": {p.NextCursor}, "limit": {"2"}})
+	if err != nil || next.Offset != 2 {
+		t.Fatalf("parse cursor = %+v, %v", next, err)
+	}
+	p = pageOf(items, apiPageParams{Offset: 4, Limit: 2})
+	if got := p.Items.([]int); len(got) != 1 || got[0] != 5 || p.NextCursor != "" {
+		t.Errorf("last page = %+v", p)
+	}
+	if p := pageOf(items, apiPageParams{Offset: 10, Limit: 2}); len(p.Items.([]int)) != 0 {
+		t.Errorf("past the end = %+v", p)
+	}
+
+	for _, q := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"501"}},
+		{"limit": {"ten"}},
+		{"cursor": {"!!"}},
+		{"cursor": {"MTA"}}, // "10" without the offset marker
+	} {
+		if _, err := parsePageParams(q); apiErrorCodeOf(err) != "invalid_field" {
+			t.Errorf("parsePageParams(%v) err = %v, want invalid_field", q, err)
+		}
+	}
+}
+
+func apiErrorCodeOf(err error) string {
+	if ae, ok := err.(*apiError); ok {
+		return ae.Code
+	}
+	return ""
+}
+
+func TestAPIRouting(t *testing.T) {
+	_, do := apiTestServer(t)
+
+	code, out := do(http.MethodGet, "/api/v1/projects", "")
+	items, _ := out["items"].([]interface{})
+	if code != http.StatusOK || len(items) != 1 || items[0].(map[string]interface{})["tag"] != "api" {
+		t.Errorf("projects = %d %v", code, out)
+	}
+
+	tests := []struct {
+		method, path string
+		wantStatus   int
+		wantCode     string
+	}{
+		{http.MethodGet, "/api/v1/nope", http.StatusNotFound, "not_found"},
+		{http.MethodGet, "/api/v1/projects/missing/tickets", http.StatusNotFound, "not_found"},
+		{http.MethodGet, "/api/v1/projects/api/tickets/ap-zzzz", http.StatusNotFound, "not_found"},
+		{http.MethodDelete, "/api/v1/projects/api/tickets", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodPost, "/api/v1/projects/api/tickets", http.StatusBadRequest, "bad_request"},
+	}
+	for _, tt := range tests {
+		code, out := do(tt.method, tt.path, "")
+		if code != tt.wantStatus || apiErrorCode(out) != tt.wantCode {
+			t.Errorf("%s %s = %d %v, want %d %s", tt.method, tt.path, code, out, tt.wantStatus, tt.wantCode)
+		}
+	}
+}
+
+func TestAPIProjectsFilteredByToken(t *testing.T) {
+	apiTestServer(t)
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/projects", nil)
+	tok := &ServeToken{Name: "web", Scopes: []string{"read"}, Projects: []string{"web"}}
+	r = r.WithContext(context.WithValue(r.Context(), serveTokenKey{}, tok))
+	_, page, err := apiListProjects(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := page.(apiPage).Items.([]apiProjectEntry); len(items) != 0 {
+		t.Errorf("restricted token sees %v", items)
+	}
+}
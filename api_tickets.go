@@ -0,0 +1,330 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiTicketCreate is the body of POST .../tickets. With a template, the
+// template's defaults apply to whatever is left out.
+type apiTicketCreate struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Priority    *int     `json:"priority"`
+	Assignee    string   `json:"assignee"`
+	Parent      string   `json:"parent"`
+	ExternalRef string   `json:"external_ref"`
+	Tags        []string `json:"tags"`
+	Scope       []string `json:"scope"`
+	Snooze      string   `json:"snooze"`
+	Triage      string   `json:"triage"`
+	Template    string   `json:"template"`
+}
+
+// apiTicketPatch is the body of PATCH .../tickets/{id}. Absent fields are
+// left alone; tags and scope replace the existing lists.
+type apiTicketPatch struct {
+	Title       *string   `json:"title"`
+	Status      *string   `json:"status"`
+	Type        *string   `json:"type"`
+	Priority    *int      `json:"priority"`
+	Assignee    *string   `json:"assignee"`
+	Parent      *string   `json:"parent"`
+	ExternalRef *string   `json:"external_ref"`
+	Tags        *[]string `json:"tags"`
+	Scope       *[]string `json:"scope"`
+	Snooze      *string   `json:"snooze"`
+	Triage      *string   `json:"triage"`
+}
+
+// validateTicketFields checks the fields shared by create and patch.
+func validateTicketFields(title, status, snooze *string, priority *int) error {
+	if title != nil && strings.TrimSpace(*title) == "" {
+		return errInvalidField("title", "must not be empty")
+	}
+	if title != nil && strings.ContainsAny(*title, "\r\n") {
+		return errInvalidField("title", "must be a single line")
+	}
+	if status != nil && !ValidStatus(*status) {
+		return errInvalidField("status", "must be one of %s", strings.Join(Statuses, ", "))
+	}
+	if priority != nil && (*priority < 0 || *priority > 4) {
+		return errInvalidField("priority", "must be 0-4")
+	}
+	if snooze != nil && *snooze != "" {
+		if _, err := time.Parse("2006-01-02", *snooze); err != nil {
+			return errInvalidField("snooze", "must be an ISO 8601 date (e.g. 2026-05-01)")
+		}
+	}
+	return nil
+}
+
+// applyTicketPatch applies p to t and reports whether anything changed.
+// The parent must already be resolved to a full ID.
+func applyTicketPatch(t *Ticket, p apiTicketPatch) bool {
+	changed := false
+	setString := func(dst *string, v *string) {
+		if v != nil && *dst != *v {
+			*dst = *v
+			changed = true
+		}
+	}
+	setString(&t.Title, p.Title)
+	setString(&t.Status, p.Status)
+	setString(&t.Type, p.Type)
+	setString(&t.Assignee, p.Assignee)
+	setString(&t.Parent, p.Parent)
+	setString(&t.ExternalRef, p.ExternalRef)
+	setString(&t.Snooze, p.Snooze)
+	setString(&t.Triage, p.Triage)
+	if p.Priority != nil && t.Priority != *p.Priority {
+		t.Priority = *p.Priority
+		changed = true
+	}
+	if p.Tags != nil && strings.Join(t.Tags, ",") != strings.Join(*p.Tags, ",") {
+		t.Tags = cleanList(*p.Tags)
+		changed = true
+	}
+	if p.Scope != nil && strings.Join(t.Scope, ",") != strings.Join(*p.Scope, ",") {
+		t.Scope = cleanList(*p.Scope)
+		changed = true
+	}
+	return changed
+}
+
+// cleanList trims entries and drops empty ones.
+func cleanList(list []string) []string {
+	var out []string
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// filterAPITickets applies the ?status=, ?type=, ?tag=, ?assignee= and
+// ?parent= filters. Without ?status= or ?all=true closed tickets are
+// hidden, as in ko ls.
+func filterAPITickets(tickets []*Ticket, q url.Values) []*Ticket {
+	get := q.Get
+	statuses := map[string]bool{}
+	for _, s := range strings.Split(get("status"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			statuses[s] = true
+		}
+	}
+	all, _ := strconv.ParseBool(get("all"))
+	var out []*Ticket
+	for _, t := range tickets {
+		switch {
+		case len(statuses) > 0 && !statuses[t.Status]:
+		case len(statuses) == 0 && !all && t.Status == "closed":
+		case get("type") != "" && t.Type != get("type"):
+		case get("assignee") != "" && t.Assignee != get("assignee"):
+		case get("parent") != "" && t.Parent != get("parent"):
+		case get("tag") != "" && !contains(t.Tags, get("tag")):
+		default:
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// apiTicketDetail is the full view of a ticket, as in ko show --json.
+func apiTicketDetail(ticketsDir string, t *Ticket) showJSON {
+	return newShowJSON(t, openDeps(ticketsDir, t.Deps), findBlocking(ticketsDir, t.ID), findChildren(ticketsDir, t.ID))
+}
+
+func apiListTickets(r *http.Request) (int, interface{}, error) {
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		return 0, nil, err
+	}
+	ticketsDir, _, err := apiProject(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	q := r.URL.Query()
+	if s := q.Get("status"); s != "" {
+		for _, st := range strings.Split(s, ",") {
+			st = strings.TrimSpace(st)
+			if err := validateTicketFields(nil, &st, nil, nil); err != nil {
+				return 0, nil, err
+			}
+		}
+	}
+	tickets, err := ListTickets(ticketsDir)
+	if err != nil {
+		return 0, nil, err
+	}
+	tickets = filterAPITickets(tickets, q)
+	SortByPriorityThenModified(tickets)
+
+	p := pageOf(tickets, page)
+	items := []ticketJSON{}
+	for _, t := range p.Items.([]*Ticket) {
+		items = append(items, ticketToJSON(t, ticketsDir))
+	}
+	p.Items = items
+	return http.StatusOK, p, nil
+}
+
+// apiListTemplates lists the ticket templates the project can create
+// tickets from.
+func apiListTemplates(r *http.Request) (int, interface{}, error) {
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		return 0, nil, err
+	}
+	ticketsDir, _, err := apiProject(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	templates, err := ListTicketTemplates(ticketsDir)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, pageOf(templatesToJSON(templates), page), nil
+}
+
+func apiGetTicket(r *http.Request) (int, interface{}, error) {
+	ticketsDir, t, err := apiTicket(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, apiTicketDetail(ticketsDir, t), nil
+}
+
+func apiCreateTicket(r *http.Request) (int, interface{}, error) {
+	ticketsDir, prefix, err := apiProject(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	var in apiTicketCreate
+	if err := decodeAPIBody(r, &in); err != nil {
+		return 0, nil, err
+	}
+	if err := validateTicketFields(&in.Title, nil, &in.Snooze, in.Priority); err != nil {
+		return 0, nil, err
+	}
+
+	apiMu.Lock()
+	defer apiMu.Unlock()
+	if err := EnsureTicketsDir(ticketsDir); err != nil {
+		return 0, nil, err
+	}
+	var tmpl *TicketTemplate
+	if in.Template != "" {
+		if tmpl, err = LoadTicketTemplate(ticketsDir, in.Template); err != nil {
+			return 0, nil, errInvalidField("template", "%v", err)
+		}
+	}
+	if prefix == "" {
+		prefix = detectPrefix(ticketsDir)
+	}
+	var t *Ticket
+	if in.Parent != "" {
+		parent, err := apiLoadTicket(ticketsDir, in.Parent)
+		if err != nil {
+			return 0, nil, errInvalidField("parent", "%v", err)
+		}
+		t = NewChildTicket(parent.ID, in.Title)
+	} else {
+		t = NewTicket(prefix, in.Title)
+	}
+	t.Status = "open"
+	if tmpl != nil {
+		if err := tmpl.Apply(t, in.Description); err != nil {
+			return 0, nil, err
+		}
+	} else if in.Description != "" {
+		t.Body += "\n" + in.Description + "\n"
+	}
+	if in.Type != "" {
+		t.Type = in.Type
+	}
+	if in.Priority != nil {
+		t.Priority = *in.Priority
+	}
+	t.Assignee = in.Assignee
+	t.ExternalRef = in.ExternalRef
+	if in.Tags != nil {
+		t.Tags = cleanList(in.Tags)
+	}
+	t.Scope = cleanList(in.Scope)
+	t.Snooze = in.Snooze
+	if in.Triage != "" {
+		t.Triage = in.Triage
+	}
+
+	if err := SaveTicket(ticketsDir, t); err != nil {
+		return 0, nil, err
+	}
+	EmitMutationEvent(ticketsDir, t.ID, "create", map[string]interface{}{
+		"title": t.Title,
+	})
+	if t.Triage != "" {
+		maybeAutoTriage(ticketsDir, t.ID)
+	}
+	maybeAutoAgent(ticketsDir)
+
+	if saved, err := LoadTicket(ticketsDir, t.ID); err == nil {
+		t = saved
+	}
+	return http.StatusCreated, apiTicketDetail(ticketsDir, t), nil
+}
+
+func apiPatchTicket(r *http.Request) (int, interface{}, error) {
+	var p apiTicketPatch
+	if err := decodeAPIBody(r, &p); err != nil {
+		return 0, nil, err
+	}
+	if err := validateTicketFields(p.Title, p.Status, p.Snooze, p.Priority); err != nil {
+		return 0, nil, err
+	}
+
+	apiMu.Lock()
+	defer apiMu.Unlock()
+	ticketsDir, t, err := apiTicket(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if p.Parent != nil && *p.Parent != "" {
+		parent, err := apiLoadTicket(ticketsDir, *p.Parent)
+		if err != nil {
+			return 0, nil, errInvalidField("parent", "%v", err)
+		}
+		if parent.ID == t.ID {
+			return 0, nil, errInvalidField("parent", "a ticket cannot be its own parent")
+		}
+		p.Parent = &parent.ID
+	}
+
+	fromStatus := t.Status
+	if applyTicketPatch(t, p) {
+		if err := SaveTicket(ticketsDir, t); err != nil {
+			return 0, nil, err
+		}
+		EmitMutationEvent(ticketsDir, t.ID, "update", map[string]interface{}{
+			"ticket": t.ID,
+		})
+		if t.Status != fromStatus {
+			EmitMutationEvent(ticketsDir, t.ID, "status", map[string]interface{}{
+				"from": fromStatus,
+				"to":   t.Status,
+			})
+		}
+		if p.Triage != nil && *p.Triage != "" {
+			maybeAutoTriage(ticketsDir, t.ID)
+		}
+	}
+	if saved, err := LoadTicket(ticketsDir, t.ID); err == nil {
+		t = saved
+	}
+	return http.StatusOK, apiTicketDetail(ticketsDir, t), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyTicketPatch(t *testing.T) {
+	tk := &Ticket{Title: "Old", Status: "open", Priority: 2, Tags: []string{"a"}}
+	title, status, prio := "New", "in_progress", 2
+	tags := []string{" b ", ""}
+	if !applyTicketPatch(tk, apiTicketPatch{Title: &title, Status: &status, Priority: &prio, Tags: &tags}) {
+		t.Fatal("expected a change")
+	}
+	if tk.Title != "New" || tk.Status != "in_progress" || strings.Join(tk.Tags, ",") != "b" {
+		t.Errorf("patched = %+v", tk)
+	}
+	if applyTicketPatch(tk, apiTicketPatch{Priority: &prio}) {
+		t.Error("same priority reported as a change")
+	}
+
+	bad, empty, snooze, p9 := "shipped", " ", "tomorrow", 9
+	for _, err := range []error{
+		validateTicketFields(&empty, nil, nil, nil),
+		validateTicketFields(nil, &bad, nil, nil),
+		validateTicketFields(nil, nil, &snooze, nil),
+		validateTicketFields(nil, nil, nil, &p9),
+	} {
+		if apiErrorCodeOf(err) != "invalid_field" {
+			t.Errorf("err = %v, want invalid_field", err)
+		}
+	}
+}
+
+func TestAPITickets(t *testing.T) {
+	_, do := apiTestServer(t)
+
+	code, out := do(http.MethodPost, "/api/v1/projects/api/tickets",
+		`{"title": "Fix login", "description": "Users see a 500", "priority": 1, "tags": ["auth"]}`)
+	if code != http.StatusCreated {
+		t.Fatalf("create = %d %v", code, out)
+	}
+	id, _ := out["id"].(string)
+	if !strings.HasPrefix(id, "ap-") || out["status"] != "open" || !strings.Contains(out["body"].(string), "Users see a 500") {
+		t.Errorf("created = %v", out)
+	}
+	do(http.MethodPost, "/api/v1/projects/api/tickets", `{"title": "Second", "priority": 3}`)
+
+	if code, out := do(http.MethodPost, "/api/v1/projects/api/tickets", `{"title": "x", "priority": 7}`); code != http.StatusUnprocessableEntity ||
+		apiErrorCode(out) != "invalid_field" {
+		t.Errorf("bad priority = %d %v", code, out)
+	}
+	if code, out := do(http.MethodPost, "/api/v1/projects/api/tickets", `{"title": "x", "colour": "red"}`); code != http.StatusBadRequest {
+		t.Errorf("unknown field = %d %v", code, out)
+	}
+
+	code, out = do(http.MethodGet, "/api/v1/projects/api/tickets?limit=1", "")
+	items, _ := out["items"].([]interface{})
+	if code != http.StatusOK || len(items) != 1 || items[0].(map[string]interface{})["id"] != id || out["next_cursor"] == nil {
+		t.Fatalf("list page 1 = %d %v", code, out)
+	}
+	_, out = do(http.MethodGet, "/api/v1/projects/api/tickets?limit=1&cursor="+out["next_cursor"].(string), "")
+	if items, _ := out["items"].([]interface{}); len(items) != 1 || out["next_cursor"] != nil {
+		t.Errorf("list page 2 = %v", out)
+	}
+
+	code, out = do(http.MethodPatch, "/api/v1/projects/api/tickets/"+id, `{"status": "closed", "assignee": "sam"}`)
+	if code != http.StatusOK || out["status"] != "closed" || out["assignee"] != "sam" {
+		t.Fatalf("patch = %d %v", code, out)
+	}
+	_, out = do(http.MethodGet, "/api/v1/projects/api/tickets", "")
+	if items, _ := out["items"].([]interface{}); len(items) != 1 {
+		t.Errorf("closed ticket listed by default: %v", out)
+	}
+	_, out = do(http.MethodGet, "/api/v1/projects/api/tickets?status=closed", "")
+	if items, _ := out["items"].([]interface{}); len(items) != 1 || items[0].(map[string]interface{})["id"] != id {
+		t.Errorf("status filter = %v", out)
+	}
+	if code, out := do(http.MethodGet, "/api/v1/projects/api/tickets?status=shipped", ""); code != http.StatusUnprocessableEntity {
+		t.Errorf("bad status filter = %d %v", code, out)
+	}
+
+	code, out = do(http.MethodGet, "/api/v1/projects/api/tickets/"+id, "")
+	if code != http.StatusOK || out["title"] != "Fix login" {
+		t.Errorf("get = %d %v", code, out)
+	}
+}
+
+func TestAPITicketTemplates(t *testing.T) {
+	ticketsDir, do := apiTestServer(t)
+	templatesDir := filepath.Join(filepath.Dir(ticketsDir), "templates")
+	os.MkdirAll(templatesDir, 0755)
+	os.WriteFile(filepath.Join(templatesDir, "bug.md"),
+		[]byte("---\ndescription: Bug report\ntype: bug\npriority: 1\ntags: [bug]\n---\n## Steps to reproduce\n\n{{.description}}\n"), 0644)
+
+	code, out := do(http.MethodGet, "/api/v1/projects/api/templates", "")
+	items, _ := out["items"].([]interface{})
+	if code != http.StatusOK || len(items) != 1 || items[0].(map[string]interface{})["name"] != "bug" {
+		t.Fatalf("list = %d %v", code, out)
+	}
+
+	code, out = do(http.MethodPost, "/api/v1/projects/api/tickets",
+		`{"title": "Login 500s", "description": "POST /login", "template": "bug", "priority": 0}`)
+	if code != http.StatusCreated {
+		t.Fatalf("create = %d %v", code, out)
+	}
+	if out["type"] != "bug" || out["priority"] != float64(0) || !strings.Contains(out["body"].(string), "## Steps to reproduce\n\nPOST /login") {
+		t.Errorf("created = %v", out)
+	}
+	if tags, _ := out["tags"].([]interface{}); len(tags) != 1 || tags[0] != "bug" {
+		t.Errorf("tags = %v", out["tags"])
+	}
+
+	if code, out := do(http.MethodPost, "/api/v1/projects/api/tickets", `{"title": "x", "template": "../bug"}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("bad template = %d %v", code, out)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// ko archive retires closed tickets from the everyday views and ko rm
+// deletes tickets outright. The functions here pick what to archive and
+// rewire dependents, cmd_archive.go and cmd_rm.go do the I/O.
+
+// archiveCandidates returns the closed tickets last modified before the
+// given time, or all closed tickets if before is zero, sorted by ID.
+func archiveCandidates(tickets []*Ticket, before time.Time) []*Ticket {
+	var out []*Ticket
+	for _, t := range tickets {
+		if t.Status != "closed" {
+			continue
+		}
+		if !before.IsZero() && !t.ModTime.Before(before) {
+			continue
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// rewireDeps replaces the dep of t on removed with removed's own deps, so
+// whatever ordering removed imposed still holds once it is gone, and
+// returns what changed.
+func rewireDeps(t, removed *Ticket) []fieldChange {
+	if !contains(t.Deps, removed.ID) {
+		return nil
+	}
+	var deps []string
+	add := func(id string) {
+		if id != t.ID && id != removed.ID && !contains(deps, id) {
+			deps = append(deps, id)
+		}
+	}
+	for _, dep := range t.Deps {
+		if dep != removed.ID {
+			add(dep)
+			continue
+		}
+		for _, inherited := range removed.Deps {
+			add(inherited)
+		}
+	}
+	if deps == nil {
+		deps = []string{}
+	}
+	changes := []fieldChange{{"deps", t.Deps, deps}}
+	t.Deps = deps
+	return changes
+}
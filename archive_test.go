@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveCandidates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	tickets := []*Ticket{
+		{ID: "ko-a003", Status: "closed", ModTime: day(1)},
+		{ID: "ko-a001", Status: "closed", ModTime: day(20)},
+		{ID: "ko-a002", Status: "open", ModTime: day(1)},
+		{ID: "ko-a004", Status: "resolved", ModTime: day(1)},
+	}
+	ids := func(ts []*Ticket) string {
+		var out []string
+		for _, t := range ts {
+			out = append(out, t.ID)
+		}
+		return strings.Join(out, " ")
+	}
+	if got := ids(archiveCandidates(tickets, time.Time{})); got != "ko-a001 ko-a003" {
+		t.Errorf("all closed = %q", got)
+	}
+	if got := ids(archiveCandidates(tickets, day(10))); got != "ko-a003" {
+		t.Errorf("closed before = %q", got)
+	}
+}
+
+func TestRewireDeps(t *testing.T) {
+	removed := &Ticket{ID: "ko-a002", Deps: []string{"ko-a001", "ko-a003", "ko-a004"}}
+	dependent := &Ticket{ID: "ko-a004", Deps: []string{"ko-a003", "ko-a002", "ko-a005"}}
+	changes := rewireDeps(dependent, removed)
+	// ko-a003 is not repeated and ko-a004 does not depend on itself
+	if got := strings.Join(dependent.Deps, " "); got != "ko-a003 ko-a001 ko-a005" {
+		t.Errorf("deps = %q", got)
+	}
+	if len(changes) != 1 || changes[0].Field != "deps" {
+		t.Errorf("changes = %+v", changes)
+	}
+
+	leaf := &Ticket{ID: "ko-a006", Deps: []string{"ko-a002"}}
+	if rewireDeps(leaf, &Ticket{ID: "ko-a002"}); leaf.Deps == nil || len(leaf.Deps) != 0 {
+		t.Errorf("deps = %#v, want empty", leaf.Deps)
+	}
+	if c := rewireDeps(&Ticket{ID: "ko-a007", Deps: []string{"ko-a001"}}, removed); c != nil {
+		t.Errorf("unrelated ticket changed: %+v", c)
+	}
+}
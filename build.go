@@ -71,6 +71,17 @@ func RunBuild(ticketsDir string, t *Ticket, p *Pipeline, log *EventLogger, verbo
 		return OutcomeFail, fmt.Errorf("%s", msg)
 	}
 
+	// Scrub secrets from everything the build persists or streams
+	secrets, err := loadSecrets(ticketsDir)
+	if err != nil {
+		return OutcomeFail, err
+	}
+	redactor, err := NewPipelineRedactor(p, secrets)
+	if err != nil {
+		return OutcomeFail, err
+	}
+	SetRedactor(redactor)
+
 	// Ensure artifact directory exists (persists across builds and after close)
 	artifactDir, err := EnsureArtifactDir(ticketsDir, t.ID)
 	if err != nil {
@@ -92,14 +103,32 @@ func RunBuild(ticketsDir string, t *Ticket, p *Pipeline, log *EventLogger, verbo
 		return OutcomeFail, fmt.Errorf("failed to open build history: %v", err)
 	}
 	defer hist.Close()
+
+	// Capture every node attempt's stdout/stderr under builds/<n>/. This
+	// truncates the live log, so it comes before build_start: a stream
+	// that sees build_start never reads the previous build's output.
+	outs, err := StartBuildOutputs(artifactDir, p, hist)
+	if err != nil {
+		return OutcomeFail, fmt.Errorf("failed to create build output directory: %v", err)
+	}
+	defer outs.Close()
 	hist.BuildStart(t.ID)
 
 	// Mark ticket as in_progress
 	setStatus(ticketsDir, t, "in_progress")
 
+	// on_start runs before anything else (e.g. to create a branch); a
+	// failure aborts the build.
+	if err := runBuildHooks(ticketsDir, t, p, "main", HookOnStart, ChangeSet{}, nil, wsDir, log, hist); err != nil {
+		applyFailOutcome(ticketsDir, t, HookOnStart, err.Error())
+		finishBuild(ticketsDir, t, "fail", log, hist)
+		runFailHooks(ticketsDir, t, p, "main", wsDir, log, hist)
+		return OutcomeFail, nil
+	}
+
 	// Snapshot project files before stages run
 	projectRoot := ProjectRoot(ticketsDir)
-	beforeSnapshot := snapshotFiles(projectRoot)
+	beforeSnapshot := snapshotFiles(projectRoot, p.Ignore)
 
 	// Visit counters: node name -> visit count
 	visits := make(map[string]int)
@@ -107,36 +136,31 @@ func RunBuild(ticketsDir string, t *Ticket, p *Pipeline, log *EventLogger, verbo
 	// Execute starting from "main" workflow
 	log.WorkflowStart(t.ID, "main")
 	hist.WorkflowStart(t.ID, "main")
-	outcome, finalWorkflow, err := runWorkflow(ticketsDir, t, p, "main", visits, wsDir, artifactDir, log, hist, verbose)
+	outcome, finalWorkflow, err := runWorkflow(ticketsDir, t, p, "main", visits, wsDir, artifactDir, log, hist, outs, verbose)
 	if err != nil {
-		log.WorkflowComplete(t.ID, "fail")
-		hist.BuildComplete(t.ID, "fail")
 		applyFailOutcome(ticketsDir, t, "build", err.Error())
-		if hookErr := runHooks(ticketsDir, t, p.OnFail, "", wsDir, hist.Path()); hookErr != nil {
-			log.BuildError(t.ID, "on_fail_hook", hookErr.Error())
-			hist.BuildError(t.ID, "on_fail_hook", hookErr.Error())
-		}
+		finishBuild(ticketsDir, t, "fail", log, hist)
+		runFailHooks(ticketsDir, t, p, finalWorkflow, wsDir, log, hist)
 		return OutcomeFail, nil
 	}
 
 	if outcome == OutcomeFail {
-		log.WorkflowComplete(t.ID, "fail")
-		hist.BuildComplete(t.ID, "fail")
-		if hookErr := runHooks(ticketsDir, t, p.OnFail, "", wsDir, hist.Path()); hookErr != nil {
-			log.BuildError(t.ID, "on_fail_hook", hookErr.Error())
-			hist.BuildError(t.ID, "on_fail_hook", hookErr.Error())
-		}
+		finishBuild(ticketsDir, t, "fail", log, hist)
+		runFailHooks(ticketsDir, t, p, finalWorkflow, wsDir, log, hist)
 		return OutcomeFail, nil
 	}
 
 	if outcome != OutcomeSucceed {
-		log.WorkflowComplete(t.ID, outcomeString(outcome))
-		hist.BuildComplete(t.ID, outcomeString(outcome))
+		finishBuild(ticketsDir, t, outcomeString(outcome), log, hist)
+		if outcome == OutcomeBlocked {
+			runBuildHooks(ticketsDir, t, p, finalWorkflow, HookOnBlock, ChangeSet{},
+				blockHookEnv(t.blockReason, t.blockedOn), wsDir, log, hist)
+		}
 		return outcome, nil
 	}
 
 	// Compute changed files before close (close removes artifact dir)
-	changedFiles := computeChangedFiles(projectRoot, beforeSnapshot)
+	changes := computeChanges(projectRoot, p.Ignore, beforeSnapshot)
 
 	// Determine target status based on final workflow's on_success config
 	targetStatus := "resolved" // default: agent marks resolved, human closes
@@ -153,23 +177,24 @@ func RunBuild(ticketsDir string, t *Ticket, p *Pipeline, log *EventLogger, verbo
 	// hooks, the ticket should stay resolved/closed rather than being reset
 	// to open with committed but untracked changes.
 	AddNote(t, "ko: SUCCEED")
-	log.WorkflowComplete(t.ID, "succeed")
-	hist.BuildComplete(t.ID, "succeed")
+	finishBuild(ticketsDir, t, "succeed", log, hist)
 	setStatus(ticketsDir, t, targetStatus)
 
 	// Run on_succeed hooks (ticket already closed)
-	if err := runHooks(ticketsDir, t, p.OnSucceed, changedFiles, wsDir, hist.Path()); err != nil {
+	if err := runBuildHooks(ticketsDir, t, p, finalWorkflow, HookOnSucceed, changes, nil, wsDir, log, hist); err != nil {
 		// Reopen — the succeed hooks failed, so this isn't actually done
-		AddNote(t, fmt.Sprintf("ko: on_succeed failed — %s", err.Error()))
-		log.BuildError(t.ID, "on_succeed_hook", err.Error())
-		hist.BuildError(t.ID, "on_succeed_hook", err.Error())
+		reason := fmt.Sprintf("on_succeed failed — %s", err.Error())
+		AddNote(t, "ko: "+reason)
+		t.blockReason = reason
+		t.dispReason = reason
+		t.buildOutcome = "fail"
 		setStatus(ticketsDir, t, "blocked")
-		runHooks(ticketsDir, t, p.OnFail, "", wsDir, hist.Path()) // best-effort
+		runFailHooks(ticketsDir, t, p, finalWorkflow, wsDir, log, hist) // best-effort
 		return OutcomeFail, nil
 	}
 
 	// Run on_close hooks
-	runHooks(ticketsDir, t, p.OnClose, changedFiles, wsDir, hist.Path())
+	runBuildHooks(ticketsDir, t, p, finalWorkflow, HookOnClose, changes, nil, wsDir, log, hist)
 
 	return OutcomeSucceed, nil
 }
@@ -198,13 +223,22 @@ func writeBackNoteArtifacts(t *Ticket, p *Pipeline, finalWorkflow, artifactDir s
 }
 
 // runWorkflow executes a single workflow, following route dispositions to other
-// workflows. Returns the terminal outcome and the name of the final workflow that completed.
-func runWorkflow(ticketsDir string, t *Ticket, p *Pipeline, wfName string, visits map[string]int, wsDir, artifactDir string, log *EventLogger, hist *BuildHistoryLogger, verbose bool) (Outcome, string, error) {
+// workflows. Returns the terminal outcome and the name of the final workflow
+// that completed (or, on any other outcome, the workflow the build stopped in).
+func runWorkflow(ticketsDir string, t *Ticket, p *Pipeline, wfName string, visits map[string]int, wsDir, artifactDir string, log *EventLogger, hist *BuildHistoryLogger, outs *BuildOutputs, verbose bool) (Outcome, string, error) {
 	wf, ok := p.Workflows[wfName]
 	if !ok {
 		return OutcomeFail, "", fmt.Errorf("unknown workflow '%s'", wfName)
 	}
 
+	// nodeDone records a node's result and runs the on_node hooks.
+	nodeDone := func(node *Node, result string) {
+		log.NodeComplete(t.ID, wfName, node.Name, result)
+		hist.NodeComplete(t.ID, wfName, node.Name, result)
+		runBuildHooks(ticketsDir, t, p, wfName, HookOnNode, ChangeSet{},
+			nodeHookEnv(wsDir, wfName, node.Name, result), wsDir, log, hist)
+	}
+
 	for i := 0; i < len(wf.Nodes); i++ {
 		node := &wf.Nodes[i]
 
@@ -213,7 +247,7 @@ func runWorkflow(ticketsDir string, t *Ticket, p *Pipeline, wfName string, visit
 		if visits[node.Name] > node.MaxVisits {
 			applyFailOutcome(ticketsDir, t, node.Name,
 				fmt.Sprintf("node '%s' exceeded max_visits (%d)", node.Name, node.MaxVisits))
-			return OutcomeFail, "", nil
+			return OutcomeFail, wfName, nil
 		}
 
 		// Resolve overrides: node > workflow > pipeline
@@ -222,21 +256,31 @@ func runWorkflow(ticketsDir string, t *Ticket, p *Pipeline, wfName string, visit
 		allowedTools := resolveAllowedTools(p, wf, node)
 		timeout, err := resolveTimeout(p, node)
 		if err != nil {
-			log.NodeComplete(t.ID, wfName, node.Name, "error")
-			hist.NodeComplete(t.ID, wfName, node.Name, "error")
+			nodeDone(node, "error")
 			applyFailOutcome(ticketsDir, t, node.Name, fmt.Sprintf("invalid timeout: %v", err))
-			return OutcomeFail, "", nil
+			return OutcomeFail, wfName, nil
+		}
+		env, err := resolveNodeEnv(ticketsDir, p, wf, node)
+		if err != nil {
+			nodeDone(node, "error")
+			applyFailOutcome(ticketsDir, t, node.Name, fmt.Sprintf("invalid env: %v", err))
+			return OutcomeFail, wfName, nil
+		}
+		sb, err := resolveSandbox(p, node, ProjectRoot(ticketsDir), artifactDir)
+		if err != nil {
+			nodeDone(node, "error")
+			applyFailOutcome(ticketsDir, t, node.Name, fmt.Sprintf("invalid sandbox: %v", err))
+			return OutcomeFail, wfName, nil
 		}
 
 		// Execute the node
 		log.NodeStart(t.ID, wfName, node.Name)
 		hist.NodeStart(t.ID, wfName, node.Name)
-		output, err := runNode(ticketsDir, t, p, node, model, allowAll, allowedTools, timeout, wsDir, artifactDir, wfName, hist.Path(), verbose, log, hist)
+		output, err := runNode(ticketsDir, t, p, node, model, allowAll, allowedTools, timeout, env, sb, wsDir, artifactDir, wfName, hist.Path(), verbose, log, hist, outs)
 		if err != nil {
-			log.NodeComplete(t.ID, wfName, node.Name, "error")
-			hist.NodeComplete(t.ID, wfName, node.Name, "error")
+			nodeDone(node, "error")
 			applyFailOutcome(ticketsDir, t, node.Name, err.Error())
-			return OutcomeFail, "", nil
+			return OutcomeFail, wfName, nil
 		}
 
 		// Tee output to workspace
@@ -244,8 +288,7 @@ func runWorkflow(ticketsDir string, t *Ticket, p *Pipeline, wfName string, visit
 
 		// Action nodes: output isn't parsed, just continue
 		if node.Type == NodeAction {
-			log.NodeComplete(t.ID, wfName, node.Name, "done")
-			hist.NodeComplete(t.ID, wfName, node.Name, "done")
+			nodeDone(node, "done")
 			continue
 		}
 
@@ -253,19 +296,22 @@ func runWorkflow(ticketsDir string, t *Ticket, p *Pipeline, wfName string, visit
 		disp, err := extractDisposition(output)
 		if err != nil {
 			// Should not happen — retries already exhausted in runNode
-			log.NodeComplete(t.ID, wfName, node.Name, "error")
-			hist.NodeComplete(t.ID, wfName, node.Name, "error")
+			nodeDone(node, "error")
 			applyFailOutcome(ticketsDir, t, node.Name, err.Error())
-			return OutcomeFail, "", nil
+			return OutcomeFail, wfName, nil
 		}
-		log.NodeComplete(t.ID, wfName, node.Name, disp.Type)
-		hist.NodeComplete(t.ID, wfName, node.Name, disp.Type)
+		t.dispReason = disp.Reason
+		nodeDone(node, disp.Type)
 
-		outcome, finalWF, err := applyDisposition(ticketsDir, t, p, node, wfName, disp, visits, wsDir, artifactDir, log, hist, verbose)
+		outcome, finalWF, err := applyDisposition(ticketsDir, t, p, node, wfName, disp, visits, wsDir, artifactDir, log, hist, outs, verbose)
 		if err != nil {
-			return OutcomeFail, "", err
+			return OutcomeFail, wfName, err
 		}
 		if outcome != OutcomeSucceed {
+			// Report where the build stopped, for workflow hook overrides
+			if finalWF == "" {
+				finalWF = wfName
+			}
 			return outcome, finalWF, nil
 		}
 		// OutcomeSucceed from applyDisposition means "continue" — next node.
@@ -280,26 +326,40 @@ func runWorkflow(ticketsDir string, t *Ticket, p *Pipeline, wfName string, visit
 }
 
 // runNode executes a single node with retry logic.
-func runNode(ticketsDir string, t *Ticket, p *Pipeline, node *Node, model string, allowAll bool, allowedTools []string, timeout time.Duration, wsDir, artifactDir, wfName, histPath string, verbose bool, log *EventLogger, hist *BuildHistoryLogger) (string, error) {
+func runNode(ticketsDir string, t *Ticket, p *Pipeline, node *Node, model string, allowAll bool, allowedTools []string, timeout time.Duration, env []string, sb *sandboxConfig, wsDir, artifactDir, wfName, histPath string, verbose bool, log *EventLogger, hist *BuildHistoryLogger, outs *BuildOutputs) (string, error) {
 	maxAttempts := p.MaxRetries + 1
 
+	// Action nodes with a write scope are checked against the state before
+	// their first attempt; a violation is not retried (see enforceScope).
+	projectRoot := ProjectRoot(ticketsDir)
+	scopeBefore := takeScopeSnapshot(projectRoot, artifactDir, t, node, p)
+	defer scopeBefore.Close()
+
 	for attempt := 0; attempt < maxAttempts; attempt++ {
 		var output string
 		var err error
+		captured := outs.NewAttempt(node.Name)
 
 		if node.IsPromptNode() {
-			output, err = runPromptNode(ticketsDir, t, p, node, model, allowAll, allowedTools, timeout, wsDir, artifactDir, wfName, histPath, verbose)
+			output, err = runPromptNode(ticketsDir, t, p, node, model, allowAll, allowedTools, timeout, env, sb, wsDir, artifactDir, wfName, histPath, verbose, captured)
 		} else if node.IsRunNode() {
-			output, err = runRunNode(node, timeout, wsDir, artifactDir, histPath, wfName, verbose)
+			output, err = runRunNode(node, timeout, env, sb, wsDir, artifactDir, histPath, wfName, verbose, captured)
 		} else {
 			return "", fmt.Errorf("node '%s' has neither prompt nor run", node.Name)
 		}
+		outs.Record(node.Name, captured)
+		if err == nil && scopeBefore != nil {
+			err = enforceScope(projectRoot, t, node, p, scopeBefore)
+		}
 
 		if err != nil {
 			// Emit node_fail event with attempt number (1-indexed)
 			log.NodeFail(t.ID, wfName, node.Name, err.Error(), attempt+1)
 			hist.NodeFail(t.ID, wfName, node.Name, err.Error(), attempt+1)
 
+			if _, ok := err.(*scopeViolationError); ok {
+				return "", fmt.Errorf("node '%s' failed: %v", node.Name, err)
+			}
 			if attempt+1 < maxAttempts {
 				// Emit node_retry event with next attempt number
 				log.NodeRetry(t.ID, wfName, node.Name, attempt+2)
@@ -343,7 +403,7 @@ func extractDisposition(output string) (Disposition, error) {
 
 // applyDisposition handles a parsed disposition from a decision node.
 // Returns OutcomeSucceed for "continue" (advance to next node).
-func applyDisposition(ticketsDir string, t *Ticket, p *Pipeline, node *Node, currentWF string, disp Disposition, visits map[string]int, wsDir, artifactDir string, log *EventLogger, hist *BuildHistoryLogger, verbose bool) (Outcome, string, error) {
+func applyDisposition(ticketsDir string, t *Ticket, p *Pipeline, node *Node, currentWF string, disp Disposition, visits map[string]int, wsDir, artifactDir string, log *EventLogger, hist *BuildHistoryLogger, outs *BuildOutputs, verbose bool) (Outcome, string, error) {
 	switch disp.Type {
 	case "continue":
 		return OutcomeSucceed, "", nil
@@ -357,7 +417,12 @@ func applyDisposition(ticketsDir string, t *Ticket, p *Pipeline, node *Node, cur
 		return outcome, "", err
 
 	case "decompose":
-		outcome, err := applyDecomposeDisposition(ticketsDir, t, p, node, disp)
+		outcome, childIDs, err := applyDecomposeDisposition(ticketsDir, t, p, node, disp)
+		if outcome == OutcomeDecompose {
+			t.buildOutcome = outcomeString(outcome)
+			runBuildHooks(ticketsDir, t, p, currentWF, HookOnDecompose, ChangeSet{},
+				decomposeHookEnv(childIDs), wsDir, log, hist)
+		}
 		return outcome, "", err
 
 	case "route":
@@ -369,10 +434,11 @@ func applyDisposition(ticketsDir string, t *Ticket, p *Pipeline, node *Node, cur
 		}
 		log.WorkflowStart(t.ID, disp.Workflow)
 		hist.WorkflowStart(t.ID, disp.Workflow)
-		return runWorkflow(ticketsDir, t, p, disp.Workflow, visits, wsDir, artifactDir, log, hist, verbose)
+		return runWorkflow(ticketsDir, t, p, disp.Workflow, visits, wsDir, artifactDir, log, hist, outs, verbose)
 
 	case "needs_input":
 		t.PlanQuestions = disp.PlanQuestions
+		t.blockReason = fmt.Sprintf("needs input: %d plan questions at node '%s'", len(disp.PlanQuestions), node.Name)
 		setStatus(ticketsDir, t, "blocked")
 		return OutcomeFail, "", nil
 
@@ -401,23 +467,27 @@ func applyBlockedDisposition(ticketsDir string, t *Ticket, node *Node, disp Disp
 			return OutcomeFail, nil
 		}
 		t.Deps = append(t.Deps, blockID)
+		t.blockedOn = blockID
 	}
 	note := fmt.Sprintf("ko: BLOCKED at node '%s'", node.Name)
 	if disp.Reason != "" {
 		note += " — " + disp.Reason
 	}
+	t.blockReason = strings.TrimPrefix(note, "ko: ")
 	AddNote(t, note)
 	setStatus(ticketsDir, t, "open")
 	return OutcomeBlocked, nil
 }
 
 // applyDecomposeDisposition creates child tickets and blocks the parent.
-func applyDecomposeDisposition(ticketsDir string, t *Ticket, p *Pipeline, node *Node, disp Disposition) (Outcome, error) {
+// Returns the IDs of the children it created.
+func applyDecomposeDisposition(ticketsDir string, t *Ticket, p *Pipeline, node *Node, disp Disposition) (Outcome, []string, error) {
 	depth := Depth(t.ID)
 	if depth >= p.MaxDepth {
-		AddNote(t, fmt.Sprintf("ko: DECOMPOSE denied — max decomposition depth (%d) reached", p.MaxDepth))
+		t.blockReason = fmt.Sprintf("DECOMPOSE denied — max decomposition depth (%d) reached", p.MaxDepth)
+		AddNote(t, "ko: "+t.blockReason)
 		setStatus(ticketsDir, t, "blocked")
-		return OutcomeFail, nil
+		return OutcomeFail, nil, nil
 	}
 
 	var childIDs []string
@@ -425,7 +495,7 @@ func applyDecomposeDisposition(ticketsDir string, t *Ticket, p *Pipeline, node *
 		child := NewChildTicket(t.ID, subtask)
 		if err := SaveTicket(ticketsDir, child); err != nil {
 			applyFailOutcome(ticketsDir, t, node.Name, "failed to create child ticket: "+err.Error())
-			return OutcomeFail, nil
+			return OutcomeFail, nil, nil
 		}
 		childIDs = append(childIDs, child.ID)
 	}
@@ -434,11 +504,11 @@ func applyDecomposeDisposition(ticketsDir string, t *Ticket, p *Pipeline, node *
 	AddNote(t, fmt.Sprintf("ko: DECOMPOSE — created %d children: %s", len(childIDs), strings.Join(childIDs, ", ")))
 	setStatus(ticketsDir, t, "open")
 
-	return OutcomeDecompose, nil
+	return OutcomeDecompose, childIDs, nil
 }
 
 // runPromptNode invokes the configured command with ticket context.
-func runPromptNode(ticketsDir string, t *Ticket, p *Pipeline, node *Node, model string, allowAll bool, allowedTools []string, timeout time.Duration, wsDir, artifactDir, wfName, histPath string, verbose bool) (string, error) {
+func runPromptNode(ticketsDir string, t *Ticket, p *Pipeline, node *Node, model string, allowAll bool, allowedTools []string, timeout time.Duration, env []string, sb *sandboxConfig, wsDir, artifactDir, wfName, histPath string, verbose bool, captured *attemptOutput) (string, error) {
 	// Skill invocation is not yet supported by Claude adapter
 	// This will be implemented in ko-1930 (multi-agent harness)
 	if node.Skill != "" {
@@ -508,40 +578,41 @@ func runPromptNode(ticketsDir string, t *Ticket, p *Pipeline, node *Node, model
 	// cmdArgs[0] is the program name, cmdArgs[1:] are the arguments
 	cmdCtx := exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
 	cmdCtx.Stdin = cmd.Stdin
-	cmdCtx.Stdout = cmd.Stdout
-	cmdCtx.Stderr = cmd.Stderr
 	// Preserve any Env set by the adapter, then add our workspace vars
-	if cmd.Env != nil {
-		cmdCtx.Env = append(cmd.Env,
-			"KO_TICKET_WORKSPACE="+wsDir,
-			"KO_ARTIFACT_DIR="+artifactDir,
-			"KO_BUILD_HISTORY="+histPath,
-		)
-	} else {
-		cmdCtx.Env = append(os.Environ(),
-			"KO_TICKET_WORKSPACE="+wsDir,
-			"KO_ARTIFACT_DIR="+artifactDir,
-			"KO_BUILD_HISTORY="+histPath,
-		)
+	cmdCtx.Env = cmd.Env
+	if cmdCtx.Env == nil {
+		cmdCtx.Env = os.Environ()
 	}
+	cmdCtx.Env = append(cmdCtx.Env,
+		"KO_TICKET_WORKSPACE="+wsDir,
+		"KO_ARTIFACT_DIR="+artifactDir,
+		"KO_BUILD_HISTORY="+histPath,
+	)
+	// Node env comes last so it wins over inherited values
+	cmdCtx.Env = append(cmdCtx.Env, env...)
 	cmdCtx.Dir = ProjectRoot(ticketsDir)
+	stop, err := sandboxCommand(cmdCtx, sb)
+	if err != nil {
+		return "", err
+	}
+	defer stop()
 
 	if verbose {
-		return runCmdVerbose(cmdCtx, wfName, node.Name)
+		return runCmdVerbose(cmdCtx, wfName, node.Name, captured)
 	}
 
-	out, err := cmdCtx.Output()
-	if err != nil {
+	cmdCtx.Stdout, cmdCtx.Stderr = captured.writers()
+	if err := cmdCtx.Run(); err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			return "", fmt.Errorf("step timed out after %v", timeout)
 		}
 		return "", fmt.Errorf("agent command failed: %v", err)
 	}
-	return string(out), nil
+	return captured.stdout.String(), nil
 }
 
 // runRunNode executes a shell command.
-func runRunNode(node *Node, timeout time.Duration, wsDir, artifactDir, histPath, wfName string, verbose bool) (string, error) {
+func runRunNode(node *Node, timeout time.Duration, env []string, sb *sandboxConfig, wsDir, artifactDir, histPath, wfName string, verbose bool, captured *attemptOutput) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
@@ -562,28 +633,33 @@ func runRunNode(node *Node, timeout time.Duration, wsDir, artifactDir, histPath,
 		"KO_ARTIFACT_DIR="+artifactDir,
 		"KO_BUILD_HISTORY="+histPath,
 	)
+	cmd.Env = append(cmd.Env, env...)
+	stop, err := sandboxCommand(cmd, sb)
+	if err != nil {
+		return "", err
+	}
+	defer stop()
 
 	if verbose {
-		return runCmdVerbose(cmd, wfName, node.Name)
+		return runCmdVerbose(cmd, wfName, node.Name, captured)
 	}
 
-	out, err := cmd.CombinedOutput()
-	if err != nil {
+	cmd.Stdout, cmd.Stderr = captured.writers()
+	if err := cmd.Run(); err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			return "", fmt.Errorf("step timed out after %v", timeout)
 		}
-		return "", fmt.Errorf("command failed: %s\n%s", err, string(out))
+		return "", fmt.Errorf("command failed: %s\n%s", err, captured.combined.String())
 	}
-	return string(out), nil
+	return captured.combined.String(), nil
 }
 
 // runCmdVerbose runs a command, streaming its stdout/stderr to the terminal
-// with a "[node] " prefix on each line, while also capturing the full stdout
-// output for return.
-func runCmdVerbose(cmd *exec.Cmd, wfName, nodeName string) (string, error) {
+// with a "[node] " prefix on each line, while also capturing both streams
+// into captured. Returns the full stdout output.
+func runCmdVerbose(cmd *exec.Cmd, wfName, nodeName string, captured *attemptOutput) (string, error) {
 	prefix := fmt.Sprintf("[%s] ", nodeName)
-
-	var capture bytes.Buffer
+	stdoutW, stderrW := captured.writers()
 
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
@@ -602,12 +678,12 @@ func runCmdVerbose(cmd *exec.Cmd, wfName, nodeName string) (string, error) {
 	// Stream stderr with prefix in background
 	done := make(chan struct{})
 	go func() {
-		streamPrefixed(stderrPipe, os.Stderr, prefix)
+		streamPrefixed(io.TeeReader(stderrPipe, stderrW), os.Stderr, prefix)
 		done <- struct{}{}
 	}()
 
 	// Stream stdout with prefix, tee to capture buffer
-	tee := io.TeeReader(stdoutPipe, &capture)
+	tee := io.TeeReader(stdoutPipe, stdoutW)
 	streamPrefixed(tee, os.Stdout, prefix)
 
 	<-done // wait for stderr goroutine
@@ -616,7 +692,7 @@ func runCmdVerbose(cmd *exec.Cmd, wfName, nodeName string) (string, error) {
 		return "", fmt.Errorf("command failed: %v", err)
 	}
 
-	return capture.String(), nil
+	return captured.stdout.String(), nil
 }
 
 // streamPrefixed reads from r line by line and writes each line to w
@@ -626,7 +702,7 @@ func streamPrefixed(r io.Reader, w io.Writer, prefix string) {
 	for {
 		line, isPrefix, err := br.ReadLine()
 		if len(line) > 0 {
-			fmt.Fprintf(w, "%s%s", prefix, string(line))
+			fmt.Fprintf(w, "%s%s", prefix, redact(string(line)))
 			if !isPrefix {
 				fmt.Fprintln(w)
 			}
@@ -639,7 +715,7 @@ func streamPrefixed(r io.Reader, w io.Writer, prefix string) {
 		for isPrefix {
 			line, isPrefix, err = br.ReadLine()
 			if len(line) > 0 {
-				fmt.Fprint(w, string(line))
+				fmt.Fprint(w, redact(string(line)))
 				if !isPrefix {
 					fmt.Fprintln(w)
 				}
@@ -714,94 +790,11 @@ func applyFailOutcome(ticketsDir string, t *Ticket, nodeName, reason string) {
 		note += " — " + reason
 	}
 	AddNote(t, note)
+	t.blockReason = strings.TrimPrefix(note, "ko: ")
+	t.dispReason = reason
 	setStatus(ticketsDir, t, "blocked")
 }
 
-// runHooks executes a list of shell commands with env vars set.
-func runHooks(ticketsDir string, t *Ticket, hooks []string, changedFiles, wsDir, histPath string) error {
-	if len(hooks) == 0 {
-		return nil
-	}
-
-	projectRoot := ProjectRoot(ticketsDir)
-
-	for _, hook := range hooks {
-		expanded := os.Expand(hook, func(key string) string {
-			switch key {
-			case "TICKET_ID":
-				return t.ID
-			case "TICKET_TITLE":
-				return t.Title
-			case "CHANGED_FILES":
-				return changedFiles
-			default:
-				return os.Getenv(key)
-			}
-		})
-
-		cmd := exec.Command("sh", "-c", expanded)
-		cmd.Dir = projectRoot
-		cmd.Env = append(os.Environ(),
-			"TICKET_ID="+t.ID,
-			"TICKET_TITLE="+t.Title,
-			"CHANGED_FILES="+changedFiles,
-			"KO_TICKET_WORKSPACE="+wsDir,
-			"KO_ARTIFACT_DIR="+ArtifactDir(ticketsDir, t.ID),
-			"KO_BUILD_HISTORY="+histPath,
-		)
-
-		if out, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("hook '%s' failed: %v\n%s", hook, err, string(out))
-		}
-	}
-	return nil
-}
-
-
-// fileSnapshot records mod times for files in a project directory.
-type fileSnapshot map[string]int64
-
-func snapshotFiles(projectRoot string) fileSnapshot {
-	snap := make(fileSnapshot)
-	filepath.Walk(projectRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if info.IsDir() {
-			base := filepath.Base(path)
-			if base == ".ko" || base == ".git" {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-		rel, err := filepath.Rel(projectRoot, path)
-		if err != nil {
-			return nil
-		}
-		snap[rel] = info.ModTime().UnixNano()
-		return nil
-	})
-	return snap
-}
-
-func changedFilesList(before, after fileSnapshot) []string {
-	var changed []string
-	for path, modTime := range after {
-		prevMod, existed := before[path]
-		if !existed || modTime != prevMod {
-			changed = append(changed, path)
-		}
-	}
-	sortStrings(changed)
-	return changed
-}
-
-func computeChangedFiles(projectRoot string, before fileSnapshot) string {
-	after := snapshotFiles(projectRoot)
-	changed := changedFilesList(before, after)
-	return strings.Join(changed, "\n")
-}
-
 // outcomeString returns a string representation of an Outcome.
 func outcomeString(o Outcome) string {
 	switch o {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -22,6 +23,7 @@ type BuildHistoryLogger struct {
 	path       string
 	ticketID   string // user-visible ticket ID for shadow writes
 	ticketsDir string // tickets directory for shadow writes
+	buildID    int64  // shadow DB builds.id for the current build (0 if none)
 }
 
 // OpenBuildHistory opens (or creates) the build history file for a ticket.
@@ -51,6 +53,7 @@ func (h *BuildHistoryLogger) emit(fields map[string]interface{}) {
 	if h.file == nil {
 		return
 	}
+	redactFields(fields)
 	ts := time.Now().UTC().Format(time.RFC3339)
 	fields["ts"] = ts
 	data, err := json.Marshal(fields)
@@ -66,14 +69,40 @@ func (h *BuildHistoryLogger) emit(fields map[string]interface{}) {
 	// Shadow write to SQLite.
 	if db := getShadowDB(); db != nil {
 		eventType, _ := fields["event"].(string)
-		prefix := extractPrefix(h.ticketID)
-		uuid := ticketUUID(prefix, h.ticketID)
-		db.InsertBuildEvent(uuid, eventType, ts, string(data))
+		uuid := h.ticketUUID()
+		db.InsertBuildEvent(h.buildID, uuid, eventType, ts, string(data))
 	}
 }
 
-// BuildStart records the beginning of a build.
+// ticketUUID returns the shadow DB key for the logger's ticket.
+func (h *BuildHistoryLogger) ticketUUID() string {
+	return ticketUUID(extractPrefix(h.ticketID), h.ticketID)
+}
+
+// RecordArtifact indexes a captured build artifact in the shadow DB.
+// Best-effort: skipped when no build row was opened.
+func (h *BuildHistoryLogger) RecordArtifact(node, path, content string) {
+	if h == nil || h.buildID == 0 {
+		return
+	}
+	if db := getShadowDB(); db != nil {
+		ts := time.Now().UTC().Format(time.RFC3339)
+		if err := db.InsertBuildArtifact(h.buildID, node, path, content, ts); err != nil {
+			fmt.Fprintf(os.Stderr, "ko: shadow write artifact: %v\n", err)
+		}
+	}
+}
+
+// BuildStart records the beginning of a build and opens its shadow DB row.
 func (h *BuildHistoryLogger) BuildStart(ticket string) {
+	if db := getShadowDB(); db != nil {
+		ts := time.Now().UTC().Format(time.RFC3339)
+		if id, err := db.InsertBuild(h.ticketUUID(), "main", ts); err == nil {
+			h.buildID = id
+		} else {
+			fmt.Fprintf(os.Stderr, "ko: shadow write build: %v\n", err)
+		}
+	}
 	h.emit(map[string]interface{}{
 		"event":  "build_start",
 		"ticket": ticket,
@@ -87,6 +116,12 @@ func (h *BuildHistoryLogger) BuildComplete(ticket, outcome string) {
 		"ticket":  ticket,
 		"outcome": outcome,
 	})
+	if db := getShadowDB(); db != nil && h.buildID != 0 {
+		ts := time.Now().UTC().Format(time.RFC3339)
+		if err := db.CompleteBuild(h.buildID, outcome, ts); err != nil {
+			fmt.Fprintf(os.Stderr, "ko: shadow write build: %v\n", err)
+		}
+	}
 }
 
 // NodeStart records a node beginning execution.
@@ -151,3 +186,18 @@ func (h *BuildHistoryLogger) BuildError(ticket, stage, reason string) {
 		"reason": reason,
 	})
 }
+
+// HookRun records one lifecycle hook command: its status (ok, failed,
+// timeout or skipped), exit code, duration and combined output.
+func (h *BuildHistoryLogger) HookRun(ticket, hook, command, status string, exitCode int, duration time.Duration, output string) {
+	h.emit(map[string]interface{}{
+		"event":       "hook_run",
+		"ticket":      ticket,
+		"hook":        hook,
+		"command":     command,
+		"status":      status,
+		"exit_code":   exitCode,
+		"duration_ms": duration.Milliseconds(),
+		"output":      output,
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// liveLogName is the file in a ticket's artifact directory that holds the
+// running build's output, one "[node] " prefixed line at a time, the same
+// text runCmdVerbose prints. It is truncated when a build starts, and
+// ko serve tails it for /builds/<id>/stream.
+const liveLogName = "live.log"
+
+// LiveLogPath returns the live output path for a ticket's artifact dir.
+func LiveLogPath(artifactDir string) string {
+	return filepath.Join(artifactDir, liveLogName)
+}
+
+// liveLog is the open live output file of one build. Writers from every
+// stream share it, so whole lines are written under a lock.
+type liveLog struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// openLiveLog truncates and opens the live log for a new build.
+func openLiveLog(artifactDir string) (*liveLog, error) {
+	f, err := os.OpenFile(LiveLogPath(artifactDir), os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &liveLog{f: f}, nil
+}
+
+func (l *liveLog) writeLine(line []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.f.Write(line)
+}
+
+func (l *liveLog) Close() {
+	if l != nil {
+		l.f.Close()
+	}
+}
+
+// liveLineWriter buffers one stream's prefixed output and forwards it to
+// the live log a whole line at a time, so stdout and stderr lines never
+// interleave mid-line.
+type liveLineWriter struct {
+	log  *liveLog
+	line []byte
+}
+
+func (w *liveLineWriter) Write(p []byte) (int, error) {
+	w.line = append(w.line, p...)
+	if i := bytes.LastIndexByte(w.line, '\n'); i >= 0 {
+		w.log.writeLine(w.line[:i+1])
+		w.line = append(w.line[:0], w.line[i+1:]...)
+	}
+	return len(p), nil
+}
+
+// flush writes a trailing line that had no newline.
+func (w *liveLineWriter) flush() {
+	if len(w.line) > 0 {
+		w.log.writeLine(append(w.line, '\n'))
+		w.line = nil
+	}
+}
+
+// liveStreams feeds a node attempt's stdout and stderr into the live log
+// through streamPrefixed, which prefixes and redacts each line.
+type liveStreams struct {
+	log    *liveLog
+	prefix string
+	pipes  []*io.PipeWriter
+	wg     sync.WaitGroup
+}
+
+// writer returns a writer for one stream of the attempt.
+func (s *liveStreams) writer() io.Writer {
+	pr, pw := io.Pipe()
+	s.pipes = append(s.pipes, pw)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		lw := &liveLineWriter{log: s.log}
+		streamPrefixed(pr, lw, s.prefix)
+		lw.flush()
+		io.Copy(io.Discard, pr) // never block the command on a reader error
+	}()
+	return pw
+}
+
+// close waits until everything written so far has reached the live log.
+func (s *liveStreams) close() {
+	if s == nil {
+		return
+	}
+	for _, pw := range s.pipes {
+		pw.Close()
+	}
+	s.wg.Wait()
+	s.pipes = nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLiveLogPrefixesWholeLines(t *testing.T) {
+	artifactDir := t.TempDir()
+	outs, err := StartBuildOutputs(artifactDir, &Pipeline{}, &BuildHistoryLogger{})
+	if err != nil {
+		t.Fatalf("StartBuildOutputs: %v", err)
+	}
+	defer outs.Close()
+
+	out := outs.NewAttempt("implement")
+	stdout, stderr := out.writers()
+	stdout.Write([]byte("compil"))
+	stderr.Write([]byte("warning: unused\n"))
+	stdout.Write([]byte("ing\nno trailing newline"))
+	outs.Record("implement", out)
+
+	data, _ := os.ReadFile(LiveLogPath(artifactDir))
+	want := "[implement] warning: unused\n[implement] compiling\n[implement] no trailing newline\n"
+	if string(data) != want {
+		t.Errorf("live log = %q, want %q", data, want)
+	}
+	// The capture itself is unprefixed
+	if got := out.stdout.String(); got != "compiling\nno trailing newline" {
+		t.Errorf("stdout = %q", got)
+	}
+
+	// The next build starts with an empty live log
+	outs2, err := StartBuildOutputs(artifactDir, &Pipeline{}, &BuildHistoryLogger{})
+	if err != nil {
+		t.Fatalf("StartBuildOutputs: %v", err)
+	}
+	defer outs2.Close()
+	if data, _ := os.ReadFile(LiveLogPath(artifactDir)); len(data) != 0 {
+		t.Errorf("live log not truncated: %q", data)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default retention for per-attempt build output.
+const (
+	defaultKeepBuilds     = 10      // build directories kept per ticket
+	defaultMaxOutputBytes = 1 << 20 // bytes kept per stream per attempt
+)
+
+// BuildsDir returns the directory holding per-build output captures.
+// Layout: <id>.artifacts/builds/<build-n>/<node>.<attempt>.{out,err}
+func BuildsDir(artifactDir string) string {
+	return filepath.Join(artifactDir, "builds")
+}
+
+// BuildOutputDir returns the output directory for build number n.
+func BuildOutputDir(artifactDir string, n int) string {
+	return filepath.Join(BuildsDir(artifactDir), strconv.Itoa(n))
+}
+
+// ListBuildNumbers returns the build numbers present under the builds
+// directory, ascending. Non-numeric entries are ignored.
+func ListBuildNumbers(artifactDir string) []int {
+	entries, err := os.ReadDir(BuildsDir(artifactDir))
+	if err != nil {
+		return nil
+	}
+	var nums []int
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		n, err := strconv.Atoi(e.Name())
+		if err != nil || n < 1 {
+			continue
+		}
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// buildsToPrune decides which builds fall outside the retention window.
+// existing must be ascending; keep <= 0 means keep everything.
+func buildsToPrune(existing []int, keep int) []int {
+	if keep <= 0 || len(existing) <= keep {
+		return nil
+	}
+	return existing[:len(existing)-keep]
+}
+
+// truncateOutput caps s at max bytes, appending a marker noting how much
+// was dropped. max <= 0 disables truncation.
+func truncateOutput(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	return s[:max] + fmt.Sprintf("\n[ko: output truncated, %d bytes dropped]\n", len(s)-max)
+}
+
+// attemptOutputName returns the capture filename for one stream of a node
+// attempt, e.g. "implement.2.err".
+func attemptOutputName(node string, attempt int, stream string) string {
+	return fmt.Sprintf("%s.%d.%s", node, attempt, stream)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writers. Stdout and
+// stderr are copied by separate goroutines but both feed the combined stream.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// attemptOutput collects the streams of a single node attempt. Combined
+// interleaves both streams in arrival order, matching CombinedOutput.
+type attemptOutput struct {
+	stdout   syncBuffer
+	stderr   syncBuffer
+	combined syncBuffer
+	live     *liveStreams // also copied to the live log when set
+}
+
+// writers returns the stdout and stderr writers to attach to a command.
+func (o *attemptOutput) writers() (io.Writer, io.Writer) {
+	if o.live != nil {
+		return io.MultiWriter(&o.stdout, &o.combined, o.live.writer()),
+			io.MultiWriter(&o.stderr, &o.combined, o.live.writer())
+	}
+	return io.MultiWriter(&o.stdout, &o.combined), io.MultiWriter(&o.stderr, &o.combined)
+}
+
+// BuildOutputs records every node attempt of one build to disk and indexes
+// each capture in the shadow DB via the build history logger.
+type BuildOutputs struct {
+	dir      string
+	maxBytes int
+	hist     *BuildHistoryLogger
+	attempts map[string]int // node name -> attempts recorded this build
+	live     *liveLog       // nil when the live log could not be opened
+}
+
+// StartBuildOutputs allocates the next build directory for a ticket and
+// prunes older builds beyond the pipeline's keep_builds setting.
+func StartBuildOutputs(artifactDir string, p *Pipeline, hist *BuildHistoryLogger) (*BuildOutputs, error) {
+	existing := ListBuildNumbers(artifactDir)
+	n := 1
+	if len(existing) > 0 {
+		n = existing[len(existing)-1] + 1
+	}
+	dir := BuildOutputDir(artifactDir, n)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	for _, old := range buildsToPrune(append(existing, n), p.KeepBuilds) {
+		os.RemoveAll(BuildOutputDir(artifactDir, old))
+	}
+	// Best-effort: a build without a live log only loses /builds/<id>/stream.
+	live, _ := openLiveLog(artifactDir)
+	return &BuildOutputs{
+		dir:      dir,
+		maxBytes: p.MaxOutputBytes,
+		hist:     hist,
+		attempts: make(map[string]int),
+		live:     live,
+	}, nil
+}
+
+// Dir returns the output directory for this build.
+func (b *BuildOutputs) Dir() string {
+	return b.dir
+}
+
+// Close closes the live log.
+func (b *BuildOutputs) Close() {
+	if b != nil {
+		b.live.Close()
+	}
+}
+
+// NewAttempt returns the capture for a node's next attempt. Its output is
+// also appended to the live log, line by line, as it arrives.
+func (b *BuildOutputs) NewAttempt(node string) *attemptOutput {
+	if b == nil || b.live == nil {
+		return &attemptOutput{}
+	}
+	return &attemptOutput{live: &liveStreams{log: b.live, prefix: fmt.Sprintf("[%s] ", node)}}
+}
+
+// Record writes one attempt's stdout and stderr. Attempts are numbered per
+// node across the whole build, so revisits and retries never collide.
+func (b *BuildOutputs) Record(node string, out *attemptOutput) {
+	if b == nil || out == nil {
+		return
+	}
+	out.live.close()
+	b.attempts[node]++
+	attempt := b.attempts[node]
+	for _, s := range []struct {
+		stream  string
+		content string
+	}{
+		{"out", out.stdout.String()},
+		{"err", out.stderr.String()},
+	} {
+		content := truncateOutput(redact(s.content), b.maxBytes)
+		path := filepath.Join(b.dir, attemptOutputName(node, attempt, s.stream))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			continue
+		}
+		b.hist.RecordArtifact(node, path, content)
+	}
+}
+
+// BuildOutputFile is a single captured stream on disk.
+type BuildOutputFile struct {
+	Name string
+	Path string
+	Node string
+}
+
+// ListBuildOutputs returns the captures in a build directory in the order
+// they were written, optionally restricted to a single node.
+func ListBuildOutputs(dir, node string) ([]BuildOutputFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type entry struct {
+		file    BuildOutputFile
+		attempt int
+		mtime   int64
+	}
+	var files []entry
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		nodeName, attempt, ok := parseOutputName(name)
+		if !ok || (node != "" && nodeName != node) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, entry{
+			file:    BuildOutputFile{Name: name, Path: filepath.Join(dir, name), Node: nodeName},
+			attempt: attempt,
+			mtime:   info.ModTime().UnixNano(),
+		})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if a.mtime != b.mtime {
+			return a.mtime < b.mtime
+		}
+		if a.attempt != b.attempt {
+			return a.attempt < b.attempt
+		}
+		if a.file.Node != b.file.Node {
+			return a.file.Node < b.file.Node
+		}
+		return a.file.Name > b.file.Name // .out before .err
+	})
+	result := make([]BuildOutputFile, len(files))
+	for i, f := range files {
+		result[i] = f.file
+	}
+	return result, nil
+}
+
+// parseOutputName splits "<node>.<attempt>.<out|err>" into node and attempt.
+func parseOutputName(name string) (string, int, bool) {
+	var stream string
+	switch {
+	case strings.HasSuffix(name, ".out"):
+		stream = ".out"
+	case strings.HasSuffix(name, ".err"):
+		stream = ".err"
+	default:
+		return "", 0, false
+	}
+	rest := strings.TrimSuffix(name, stream)
+	dot := strings.LastIndex(rest, ".")
+	if dot <= 0 {
+		return "", 0, false
+	}
+	attempt, err := strconv.Atoi(rest[dot+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return rest[:dot], attempt, true
+}
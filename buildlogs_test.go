@@ -0,0 +1,183 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildsToPrune(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []int
+		keep     int
+		want     []int
+	}{
+		{"under limit", []int{1, 2}, 3, nil},
+		{"at limit", []int{1, 2, 3}, 3, nil},
+		{"over limit", []int{1, 2, 3, 4, 5}, 2, []int{1, 2, 3}},
+		{"keep zero keeps all", []int{1, 2, 3}, 0, nil},
+		{"gaps are pruned oldest first", []int{2, 5, 9}, 1, []int{2, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildsToPrune(tt.existing, tt.keep)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildsToPrune(%v, %d) = %v, want %v", tt.existing, tt.keep, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	if got := truncateOutput("short", 10); got != "short" {
+		t.Errorf("under limit changed: %q", got)
+	}
+	if got := truncateOutput("anything", 0); got != "anything" {
+		t.Errorf("zero limit should not truncate: %q", got)
+	}
+	got := truncateOutput("0123456789", 4)
+	if !strings.HasPrefix(got, "0123") || !strings.Contains(got, "6 bytes dropped") {
+		t.Errorf("truncateOutput = %q", got)
+	}
+}
+
+func TestParseOutputName(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    string
+		attempt int
+		ok      bool
+	}{
+		{"implement.1.out", "implement", 1, true},
+		{"verify.12.err", "verify", 12, true},
+		{"my.node.3.out", "my.node", 3, true},
+		{"implement.out", "", 0, false},
+		{"implement.x.out", "", 0, false},
+		{"main.implement.md", "", 0, false},
+	}
+	for _, tt := range tests {
+		node, attempt, ok := parseOutputName(tt.name)
+		if node != tt.node || attempt != tt.attempt || ok != tt.ok {
+			t.Errorf("parseOutputName(%q) = (%q, %d, %v), want (%q, %d, %v)",
+				tt.name, node, attempt, ok, tt.node, tt.attempt, tt.ok)
+		}
+	}
+}
+
+func TestStartBuildOutputsNumbersAndPrunes(t *testing.T) {
+	artifactDir := t.TempDir()
+	p := &Pipeline{KeepBuilds: 2}
+
+	for i := 0; i < 3; i++ {
+		if _, err := StartBuildOutputs(artifactDir, p, &BuildHistoryLogger{}); err != nil {
+			t.Fatalf("StartBuildOutputs: %v", err)
+		}
+	}
+
+	got := ListBuildNumbers(artifactDir)
+	if !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("builds after pruning = %v, want [2 3]", got)
+	}
+}
+
+func TestBuildOutputsRecordNumbersAttemptsPerNode(t *testing.T) {
+	artifactDir := t.TempDir()
+	outs, err := StartBuildOutputs(artifactDir, &Pipeline{}, &BuildHistoryLogger{})
+	if err != nil {
+		t.Fatalf("StartBuildOutputs: %v", err)
+	}
+
+	for _, text := range []string{"first", "second"} {
+		out := &attemptOutput{}
+		stdout, stderr := out.writers()
+		stdout.Write([]byte(text + " out"))
+		stderr.Write([]byte(text + " err"))
+		outs.Record("implement", out)
+	}
+
+	for name, want := range map[string]string{
+		"implement.1.out": "first out",
+		"implement.1.err": "first err",
+		"implement.2.out": "second out",
+		"implement.2.err": "second err",
+	} {
+		data, err := os.ReadFile(filepath.Join(outs.Dir(), name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+
+	files, err := ListBuildOutputs(outs.Dir(), "implement")
+	if err != nil {
+		t.Fatalf("ListBuildOutputs: %v", err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	want := []string{"implement.1.out", "implement.1.err", "implement.2.out", "implement.2.err"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ListBuildOutputs order = %v, want %v", names, want)
+	}
+}
+
+func TestAttemptOutputCombined(t *testing.T) {
+	out := &attemptOutput{}
+	stdout, stderr := out.writers()
+	stdout.Write([]byte("a"))
+	stderr.Write([]byte("b"))
+	stdout.Write([]byte("c"))
+	if got := out.combined.String(); got != "abc" {
+		t.Errorf("combined = %q, want %q", got, "abc")
+	}
+	if got := out.stdout.String(); got != "ac" {
+		t.Errorf("stdout = %q, want %q", got, "ac")
+	}
+	if got := out.stderr.String(); got != "b" {
+		t.Errorf("stderr = %q, want %q", got, "b")
+	}
+}
+
+func TestBuildHistoryIndexesArtifacts(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ticketsDir := filepath.Join(t.TempDir(), ".ko", "tickets")
+	os.MkdirAll(ticketsDir, 0755)
+	ticket := &Ticket{ID: "ko-a001", Title: "Artifacts", Status: "open", Type: "task", Priority: 2}
+	if err := SaveTicket(ticketsDir, ticket); err != nil {
+		t.Fatalf("SaveTicket: %v", err)
+	}
+
+	hist, err := OpenBuildHistory(ticketsDir, ticket.ID)
+	if err != nil {
+		t.Fatalf("OpenBuildHistory: %v", err)
+	}
+	defer hist.Close()
+	hist.BuildStart(ticket.ID)
+	hist.RecordArtifact("implement", "/tmp/implement.1.out", "hello")
+	hist.BuildComplete(ticket.ID, "decompose")
+
+	db := getShadowDB()
+	var count int
+	if err := db.db.QueryRow("SELECT COUNT(*) FROM build_artifacts WHERE node_name = 'implement'").Scan(&count); err != nil {
+		t.Fatalf("query artifacts: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("build_artifacts rows = %d, want 1", count)
+	}
+
+	builds, err := db.QueryTicketBuilds(ticket.ID, 10)
+	if err != nil {
+		t.Fatalf("QueryTicketBuilds: %v", err)
+	}
+	if len(builds) != 1 || builds[0].Outcome.String != "decompose" {
+		t.Errorf("builds = %+v, want one decompose build", builds)
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// fileState records what a snapshot knows about one file. Snapshots take
+// only mtime and size; Hash is filled in for the files a diff has to look
+// into (see changes_hash.go).
+type fileState struct {
+	ModTime int64
+	Size    int64
+	Hash    string
+}
+
+// fileSnapshot maps project-relative paths (slash-separated) to their state.
+type fileSnapshot map[string]fileState
+
+// ChangeSet is the set of files a build added, modified, or deleted,
+// relative to the project root. Each list is sorted.
+type ChangeSet struct {
+	Added    []string
+	Modified []string
+	Deleted  []string
+}
+
+// All returns every changed path (added, modified, and deleted), sorted.
+func (c ChangeSet) All() []string {
+	all := make([]string, 0, len(c.Added)+len(c.Modified)+len(c.Deleted))
+	all = append(all, c.Added...)
+	all = append(all, c.Modified...)
+	all = append(all, c.Deleted...)
+	sort.Strings(all)
+	return all
+}
+
+// hookEnv returns the changed-file variables exposed to hooks.
+func (c ChangeSet) hookEnv() map[string]string {
+	return map[string]string{
+		"CHANGED_FILES":  strings.Join(c.All(), "\n"),
+		"ADDED_FILES":    strings.Join(c.Added, "\n"),
+		"MODIFIED_FILES": strings.Join(c.Modified, "\n"),
+		"DELETED_FILES":  strings.Join(c.Deleted, "\n"),
+	}
+}
+
+// diffSnapshots classifies the difference between two snapshots. A file
+// whose mtime and size are unchanged is assumed unchanged; a size change
+// is a modification; otherwise hashAfter is consulted and compared with
+// the recorded before-hash, so touched-but-identical files are ignored. A
+// file without a before-hash counts as modified, but is still hashed so
+// the next diff knows it.
+func diffSnapshots(before, after fileSnapshot, hashAfter func(rel string) string) ChangeSet {
+	var cs ChangeSet
+	for path, cur := range after {
+		prev, existed := before[path]
+		switch {
+		case !existed:
+			cs.Added = append(cs.Added, path)
+		case cur.ModTime == prev.ModTime && cur.Size == prev.Size:
+			// untouched
+		case cur.Size != prev.Size:
+			cs.Modified = append(cs.Modified, path)
+		case hashAfter(path) != prev.Hash || prev.Hash == "":
+			cs.Modified = append(cs.Modified, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			cs.Deleted = append(cs.Deleted, path)
+		}
+	}
+	sort.Strings(cs.Added)
+	sort.Strings(cs.Modified)
+	sort.Strings(cs.Deleted)
+	return cs
+}
+
+// snapshotFiles walks projectRoot, skipping .ko, .git, anything matched by
+// .gitignore files (root, nested, and .git/info/exclude) or by the
+// pipeline's ignore globs.
+func snapshotFiles(projectRoot string, ignore []string) fileSnapshot {
+	snap := make(fileSnapshot)
+	m := &ignoreMatcher{}
+	m.add("", readIgnoreFile(filepath.Join(projectRoot, ".git", "info", "exclude")))
+	m.add("", ignore)
+
+	filepath.WalkDir(projectRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(projectRoot, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel != "." {
+				if d.Name() == ".ko" || d.Name() == ".git" || m.match(rel, true) {
+					return filepath.SkipDir
+				}
+			}
+			base := rel
+			if base == "." {
+				base = ""
+			}
+			m.add(base, readIgnoreFile(filepath.Join(path, ".gitignore")))
+			return nil
+		}
+		if !d.Type().IsRegular() || m.match(rel, false) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		snap[rel] = fileState{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+		return nil
+	})
+	return snap
+}
+
+// computeChanges snapshots projectRoot again and diffs it against before.
+// The earlier hashes of changed files are filled into before; the files
+// hashed on the way are added to the hash cache.
+func computeChanges(projectRoot string, ignore []string, before fileSnapshot) ChangeSet {
+	after := snapshotFiles(projectRoot, ignore)
+	fillBeforeHashes(projectRoot, before, after)
+	hashed := make(fileSnapshot)
+	cs := diffSnapshots(before, after, func(rel string) string {
+		st := after[rel]
+		st.Hash = hashFile(filepath.Join(projectRoot, filepath.FromSlash(rel)))
+		hashed[rel] = st
+		return st.Hash
+	})
+	if db := getShadowDB(); db != nil && len(hashed) > 0 {
+		db.CacheFileHashes(projectRoot, hashed)
+	}
+	return cs
+}
+
+// readIgnoreFile returns the lines of a gitignore-style file, or nil.
+func readIgnoreFile(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines
+}
+
+// ignoreRule is one compiled gitignore pattern.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher applies gitignore rules in order; the last matching rule
+// wins, so negations and deeper .gitignore files override earlier ones.
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// add compiles patterns declared in the directory base (project-relative,
+// "" for the root) and appends them.
+func (m *ignoreMatcher) add(base string, patterns []string) {
+	for _, p := range patterns {
+		if r, ok := compileIgnorePattern(base, p); ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+}
+
+// match reports whether the project-relative path is ignored.
+func (m *ignoreMatcher) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// compileIgnorePattern turns a gitignore line into a rule. Patterns with a
+// slash (other than a trailing one) are anchored to base; others match a
+// name at any depth below base.
+func compileIgnorePattern(base, pattern string) (ignoreRule, bool) {
+	p := strings.TrimRight(pattern, " \t\r")
+	if p == "" || strings.HasPrefix(p, "#") {
+		return ignoreRule{}, false
+	}
+	var r ignoreRule
+	if strings.HasPrefix(p, "!") {
+		r.negate = true
+		p = p[1:]
+	}
+	p = strings.TrimPrefix(p, `\`)
+	if strings.HasSuffix(p, "/") {
+		r.dirOnly = true
+		p = strings.TrimSuffix(p, "/")
+	}
+	if p == "" {
+		return ignoreRule{}, false
+	}
+
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	prefix := ""
+	if base != "" {
+		prefix = regexp.QuoteMeta(base) + "/"
+	}
+	expr := "^" + prefix
+	if !anchored {
+		expr += "(?:.*/)?"
+	}
+	expr += globToRegexp(p) + "$"
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+// globToRegexp converts a gitignore glob to a regexp fragment: "*" and "?"
+// stay within one path segment, "**" crosses segments, and [...] classes
+// pass through.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The before snapshot of a build records only mtime and size. When a file
+// turns out to have changed, its earlier content hash comes from the
+// file_hashes cache, which keeps the hash of every file a diff has read,
+// or from the git index, whose entries carry the blob ID of a file as it
+// was when its mtime and size were recorded. Hashes are git blob IDs so
+// the two sources agree.
+
+// hashFile returns the git blob ID of a file's contents, or "" on error.
+func hashFile(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", info.Size())
+	if n, err := io.Copy(h, f); err != nil || n != info.Size() {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// blobID returns the git blob ID of content.
+func blobID(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fillBeforeHashes records in before the content hash of every file whose
+// mtime or size differs in after, or that is gone, when the cache or the
+// git index knows it.
+func fillBeforeHashes(projectRoot string, before, after fileSnapshot) {
+	var index map[string]gitIndexEntry
+	indexRead := false
+	db := getShadowDB()
+	for rel, prev := range before {
+		if cur, ok := after[rel]; prev.Hash != "" || ok && cur.ModTime == prev.ModTime && cur.Size == prev.Size {
+			continue
+		}
+		if db != nil {
+			prev.Hash = db.CachedFileHash(projectRoot, rel, prev)
+		}
+		if prev.Hash == "" {
+			if !indexRead {
+				index, indexRead = readGitIndex(projectRoot), true
+			}
+			if e, ok := index[rel]; ok && e.ModTime == prev.ModTime && e.Size == uint32(prev.Size) {
+				prev.Hash = e.ID
+			}
+		}
+		before[rel] = prev
+	}
+}
+
+// CachedFileHash returns the cached hash of a file in the given state, or
+// "".
+func (d *DB) CachedFileHash(root, rel string, st fileState) string {
+	var hash string
+	d.db.QueryRow(`SELECT hash FROM file_hashes
+		WHERE root = ? AND path = ? AND mtime = ? AND size = ?`,
+		root, rel, st.ModTime, st.Size).Scan(&hash)
+	return hash
+}
+
+// CacheFileHashes stores the hashed files of a snapshot, replacing what
+// was cached for those paths.
+func (d *DB) CacheFileHashes(root string, snap fileSnapshot) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for rel, st := range snap {
+		if st.Hash == "" {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO file_hashes (root, path, mtime, size, hash)
+			VALUES (?, ?, ?, ?, ?)`, root, rel, st.ModTime, st.Size, st.Hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// gitIndexEntry is the stat data and blob ID the git index holds for a
+// path.
+type gitIndexEntry struct {
+	ModTime int64 // Unix nanoseconds
+	Size    uint32
+	ID      string
+}
+
+// readGitIndex returns the index entries of the repository holding
+// projectRoot, keyed by path relative to projectRoot. Entries that git
+// itself would have to re-check (mtime not before the index was written)
+// are left out, as is everything when there is no readable index.
+func readGitIndex(projectRoot string) map[string]gitIndexEntry {
+	gitDir, prefix := findGitDir(projectRoot)
+	if gitDir == "" {
+		return nil
+	}
+	path := filepath.Join(gitDir, "index")
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	entries, err := parseGitIndex(data)
+	if err != nil {
+		return nil
+	}
+	written := info.ModTime().UnixNano()
+	out := make(map[string]gitIndexEntry)
+	for name, e := range entries {
+		rel, ok := strings.CutPrefix(name, prefix)
+		if ok && e.ModTime < written {
+			out[rel] = e
+		}
+	}
+	return out
+}
+
+// findGitDir returns the git directory of the repository holding dir and
+// dir's slash-terminated path inside the work tree ("" at its root).
+func findGitDir(dir string) (gitDir, prefix string) {
+	for cur := dir; ; cur = filepath.Dir(cur) {
+		dotGit := filepath.Join(cur, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			gitDir = dotGit
+			if !info.IsDir() {
+				// A worktree or submodule: ".git" names the real directory.
+				data, err := os.ReadFile(dotGit)
+				target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+				if err != nil || !ok {
+					return "", ""
+				}
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(cur, target)
+				}
+				gitDir = target
+			}
+			if rel, err := filepath.Rel(cur, dir); err == nil && rel != "." {
+				prefix = filepath.ToSlash(rel) + "/"
+			}
+			return gitDir, prefix
+		}
+		if filepath.Dir(cur) == cur {
+			return "", ""
+		}
+	}
+}
+
+// parseGitIndex decodes the entries of a version 2, 3 or 4 git index of a
+// SHA-1 repository.
+func parseGitIndex(data []byte) (map[string]gitIndexEntry, error) {
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("not a git index")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported git index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+	entries := make(map[string]gitIndexEntry, count)
+	pos, prev := 12, ""
+	for i := uint32(0); i < count; i++ {
+		const fixed = 62 // stat data, blob ID and flags
+		if pos+fixed > len(data) {
+			return nil, fmt.Errorf("truncated git index")
+		}
+		e := data[pos:]
+		start := pos
+		flags := binary.BigEndian.Uint16(e[60:62])
+		pos += fixed
+		if version >= 3 && flags&0x4000 != 0 {
+			pos += 2 // extended flags
+		}
+		var name string
+		if version == 4 {
+			strip, n := gitIndexVarint(data[pos:])
+			if n == 0 || strip > len(prev) {
+				return nil, fmt.Errorf("bad git index path")
+			}
+			pos += n
+			end := bytes.IndexByte(data[pos:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("truncated git index")
+			}
+			name = prev[:len(prev)-strip] + string(data[pos:pos+end])
+			pos += end + 1
+		} else {
+			end := bytes.IndexByte(data[pos:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("truncated git index")
+			}
+			name = string(data[pos : pos+end])
+			// NUL padding to a multiple of 8 bytes, at least one
+			pos = start + (pos-start+end+8)&^7
+		}
+		prev = name
+		if flags&0x3000 != 0 {
+			continue // merge stage: not a plain entry
+		}
+		entries[name] = gitIndexEntry{
+			ModTime: int64(binary.BigEndian.Uint32(e[8:12]))*1e9 + int64(binary.BigEndian.Uint32(e[12:16])),
+			Size:    binary.BigEndian.Uint32(e[36:40]),
+			ID:      hex.EncodeToString(e[40:60]),
+		}
+	}
+	return entries, nil
+}
+
+// gitIndexVarint decodes the offset varint of index version 4 and returns
+// it with the number of bytes read, 0 if b is too short.
+func gitIndexVarint(b []byte) (int, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	v, n := int(b[0]&127), 1
+	for b[n-1]&128 != 0 {
+		if n == len(b) {
+			return 0, 0
+		}
+		v = (v+1)<<7 | int(b[n]&127)
+		n++
+	}
+	return v, n
+}
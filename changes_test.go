@@ -0,0 +1,217 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	before := fileSnapshot{
+		"main.go":    {ModTime: 1000, Size: 10, Hash: "a"},
+		"readme.md":  {ModTime: 2000, Size: 20, Hash: "b"},
+		"touched.go": {ModTime: 3000, Size: 30, Hash: "c"},
+		"edited.go":  {ModTime: 4000, Size: 40, Hash: "d"},
+		"old.txt":    {ModTime: 5000, Size: 50, Hash: "e"},
+	}
+	after := fileSnapshot{
+		"main.go":    {ModTime: 1000, Size: 10}, // unchanged
+		"readme.md":  {ModTime: 2500, Size: 25}, // size changed
+		"touched.go": {ModTime: 3500, Size: 30}, // mtime moved, same content
+		"edited.go":  {ModTime: 4500, Size: 40}, // mtime moved, new content
+		"newfile.go": {ModTime: 6000, Size: 1},  // added
+		// old.txt deleted
+	}
+	afterHashes := map[string]string{"touched.go": "c", "edited.go": "changed"}
+	hashed := map[string]bool{}
+
+	got := diffSnapshots(before, after, func(rel string) string {
+		hashed[rel] = true
+		return afterHashes[rel]
+	})
+	want := ChangeSet{
+		Added:    []string{"newfile.go"},
+		Modified: []string{"edited.go", "readme.md"},
+		Deleted:  []string{"old.txt"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffSnapshots = %+v, want %+v", got, want)
+	}
+	if hashed["main.go"] || hashed["readme.md"] {
+		t.Errorf("hashed files whose mtime or size settled the question: %v", hashed)
+	}
+	if !reflect.DeepEqual(got.All(), []string{"edited.go", "newfile.go", "old.txt", "readme.md"}) {
+		t.Errorf("All() = %v", got.All())
+	}
+}
+
+func TestDiffSnapshotsNoChanges(t *testing.T) {
+	snap := fileSnapshot{"main.go": {ModTime: 1000, Size: 1, Hash: "x"}}
+	got := diffSnapshots(snap, snap, func(string) string { return "x" })
+	if len(got.All()) != 0 {
+		t.Errorf("expected no changes, got %+v", got)
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	m := &ignoreMatcher{}
+	m.add("", []string{
+		"# comment",
+		"node_modules/",
+		"*.log",
+		"!keep.log",
+		"/build",
+		"docs/**/*.tmp",
+	})
+	m.add("sub", []string{"local.txt", "/only-here"})
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"node_modules", false, false}, // dir-only pattern
+		{"app.log", false, true},
+		{"deep/dir/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"src/build", true, false}, // anchored to root
+		{"docs/a/b/x.tmp", false, true},
+		{"docs/x.tmp", false, true},
+		{"sub/local.txt", false, true},
+		{"sub/deeper/local.txt", false, true},
+		{"local.txt", false, false}, // scoped to sub/
+		{"sub/only-here", false, true},
+		{"sub/x/only-here", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("match(%q, dir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotFilesRespectsIgnores(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	write(".gitignore", "node_modules/\n")
+	write("main.go", "package main\n")
+	write("node_modules/dep/index.js", "x")
+	write("out.log", "log")
+	write("pkg/.gitignore", "gen.go\n")
+	write("pkg/gen.go", "generated")
+	write("pkg/lib.go", "package pkg\n")
+	write(".ko/tickets/ko-a001.md", "ticket")
+
+	snap := snapshotFiles(root, []string{"*.log"})
+	var got []string
+	for rel := range snap {
+		got = append(got, rel)
+	}
+	sortStrings(got)
+	want := []string{".gitignore", "main.go", "pkg/.gitignore", "pkg/lib.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot paths = %v, want %v", got, want)
+	}
+	if snap["main.go"].Hash != "" {
+		t.Error("a snapshot should record only mtime and size")
+	}
+}
+
+func TestComputeChangesIgnoresTouchOnly(t *testing.T) {
+	defer setupTestDB(t)()
+	root := t.TempDir()
+	gitInit(t, root)
+	same := filepath.Join(root, "same.txt")
+	os.WriteFile(same, []byte("content"), 0644)
+	os.WriteFile(filepath.Join(root, "gone.txt"), []byte("bye"), 0644)
+	gitCommitAll(t, root)
+	scratch := filepath.Join(root, "scratch.txt")
+	os.WriteFile(scratch, []byte("untracked"), 0644)
+
+	// A committed file's earlier hash comes from the git index
+	before := snapshotFiles(root, nil)
+	touch := func(path, content string, at time.Time) {
+		os.WriteFile(path, []byte(content), 0644)
+		os.Chtimes(path, at, at)
+	}
+	future := time.Now().Add(time.Hour)
+	touch(same, "content", future)
+	touch(scratch, "untracked", future)
+	os.Remove(filepath.Join(root, "gone.txt"))
+	os.WriteFile(filepath.Join(root, "new.txt"), []byte("hi"), 0644)
+
+	got := computeChanges(root, nil, before)
+	want := ChangeSet{Added: []string{"new.txt"}, Modified: []string{"scratch.txt"}, Deleted: []string{"gone.txt"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("computeChanges = %+v, want %+v", got, want)
+	}
+	if before["same.txt"].Hash != blobID([]byte("content")) || before["gone.txt"].Hash != blobID([]byte("bye")) {
+		t.Errorf("before hashes = %+v", before)
+	}
+
+	// An untracked file is known once a diff has hashed it
+	before = snapshotFiles(root, nil)
+	touch(scratch, "untracked", future.Add(time.Hour))
+	if got := computeChanges(root, nil, before); len(got.All()) != 0 {
+		t.Errorf("second touch: computeChanges = %+v, want no changes", got)
+	}
+}
+
+func TestParseGitIndexVersions(t *testing.T) {
+	for _, version := range []string{"2", "3", "4"} {
+		root := t.TempDir()
+		gitInit(t, root)
+		os.MkdirAll(filepath.Join(root, "pkg"), 0755)
+		os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644)
+		os.WriteFile(filepath.Join(root, "pkg", "lib.go"), []byte("package pkg\n"), 0644)
+		os.WriteFile(filepath.Join(root, "pkg", "lib_test.go"), []byte("package pkg\n\n"), 0644)
+		gitCommitAll(t, root)
+		git(t, root, "update-index", "--index-version", version)
+
+		entries, err := parseGitIndexFile(filepath.Join(root, ".git", "index"))
+		if err != nil {
+			t.Fatalf("v%s: %v", version, err)
+		}
+		if len(entries) != 3 || entries["pkg/lib_test.go"].ID != blobID([]byte("package pkg\n\n")) ||
+			entries["main.go"].Size != uint32(len("package main\n")) {
+			t.Errorf("v%s: entries = %+v", version, entries)
+		}
+	}
+}
+
+// parseGitIndexFile reads and parses a git index.
+func parseGitIndexFile(path string) (map[string]gitIndexEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseGitIndex(data)
+}
+
+func TestParsePipelineIgnore(t *testing.T) {
+	for _, config := range []string{
+		"ignore: [\"*.log\", tmp/]\nworkflows:\n  main:\n    - name: impl\n      type: action\n      prompt: impl.md\n",
+		"ignore:\n  - \"*.log\"\n  - tmp/\nworkflows:\n  main:\n    - name: impl\n      type: action\n      prompt: impl.md\n",
+	} {
+		p, err := ParsePipeline(config)
+		if err != nil {
+			t.Fatalf("ParsePipeline failed: %v", err)
+		}
+		if len(p.Ignore) != 2 || p.Ignore[0] != "*.log" || p.Ignore[1] != "tmp/" {
+			t.Errorf("Ignore = %v, want [*.log tmp/]", p.Ignore)
+		}
+		if len(p.Workflows["main"].Nodes) != 1 {
+			t.Errorf("workflows not parsed after ignore list")
+		}
+	}
+}
@@ -355,8 +355,12 @@ func cleanupAfterStop(ticketsDir string, p *Pipeline) {
 		fmt.Printf("cleanup: resetting %s to open\n", t.ID)
 
 		if p != nil && len(p.OnFail) > 0 {
-			projectRoot := ProjectRoot(ticketsDir)
-			runHooks(ticketsDir, t, p.OnFail, projectRoot, projectRoot, BuildHistoryPath(ticketsDir, t.ID))
+			if hist, err := OpenBuildHistory(ticketsDir, t.ID); err == nil {
+				t.buildOutcome = "fail"
+				t.dispReason = "agent stopped"
+				runHooks(ticketsDir, t, p, HookOnFail, p.OnFail, ChangeSet{}, nil, ProjectRoot(ticketsDir), hist)
+				hist.Close()
+			}
 		}
 
 		AddNote(t, "ko: reset to open (agent stopped)")
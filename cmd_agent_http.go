@@ -11,6 +11,24 @@ import (
 
 const serveURL = "http://localhost:19876"
 
+// postLocalServe POSTs a JSON body to the local ko serve, with the token
+// from the global config when one is set.
+func postLocalServe(path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, serveURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg, err := LoadGlobalConfig(); err == nil && cfg.Token != "" {
+		token, err := resolveGlobalSecret(cfg.Token)
+		if err != nil {
+			return nil, fmt.Errorf("token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return http.DefaultClient.Do(req)
+}
+
 // isServeRunning checks if ko serve is reachable.
 func isServeRunning() bool {
 	resp, err := http.Get(serveURL + "/agent/status?project=_ping")
@@ -29,7 +47,7 @@ func agentStartViaServe(projectRoot string) (int, error) {
 	}
 
 	reqBody, _ := json.Marshal(map[string]string{"project": projectRoot})
-	resp, err := http.Post(serveURL+"/agent/spawn", "application/json", bytes.NewReader(reqBody))
+	resp, err := postLocalServe("/agent/spawn", reqBody)
 	if err != nil {
 		return -1, fmt.Errorf("failed to contact serve: %v", err)
 	}
@@ -66,7 +84,7 @@ func agentStopViaServe(projectRoot string) (int, bool) {
 	}
 
 	reqBody, _ := json.Marshal(map[string]string{"project": projectRoot})
-	resp, err := http.Post(serveURL+"/agent/kill", "application/json", bytes.NewReader(reqBody))
+	resp, err := postLocalServe("/agent/kill", reqBody)
 	if err != nil {
 		return -1, false
 	}
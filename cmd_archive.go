@@ -0,0 +1,183 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cmdArchive archives the closed tickets of a project (see archive.go).
+// Archived tickets drop out of ls, ready and stats but stay readable by
+// show, search --archived and export; their artifacts are compressed.
+func cmdArchive(args []string) int {
+	ticketsDir, args, err := resolveProjectTicketsDir(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko archive: %v\n", err)
+		return 1
+	}
+	if ticketsDir == "" {
+		fmt.Fprintln(os.Stderr, "ko archive: no .ko/tickets directory found (use --project to pick one)")
+		return 1
+	}
+
+	args = reorderArgs(args, map[string]bool{"closed-before": true})
+
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	closedBefore := fs.String("closed-before", "", "only archive tickets closed before this date (YYYY-MM-DD)")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "ko archive: %v\n", err)
+		return 1
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: ko archive [--closed-before YYYY-MM-DD]")
+		return 1
+	}
+	var before time.Time
+	if *closedBefore != "" {
+		if before, err = time.Parse("2006-01-02", *closedBefore); err != nil {
+			fmt.Fprintf(os.Stderr, "ko archive: invalid --closed-before date %q (want YYYY-MM-DD)\n", *closedBefore)
+			return 1
+		}
+	}
+
+	db := getShadowDB()
+	if db == nil {
+		fmt.Fprintln(os.Stderr, "ko archive: database not available")
+		return 1
+	}
+	tickets, err := ListTickets(ticketsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko archive: %v\n", err)
+		return 1
+	}
+	cands := archiveCandidates(tickets, before)
+	if len(cands) == 0 {
+		fmt.Println("No closed tickets to archive")
+		return 0
+	}
+
+	// Compress first so a failure leaves the tickets unarchived; the
+	// directories go only once the archive is recorded.
+	var ids, compressed []string
+	for _, t := range cands {
+		ok, err := compressArtifactDir(ticketsDir, t.ID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ko archive: %s: %v\n", t.ID, err)
+			continue
+		}
+		ids = append(ids, t.ID)
+		if ok {
+			compressed = append(compressed, t.ID)
+		}
+	}
+	now := time.Now()
+	if err := db.ArchiveTickets(ids, now); err != nil {
+		for _, id := range compressed {
+			os.Remove(ArtifactArchive(ticketsDir, id))
+		}
+		fmt.Fprintf(os.Stderr, "ko archive: %v\n", err)
+		return 1
+	}
+
+	for _, id := range ids {
+		data := map[string]interface{}{"archived_at": now.UTC().Format(time.RFC3339)}
+		if contains(compressed, id) {
+			os.RemoveAll(ArtifactDir(ticketsDir, id))
+			data["artifacts"] = ArtifactArchive(ticketsDir, id)
+		}
+		EmitMutationEvent(ticketsDir, id, "archive", data)
+		fmt.Printf("%s archived\n", id)
+	}
+	if len(ids) < len(cands) {
+		return 1
+	}
+	return 0
+}
+
+// ArtifactArchive returns the path of the compressed artifact directory of
+// an archived ticket.
+func ArtifactArchive(ticketsDir, id string) string {
+	return ArtifactDir(ticketsDir, id) + ".tar.gz"
+}
+
+// compressArtifactDir writes the artifact directory of a ticket, if it has
+// one, to its ArtifactArchive and reports whether it did. The directory
+// itself is left in place.
+func compressArtifactDir(ticketsDir, id string) (bool, error) {
+	dir := ArtifactDir(ticketsDir, id)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return false, nil
+	}
+	dest := ArtifactArchive(ticketsDir, id)
+	tmp := dest + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp)
+
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return addTarEntry(tw, filepath.Dir(dir), path, d)
+	})
+	for _, c := range []io.Closer{tw, zw, f} {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, dest)
+}
+
+// addTarEntry writes path, named relative to base, to tw.
+func addTarEntry(tw *tar.Writer, base, path string, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	var link string
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	} else if !info.Mode().IsRegular() && !info.IsDir() {
+		return nil
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	name, err := filepath.Rel(base, path)
+	if err != nil {
+		return err
+	}
+	hdr.Name = filepath.ToSlash(name)
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(tw, src)
+	return err
+}
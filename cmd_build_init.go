@@ -48,8 +48,8 @@ func cmdAgentInit(args []string) int {
 	}
 	os.WriteFile(filepath.Join(buildsDir, ".gitignore"), []byte("*\n!.gitignore\n"), 0644)
 
-	// Gitignore agent runtime files in .ko/
-	os.WriteFile(filepath.Join(koDir, ".gitignore"), []byte("agent.lock\nagent.pid\nagent.log\nagent.heartbeat\n"), 0644)
+	// Gitignore agent runtime files and node secrets in .ko/
+	os.WriteFile(filepath.Join(koDir, ".gitignore"), []byte("agent.lock\nagent.pid\nagent.log\nagent.heartbeat\nsecrets.env\n"), 0644)
 
 	// Write unified config
 	if err := os.WriteFile(configPath, []byte(defaultConfigYML), 0644); err != nil {
@@ -117,7 +117,8 @@ pipeline:
         prompt: review.md
 
   # Commands to run after all nodes succeed (before ticket is closed).
-  # $TICKET_ID, $CHANGED_FILES, and $KO_TICKET_WORKSPACE are available.
+  # $TICKET_ID, $CHANGED_FILES (also $ADDED_FILES, $MODIFIED_FILES,
+  # $DELETED_FILES), and $KO_TICKET_WORKSPACE are available.
   # on_succeed:
   #   - git add -A
   #   - git commit -m "ko: implement ${TICKET_ID}"
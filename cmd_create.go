@@ -21,7 +21,8 @@ func cmdCreate(args []string) int {
 		"d": true, "t": true, "p": true, "a": true,
 		"parent": true, "external-ref": true, "design": true,
 		"acceptance": true, "tags": true, "project": true,
-		"snooze": true, "triage": true,
+		"snooze": true, "triage": true, "scope": true, "field": true,
+		"template": true, "from": true,
 	})
 
 	fs := flag.NewFlagSet("create", flag.ContinueOnError)
@@ -37,12 +38,36 @@ func cmdCreate(args []string) int {
 	projectTag := fs.String("project", "", "target project tag")
 	snooze := fs.String("snooze", "", "snooze date (ISO 8601, e.g. 2026-05-01)")
 	triage := fs.String("triage", "", "triage note (free text)")
+	scope := fs.String("scope", "", "comma-separated allowed write globs")
+	templateName := fs.String("template", "", "ticket template name")
+	from := fs.String("from", "", "plan file (.md or .yaml) to create a ticket hierarchy from")
+	var fields stringList
+	fs.Var(&fields, "field", "custom field name=value (repeatable)")
 
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
 		return 1
 	}
 
+	if *from != "" && (fs.NArg() > 0 || *templateName != "") {
+		fmt.Fprintln(os.Stderr, "ko add: --from takes no title or template; the plan file holds the tickets")
+		return 1
+	}
+	if *from != "" {
+		// The plan sets every field per item; only where it goes may be
+		// given here.
+		var extra []string
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name != "from" && f.Name != "parent" && f.Name != "project" {
+				extra = append(extra, "-"+f.Name)
+			}
+		})
+		if len(extra) > 0 {
+			fmt.Fprintf(os.Stderr, "ko add: --from takes only --parent and --project, not %s\n", strings.Join(extra, ", "))
+			return 1
+		}
+	}
+
 	title := "Untitled"
 	if fs.NArg() > 0 {
 		title = fs.Arg(0)
@@ -114,6 +139,16 @@ func cmdCreate(args []string) int {
 
 	// Ensure target tickets directory exists
 	ticketsDir := resolveTicketsDir(targetPath)
+	if *from != "" {
+		return createFromPlan(ticketsDir, *from, *parent)
+	}
+	var tmpl *TicketTemplate
+	if *templateName != "" {
+		if tmpl, err = LoadTicketTemplate(ticketsDir, *templateName); err != nil {
+			fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
+			return 1
+		}
+	}
 	if err := EnsureTicketsDir(ticketsDir); err != nil {
 		fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
 		return 1
@@ -154,10 +189,17 @@ func cmdCreate(args []string) int {
 	t.Status = "open"
 
 	// Apply strict priority for description: stdin > arg > -d flag
-	if descFromInput != "" {
+	if descFromInput == "" {
+		descFromInput = *desc
+	}
+	// A template sets defaults the flags below override.
+	if tmpl != nil {
+		if err := tmpl.Apply(t, descFromInput); err != nil {
+			fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
+			return 1
+		}
+	} else if descFromInput != "" {
 		t.Body += "\n" + descFromInput + "\n"
-	} else if *desc != "" {
-		t.Body += "\n" + *desc + "\n"
 	}
 	if *typ != "" {
 		t.Type = *typ
@@ -186,6 +228,9 @@ func cmdCreate(args []string) int {
 		}
 		t.Tags = ticketTags
 	}
+	if *scope != "" {
+		t.Scope = splitScopeFlag(*scope)
+	}
 
 	if *snooze != "" {
 		if _, err := time.Parse("2006-01-02", *snooze); err != nil {
@@ -198,6 +243,19 @@ func cmdCreate(args []string) int {
 		t.Triage = *triage
 	}
 
+	// Custom fields: explicit values, then the declared defaults. A broken
+	// config only matters when fields were asked for.
+	schema, err := loadFieldSchema(ticketsDir)
+	if err != nil && len(fields) > 0 {
+		fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
+		return 1
+	}
+	if err := applyFieldAssignments(t, schema, fields); err != nil {
+		fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
+		return 1
+	}
+	applyFieldDefaults(t, schema)
+
 	if err := SaveTicket(ticketsDir, t); err != nil {
 		fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
 		return 1
@@ -207,7 +265,7 @@ func cmdCreate(args []string) int {
 		"title": t.Title,
 	})
 
-	if *triage != "" {
+	if t.Triage != "" {
 		maybeAutoTriage(ticketsDir, t.ID)
 	}
 
@@ -252,7 +310,10 @@ func WritePrefix(ticketsDir, prefix string) error {
 }
 
 // WriteConfigPrefix writes the prefix to .ko/config.yaml.
-// If config.yaml already exists, it updates the project.prefix field.
+// If config.yaml already exists, it sets project.prefix in place, leaving the
+// rest of the file byte for byte. A config.yaml laid out as a bare pipeline
+// (no top-level sections) is left alone, since adding project: would change
+// how it is read.
 // If it doesn't exist, it creates a minimal config with just the project section.
 func WriteConfigPrefix(ticketsDir, prefix string) error {
 	root := ProjectRoot(ticketsDir)
@@ -266,47 +327,22 @@ func WriteConfigPrefix(ticketsDir, prefix string) error {
 	// Check if config.yaml already exists
 	existingData, err := os.ReadFile(configPath)
 	if err == nil {
-		// Config exists - update the project.prefix field
 		content := string(existingData)
-		lines := strings.Split(content, "\n")
-		var result []string
-		inProject := false
-		foundPrefix := false
-
-		for _, line := range lines {
-			trimmed := strings.TrimSpace(line)
-
-			// Detect project: section
-			if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
-				if trimmed == "project:" {
-					inProject = true
-					result = append(result, line)
-					continue
-				} else if strings.HasSuffix(trimmed, ":") {
-					inProject = false
-				}
-			}
-
-			// If in project section and this is the prefix line, replace it
-			if inProject {
-				if key, _, ok := parseYAMLLine(trimmed); ok && key == "prefix" {
-					result = append(result, "  prefix: "+prefix)
-					foundPrefix = true
-					continue
-				}
-			}
-
-			result = append(result, line)
+		doc, err := parseYAML(content)
+		if err != nil {
+			return fmt.Errorf("%s: %v", configPath, err)
 		}
-
-		// If we didn't find a prefix line but are in project section, we need to add it
-		// For now, just rewrite if prefix wasn't found
-		if !foundPrefix {
-			// Append to end of project section or create project section
-			return os.WriteFile(configPath, []byte(strings.Join(result, "\n")), 0644)
+		if doc.Kind == yamlMapping && !hasConfigSection(doc) {
+			return nil
 		}
-
-		return os.WriteFile(configPath, []byte(strings.Join(result, "\n")), 0644)
+		updated, err := yamlSet(content, []string{"project", "prefix"}, prefix)
+		if err != nil {
+			return fmt.Errorf("%s: %v", configPath, err)
+		}
+		if updated == content {
+			return nil
+		}
+		return os.WriteFile(configPath, []byte(updated), 0644)
 	}
 
 	// Config doesn't exist - create minimal config with just project.prefix
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// createFromPlan is ko add --from: it creates every ticket of a plan file
+// (see plan.go) in one transaction, so a bad plan writes nothing, and
+// prints the outline number and ID of each.
+func createFromPlan(ticketsDir, path, parent string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
+		return 1
+	}
+	items, warnings, err := parsePlan(path, string(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko add: %s: %v\n", path, err)
+		return 1
+	}
+	warnYAML(path, warnings)
+
+	var parentID string
+	if parent != "" {
+		if _, parentID, err = ResolveTicket(ticketsDir, parent); err != nil {
+			fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
+			return 1
+		}
+	}
+	db := getShadowDB()
+	if db == nil {
+		fmt.Fprintln(os.Stderr, "ko add: database not available")
+		return 1
+	}
+	if err := EnsureTicketsDir(ticketsDir); err != nil {
+		fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
+		return 1
+	}
+	prefix := detectPrefix(ticketsDir)
+
+	used := map[string]bool{}
+	planned := buildPlan(items, prefix, parentID, "", func(id string) bool {
+		if _, err := LoadTicket(ticketsDir, id); used[id] || err == nil {
+			return false
+		}
+		used[id] = true
+		return true
+	})
+	schema, _ := loadFieldSchema(ticketsDir)
+	tickets := make([]*Ticket, len(planned))
+	for i, p := range planned {
+		applyFieldDefaults(p.Ticket, schema)
+		tickets[i] = p.Ticket
+	}
+	if err := db.UpsertTickets(tickets, ticketsDir); err != nil {
+		fmt.Fprintf(os.Stderr, "ko add: %v\n", err)
+		return 1
+	}
+
+	refWidth, idWidth := 0, 0
+	for _, p := range planned {
+		refWidth = max(refWidth, len(p.Ref))
+		idWidth = max(idWidth, len(p.Ticket.ID))
+	}
+	for _, p := range planned {
+		t := p.Ticket
+		EmitMutationEvent(ticketsDir, t.ID, "create", map[string]interface{}{
+			"title": t.Title,
+		})
+		for _, dep := range t.Deps {
+			EmitMutationEvent(ticketsDir, t.ID, "dep", map[string]interface{}{
+				"dep": dep,
+			})
+		}
+		fmt.Printf("%-*s  %-*s  %s\n", refWidth, p.Ref, idWidth, t.ID, t.Title)
+	}
+	maybeAutoAgent(ticketsDir)
+	return 0
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// cmdEdit opens tickets in $EDITOR as markdown with frontmatter and saves
+// what changed. ko edit <id> edits one ticket; ko edit --batch <filter>
+// edits every matching ticket in one buffer (see edit.go).
+func cmdEdit(args []string) int {
+	args = reorderArgs(args, map[string]bool{"project": true})
+	ticketsDir, args, err := resolveProjectTicketsDir(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko edit: %v\n", err)
+		return 1
+	}
+
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	batch := fs.Bool("batch", false, "edit every ticket matching a filter in one buffer")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "ko edit: %v\n", err)
+		return 1
+	}
+
+	if *batch {
+		if ticketsDir == "" {
+			fmt.Fprintln(os.Stderr, "ko edit: no .ko/tickets directory found (use --project or run from a project dir)")
+			return 1
+		}
+		tickets, err := editBatchTickets(ticketsDir, strings.Join(fs.Args(), " "))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ko edit: %v\n", err)
+			return 1
+		}
+		return editTickets(ticketsDir, tickets, true)
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "ko edit: ticket ID required")
+		fmt.Fprintln(os.Stderr, "usage: ko edit <id>  |  ko edit --batch <filter>")
+		return 1
+	}
+	ticketsDir, id, err := ResolveTicket(ticketsDir, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko edit: %v\n", err)
+		return 1
+	}
+	t, err := LoadTicket(ticketsDir, id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko edit: %v\n", err)
+		return 1
+	}
+	return editTickets(ticketsDir, []*Ticket{t}, false)
+}
+
+// editBatchTickets returns the tickets of the project matching a
+// ko edit --batch filter.
+func editBatchTickets(ticketsDir, filter string) ([]*Ticket, error) {
+	schema, err := loadFieldSchema(ticketsDir)
+	if err != nil {
+		return nil, err
+	}
+	q, fields, err := parseEditFilter(filter, schema)
+	if err != nil {
+		return nil, err
+	}
+	all, err := ListTickets(ticketsDir)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Ticket
+	for _, t := range filterAPITickets(all, q) {
+		if matchFieldFilters(t, fields) {
+			out = append(out, t)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no tickets match %q", filter)
+	}
+	return out, nil
+}
+
+// editTickets runs the editor on the tickets, then validates and saves the
+// result in one transaction. When the edit is rejected the buffer is kept
+// so the work is not lost.
+func editTickets(ticketsDir string, originals []*Ticket, batch bool) int {
+	content := FormatTicket(originals[0])
+	if batch {
+		content = formatEditBuffer(originals)
+	}
+	f, err := os.CreateTemp("", "ko-edit-*.md")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko edit: %v\n", err)
+		return 1
+	}
+	path := f.Name()
+	_, err = f.WriteString(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = runEditor(path)
+	}
+	if err != nil {
+		os.Remove(path)
+		fmt.Fprintf(os.Stderr, "ko edit: %v\n", err)
+		return 1
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko edit: %v\n", err)
+		return 1
+	}
+	if string(data) == content {
+		os.Remove(path)
+		fmt.Println("No changes.")
+		return 0
+	}
+
+	if err := saveEdits(ticketsDir, originals, string(data), batch); err != nil {
+		fmt.Fprintf(os.Stderr, "ko edit: %v\nko edit: your changes are saved in %s\n", err, path)
+		return 1
+	}
+	os.Remove(path)
+	return 0
+}
+
+// runEditor opens path in $EDITOR (vi if unset) on the terminal. EDITOR
+// may carry arguments, as in "code --wait".
+func runEditor(path string) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "ko-edit", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q: %v", editor, err)
+	}
+	return nil
+}
+
+// saveEdits parses the edited buffer, checks every changed ticket and
+// saves them, emitting one update event per changed field.
+func saveEdits(ticketsDir string, originals []*Ticket, content string, batch bool) error {
+	edited := map[string]*Ticket{}
+	if batch {
+		var err error
+		if _, edited, err = parseEditBuffer(content); err != nil {
+			return err
+		}
+	} else {
+		t, err := ParseTicket(content)
+		if err != nil {
+			return err
+		}
+		edited[originals[0].ID] = t
+	}
+	byID := map[string]*Ticket{}
+	for _, t := range originals {
+		byID[t.ID] = t
+	}
+	for id := range edited {
+		if byID[id] == nil {
+			return fmt.Errorf("%s is not one of the tickets being edited", id)
+		}
+	}
+
+	lookup := func(id string) *Ticket {
+		if t := edited[id]; t != nil {
+			return t
+		}
+		if t, err := LoadTicket(ticketsDir, id); err == nil {
+			return t
+		}
+		if dir, resolved, err := ResolveTicket(ticketsDir, id); err == nil && resolved == id {
+			if t, err := LoadTicket(dir, id); err == nil {
+				return t
+			}
+		}
+		return nil
+	}
+	schema, err := loadFieldSchema(ticketsDir)
+	if err != nil {
+		return err
+	}
+
+	var save []*Ticket
+	changes := map[string][]fieldChange{}
+	for _, orig := range originals {
+		t := edited[orig.ID]
+		if t == nil {
+			continue
+		}
+		if err := validateEdit(orig, t, lookup); err != nil {
+			return fmt.Errorf("%s: %v", orig.ID, err)
+		}
+		if err := applyEditedFields(orig, t, schema); err != nil {
+			return fmt.Errorf("%s: %v", orig.ID, err)
+		}
+		if c := ticketChanges(orig, t); len(c) > 0 {
+			current, err := LoadTicket(ticketsDir, orig.ID)
+			if err != nil {
+				return err
+			}
+			if FormatTicket(current) != FormatTicket(orig) {
+				return fmt.Errorf("%s was changed by someone else while you were editing", orig.ID)
+			}
+			save = append(save, t)
+			changes[t.ID] = c
+		}
+	}
+	if len(save) == 0 {
+		fmt.Println("No changes.")
+		return nil
+	}
+
+	db := getShadowDB()
+	if db == nil {
+		return fmt.Errorf("database not available")
+	}
+	if err := db.UpsertTickets(save, ticketsDir); err != nil {
+		return err
+	}
+	for _, t := range save {
+		var names []string
+		for _, c := range changes[t.ID] {
+			names = append(names, c.Field)
+			EmitMutationEvent(ticketsDir, t.ID, "update", map[string]interface{}{
+				"ticket": t.ID,
+				"field":  c.Field,
+				"from":   c.Before,
+				"to":     c.After,
+			})
+			if c.Field == "status" {
+				EmitMutationEvent(ticketsDir, t.ID, "status", map[string]interface{}{
+					"from": c.Before,
+					"to":   c.After,
+				})
+			}
+		}
+		fmt.Printf("%s updated (%s)\n", t.ID, strings.Join(names, ", "))
+		if contains(names, "triage") && t.Triage != "" {
+			maybeAutoTriage(ticketsDir, t.ID)
+		}
+	}
+	return nil
+}
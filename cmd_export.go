@@ -38,23 +38,25 @@ type ExportProject struct {
 // ExportTicket is a single ticket with every field ko persists. deps and tags
 // are always present (possibly empty) so importers have a stable contract.
 type ExportTicket struct {
-	ID            string         `json:"id"`
-	Title         string         `json:"title"`
-	Body          string         `json:"body"`
-	Status        string         `json:"status"`
-	Type          string         `json:"type"`
-	Priority      int            `json:"priority"`
-	Assignee      string         `json:"assignee,omitempty"`
-	Parent        string         `json:"parent,omitempty"`
-	ExternalRef   string         `json:"external_ref,omitempty"`
-	Snooze        string         `json:"snooze,omitempty"`
-	Triage        string         `json:"triage,omitempty"`
-	Deps          []string       `json:"deps"`
-	Tags          []string       `json:"tags"`
-	PlanQuestions []PlanQuestion `json:"plan_questions,omitempty"`
-	Created       string         `json:"created"`
-	Modified      string         `json:"modified"`
-	History       []ExportEvent  `json:"history,omitempty"`
+	ID            string                 `json:"id"`
+	Title         string                 `json:"title"`
+	Body          string                 `json:"body"`
+	Status        string                 `json:"status"`
+	Type          string                 `json:"type"`
+	Priority      int                    `json:"priority"`
+	Assignee      string                 `json:"assignee,omitempty"`
+	Parent        string                 `json:"parent,omitempty"`
+	ExternalRef   string                 `json:"external_ref,omitempty"`
+	Snooze        string                 `json:"snooze,omitempty"`
+	Triage        string                 `json:"triage,omitempty"`
+	Deps          []string               `json:"deps"`
+	Tags          []string               `json:"tags"`
+	Fields        map[string]interface{} `json:"fields,omitempty"`
+	PlanQuestions []PlanQuestion         `json:"plan_questions,omitempty"`
+	Created       string                 `json:"created"`
+	Modified      string                 `json:"modified"`
+	Archived      string                 `json:"archived,omitempty"`
+	History       []ExportEvent          `json:"history,omitempty"`
 }
 
 // ExportEvent is one entry of a ticket's mutation history (create, update, dep,
@@ -103,9 +105,10 @@ func cmdExport(args []string) int {
 		return 1
 	}
 
+	db := getShadowDB()
 	var history map[string][]MutationEntry
 	if includeHistory {
-		if db := getShadowDB(); db != nil {
+		if db != nil {
 			if h, herr := db.ExportTicketHistory(); herr == nil {
 				history = h
 			} else {
@@ -139,7 +142,7 @@ func cmdExport(args []string) int {
 			abs = ticketsDir
 		}
 
-		tickets, terr := ListTickets(abs)
+		tickets, terr := ListAllTickets(abs)
 		if terr != nil {
 			fmt.Fprintf(os.Stderr, "ko export: warning: project %q: %v\n", tag, terr)
 		}
@@ -154,7 +157,11 @@ func cmdExport(args []string) int {
 			Tickets:   make([]ExportTicket, 0, len(tickets)),
 		}
 		for _, t := range tickets {
-			pj.Tickets = append(pj.Tickets, ticketToExport(t, history))
+			et := ticketToExport(t, history)
+			if db != nil {
+				et.Archived = db.ArchivedAt(t.ID)
+			}
+			pj.Tickets = append(pj.Tickets, et)
 			exp.TicketCount++
 		}
 		exp.Projects = append(exp.Projects, pj)
@@ -215,6 +222,7 @@ func ticketToExport(t *Ticket, history map[string][]MutationEntry) ExportTicket
 		Triage:        t.Triage,
 		Deps:          deps,
 		Tags:          tags,
+		Fields:        fieldsJSON(t.Fields),
 		PlanQuestions: t.PlanQuestions,
 		Created:       t.Created,
 		Modified:      modified,
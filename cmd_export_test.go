@@ -26,6 +26,7 @@ func TestCmdExport(t *testing.T) {
 		ID: "tp-0001", Title: "Parent ticket", Status: "open", Type: "task",
 		Priority: 1, Deps: []string{}, Created: "2026-01-01T00:00:00Z",
 		Body: "parent body", Tags: []string{"alpha", "beta"},
+		Fields: []TicketField{{Name: "estimate", Type: "int", Value: "3"}},
 	}
 	child := &Ticket{
 		ID: "tp-0002", Title: "Child ticket", Status: "closed", Type: "bug",
@@ -90,6 +91,9 @@ func TestCmdExport(t *testing.T) {
 	if len(p.Tags) != 2 {
 		t.Errorf("parent tags = %v, want 2", p.Tags)
 	}
+	if p.Fields["estimate"] != float64(3) {
+		t.Errorf("parent custom fields = %v, want estimate 3", p.Fields)
+	}
 	if len(p.History) != 1 || p.History[0].EventType != "create" {
 		t.Errorf("parent history = %+v, want one create event", p.History)
 	}
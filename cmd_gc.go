@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func cmdGC(args []string) int {
+	ticketsDir, args, err := resolveProjectTicketsDir(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko gc: %v\n", err)
+		return 1
+	}
+	if ticketsDir == "" {
+		fmt.Fprintln(os.Stderr, "ko gc: no .ko/tickets directory found (use --project to pick one)")
+		return 1
+	}
+
+	args = reorderArgs(args, map[string]bool{"older-than": true})
+
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	olderThan := fs.String("older-than", "30d", "only prune artifacts untouched for this long (e.g. 30d, 2w, 12h)")
+	closedOnly := fs.Bool("closed-only", false, "only prune artifacts of closed tickets")
+	orphans := fs.Bool("orphans", false, "also prune artifacts of tickets that no longer exist")
+	dryRun := fs.Bool("dry-run", false, "report what would be removed without removing it")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "ko gc: %v\n", err)
+		return 1
+	}
+
+	age, err := parseAge(*olderThan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko gc: --older-than: %v\n", err)
+		return 1
+	}
+
+	cands, err := scanGCCandidates(ticketsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko gc: %v\n", err)
+		return 1
+	}
+
+	// Archived tickets are closed, not gone. Without the database every
+	// ticket would look gone, so gc stops rather than guess.
+	if getShadowDB() == nil {
+		fmt.Fprintln(os.Stderr, "ko gc: database not available")
+		return 1
+	}
+	tickets, err := ListAllTickets(ticketsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko gc: %v\n", err)
+		return 1
+	}
+	statuses := map[string]string{}
+	for _, t := range tickets {
+		statuses[t.ID] = t.Status
+	}
+
+	verb := "removed"
+	if *dryRun {
+		verb = "would remove"
+	}
+
+	policy := gcPolicy{Cutoff: time.Now().Add(-age), ClosedOnly: *closedOnly, Orphans: *orphans}
+	var reclaimed int64
+	var removed int
+	for _, c := range selectGCCandidates(cands, statuses, policy) {
+		if !*dryRun {
+			if err := os.RemoveAll(c.Path); err != nil {
+				fmt.Fprintf(os.Stderr, "ko gc: %s: %v\n", c.Label(), err)
+				continue
+			}
+			if db := getShadowDB(); db != nil && c.Kind == "build" {
+				db.DeleteBuildArtifactsUnder(c.Path)
+			}
+		}
+		fmt.Printf("%s %s (%s)\n", verb, c.Label(), formatBytes(c.Size))
+		reclaimed += c.Size
+		removed++
+	}
+
+	branches := gcWorkerBranches(ProjectRoot(ticketsDir), *dryRun)
+
+	switch {
+	case removed == 0 && branches == 0:
+		fmt.Println("gc: nothing to collect")
+	case *dryRun:
+		fmt.Printf("gc: would reclaim %s from %d directories, %d worker branches\n", formatBytes(reclaimed), removed, branches)
+	default:
+		fmt.Printf("gc: reclaimed %s from %d directories, %d worker branches\n", formatBytes(reclaimed), removed, branches)
+	}
+	return 0
+}
+
+// gcWorkerBranches deletes ko-worker-* branches (and their stale worktrees)
+// left behind by crashed parallel loops. Returns the number of branches
+// deleted, or that would be deleted in dry-run mode. Projects outside a
+// git repo have nothing to prune.
+func gcWorkerBranches(projectRoot string, dryRun bool) int {
+	commonDir := gitCommonDir(projectRoot)
+	if commonDir == "" {
+		return 0
+	}
+	worktrees, adminDirs := readWorktrees(commonDir)
+
+	var count int
+	for _, o := range orphanWorkerBranches(listWorkerBranches(commonDir), worktrees, isProcessAlive) {
+		if dryRun {
+			fmt.Printf("would delete branch %s\n", o.Branch)
+			count++
+			continue
+		}
+		if o.Path != "" {
+			if err := removeLinkedWorktree(o.Path, adminDirs[o.Path]); err != nil {
+				fmt.Fprintf(os.Stderr, "ko gc: remove worktree %s: %v\n", o.Path, err)
+				continue
+			}
+		}
+		if err := deleteGitBranch(commonDir, o.Branch); err != nil {
+			fmt.Fprintf(os.Stderr, "ko gc: delete branch %s: %v\n", o.Branch, err)
+			continue
+		}
+		fmt.Printf("deleted branch %s\n", o.Branch)
+		count++
+	}
+	return count
+}
@@ -153,6 +153,6 @@ func importBuildHistory(db *DB, ticketID, jsonlPath string) {
 		if eventType == "" || ts == "" {
 			continue
 		}
-		db.InsertBuildEvent(uuid, eventType, ts, line)
+		db.InsertBuildEvent(0, uuid, eventType, ts, line)
 	}
 }
@@ -22,6 +22,7 @@ type ticketJSON struct {
 	Assignee          string         `json:"assignee,omitempty"`
 	Parent            string         `json:"parent,omitempty"`
 	Tags              []string       `json:"tags,omitempty"`
+	Fields            map[string]interface{} `json:"fields,omitempty"`
 	Description       string         `json:"description,omitempty"`
 	HasUnresolvedDep  bool           `json:"hasUnresolvedDep"`
 	PlanQuestions     []PlanQuestion `json:"plan-questions,omitempty"`
@@ -47,6 +48,7 @@ func ticketToJSON(t *Ticket, ticketsDir string) ticketJSON {
 		Assignee:         t.Assignee,
 		Parent:           t.Parent,
 		Tags:             t.Tags,
+		Fields:           fieldsJSON(t.Fields),
 		Description:      t.Body,
 		HasUnresolvedDep: !AllDepsResolved(ticketsDir, t.Deps),
 		PlanQuestions:    t.PlanQuestions,
@@ -124,7 +126,7 @@ func resolveProjectTicketsDir(args []string) (string, []string, error) {
 }
 
 func cmdLs(args []string) int {
-	args = reorderArgs(args, map[string]bool{"project": true, "status": true, "limit": true})
+	args = reorderArgs(args, map[string]bool{"project": true, "status": true, "limit": true, "field": true})
 
 	ticketsDir, args, err := resolveProjectTicketsDir(args)
 	if err != nil {
@@ -137,16 +139,23 @@ func cmdLs(args []string) int {
 	limit := fs.Int("limit", 0, "max tickets to show")
 	jsonOutput := fs.Bool("json", false, "output as JSON array")
 	allTickets := fs.Bool("all", false, "include closed tickets")
+	var fieldFlags stringList
+	fs.Var(&fieldFlags, "field", "filter by custom field: name=value, or !=, <, <=, >, >= (repeatable)")
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "ko ls: %v\n", err)
 		return 1
 	}
+	filters, err := fieldFiltersFor(ticketsDir, fieldFlags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko ls: %v\n", err)
+		return 1
+	}
 
 	db, dbErr := OpenReadDB()
 	if dbErr == nil {
 		defer db.Close()
 		project, _ := db.ResolveProjectTag(ticketsDir)
-		tickets, err := db.ListTicketsDB(project, *statusFilter, *allTickets, *limit)
+		tickets, err := db.ListTicketsDB(project, *statusFilter, *allTickets, *limit, filters...)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "ko ls: %v\n", err)
 			return 1
@@ -195,6 +204,9 @@ func cmdLs(args []string) int {
 			if *statusFilter == "" && !*allTickets && t.Status == "closed" {
 				continue
 			}
+			if !matchFieldFilters(t, filters) {
+				continue
+			}
 			result = append(result, ticketToJSON(t, ticketsDir))
 			count++
 			if *limit > 0 && count >= *limit {
@@ -213,6 +225,9 @@ func cmdLs(args []string) int {
 			if *statusFilter == "" && !*allTickets && t.Status == "closed" {
 				continue
 			}
+			if !matchFieldFilters(t, filters) {
+				continue
+			}
 			line := fmt.Sprintf("%s [%s] (p%d) %s", t.ID, t.Status, t.Priority, t.Title)
 			if len(t.Deps) > 0 {
 				line += fmt.Sprintf(" <- [%s]", strings.Join(t.Deps, ", "))
@@ -245,6 +260,7 @@ func ticketToJSONFromDB(t *Ticket, db *DB) ticketJSON {
 		Assignee:         t.Assignee,
 		Parent:           t.Parent,
 		Tags:             t.Tags,
+		Fields:           fieldsJSON(t.Fields),
 		Description:      t.Body,
 		HasUnresolvedDep: !db.AllDepsResolvedDB(t.ID),
 		PlanQuestions:    t.PlanQuestions,
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func cmdLogs(args []string) int {
+	ticketsDir, args, err := resolveProjectTicketsDir(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko logs: %v\n", err)
+		return 1
+	}
+
+	args = reorderArgs(args, map[string]bool{
+		"build": true,
+		"node":  true,
+	})
+
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	buildFlag := fs.Int("build", 0, "build number (default: latest)")
+	nodeFlag := fs.String("node", "", "only show output for this node")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "ko logs: %v\n", err)
+		return 1
+	}
+
+	if fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "ko logs: ticket ID required")
+		return 1
+	}
+
+	ticketsDir, id, err := ResolveTicket(ticketsDir, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko logs: %v\n", err)
+		return 1
+	}
+
+	artifactDir := ArtifactDir(ticketsDir, id)
+	builds := ListBuildNumbers(artifactDir)
+	if len(builds) == 0 {
+		fmt.Fprintf(os.Stderr, "ko logs: no build output recorded for %s\n", id)
+		return 1
+	}
+
+	build := builds[len(builds)-1]
+	if *buildFlag != 0 {
+		build = *buildFlag
+		if !containsInt(builds, build) {
+			fmt.Fprintf(os.Stderr, "ko logs: build %d not found for %s (available: %s)\n",
+				build, id, joinInts(builds))
+			return 1
+		}
+	}
+
+	files, err := ListBuildOutputs(BuildOutputDir(artifactDir, build), *nodeFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko logs: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		if *nodeFlag != "" {
+			fmt.Fprintf(os.Stderr, "ko logs: no output for node '%s' in build %d\n", *nodeFlag, build)
+		} else {
+			fmt.Fprintf(os.Stderr, "ko logs: build %d has no recorded output\n", build)
+		}
+		return 1
+	}
+
+	fmt.Printf("build %d of %s\n", build, id)
+	for _, f := range files {
+		data, err := os.ReadFile(f.Path)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		fmt.Printf("\n==> %s <==\n", f.Name)
+		os.Stdout.Write(data)
+		if data[len(data)-1] != '\n' {
+			fmt.Println()
+		}
+	}
+	return 0
+}
+
+// containsInt checks if a slice contains an int.
+func containsInt(ns []int, n int) bool {
+	for _, v := range ns {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// joinInts formats ints as a comma-separated list.
+func joinInts(ns []int) string {
+	parts := make([]string, len(ns))
+	for i, n := range ns {
+		parts[i] = fmt.Sprint(n)
+	}
+	return strings.Join(parts, ", ")
+}
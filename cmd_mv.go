@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cmdMv moves a ticket and its descendants to another registered project
+// (see move.go). References in every registered project are rewritten and
+// the old IDs stay resolvable as aliases.
+func cmdMv(args []string) int {
+	if len(args) != 2 || !strings.HasPrefix(args[1], "#") {
+		fmt.Fprintln(os.Stderr, "ko mv: ticket ID and target project required")
+		fmt.Fprintln(os.Stderr, "usage: ko mv <id> '#project'")
+		return 1
+	}
+	tag := CleanTag(args[1])
+
+	localDir, err := FindTicketsDir()
+	if err != nil && !errors.Is(err, ErrNoLocalProject) {
+		fmt.Fprintf(os.Stderr, "ko mv: %v\n", err)
+		return 1
+	}
+	_, id, err := ResolveTicket(localDir, args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko mv: %v\n", err)
+		return 1
+	}
+	db := getShadowDB()
+	if db == nil {
+		fmt.Fprintln(os.Stderr, "ko mv: database not available")
+		return 1
+	}
+	fromDir, err := db.TicketsDirOf(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko mv: %v\n", err)
+		return 1
+	}
+
+	reg, err := LoadRegistry(RegistryPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko mv: %v\n", err)
+		return 1
+	}
+	projectPath, ok := reg.Projects[tag]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ko mv: unknown project '%s'\n", tag)
+		return 1
+	}
+	toDir := resolveTicketsDir(projectPath)
+	if toDir == fromDir {
+		fmt.Fprintf(os.Stderr, "ko mv: %s is already in #%s\n", id, tag)
+		return 1
+	}
+	prefix := reg.Prefixes[tag]
+	if prefix == "" {
+		prefix = detectPrefix(toDir)
+	}
+
+	root, err := LoadTicket(fromDir, id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko mv: %v\n", err)
+		return 1
+	}
+	all, err := ListTickets(fromDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko mv: %v\n", err)
+		return 1
+	}
+	ids, moved := planMove(all, root, prefix, func(newID string) bool {
+		// An alias of a ticket being moved may be reclaimed.
+		t, err := LoadTicket(toDir, newID)
+		return err == nil && !strings.HasPrefix(t.ID+".", root.ID+".")
+	})
+	referrers := moveReferrers(reg, fromDir, ids)
+	oldOf := map[string]string{}
+	for oldID, newID := range ids {
+		oldOf[newID] = oldID
+	}
+
+	// Deps left behind must still resolve from the new project.
+	lookup := CrossProjectLookup(toDir, reg)
+	for _, t := range moved {
+		for _, dep := range t.Deps {
+			if _, ok := lookup(dep); !ok && oldOf[dep] == "" {
+				fmt.Fprintf(os.Stderr, "ko mv: warning: %s depends on %s, which does not resolve from #%s\n", t.ID, dep, tag)
+			}
+		}
+	}
+
+	renamed, err := moveArtifactDirs(ids, fromDir, toDir)
+	if err == nil {
+		err = db.MoveTickets(ids, moved, fromDir, toDir)
+	}
+	if err != nil {
+		for oldID, newID := range renamed {
+			os.Rename(ArtifactDir(toDir, newID), ArtifactDir(fromDir, oldID))
+		}
+		fmt.Fprintf(os.Stderr, "ko mv: %v\n", err)
+		return 1
+	}
+
+	for _, t := range moved {
+		oldID := oldOf[t.ID]
+		EmitMutationEvent(toDir, t.ID, "move", map[string]interface{}{
+			"from":    oldID,
+			"to":      t.ID,
+			"project": tag,
+		})
+		fmt.Printf("%s -> %s\n", oldID, t.ID)
+	}
+	if root.Parent != "" {
+		fmt.Printf("%s is now a top-level ticket (was a child of %s)\n", ids[root.ID], root.Parent)
+	}
+	for _, r := range referrers {
+		for _, c := range r.changes {
+			EmitMutationEvent(r.dir, r.ticket.ID, "update", map[string]interface{}{
+				"ticket": r.ticket.ID,
+				"field":  c.Field,
+				"from":   c.Before,
+				"to":     c.After,
+			})
+		}
+	}
+	if len(referrers) > 0 {
+		fmt.Printf("Updated references in %d tickets\n", len(referrers))
+	}
+	return 0
+}
+
+// moveReferrer is a ticket outside a move whose deps or parent pointed at
+// a moved ticket.
+type moveReferrer struct {
+	dir     string
+	ticket  *Ticket
+	changes []fieldChange
+}
+
+// moveReferrers finds the tickets of fromDir and every registered project
+// that refer to a moved ticket, with their references rewritten.
+func moveReferrers(reg *Registry, fromDir string, ids map[string]string) []moveReferrer {
+	dirs := []string{fromDir}
+	tags := make([]string, 0, len(reg.Projects))
+	for tag := range reg.Projects {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		if dir := resolveTicketsDir(reg.Projects[tag]); !contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	var out []moveReferrer
+	for _, dir := range dirs {
+		tickets, _ := ListTickets(dir)
+		for _, t := range tickets {
+			if _, moving := ids[t.ID]; moving {
+				continue
+			}
+			if c := rewriteRefs(t, ids); len(c) > 0 {
+				out = append(out, moveReferrer{dir, t, c})
+			}
+		}
+	}
+	return out
+}
+
+// moveArtifactDirs renames the artifact directories of moved tickets and
+// returns the ones it renamed, so a failed move can put them back.
+func moveArtifactDirs(ids map[string]string, fromDir, toDir string) (map[string]string, error) {
+	renamed := map[string]string{}
+	for oldID, newID := range ids {
+		src := ArtifactDir(fromDir, oldID)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		dst := ArtifactDir(toDir, newID)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return renamed, err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return renamed, err
+		}
+		renamed[oldID] = newID
+	}
+	return renamed, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func cmdNotify(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "ko notify: subcommand required (list, flush)")
+		return 1
+	}
+	switch args[0] {
+	case "list", "ls":
+		return cmdNotifyList(args[1:])
+	case "flush":
+		return cmdNotifyFlush(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "ko notify: unknown subcommand '%s'\n", args[0])
+		return 1
+	}
+}
+
+// cmdNotifyList shows recent webhook deliveries, newest first.
+func cmdNotifyList(args []string) int {
+	fs := flag.NewFlagSet("notify list", flag.ContinueOnError)
+	statusFlag := fs.String("status", "", "only deliveries with this status (pending, delivered, failed)")
+	limitFlag := fs.Int("limit", 20, "maximum deliveries to show")
+	jsonFlag := fs.Bool("json", false, "output as JSON")
+	if err := fs.Parse(reorderArgs(args, map[string]bool{"status": true, "limit": true})); err != nil {
+		fmt.Fprintf(os.Stderr, "ko notify list: %v\n", err)
+		return 1
+	}
+
+	db, err := OpenDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko notify list: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	deliveries, err := db.ListNotifications(*statusFlag, *limitFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko notify list: %v\n", err)
+		return 1
+	}
+
+	if *jsonFlag {
+		if deliveries == nil {
+			deliveries = []NotificationDelivery{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(deliveries)
+		return 0
+	}
+
+	if len(deliveries) == 0 {
+		fmt.Println("No notifications.")
+		return 0
+	}
+	for _, n := range deliveries {
+		line := fmt.Sprintf("%d  %s  %-9s %-14s %-12s %s", n.ID, formatTime(n.CreatedAt), n.Status, n.Event, n.Ticket, n.Target)
+		if n.Attempts > 0 && n.Status != "delivered" {
+			line += fmt.Sprintf(" (%d attempts: %s)", n.Attempts, n.LastError)
+		}
+		fmt.Println(line)
+	}
+	return 0
+}
+
+// cmdNotifyFlush delivers every due notification now. --force also retries
+// pending deliveries that are still backing off.
+func cmdNotifyFlush(args []string) int {
+	fs := flag.NewFlagSet("notify flush", flag.ContinueOnError)
+	force := fs.Bool("force", false, "retry pending deliveries without waiting for their backoff")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "ko notify flush: %v\n", err)
+		return 1
+	}
+
+	db, err := OpenDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko notify flush: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	delivered, failed := flushNotifications(db, time.Now(), *force)
+	fmt.Printf("%d delivered, %d failed\n", delivered, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
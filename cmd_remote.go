@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func cmdRemote(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "ko remote: subcommand required (status, sync, retry, drop)")
+		return 1
+	}
+	switch args[0] {
+	case "status":
+		return cmdRemoteStatus(args[1:])
+	case "sync":
+		return cmdRemoteSync(args[1:])
+	case "retry":
+		return cmdRemoteRetry(args[1:])
+	case "drop":
+		return cmdRemoteDrop(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "ko remote: unknown subcommand '%s'\n", args[0])
+		return 1
+	}
+}
+
+// cmdRemoteStatus shows the configured server and the offline queue.
+func cmdRemoteStatus(args []string) int {
+	fs := flag.NewFlagSet("remote status", flag.ContinueOnError)
+	allFlag := fs.Bool("all", false, "include commands that were already applied")
+	jsonFlag := fs.Bool("json", false, "output as JSON")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "ko remote status: %v\n", err)
+		return 1
+	}
+
+	db, err := OpenDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko remote status: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	queue, err := db.ListRemoteMutations(*allFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko remote status: %v\n", err)
+		return 1
+	}
+
+	if *jsonFlag {
+		if queue == nil {
+			queue = []RemoteMutation{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(queue)
+		return 0
+	}
+
+	if cfg, err := LoadGlobalConfig(); err == nil && cfg.Server != "" {
+		fmt.Printf("server: %s\n", cfg.Server)
+	} else {
+		fmt.Println("server: none (local mode)")
+	}
+	if len(queue) == 0 {
+		fmt.Println("No queued commands.")
+		return 0
+	}
+	for _, m := range queue {
+		line := fmt.Sprintf("#%d  %s  %-8s %s", m.ID, formatTime(m.CreatedAt), m.Status, strings.Join(m.Argv, " "))
+		if m.LastError != "" {
+			line += "\n      " + m.LastError
+		}
+		fmt.Println(line)
+	}
+	return 0
+}
+
+// cmdRemoteSync replays the queued commands for the configured server.
+func cmdRemoteSync(args []string) int {
+	cfg, err := LoadGlobalConfig()
+	if err != nil || cfg.Server == "" {
+		fmt.Fprintln(os.Stderr, "ko remote sync: no server: configured")
+		return 1
+	}
+	db, err := OpenDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko remote sync: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	applied, held, err := replayRemoteQueue(db, cfg.Server, cfg.Token, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko remote sync: server unreachable: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%d replayed, %d held\n", applied, held)
+	if held > 0 {
+		return 1
+	}
+	return 0
+}
+
+// cmdRemoteRetry queues conflicting or failed commands again, skipping the
+// conflict check, and replays the queue.
+func cmdRemoteRetry(args []string) int {
+	return remoteQueueEdit("retry", args, func(db *DB, id int64) error {
+		return db.RetryRemoteMutation(id)
+	}, true)
+}
+
+// cmdRemoteDrop removes commands from the queue without applying them.
+func cmdRemoteDrop(args []string) int {
+	return remoteQueueEdit("drop", args, func(db *DB, id int64) error {
+		return db.DropRemoteMutation(id)
+	}, false)
+}
+
+// remoteQueueEdit applies edit to each #id in args, then replays the queue
+// when sync is set.
+func remoteQueueEdit(name string, args []string, edit func(*DB, int64) error, sync bool) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "ko remote %s: queue ID required\n", name)
+		return 1
+	}
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ko remote %s: invalid queue ID '%s'\n", name, arg)
+			return 1
+		}
+		ids = append(ids, id)
+	}
+
+	db, err := OpenDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko remote %s: %v\n", name, err)
+		return 1
+	}
+	for _, id := range ids {
+		if err := edit(db, id); err != nil {
+			db.Close()
+			fmt.Fprintf(os.Stderr, "ko remote %s: %v\n", name, err)
+			return 1
+		}
+	}
+	db.Close()
+	if !sync {
+		return 0
+	}
+	return cmdRemoteSync(nil)
+}
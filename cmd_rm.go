@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// cmdRm deletes a ticket and its artifacts. Tickets that others depend on
+// are kept unless --rewire hands their deps to the dependents (see
+// rewireDeps); tickets with children are always kept.
+func cmdRm(args []string) int {
+	args = reorderArgs(args, map[string]bool{})
+
+	fs := flag.NewFlagSet("rm", flag.ContinueOnError)
+	rewire := fs.Bool("rewire", false, "make dependents depend on the ticket's own deps instead")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "ko rm: %v\n", err)
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: ko rm <id> [--rewire]")
+		return 1
+	}
+
+	localDir, err := FindTicketsDir()
+	if err != nil && !errors.Is(err, ErrNoLocalProject) {
+		fmt.Fprintf(os.Stderr, "ko rm: %v\n", err)
+		return 1
+	}
+	_, id, err := ResolveTicket(localDir, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko rm: %v\n", err)
+		return 1
+	}
+	db := getShadowDB()
+	if db == nil {
+		fmt.Fprintln(os.Stderr, "ko rm: database not available")
+		return 1
+	}
+	ticketsDir, err := db.TicketsDirOf(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko rm: %v\n", err)
+		return 1
+	}
+	t, err := LoadTicket(ticketsDir, id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko rm: %v\n", err)
+		return 1
+	}
+
+	if children, _ := db.GetChildrenDB(id); len(children) > 0 {
+		fmt.Fprintf(os.Stderr, "ko rm: %s has children (%s); remove or move them first\n", id, strings.Join(children, ", "))
+		return 1
+	}
+	dependents, err := db.GetBlockingDB(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko rm: %v\n", err)
+		return 1
+	}
+	if len(dependents) > 0 && !*rewire {
+		fmt.Fprintf(os.Stderr, "ko rm: %s is a dependency of %s (use --rewire to hand its deps to them)\n", id, strings.Join(dependents, ", "))
+		return 1
+	}
+
+	rewired := map[string][]*Ticket{}
+	changes := map[string][]fieldChange{}
+	dirOf := map[string]string{}
+	for _, depID := range dependents {
+		dir, err := db.TicketsDirOf(depID)
+		if err == nil {
+			var dt *Ticket
+			if dt, err = LoadTicket(dir, depID); err == nil {
+				rewired[dir] = append(rewired[dir], dt)
+				changes[depID] = rewireDeps(dt, t)
+				dirOf[depID] = dir
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ko rm: %s: %v\n", depID, err)
+			return 1
+		}
+	}
+
+	if err := db.DeleteTicket(id, rewired); err != nil {
+		fmt.Fprintf(os.Stderr, "ko rm: %v\n", err)
+		return 1
+	}
+	os.RemoveAll(ArtifactDir(ticketsDir, id))
+	os.Remove(ArtifactArchive(ticketsDir, id))
+
+	data := map[string]interface{}{"title": t.Title, "status": t.Status}
+	if len(dependents) > 0 {
+		data["rewired"] = dependents
+	}
+	EmitMutationEvent(ticketsDir, id, "rm", data)
+	fmt.Printf("%s removed\n", id)
+	for _, depID := range dependents {
+		for _, c := range changes[depID] {
+			EmitMutationEvent(dirOf[depID], depID, "update", map[string]interface{}{
+				"ticket": depID,
+				"field":  c.Field,
+				"from":   c.Before,
+				"to":     c.After,
+			})
+		}
+	}
+	if len(dependents) > 0 {
+		fmt.Printf("Rewired deps of %d tickets\n", len(dependents))
+	}
+	return 0
+}
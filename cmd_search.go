@@ -14,8 +14,11 @@ func cmdSearch(args []string) int {
 	statusFlag := fs.String("status", "", "Filter by status")
 	typeFlag := fs.String("type", "", "Filter by ticket type")
 	tagFlag := fs.String("tag", "", "Filter by ticket tag")
+	archivedFlag := fs.Bool("archived", false, "Include archived tickets")
 	limitFlag := fs.Int("limit", 50, "Maximum results")
 	jsonFlag := fs.Bool("json", false, "Output as JSON")
+	var fieldFlags stringList
+	fs.Var(&fieldFlags, "field", "Filter by custom field: name=value, or !=, <, <=, >, >= (repeatable)")
 
 	// Reorder args to handle flags after query
 	reordered := reorderArgs(args, map[string]bool{
@@ -24,6 +27,7 @@ func cmdSearch(args []string) int {
 		"type":    true,
 		"tag":     true,
 		"limit":   true,
+		"field":   true,
 	})
 
 	if err := fs.Parse(reordered); err != nil {
@@ -31,12 +35,26 @@ func cmdSearch(args []string) int {
 	}
 
 	if fs.NArg() == 0 {
-		fmt.Fprintln(os.Stderr, "Usage: ko search <query> [--project=tag] [--status=X] [--type=X] [--tag=X] [--limit=50] [--json]")
+		fmt.Fprintln(os.Stderr, "Usage: ko search <query> [--project=tag] [--status=X] [--type=X] [--tag=X] [--field=name=value] [--archived] [--limit=50] [--json]")
 		return 1
 	}
 
 	query := strings.Join(fs.Args(), " ")
 
+	// Field values are checked against the searched project's schema,
+	// else the local one
+	schemaDir, _ := FindTicketsDir()
+	if *projectFlag != "" {
+		if dir, _, err := resolveProjectTicketsDir([]string{"--project", *projectFlag}); err == nil {
+			schemaDir = dir
+		}
+	}
+	filters, err := fieldFiltersFor(schemaDir, fieldFlags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ko: search:", err)
+		return 1
+	}
+
 	db, err := OpenReadDB()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "ko:", err)
@@ -44,7 +62,7 @@ func cmdSearch(args []string) int {
 	}
 	defer db.Close()
 
-	results, err := db.SearchTickets(query, *projectFlag, *statusFlag, *typeFlag, *tagFlag, *limitFlag)
+	results, err := db.SearchTickets(query, *projectFlag, *statusFlag, *typeFlag, *tagFlag, *archivedFlag, *limitFlag, filters...)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "ko: search:", err)
 		return 1
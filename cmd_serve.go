@@ -2,14 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"embed"
-	"encoding/json"
 	"flag"
 	"fmt"
 	iofs "io/fs"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
 	"syscall"
 	"time"
@@ -23,31 +22,14 @@ func cmdServe(args []string) int {
 	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 	port := fs.String("port", "19876", "port to listen on")
+	listen := fs.String("listen", "", "address to listen on: host:port, :port or unix:/path/ko.sock (overrides --port)")
+	tlsCert := fs.String("tls-cert", "", "TLS certificate file (PEM); serve HTTPS")
+	tlsKey := fs.String("tls-key", "", "TLS private key file (PEM)")
 
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
 
-	// Define whitelist of allowed subcommands
-	whitelist := map[string]bool{
-		"add":     true,
-		"show":    true,
-		"ls":      true,
-		"ready":   true,
-		"triage":  true,
-		"update":  true,
-		"status":  true,
-		"start":   true,
-		"close":   true,
-		"open":    true,
-		"dep":     true,
-		"undep":   true,
-		"note":    true,
-		"bump":    true,
-		"agent":   true,
-		"project": true,
-	}
-
 	// Start global event tailer
 	globalTailer.start()
 
@@ -58,69 +40,61 @@ func cmdServe(args []string) int {
 	mux.HandleFunc("/agent/spawn", handleAgentSpawn)
 	mux.HandleFunc("/agent/kill", handleAgentKill)
 	mux.HandleFunc("/agent/status", handleAgentStatus)
-	mux.HandleFunc("/ko", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-
-		// Parse JSON body
-		var req struct {
-			Argv []string `json:"argv"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, fmt.Sprintf("invalid JSON: %v", err), http.StatusBadRequest)
-			return
-		}
-
-		// Validate argv
-		if len(req.Argv) == 0 {
-			http.Error(w, "argv must have at least one element", http.StatusBadRequest)
-			return
-		}
-
-		// Check if first element is whitelisted
-		subcommand := req.Argv[0]
-		if !whitelist[subcommand] {
-			errResp := map[string]string{
-				"error": fmt.Sprintf("subcommand '%s' not allowed", subcommand),
-			}
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(errResp)
-			return
-		}
-
-		// Execute command
-		cmd := exec.Command(os.Args[0], req.Argv...)
-		output, err := cmd.CombinedOutput()
-
-		if err != nil {
-			// Non-zero exit code: return 400 with stderr
-			errResp := map[string]string{
-				"error": string(output),
-			}
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(errResp)
-			return
-		}
-
-		// Success: return 200 with stdout
-		w.Header().Set("Content-Type", "text/plain")
-		w.WriteHeader(http.StatusOK)
-		w.Write(output)
-	})
+	mux.HandleFunc("/hooks/", handleInboundHook)
+	mux.HandleFunc("/builds/", handleBuildStream)
+	registerAPI(mux)
+	mux.HandleFunc("/ko", handleKo)
 
 	// Serve web UI
 	webContent, _ := iofs.Sub(webFS, "web")
 	mux.Handle("/", http.FileServer(http.FS(webContent)))
 
-	// Create server
+	// Refuse to start on a broken tokens: section rather than serve openly
+	tokens, err := loadServeTokens()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko serve: %v\n", err)
+		return 1
+	}
+	if len(tokens) == 0 {
+		fmt.Fprintln(os.Stderr, "ko serve: warning: no tokens configured; anyone who can reach the port can read, write and spawn agents")
+	} else if global, err := LoadGlobalConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "ko serve: %v\n", err)
+		return 1
+	} else if err := requireInboundSecrets(global.Inbound); err != nil {
+		fmt.Fprintf(os.Stderr, "ko serve: %v\n", err)
+		return 1
+	}
+
+	// Open the listener up front so a bad address or busy port fails startup
 	addr := ":" + *port
+	if *listen != "" {
+		addr = *listen
+	}
+	network, address, err := parseListenAddr(addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko serve: %v\n", err)
+		return 1
+	}
+	tlsConfig, err := serveTLSConfig(*tlsCert, *tlsKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko serve: %v\n", err)
+		return 1
+	}
+	ln, err := listenServe(network, address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko serve: %v\n", err)
+		return 1
+	}
+	if network == "unix" {
+		defer os.Remove(address)
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+
+	// Create server
 	server := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+		Handler: requireToken(mux),
 	}
 
 	// Channel to signal server started
@@ -128,9 +102,9 @@ func cmdServe(args []string) int {
 
 	// Start server in goroutine
 	go func() {
-		fmt.Fprintf(os.Stdout, "ko serve: listening on %s\n", addr)
+		fmt.Fprintf(os.Stdout, "ko serve: listening on %s\n", listenURL(network, address, tlsConfig != nil))
 		close(serverStarted)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
 			fmt.Fprintf(os.Stderr, "ko serve: %v\n", err)
 		}
 	}()
@@ -138,6 +112,10 @@ func cmdServe(args []string) int {
 	// Wait for server to start
 	<-serverStarted
 
+	// Retry webhook deliveries that are backing off
+	stopNotifications := startNotificationFlusher(notificationFlushInterval)
+	defer stopNotifications()
+
 	// Set up signal handling for graceful shutdown
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// inboundAuthorized checks a request against the hook secret: either
+// "Authorization: Bearer <secret>" or an X-Ko-Signature-256 (or GitHub's
+// X-Hub-Signature-256) HMAC of the body. A hook without a secret is open;
+// handleInboundHook only serves one while ko serve has no tokens.
+func inboundAuthorized(secret string, r *http.Request, body []byte) bool {
+	if secret == "" {
+		return true
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+	sig := r.Header.Get("X-Ko-Signature-256")
+	if sig == "" {
+		sig = r.Header.Get("X-Hub-Signature-256")
+	}
+	got, ok := strings.CutPrefix(sig, "sha256=")
+	if !ok {
+		return false
+	}
+	gotMAC, err := hex.DecodeString(got)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(gotMAC, mac.Sum(nil))
+}
+
+// handleInboundHook serves POST /hooks/<name>. The global config is re-read
+// on every request so hook edits apply without restarting ko serve.
+func handleInboundHook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/hooks/")
+
+	global, err := LoadGlobalConfig()
+	if err != nil {
+		writeInboundError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var hook *InboundHook
+	for i := range global.Inbound {
+		if global.Inbound[i].Name == name {
+			hook = &global.Inbound[i]
+		}
+	}
+	if hook == nil {
+		writeInboundError(w, http.StatusNotFound, fmt.Errorf("unknown hook %q", name))
+		return
+	}
+	if len(global.Tokens) > 0 && hook.Secret == "" {
+		// Added after ko serve started, which checks this too.
+		writeInboundError(w, http.StatusForbidden, fmt.Errorf("hook %q has no secret; set one to use it with tokens: configured", name))
+		return
+	}
+
+	body, err := readLimited(r, inboundMaxBody)
+	if err != nil {
+		writeInboundError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	secret, err := resolveGlobalSecret(hook.Secret)
+	if err != nil {
+		writeInboundError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !inboundAuthorized(secret, r, body) {
+		writeInboundError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing credentials"))
+		return
+	}
+
+	var payload interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&payload); err != nil {
+		writeInboundError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+
+	reg, err := LoadRegistry(RegistryPath())
+	if err != nil {
+		writeInboundError(w, http.StatusInternalServerError, err)
+		return
+	}
+	projectPath, ok := reg.Projects[hook.Project]
+	if !ok {
+		writeInboundError(w, http.StatusInternalServerError, fmt.Errorf("hook %q: unknown project '%s'", name, hook.Project))
+		return
+	}
+	ticketsDir := resolveTicketsDir(projectPath)
+
+	items, err := inboundItems(*hook, payload)
+	if err != nil {
+		writeInboundError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	var rendered []InboundTicket
+	for _, item := range items {
+		t, ok, err := renderInboundTicket(*hook, item)
+		if err != nil {
+			writeInboundError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if ok {
+			rendered = append(rendered, t)
+		}
+	}
+
+	inboundMu.Lock()
+	defer inboundMu.Unlock()
+	results := []InboundResult{}
+	for _, t := range rendered {
+		res, err := applyInboundTicket(ticketsDir, reg.Prefixes[hook.Project], name, t)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ko serve: hook %s: %v\n", name, err)
+			writeInboundError(w, http.StatusInternalServerError, err)
+			return
+		}
+		results = append(results, res)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"project": hook.Project,
+		"tickets": results,
+	})
+}
+
+// readLimited reads a request body of at most limit bytes.
+func readLimited(r *http.Request, limit int64) ([]byte, error) {
+	var buf bytes.Buffer
+	n, err := buf.ReadFrom(http.MaxBytesReader(nil, r.Body, limit))
+	if err != nil {
+		return nil, fmt.Errorf("request body: %v", err)
+	}
+	return buf.Bytes()[:n], nil
+}
+
+// writeInboundError writes a JSON {"error": ...} response.
+func writeInboundError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInboundAuthorized(t *testing.T) {
+	body := []byte(`{"title":"x"}`)
+	req := func(header, value string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hooks/x", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		return r
+	}
+	if !inboundAuthorized("", req("", ""), body) {
+		t.Error("hook without a secret should be open")
+	}
+	if inboundAuthorized("s3cret", req("", ""), body) {
+		t.Error("missing credentials accepted")
+	}
+	if !inboundAuthorized("s3cret", req("Authorization", "Bearer s3cret"), body) {
+		t.Error("bearer token rejected")
+	}
+	if inboundAuthorized("s3cret", req("Authorization", "Bearer nope"), body) {
+		t.Error("wrong bearer token accepted")
+	}
+	sig := signNotification("s3cret", string(body))
+	if !inboundAuthorized("s3cret", req("X-Hub-Signature-256", sig), body) {
+		t.Error("valid HMAC rejected")
+	}
+	if inboundAuthorized("s3cret", req("X-Ko-Signature-256", sig), []byte(`{"title":"y"}`)) {
+		t.Error("HMAC over a different body accepted")
+	}
+}
+
+func TestHandleInboundHook(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("INBOUND_TOKEN", "tok")
+	projectDir := t.TempDir()
+	ticketsDir := filepath.Join(projectDir, ".ko", "tickets")
+	os.MkdirAll(ticketsDir, 0755)
+	os.MkdirAll(filepath.Join(configHome, "knockout"), 0755)
+	os.WriteFile(filepath.Join(configHome, "knockout", "projects.yml"),
+		[]byte("projects:\n  ops:\n    path: "+projectDir+"\n    prefix: op\n"), 0644)
+	os.WriteFile(filepath.Join(configHome, "knockout", "config.yaml"), []byte(alertmanagerHook), 0644)
+
+	post := func(name, token, body string) (int, map[string]interface{}) {
+		r := httptest.NewRequest(http.MethodPost, "/hooks/"+name, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handleInboundHook(w, r)
+		var out map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &out)
+		return w.Code, out
+	}
+	alert := `{"alerts": [{"status": "firing", "fingerprint": "abc123",
+		"labels": {"alertname": "DiskFull", "instance": "db1", "severity": "critical"}}]}`
+
+	if code, _ := post("alertmanager", "", alert); code != http.StatusUnauthorized {
+		t.Errorf("no token: code = %d, want 401", code)
+	}
+	if code, _ := post("missing", "", alert); code != http.StatusNotFound {
+		t.Errorf("unknown hook: code = %d, want 404", code)
+	}
+	if code, _ := post("alertmanager", "tok", "{not json"); code != http.StatusBadRequest {
+		t.Errorf("bad JSON: code = %d, want 400", code)
+	}
+
+	code, out := post("alertmanager", "tok", alert)
+	if code != http.StatusOK {
+		t.Fatalf("create: code = %d, body = %v", code, out)
+	}
+	results := out["tickets"].([]interface{})
+	first := results[0].(map[string]interface{})
+	id := first["id"].(string)
+	if first["action"] != "created" || !strings.HasPrefix(id, "op-") {
+		t.Fatalf("create result = %v", first)
+	}
+	tk, err := LoadTicket(ticketsDir, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Title != "DiskFull on db1" || tk.Priority != 0 || tk.Type != "bug" || tk.ExternalRef != "alertmanager:abc123" {
+		t.Errorf("ticket = %+v", tk)
+	}
+
+	// The same fingerprint again notes the open ticket
+	_, out = post("alertmanager", "tok", alert)
+	again := out["tickets"].([]interface{})[0].(map[string]interface{})
+	if again["id"] != id || again["action"] != "updated" {
+		t.Errorf("repeat result = %v, want updated %s", again, id)
+	}
+
+	// Once closed, it is reopened rather than duplicated
+	tk, _ = LoadTicket(ticketsDir, id)
+	tk.Status = "closed"
+	SaveTicket(ticketsDir, tk)
+	_, out = post("alertmanager", "tok", alert)
+	reopened := out["tickets"].([]interface{})[0].(map[string]interface{})
+	if reopened["id"] != id || reopened["action"] != "reopened" {
+		t.Errorf("reopen result = %v", reopened)
+	}
+	tk, _ = LoadTicket(ticketsDir, id)
+	if tk.Status != "open" || !strings.Contains(tk.Body, "reopened by webhook 'alertmanager'") {
+		t.Errorf("reopened ticket status = %s, body = %q", tk.Status, tk.Body)
+	}
+	all, _ := ListTickets(ticketsDir)
+	if len(all) != 1 {
+		t.Errorf("got %d tickets, want 1", len(all))
+	}
+
+	// Once tokens: is configured, the open hook is refused
+	os.WriteFile(filepath.Join(configHome, "knockout", "config.yaml"),
+		[]byte("tokens:\n  - name: admin\n    token: t0k3n\n    scopes: [read, write]\n"+alertmanagerHook), 0644)
+	if code, out := post("generic", "", `{"title": "Build failed"}`); code != http.StatusForbidden {
+		t.Errorf("open hook with tokens: code = %d, body = %v, want 403", code, out)
+	}
+	if code, _ := post("alertmanager", "tok", alert); code != http.StatusOK {
+		t.Errorf("hook with a secret: code = %d, want 200", code)
+	}
+
+	// Secrets resolve like token values, from secrets.env
+	os.WriteFile(filepath.Join(configHome, "knockout", "secrets.env"), []byte("HOOK_TOKEN=fromfile\n"), 0600)
+	os.WriteFile(filepath.Join(configHome, "knockout", "config.yaml"), []byte(strings.Replace(alertmanagerHook,
+		"$INBOUND_TOKEN", "${secrets.HOOK_TOKEN}", 1)), 0644)
+	if code, out := post("alertmanager", "fromfile", alert); code != http.StatusOK {
+		t.Errorf("secret from secrets.env: code = %d, body = %v, want 200", code, out)
+	}
+}
@@ -15,7 +15,8 @@ import (
 type subscriber struct {
 	project      string
 	ch           chan string
-	includeAgent bool // true for /status/ endpoint, false for /subscribe/
+	includeAgent bool  // true for /status/ endpoint, false for /subscribe/
+	offset       int64 // events.jsonl offset when it subscribed
 }
 
 // tailer manages the global event stream and broadcasts to subscribers.
@@ -23,7 +24,7 @@ type tailer struct {
 	mu              sync.Mutex
 	subscribers     map[*subscriber]bool
 	started         bool
-	eventID         int64
+	offset          int64 // events.jsonl offset up to which events are broadcast
 	agentPollQuit   chan struct{}
 	agentPollStatus map[string]agentStatusJSON // tracks last known status per project
 }
@@ -33,11 +34,12 @@ var globalTailer = &tailer{
 	agentPollStatus: make(map[string]agentStatusJSON),
 }
 
-// subscribe registers a new subscriber for a project.
+// subscribe registers a new subscriber for a project. Events past
+// sub.offset are sent to it; earlier ones can be replayed from the file.
 func (t *tailer) subscribe(project string, ch chan string, includeAgent bool) *subscriber {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	sub := &subscriber{project: project, ch: ch, includeAgent: includeAgent}
+	sub := &subscriber{project: project, ch: ch, includeAgent: includeAgent, offset: t.offset}
 	t.subscribers[sub] = true
 	return sub
 }
@@ -63,21 +65,6 @@ func (t *tailer) start() {
 	go t.pollAgentStatus()
 }
 
-// broadcast sends an SSE message to all subscribers for a given project (tickets-only subscribers).
-func (t *tailer) broadcast(project, message string) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	for sub := range t.subscribers {
-		if sub.project == project && !sub.includeAgent {
-			select {
-			case sub.ch <- message:
-			default:
-				// Channel full, drop event (best-effort delivery)
-			}
-		}
-	}
-}
-
 // broadcastToSubscribers sends an SSE message to subscribers matching the filter.
 func (t *tailer) broadcastToSubscribers(project, message string, includeAgentOnly bool) {
 	t.mu.Lock()
@@ -93,14 +80,6 @@ func (t *tailer) broadcastToSubscribers(project, message string, includeAgentOnl
 	}
 }
 
-// nextEventID returns the next monotonic event ID.
-func (t *tailer) nextEventID() int64 {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	t.eventID++
-	return t.eventID
-}
-
 // tailEventStream watches the mutation event file and broadcasts to subscribers.
 func (t *tailer) tailEventStream() {
 	path := mutationEventPath()
@@ -138,9 +117,11 @@ func (t *tailer) tailFile(path string) error {
 	defer f.Close()
 
 	// Seek to end — only process new events
-	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+	pos, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
 		return err
 	}
+	t.setOffset(pos)
 
 	buf := make([]byte, 4096)
 	var partial []byte
@@ -163,18 +144,16 @@ func (t *tailer) tailFile(path string) error {
 				}
 				line := partial[:newline]
 				partial = partial[newline+1:]
-
-				if len(line) == 0 {
-					continue
-				}
+				pos += int64(newline + 1)
 
 				var evt MutationEvent
-				if err := json.Unmarshal(line, &evt); err != nil {
+				if len(line) == 0 || json.Unmarshal(line, &evt) != nil {
+					t.setOffset(pos)
 					continue
 				}
 
 				// Re-query the project and broadcast to subscribers
-				t.broadcastToProject(evt.Project)
+				t.broadcastToProject(evt.Project, string(line), pos)
 			}
 		}
 		if err != nil {
@@ -187,22 +166,24 @@ func (t *tailer) tailFile(path string) error {
 	}
 }
 
-// broadcastToProject re-queries a project and sends the result as SSE to all subscribers.
-func (t *tailer) broadcastToProject(projectPath string) {
+// broadcastToProject re-queries a project and sends the result as SSE to
+// all subscribers, followed by the raw event as a "mutation" event. Both
+// carry offset, the end of the event in events.jsonl, as their ID.
+func (t *tailer) broadcastToProject(projectPath, event string, offset int64) {
 	// Find tickets directory for this project
 	ticketsDir := projectPath + "/.ko/tickets"
 
 	// Query tickets directly (avoid exec for testability)
 	tickets, err := ListTickets(ticketsDir)
 	if err != nil {
+		t.setOffset(offset)
 		return // Best-effort: skip on error
 	}
 	SortByPriorityThenModified(tickets)
 
 	// Format as SSE event for /subscribe/ endpoint (tickets only, no type wrapper)
-	eventID := t.nextEventID()
 	var message strings.Builder
-	fmt.Fprintf(&message, "id: %d\n", eventID)
+	fmt.Fprintf(&message, "id: %d\n", offset)
 
 	// Serialize tickets as JSONL with "data: " prefix
 	for _, ticket := range tickets {
@@ -214,13 +195,11 @@ func (t *tailer) broadcastToProject(projectPath string) {
 		fmt.Fprintf(&message, "data: %s\n", string(line))
 	}
 	fmt.Fprintf(&message, "\n")
-
-	t.broadcast(projectPath, message.String())
+	message.WriteString(mutationMessage(event, offset))
 
 	// Also send to /status/ subscribers with type wrappers
-	eventID2 := t.nextEventID()
 	var statusMessage strings.Builder
-	fmt.Fprintf(&statusMessage, "id: %d\n", eventID2)
+	fmt.Fprintf(&statusMessage, "id: %d\n", offset)
 
 	for _, ticket := range tickets {
 		j := ticketToJSON(ticket, ticketsDir)
@@ -235,8 +214,9 @@ func (t *tailer) broadcastToProject(projectPath string) {
 		fmt.Fprintf(&statusMessage, "data: %s\n", string(line))
 	}
 	fmt.Fprintf(&statusMessage, "\n")
+	statusMessage.WriteString(mutationMessage(event, offset))
 
-	t.broadcastToSubscribers(projectPath, statusMessage.String(), true)
+	t.publish(projectPath, message.String(), statusMessage.String(), offset)
 }
 
 // getAgentStatus returns the agent status for the given tickets directory.
@@ -318,9 +298,8 @@ func (t *tailer) checkAgentStatusChanges() {
 
 // broadcastAgentStatus sends agent status as an SSE event to all subscribers.
 func (t *tailer) broadcastAgentStatus(projectPath string, status agentStatusJSON) {
-	eventID := t.nextEventID()
 	var message strings.Builder
-	fmt.Fprintf(&message, "id: %d\n", eventID)
+	fmt.Fprintf(&message, "id: %d\n", t.currentOffset())
 
 	// Wrap status in type envelope
 	envelope := map[string]interface{}{
@@ -395,6 +374,18 @@ func handleSubscribeWithTailer(w http.ResponseWriter, r *http.Request, t *tailer
 		}
 	}
 
+	resume, err := parseResumePoint(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Register subscriber (tickets only, no agent status) before the
+	// snapshot, so no event falls between the two
+	ch := make(chan string, 10) // Buffered for backpressure handling
+	sub := t.subscribe(projectPath, ch, false)
+	defer t.unsubscribe(sub)
+
 	// Get initial snapshot
 	ticketsDir := projectPath + "/.ko/tickets"
 	tickets, err := ListTickets(ticketsDir)
@@ -420,8 +411,8 @@ func handleSubscribeWithTailer(w http.ResponseWriter, r *http.Request, t *tailer
 	fmt.Fprintf(w, "retry: 3000\n\n")
 	flusher.Flush()
 
-	// Send initial snapshot with id=0
-	fmt.Fprintf(w, "id: 0\n")
+	// Replay what a resuming client missed, then the snapshot
+	resumeSubscriber(w, resume, sub)
 	for _, ticket := range tickets {
 		j := ticketToJSON(ticket, ticketsDir)
 		line, jsonErr := json.Marshal(j)
@@ -433,11 +424,6 @@ func handleSubscribeWithTailer(w http.ResponseWriter, r *http.Request, t *tailer
 	fmt.Fprintf(w, "\n")
 	flusher.Flush()
 
-	// Register subscriber (tickets only, no agent status)
-	ch := make(chan string, 10) // Buffered for backpressure handling
-	sub := t.subscribe(projectPath, ch, false)
-	defer t.unsubscribe(sub)
-
 	// Block and forward events until client disconnects
 	ctx := r.Context()
 	for {
@@ -505,6 +491,18 @@ func handleStatusSubscribeWithTailer(w http.ResponseWriter, r *http.Request, t *
 		}
 	}
 
+	resume, err := parseResumePoint(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Register subscriber (includes both tickets and agent status) before
+	// the snapshot, so no event falls between the two
+	ch := make(chan string, 10) // Buffered for backpressure handling
+	sub := t.subscribe(projectPath, ch, true)
+	defer t.unsubscribe(sub)
+
 	// Get initial snapshot of tickets
 	ticketsDir := projectPath + "/.ko/tickets"
 	tickets, err := ListTickets(ticketsDir)
@@ -533,8 +531,8 @@ func handleStatusSubscribeWithTailer(w http.ResponseWriter, r *http.Request, t *
 	fmt.Fprintf(w, "retry: 3000\n\n")
 	flusher.Flush()
 
-	// Send initial snapshot with id=0
-	fmt.Fprintf(w, "id: 0\n")
+	// Replay what a resuming client missed, then the snapshot
+	resumeSubscriber(w, resume, sub)
 
 	// First, send agent status with type discriminator
 	agentEnvelope := map[string]interface{}{
@@ -569,11 +567,6 @@ func handleStatusSubscribeWithTailer(w http.ResponseWriter, r *http.Request, t *
 	fmt.Fprintf(w, "\n")
 	flusher.Flush()
 
-	// Register subscriber (includes both tickets and agent status)
-	ch := make(chan string, 10) // Buffered for backpressure handling
-	sub := t.subscribe(projectPath, ch, true)
-	defer t.unsubscribe(sub)
-
 	// Block and forward events until client disconnects
 	ctx := r.Context()
 	for {
@@ -115,6 +115,8 @@ func shimUnsupported(cmd string) int {
 		"stats":   "aggregate over QQL with a `qb query` on quests (filter by realm/campaign/status).",
 		"search":  "QQL has no full-text search yet; query quests by status/realm/campaign with `qb query`.",
 		"history": "quest history lives in Questbook revisions/events; query with `qb query`.",
+		"logs":    "build output is a local pipeline artifact; pipeline execution is not part of Questbook.",
+		"gc":      "build artifacts are local to the pipeline; there is nothing to collect in Questbook.",
 		"triage":  "triage is not modeled in Questbook; use quest status `blocked` or a question-type quest.",
 		"snooze":  "snooze is not modeled in Questbook; park the quest with status or a dependency.",
 		"bump":    "priority ordering is the `priority` field; set it via `ko update -p N` (proxied) — there is no mtime bump in QQL.",
@@ -24,11 +24,14 @@ type showJSON struct {
 	Snooze      string   `json:"snooze,omitempty"`
 	Triage      string   `json:"triage,omitempty"`
 	Tags          []string       `json:"tags,omitempty"`
+	Scope         []string       `json:"scope,omitempty"`
+	Fields        map[string]interface{} `json:"fields,omitempty"`
 	Blockers      []string       `json:"blockers,omitempty"`
 	Blocking      []string       `json:"blocking,omitempty"`
 	Children      []string       `json:"children,omitempty"`
 	PlanQuestions []PlanQuestion `json:"plan-questions,omitempty"`
 	Body          string         `json:"body,omitempty"`
+	Archived      string         `json:"archived,omitempty"`
 }
 
 func cmdShow(args []string) int {
@@ -63,8 +66,9 @@ func cmdShow(args []string) int {
 			blockers, _ := db.GetOpenDepsDB(t.ID)
 			blocking, _ := db.GetBlockingDB(t.ID)
 			children, _ := db.GetChildrenDB(t.ID)
+			archived := db.ArchivedAt(t.ID)
 			db.Close()
-			return showTicket(t, blockers, blocking, children, *jsonOutput)
+			return showTicket(t, blockers, blocking, children, archived, *jsonOutput)
 		}
 		db.Close()
 		// Ticket not in DB - fall through to filesystem
@@ -87,37 +91,44 @@ func cmdShow(args []string) int {
 	blocking := findBlocking(ticketsDir, t.ID)
 	children := findChildren(ticketsDir, t.ID)
 
-	return showTicket(t, blockers, blocking, children, *jsonOutput)
+	return showTicket(t, blockers, blocking, children, "", *jsonOutput)
 }
 
-func showTicket(t *Ticket, blockers, blocking, children []string, jsonOutput bool) int {
-	if jsonOutput {
-		modified := ""
-		if !t.ModTime.IsZero() {
-			modified = t.ModTime.UTC().Format(time.RFC3339)
-		}
+// newShowJSON builds the `ko show --json` shape of a ticket.
+func newShowJSON(t *Ticket, blockers, blocking, children []string) showJSON {
+	modified := ""
+	if !t.ModTime.IsZero() {
+		modified = t.ModTime.UTC().Format(time.RFC3339)
+	}
+	return showJSON{
+		ID:            t.ID,
+		Title:         t.Title,
+		Status:        t.Status,
+		Type:          t.Type,
+		Priority:      t.Priority,
+		Deps:          t.Deps,
+		Created:       t.Created,
+		Modified:      modified,
+		Assignee:      t.Assignee,
+		Parent:        t.Parent,
+		ExternalRef:   t.ExternalRef,
+		Snooze:        t.Snooze,
+		Triage:        t.Triage,
+		Tags:          t.Tags,
+		Scope:         t.Scope,
+		Fields:        fieldsJSON(t.Fields),
+		Blockers:      blockers,
+		Blocking:      blocking,
+		Children:      children,
+		PlanQuestions: t.PlanQuestions,
+		Body:          t.Body,
+	}
+}
 
-		j := showJSON{
-			ID:            t.ID,
-			Title:         t.Title,
-			Status:        t.Status,
-			Type:          t.Type,
-			Priority:      t.Priority,
-			Deps:          t.Deps,
-			Created:       t.Created,
-			Modified:      modified,
-			Assignee:      t.Assignee,
-			Parent:        t.Parent,
-			ExternalRef:   t.ExternalRef,
-			Snooze:        t.Snooze,
-			Triage:        t.Triage,
-			Tags:          t.Tags,
-			Blockers:      blockers,
-			Blocking:      blocking,
-			Children:      children,
-			PlanQuestions: t.PlanQuestions,
-			Body:          t.Body,
-		}
+func showTicket(t *Ticket, blockers, blocking, children []string, archived string, jsonOutput bool) int {
+	if jsonOutput {
+		j := newShowJSON(t, blockers, blocking, children)
+		j.Archived = archived
 
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
@@ -130,6 +141,9 @@ func showTicket(t *Ticket, blockers, blocking, children []string, jsonOutput boo
 		fmt.Printf("priority: %d\n", t.Priority)
 		fmt.Printf("deps: [%s]\n", strings.Join(t.Deps, ", "))
 		fmt.Printf("created: %s\n", t.Created)
+		if archived != "" {
+			fmt.Printf("archived: %s\n", archived)
+		}
 		if t.Assignee != "" {
 			fmt.Printf("assignee: %s\n", t.Assignee)
 		}
@@ -148,6 +162,15 @@ func showTicket(t *Ticket, blockers, blocking, children []string, jsonOutput boo
 		if len(t.Tags) > 0 {
 			fmt.Printf("tags: [%s]\n", strings.Join(t.Tags, ", "))
 		}
+		if len(t.Scope) > 0 {
+			fmt.Printf("scope: [%s]\n", strings.Join(t.Scope, ", "))
+		}
+		if len(t.Fields) > 0 {
+			fmt.Println("fields:")
+			for _, f := range t.Fields {
+				fmt.Printf("  %s: %s\n", f.Name, f.Value)
+			}
+		}
 		fmt.Println()
 		fmt.Printf("# %s\n", t.Title)
 
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// templateJSON is one entry of ko template ls --json and of the REST
+// templates list.
+type templateJSON struct {
+	Name        string   `json:"name"`
+	Source      string   `json:"source"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Priority    *int     `json:"priority,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Triage      string   `json:"triage,omitempty"`
+}
+
+func templatesToJSON(templates []*TicketTemplate) []templateJSON {
+	out := []templateJSON{}
+	for _, tt := range templates {
+		out = append(out, templateJSON{tt.Name, tt.Source, tt.Description, tt.Type, tt.Priority, tt.Tags, tt.Triage})
+	}
+	return out
+}
+
+func cmdTemplate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "ko template: subcommand required (ls)")
+		return 1
+	}
+	switch args[0] {
+	case "ls", "list":
+		return cmdTemplateLs(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "ko template: unknown subcommand '%s'\n", args[0])
+		return 1
+	}
+}
+
+// cmdTemplateLs lists the templates ko add --template can use, project
+// templates first shadowing global ones of the same name.
+func cmdTemplateLs(args []string) int {
+	ticketsDir, args, err := resolveProjectTicketsDir(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko template ls: %v\n", err)
+		return 1
+	}
+	fs := flag.NewFlagSet("template ls", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "output as JSON")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "ko template ls: %v\n", err)
+		return 1
+	}
+
+	templates, err := ListTicketTemplates(ticketsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko template ls: %v\n", err)
+		return 1
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(templatesToJSON(templates))
+		return 0
+	}
+	if len(templates) == 0 {
+		fmt.Println("No templates.")
+		return 0
+	}
+	for _, tt := range templates {
+		var defaults []string
+		if tt.Type != "" {
+			defaults = append(defaults, tt.Type)
+		}
+		if tt.Priority != nil {
+			defaults = append(defaults, fmt.Sprintf("p%d", *tt.Priority))
+		}
+		for _, tag := range tt.Tags {
+			defaults = append(defaults, "#"+tag)
+		}
+		line := fmt.Sprintf("%-12s %-8s %-24s %s", tt.Name, tt.Source, strings.Join(defaults, " "), tt.Description)
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+	return 0
+}
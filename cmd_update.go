@@ -9,15 +9,19 @@ import (
 	"time"
 )
 
+// updateValueFlags are the flags of ko update (and ko close) that take a
+// value.
+var updateValueFlags = map[string]bool{
+	"d": true, "t": true, "p": true, "a": true,
+	"title": true, "parent": true, "external-ref": true,
+	"design": true, "acceptance": true, "tags": true,
+	"questions": true, "answers": true, "status": true,
+	"snooze": true, "triage": true, "scope": true, "field": true,
+}
+
 func cmdUpdate(args []string) int {
 	// Reorder args to handle flags properly
-	args = reorderArgs(args, map[string]bool{
-		"d": true, "t": true, "p": true, "a": true,
-		"title": true, "parent": true, "external-ref": true,
-		"design": true, "acceptance": true, "tags": true,
-		"questions": true, "answers": true, "status": true,
-		"snooze": true, "triage": true,
-	})
+	args = reorderArgs(args, updateValueFlags)
 
 	// Parse flags
 	fs := flag.NewFlagSet("update", flag.ContinueOnError)
@@ -36,6 +40,9 @@ func cmdUpdate(args []string) int {
 	status := fs.String("status", "", "ticket status")
 	snooze := fs.String("snooze", "", "snooze date (ISO 8601, e.g. 2026-05-01)")
 	triage := fs.String("triage", "", "triage note (free text)")
+	scope := fs.String("scope", "", "comma-separated allowed write globs")
+	var fields stringList
+	fs.Var(&fields, "field", "custom field name=value, empty to clear (repeatable)")
 
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "ko update: %v\n", err)
@@ -73,6 +80,7 @@ func cmdUpdate(args []string) int {
 
 	// Track what changed for mutation event
 	changed := false
+	fromStatus := t.Status
 
 	// Apply updates to ticket fields
 	if *title != "" {
@@ -129,6 +137,23 @@ func cmdUpdate(args []string) int {
 		t.Tags = ticketTags
 		changed = true
 	}
+	if *scope != "" {
+		// Scope replaces, like tags
+		t.Scope = splitScopeFlag(*scope)
+		changed = true
+	}
+	if len(fields) > 0 {
+		schema, err := loadFieldSchema(ticketsDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ko update: %v\n", err)
+			return 1
+		}
+		if err := applyFieldAssignments(t, schema, fields); err != nil {
+			fmt.Fprintf(os.Stderr, "ko update: %v\n", err)
+			return 1
+		}
+		changed = true
+	}
 
 	// Handle --questions: add questions and set status to blocked
 	if *questionsJSON != "" {
@@ -256,6 +281,12 @@ func cmdUpdate(args []string) int {
 	EmitMutationEvent(ticketsDir, id, "update", map[string]interface{}{
 		"ticket": id,
 	})
+	if t.Status != fromStatus {
+		EmitMutationEvent(ticketsDir, id, "status", map[string]interface{}{
+			"from": fromStatus,
+			"to":   t.Status,
+		})
+	}
 
 	if *triage != "" {
 		maybeAutoTriage(ticketsDir, id)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/sha1"
 	"database/sql"
 	_ "embed"
@@ -87,12 +88,10 @@ func (d *DB) migrate() error {
 	err := d.db.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name='schema_migrations'").Scan(&name)
 	if err == sql.ErrNoRows {
 		// Fresh database: run full schema
-		if _, err := d.db.Exec(schemaSQL); err != nil {
+		if err := d.runMigration(10, false, schemaSQL); err != nil {
 			return fmt.Errorf("schema init: %w", err)
 		}
-		_, err := d.db.Exec("INSERT OR IGNORE INTO schema_migrations (version, applied_at) VALUES (2, ?)",
-			time.Now().UTC().Format(time.RFC3339))
-		return err
+		return nil
 	}
 	if err != nil {
 		return err
@@ -113,19 +112,97 @@ func (d *DB) migrate() error {
 			return fmt.Errorf("migrate v2: %w", err)
 		}
 	}
+	if version < 3 {
+		if err := d.migrateV3(); err != nil {
+			return fmt.Errorf("migrate v3: %w", err)
+		}
+	}
+	if version < 4 {
+		if err := d.migrateV4(); err != nil {
+			return fmt.Errorf("migrate v4: %w", err)
+		}
+	}
+	if version < 5 {
+		if err := d.migrateV5(); err != nil {
+			return fmt.Errorf("migrate v5: %w", err)
+		}
+	}
+	if version < 6 {
+		if err := d.migrateV6(); err != nil {
+			return fmt.Errorf("migrate v6: %w", err)
+		}
+	}
+	if version < 7 {
+		if err := d.migrateV7(); err != nil {
+			return fmt.Errorf("migrate v7: %w", err)
+		}
+	}
+	if version < 8 {
+		if err := d.migrateV8(); err != nil {
+			return fmt.Errorf("migrate v8: %w", err)
+		}
+	}
+	if version < 9 {
+		if err := d.migrateV9(); err != nil {
+			return fmt.Errorf("migrate v9: %w", err)
+		}
+	}
+	if version < 10 {
+		if err := d.migrateV10(); err != nil {
+			return fmt.Errorf("migrate v10: %w", err)
+		}
+	}
 
 	return nil
 }
 
+// runMigration applies the statements of one schema version and records
+// it in schema_migrations, all in one transaction, so a failure leaves the
+// database at the previous version. SQLite ignores PRAGMA foreign_keys
+// inside a transaction, so migrations that rebuild a referenced table ask
+// for it to be turned off around the transaction, on the same connection.
+func (d *DB) runMigration(version int, foreignKeysOff bool, stmts ...string) error {
+	ctx := context.Background()
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if foreignKeysOff {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, m := range stmts {
+		if _, err := tx.Exec(m); err != nil {
+			preview := m
+			if len(preview) > 40 {
+				preview = preview[:40]
+			}
+			return fmt.Errorf("exec %q: %w", preview, err)
+		}
+	}
+	if _, err := tx.Exec("INSERT OR IGNORE INTO schema_migrations (version, applied_at) VALUES (?, ?)",
+		version, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // migrateV2 removes the UNIQUE constraint from projects.prefix.
 // The prefix column is metadata describing what prefix tickets use in that directory,
 // but uniqueness is enforced at the Registry layer, not the DB layer.
 func (d *DB) migrateV2() error {
 	// SQLite doesn't support ALTER TABLE DROP CONSTRAINT, so recreate the table.
-	// DROP IF EXISTS handles partial previous runs.
-	// Disable foreign keys during migration since tickets references projects.
-	migrations := []string{
-		`PRAGMA foreign_keys = OFF`,
+	// Foreign keys are off since tickets references projects.
+	return d.runMigration(2, true,
 		`DROP TABLE IF EXISTS projects_new`,
 		`CREATE TABLE projects_new (
 			id          INTEGER PRIMARY KEY,
@@ -140,22 +217,137 @@ func (d *DB) migrateV2() error {
 		`ALTER TABLE projects_new RENAME TO projects`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_single_default
 			ON projects(is_default) WHERE is_default = 1`,
-		`PRAGMA foreign_keys = ON`,
-	}
+	)
+}
 
-	for _, m := range migrations {
-		if _, err := d.db.Exec(m); err != nil {
-			preview := m
-			if len(preview) > 40 {
-				preview = preview[:40]
-			}
-			return fmt.Errorf("exec %q: %w", preview, err)
-		}
-	}
+// migrateV3 widens builds.outcome to every terminal build outcome. Builds
+// that end blocked or decomposed are recorded as such rather than as fail.
+func (d *DB) migrateV3() error {
+	return d.runMigration(3, true,
+		`DROP VIEW IF EXISTS ticket_build_summary`,
+		`DROP TABLE IF EXISTS builds_new`,
+		`CREATE TABLE builds_new (
+			id           INTEGER PRIMARY KEY,
+			ticket_id    TEXT    NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+			workflow     TEXT    NOT NULL DEFAULT 'main',
+			started_at   TEXT    NOT NULL,
+			completed_at TEXT,
+			outcome      TEXT,
+
+			CONSTRAINT valid_outcome CHECK (outcome IN ('succeed', 'fail', 'blocked', 'decompose'))
+		)`,
+		`INSERT INTO builds_new SELECT * FROM builds`,
+		`DROP TABLE builds`,
+		`ALTER TABLE builds_new RENAME TO builds`,
+		`CREATE INDEX IF NOT EXISTS idx_builds_ticket     ON builds(ticket_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_builds_started_at ON builds(started_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_builds_outcome    ON builds(outcome) WHERE outcome IS NOT NULL`,
+		`CREATE VIEW IF NOT EXISTS ticket_build_summary AS
+		SELECT
+			ticket_id,
+			COUNT(*)                                              AS total_builds,
+			SUM(CASE WHEN outcome = 'succeed' THEN 1 ELSE 0 END) AS succeeded,
+			SUM(CASE WHEN outcome = 'fail'    THEN 1 ELSE 0 END) AS failed,
+			MAX(started_at)                                       AS last_build_at,
+			MAX(CASE WHEN outcome = 'succeed' THEN completed_at END) AS last_succeed_at
+		FROM builds
+		GROUP BY ticket_id`,
+	)
+}
+
+// migrateV4 adds ticket_scopes, the allowed write scope of a ticket.
+func (d *DB) migrateV4() error {
+	return d.runMigration(4, false, `CREATE TABLE IF NOT EXISTS ticket_scopes (
+		ticket_id  TEXT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+		glob       TEXT NOT NULL,
+		PRIMARY KEY (ticket_id, glob)
+	)`)
+}
+
+// migrateV5 adds notification_deliveries, the outbound webhook queue.
+func (d *DB) migrateV5() error {
+	return d.runMigration(5, false,
+		`CREATE TABLE IF NOT EXISTS notification_deliveries (
+			id              INTEGER PRIMARY KEY,
+			target          TEXT    NOT NULL,
+			config_path     TEXT    NOT NULL,
+			event_type      TEXT    NOT NULL,
+			ticket_id       TEXT,
+			body            TEXT    NOT NULL,
+			status          TEXT    NOT NULL DEFAULT 'pending',
+			attempts        INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at TEXT    NOT NULL,
+			last_error      TEXT,
+			created_at      TEXT    NOT NULL,
+			delivered_at    TEXT,
+
+			CONSTRAINT valid_delivery_status CHECK (status IN ('pending', 'delivered', 'failed'))
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_notification_deliveries_due
+			ON notification_deliveries(next_attempt_at) WHERE status = 'pending'`,
+	)
+}
+
+// migrateV6 adds remote_mutations, the offline queue for remote mode.
+func (d *DB) migrateV6() error {
+	return d.runMigration(6, false, `CREATE TABLE IF NOT EXISTS remote_mutations (
+		id          INTEGER PRIMARY KEY,
+		server      TEXT    NOT NULL,
+		argv        TEXT    NOT NULL,
+		stdin       BLOB,
+		ticket_id   TEXT,
+		status      TEXT    NOT NULL DEFAULT 'pending',
+		force       INTEGER NOT NULL DEFAULT 0,
+		last_error  TEXT,
+		output      TEXT,
+		created_at  TEXT    NOT NULL,
+		applied_at  TEXT,
+
+		CONSTRAINT valid_remote_status CHECK (status IN ('pending', 'applied', 'conflict', 'failed'))
+	)`)
+}
+
+// migrateV7 adds ticket_fields, the custom field values of a ticket.
+func (d *DB) migrateV7() error {
+	return d.runMigration(7, false,
+		`CREATE TABLE IF NOT EXISTS ticket_fields (
+			ticket_id  TEXT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+			name       TEXT NOT NULL,
+			type       TEXT NOT NULL DEFAULT 'string',
+			value      TEXT NOT NULL,
+			PRIMARY KEY (ticket_id, name)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_ticket_fields_name_value ON ticket_fields(name, value)`,
+	)
+}
+
+// migrateV8 adds ticket_aliases, the old IDs of moved tickets.
+func (d *DB) migrateV8() error {
+	return d.runMigration(8, false,
+		`CREATE TABLE IF NOT EXISTS ticket_aliases (
+			old_id    TEXT PRIMARY KEY,
+			new_id    TEXT NOT NULL,
+			moved_at  TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_ticket_aliases_new ON ticket_aliases(new_id)`,
+	)
+}
+
+// migrateV9 adds tickets.archived_at, set by ko archive on closed tickets.
+func (d *DB) migrateV9() error {
+	return d.runMigration(9, false, `ALTER TABLE tickets ADD COLUMN archived_at TEXT`)
+}
 
-	_, err := d.db.Exec("INSERT OR IGNORE INTO schema_migrations (version, applied_at) VALUES (2, ?)",
-		time.Now().UTC().Format(time.RFC3339))
-	return err
+// migrateV10 adds file_hashes, the content hash cache of changes.go.
+func (d *DB) migrateV10() error {
+	return d.runMigration(10, false, `CREATE TABLE IF NOT EXISTS file_hashes (
+		root   TEXT NOT NULL,
+		path   TEXT NOT NULL,
+		mtime  INTEGER NOT NULL,
+		size   INTEGER NOT NULL,
+		hash   TEXT NOT NULL,
+		PRIMARY KEY (root, path)
+	)`)
 }
 
 // Lazy global DB handle. Initialized on first shadow write.
@@ -0,0 +1,17 @@
+package main
+
+import "path/filepath"
+
+// TicketInDir reports whether ticket id belongs to the project whose
+// tickets live in ticketsDir.
+func (d *DB) TicketInDir(ticketsDir, id string) bool {
+	abs, err := filepath.Abs(ticketsDir)
+	if err != nil {
+		abs = ticketsDir
+	}
+	var n int
+	err = d.db.QueryRow(`SELECT COUNT(*) FROM tickets t
+		JOIN projects p ON t.project_id = p.id
+		WHERE p.tickets_dir = ? AND t.ticket_id = ?`, abs, id).Scan(&n)
+	return err == nil && n > 0
+}
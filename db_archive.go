@@ -0,0 +1,97 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// archivedCond is the WHERE condition that keeps archived tickets out of a
+// query on tickets t unless they were asked for.
+func archivedCond(archived bool) string {
+	if archived {
+		return "1=1"
+	}
+	return "t.archived_at IS NULL"
+}
+
+// ListAllTickets is ListTickets including archived tickets, for readers
+// such as ko export that need the whole project.
+func ListAllTickets(ticketsDir string) ([]*Ticket, error) {
+	db := getShadowDB()
+	if db == nil {
+		return nil, nil
+	}
+	abs, err := filepath.Abs(ticketsDir)
+	if err != nil {
+		abs = ticketsDir
+	}
+	return db.ListTicketsByDir(abs, true)
+}
+
+// ArchivedAt returns when ko archive archived a ticket, or "" if it is not
+// archived.
+func (d *DB) ArchivedAt(id string) string {
+	var at sql.NullString
+	d.db.QueryRow("SELECT archived_at FROM tickets WHERE ticket_id = ?", id).Scan(&at)
+	return at.String
+}
+
+// ArchiveTickets marks closed tickets as archived. Tickets that are not
+// closed are left alone; reopening an archived ticket unarchives it.
+func (d *DB) ArchiveTickets(ids []string, at time.Time) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, id := range ids {
+		if _, err := tx.Exec(`UPDATE tickets SET archived_at = ?
+			WHERE ticket_id = ? AND status = 'closed'`, at.UTC().Format(time.RFC3339), id); err != nil {
+			return fmt.Errorf("archive %s: %w", id, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteTicket removes a ticket with its notes, builds and aliases in one
+// transaction, saving first the dependents rewired off it, keyed by their
+// tickets directory. Deps on the ticket that were not rewired are dropped.
+func (d *DB) DeleteTicket(id string, rewired map[string][]*Ticket) error {
+	projectIDs := map[string]int64{}
+	for dir := range rewired {
+		projectID, err := d.ensureProject(dir)
+		if err != nil {
+			return fmt.Errorf("ensure project: %w", err)
+		}
+		projectIDs[dir] = projectID
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for dir, tickets := range rewired {
+		for _, t := range tickets {
+			if err := upsertTicketTx(tx, projectIDs[dir], t); err != nil {
+				return err
+			}
+		}
+	}
+	uuid := ticketUUID(extractPrefix(id), id)
+	for _, s := range []struct {
+		q   string
+		arg string
+	}{
+		{"DELETE FROM ticket_deps WHERE depends_on = ?", id},
+		{"DELETE FROM build_events WHERE ticket_id = ?", uuid},
+		{"DELETE FROM ticket_aliases WHERE new_id = ?", id},
+		{"DELETE FROM tickets WHERE id = ?", uuid},
+	} {
+		if _, err := tx.Exec(s.q, s.arg); err != nil {
+			return fmt.Errorf("rm %s: %w", id, err)
+		}
+	}
+	return tx.Commit()
+}
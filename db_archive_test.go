@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArchiveTickets(t *testing.T) {
+	db, err := OpenDBAt(filepath.Join(t.TempDir(), "ko.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dir := filepath.Join(t.TempDir(), ".ko", "tickets")
+
+	done := &Ticket{ID: "ko-a001", Title: "Done", Status: "closed", Type: "task"}
+	open := &Ticket{ID: "ko-a002", Title: "Open", Status: "open", Type: "task"}
+	if err := db.UpsertTickets([]*Ticket{done, open}, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ArchiveTickets([]string{"ko-a001", "ko-a002"}, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if at := db.ArchivedAt("ko-a001"); at != "2026-03-01T00:00:00Z" {
+		t.Errorf("ArchivedAt = %q", at)
+	}
+	if at := db.ArchivedAt("ko-a002"); at != "" {
+		t.Errorf("open ticket archived at %q", at)
+	}
+
+	if ts, _ := db.ListTicketsByDir(dir, false); len(ts) != 1 || ts[0].ID != "ko-a002" {
+		t.Errorf("ListTicketsByDir = %+v", ts)
+	}
+	if ts, _ := db.ListTicketsByDir(dir, true); len(ts) != 2 {
+		t.Errorf("ListTicketsByDir(archived) = %+v", ts)
+	}
+	if rs, _ := db.SearchTickets("Done", "", "", "", "", false, 0); len(rs) != 0 {
+		t.Errorf("search found archived ticket: %+v", rs)
+	}
+	if rs, _ := db.SearchTickets("Done", "", "", "", "", true, 0); len(rs) != 1 {
+		t.Errorf("search --archived = %+v", rs)
+	}
+
+	// Saving a closed ticket keeps it archived, reopening unarchives it
+	done.Title = "Done and dusted"
+	db.UpsertTicket(done, dir)
+	if db.ArchivedAt("ko-a001") == "" {
+		t.Error("update of closed ticket unarchived it")
+	}
+	done.Status = "open"
+	db.UpsertTicket(done, dir)
+	if at := db.ArchivedAt("ko-a001"); at != "" {
+		t.Errorf("reopened ticket archived at %q", at)
+	}
+}
+
+func TestDeleteTicket(t *testing.T) {
+	db, err := OpenDBAt(filepath.Join(t.TempDir(), "ko.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dir := filepath.Join(t.TempDir(), ".ko", "tickets")
+	other := filepath.Join(t.TempDir(), ".ko", "tickets")
+
+	gone := &Ticket{ID: "ko-a001", Title: "Gone", Status: "open", Type: "task"}
+	waiter := &Ticket{ID: "ko-a002", Title: "Waiter", Status: "open", Type: "task", Deps: []string{"ko-a001"}}
+	remote := &Ticket{ID: "oth-a001", Title: "Remote", Status: "open", Type: "task", Deps: []string{"ko-a001"}}
+	if err := db.UpsertTickets([]*Ticket{gone, waiter}, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertTicket(remote, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.InsertBuild(ticketUUID("ko", "ko-a001"), "main", "2026-01-01T00:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rewired dependent stays in its own project; the other dep is dropped
+	remote.Deps = []string{}
+	if err := db.DeleteTicket("ko-a001", map[string][]*Ticket{other: {remote}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetTicketDB("ko-a001"); err == nil {
+		t.Error("deleted ticket still found")
+	}
+	if builds, _ := db.QueryTicketBuilds("ko-a001", 0); len(builds) != 0 {
+		t.Errorf("builds = %+v", builds)
+	}
+	if d, _ := db.TicketsDirOf("oth-a001"); d != other {
+		t.Errorf("rewired ticket moved to %q", d)
+	}
+	if deps, _ := db.GetTicketDeps("ko-a002"); len(deps) != 0 {
+		t.Errorf("dangling deps = %v", deps)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+// GetTicketFields returns the custom fields of a ticket, sorted by name.
+func (d *DB) GetTicketFields(ticketID string) ([]TicketField, error) {
+	q := `SELECT f.name, f.type, f.value FROM ticket_fields f
+		  JOIN tickets t ON f.ticket_id = t.id
+		  WHERE t.ticket_id = ?
+		  ORDER BY f.name`
+	rows, err := d.db.Query(q, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []TicketField
+	for rows.Next() {
+		var f TicketField
+		if err := rows.Scan(&f.Name, &f.Type, &f.Value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// appendFieldConditions adds the WHERE conditions and arguments of custom
+// field filters to a query being built.
+func appendFieldConditions(conditions []string, args []interface{}, fields []FieldFilter) ([]string, []interface{}) {
+	for _, f := range fields {
+		cond, fargs := f.sql()
+		conditions = append(conditions, cond)
+		args = append(args, fargs...)
+	}
+	return conditions, args
+}
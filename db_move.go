@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// aliasOf returns the current ID of a ticket moved away from id by ko mv.
+func (d *DB) aliasOf(id string) (string, bool) {
+	var newID string
+	if d.db.QueryRow("SELECT new_id FROM ticket_aliases WHERE old_id = ?", id).Scan(&newID) != nil {
+		return "", false
+	}
+	return newID, true
+}
+
+// resolveAlias is the last resort of ResolveIDDB: the current ID of a
+// moved ticket.
+func (d *DB) resolveAlias(partial string) (string, error) {
+	if id, ok := d.aliasOf(partial); ok {
+		return id, nil
+	}
+	return "", fmt.Errorf("ticket '%s' not found", partial)
+}
+
+// getAliasedTicket is the last resort of GetTicketDB: the ticket a moved
+// ID now belongs to.
+func (d *DB) getAliasedTicket(ticketID string) (*Ticket, error) {
+	if id, ok := d.aliasOf(ticketID); ok {
+		return d.GetTicketDB(id)
+	}
+	return nil, fmt.Errorf("ticket not found: %s", ticketID)
+}
+
+// TicketsDirOf returns the tickets directory of the project that holds id.
+func (d *DB) TicketsDirOf(id string) (string, error) {
+	var dir string
+	err := d.db.QueryRow(`SELECT p.tickets_dir FROM tickets t
+		JOIN projects p ON t.project_id = p.id
+		WHERE t.ticket_id = ?`, id).Scan(&dir)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("ticket '%s' not found", id)
+	}
+	return dir, err
+}
+
+// MoveTickets moves tickets from fromDir to the project of toDir in one
+// transaction. ids maps old IDs to new ones and moved holds the tickets
+// under their new IDs, parents first. Builds, child links, deps in every
+// project and artifact index rows follow the new IDs, and each old ID
+// becomes an alias of its new one.
+func (d *DB) MoveTickets(ids map[string]string, moved []*Ticket, fromDir, toDir string) error {
+	projectID, err := d.ensureProject(toDir)
+	if err != nil {
+		return fmt.Errorf("ensure project: %w", err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, t := range moved {
+		if err := upsertTicketTx(tx, projectID, t); err != nil {
+			return err
+		}
+	}
+	oldOf := make(map[string]string, len(ids))
+	for oldID, newID := range ids {
+		oldOf[newID] = oldID
+	}
+	// Delete children before their parents.
+	for i := len(moved) - 1; i >= 0; i-- {
+		newID := moved[i].ID
+		oldID := oldOf[newID]
+		oldUUID := ticketUUID(extractPrefix(oldID), oldID)
+		newUUID := ticketUUID(extractPrefix(newID), newID)
+		oldArtifacts := ArtifactDir(fromDir, oldID)
+		stmts := []struct {
+			q    string
+			args []interface{}
+		}{
+			{"UPDATE builds SET ticket_id = ? WHERE ticket_id = ?", []interface{}{newUUID, oldUUID}},
+			{"UPDATE build_events SET ticket_id = ? WHERE ticket_id = ?", []interface{}{newUUID, oldUUID}},
+			{"UPDATE tickets SET parent_id = ? WHERE parent_id = ?", []interface{}{newUUID, oldUUID}},
+			{"UPDATE OR IGNORE ticket_deps SET depends_on = ? WHERE depends_on = ?", []interface{}{newID, oldID}},
+			{"DELETE FROM ticket_deps WHERE depends_on = ?", []interface{}{oldID}},
+			{`UPDATE build_artifacts SET artifact_path = ? || substr(artifact_path, ?)
+				WHERE artifact_path = ? OR artifact_path LIKE ?`, []interface{}{
+				ArtifactDir(toDir, newID), len(oldArtifacts) + 1, oldArtifacts, oldArtifacts + string(filepath.Separator) + "%"}},
+			{"DELETE FROM tickets WHERE id = ?", []interface{}{oldUUID}},
+			{"DELETE FROM ticket_aliases WHERE old_id = ?", []interface{}{newID}},
+			{"UPDATE ticket_aliases SET new_id = ? WHERE new_id = ?", []interface{}{newID, oldID}},
+			{"INSERT OR REPLACE INTO ticket_aliases (old_id, new_id, moved_at) VALUES (?, ?, ?)", []interface{}{oldID, newID, now}},
+		}
+		for _, s := range stmts {
+			if _, err := tx.Exec(s.q, s.args...); err != nil {
+				return fmt.Errorf("move %s: %w", oldID, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveTickets(t *testing.T) {
+	db, err := OpenDBAt(filepath.Join(t.TempDir(), "ko.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	from := filepath.Join(t.TempDir(), "a", ".ko", "tickets")
+	to := filepath.Join(t.TempDir(), "b", ".ko", "tickets")
+
+	root := &Ticket{ID: "ko-a001", Title: "Root", Status: "open", Type: "task", Tags: []string{"x"}}
+	child := &Ticket{ID: "ko-a001.b001", Title: "Child", Status: "open", Type: "task", Parent: "ko-a001"}
+	waiter := &Ticket{ID: "ko-a002", Title: "Waiter", Status: "open", Type: "task", Deps: []string{"ko-a001.b001"}}
+	if err := db.UpsertTickets([]*Ticket{root, child, waiter}, from); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.InsertBuild(ticketUUID("ko", "ko-a001"), "main", "2026-01-01T00:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, moved := planMove([]*Ticket{root, child, waiter}, root, "oth", func(string) bool { return false })
+	if err := db.MoveTickets(ids, moved, from, to); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.GetTicketDB("oth-a001.b001")
+	if err != nil || got.Parent != "oth-a001" {
+		t.Fatalf("moved child = %+v, %v", got, err)
+	}
+	if got, _ := db.GetTicketDB("oth-a001"); got == nil || len(got.Tags) != 1 {
+		t.Errorf("moved root = %+v", got)
+	}
+	if dir, _ := db.TicketsDirOf("oth-a001"); dir != to {
+		t.Errorf("project dir = %q, want %q", dir, to)
+	}
+	if builds, _ := db.QueryTicketBuilds("oth-a001", 0); len(builds) != 1 {
+		t.Errorf("builds = %+v", builds)
+	}
+	if deps, _ := db.GetTicketDeps("ko-a002"); len(deps) != 1 || deps[0] != "oth-a001.b001" {
+		t.Errorf("referrer deps = %v", deps)
+	}
+
+	// Old IDs resolve to the new ones
+	if id, err := db.ResolveIDDB(from, "ko-a001.b001"); err != nil || id != "oth-a001.b001" {
+		t.Errorf("ResolveIDDB(old) = %q, %v", id, err)
+	}
+	if got, err := db.GetTicketDB("ko-a001"); err != nil || got.ID != "oth-a001" {
+		t.Errorf("GetTicketDB(old) = %+v, %v", got, err)
+	}
+
+	// Moving again keeps the first alias pointing at the latest ID
+	ids, moved = planMove([]*Ticket{moved[0], moved[1]}, moved[0], "thr", func(string) bool { return false })
+	if err := db.MoveTickets(ids, moved, to, from); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := db.ResolveIDDB("", "ko-a001"); id != "thr-a001" {
+		t.Errorf("chained alias = %q", id)
+	}
+}
@@ -88,10 +88,10 @@ func (d *DB) QueryStats(project string) (*StatsResult, error) {
 	result := &StatsResult{}
 
 	// Build WHERE clause for project filter
-	var projectWhere string
+	projectWhere := " AND archived_at IS NULL"
 	var projectArgs []interface{}
 	if project != "" {
-		projectWhere = " AND p.tag = ?"
+		projectWhere += " AND p.tag = ?"
 		projectArgs = append(projectArgs, project)
 	}
 
@@ -205,7 +205,7 @@ func (d *DB) QueryStats(project string) (*StatsResult, error) {
 			 SUM(CASE WHEN t.status NOT IN ('closed', 'resolved') THEN 1 ELSE 0 END) as open,
 			 SUM(CASE WHEN t.status IN ('closed', 'resolved') THEN 1 ELSE 0 END) as closed
 			 FROM tickets t
-			 JOIN projects p ON t.project_id = p.id
+			 JOIN projects p ON t.project_id = p.id WHERE t.archived_at IS NULL
 			 GROUP BY p.tag
 			 ORDER BY open DESC`
 		rows, err = d.db.Query(q)
@@ -236,8 +236,8 @@ type SearchResult struct {
 }
 
 // SearchTickets searches tickets by title and body with LIKE.
-// Multiple words are ANDed together.
-func (d *DB) SearchTickets(query, project, status, ticketType, tag string, limit int) ([]SearchResult, error) {
+// Multiple words are ANDed together, as are the custom field filters.
+func (d *DB) SearchTickets(query, project, status, ticketType, tag string, archived bool, limit int, fields ...FieldFilter) ([]SearchResult, error) {
 	if limit <= 0 {
 		limit = 50
 	}
@@ -248,7 +248,7 @@ func (d *DB) SearchTickets(query, project, status, ticketType, tag string, limit
 	}
 
 	// Build WHERE clause
-	var conditions []string
+	conditions := []string{archivedCond(archived)}
 	var args []interface{}
 
 	// Each word must appear in title OR body
@@ -274,6 +274,7 @@ func (d *DB) SearchTickets(query, project, status, ticketType, tag string, limit
 		conditions = append(conditions, "EXISTS (SELECT 1 FROM ticket_tags tt WHERE tt.ticket_id = t.id AND tt.tag = ?)")
 		args = append(args, tag)
 	}
+	conditions, args = appendFieldConditions(conditions, args, fields)
 
 	q := `SELECT t.ticket_id, p.tag, t.status, t.priority, t.title, t.body
 		  FROM tickets t
@@ -559,12 +560,13 @@ func (d *DB) ResolveProjectTag(ticketsDir string) (string, error) {
 	return tag, err
 }
 
-// ListTicketsDB returns tickets for ko ls, filtered by project and status.
-func (d *DB) ListTicketsDB(project, status string, includeAll bool, limit int) ([]*Ticket, error) {
+// ListTicketsDB returns tickets for ko ls, filtered by project, status and
+// custom fields.
+func (d *DB) ListTicketsDB(project, status string, includeAll bool, limit int, fields ...FieldFilter) ([]*Ticket, error) {
 	var conditions []string
 	var args []interface{}
 
-	conditions = append(conditions, "1=1")
+	conditions = append(conditions, "t.archived_at IS NULL")
 
 	if project != "" {
 		conditions = append(conditions, "p.tag = ?")
@@ -579,6 +581,8 @@ func (d *DB) ListTicketsDB(project, status string, includeAll bool, limit int) (
 		conditions = append(conditions, "t.status != 'closed'")
 	}
 
+	conditions, args = appendFieldConditions(conditions, args, fields)
+
 	q := `SELECT t.ticket_id, t.title, t.status, t.type, t.priority,
 		         t.assignee, parent.ticket_id, t.external_ref, t.snooze, t.triage,
 		         t.created_at, t.updated_at, t.body
@@ -625,24 +629,26 @@ func (d *DB) ListTicketsDB(project, status string, includeAll bool, limit int) (
 		if tags, _ := d.GetTicketTags(t.ID); tags != nil {
 			t.Tags = tags
 		}
+		t.Scope, _ = d.GetTicketScope(t.ID)
+		t.Fields, _ = d.GetTicketFields(t.ID)
 		tickets = append(tickets, t)
 	}
 	return tickets, nil
 }
 
-// ListTicketsByDir returns all tickets belonging to the project registered
-// for the given tickets directory (absolute path). Includes all statuses.
-func (d *DB) ListTicketsByDir(ticketsDir string) ([]*Ticket, error) {
+// ListTicketsByDir returns the tickets of the project registered for the
+// given tickets directory (absolute path), archived ones only if asked.
+func (d *DB) ListTicketsByDir(ticketsDir string, archived bool) ([]*Ticket, error) {
 	q := `SELECT t.ticket_id, t.title, t.status, t.type, t.priority,
 		         t.assignee, parent.ticket_id, t.external_ref, t.snooze, t.triage,
 		         t.created_at, t.updated_at, t.body
 		  FROM tickets t
 		  JOIN projects p ON t.project_id = p.id
 		  LEFT JOIN tickets parent ON t.parent_id = parent.id
-		  WHERE p.tickets_dir = ?
+		  WHERE p.tickets_dir = ? AND (? OR t.archived_at IS NULL)
 		  ORDER BY t.priority, t.updated_at DESC`
 
-	rows, err := d.db.Query(q, ticketsDir)
+	rows, err := d.db.Query(q, ticketsDir, archived)
 	if err != nil {
 		return nil, err
 	}
@@ -675,6 +681,8 @@ func (d *DB) ListTicketsByDir(ticketsDir string) ([]*Ticket, error) {
 		if tags, _ := d.GetTicketTags(t.ID); tags != nil {
 			t.Tags = tags
 		}
+		t.Scope, _ = d.GetTicketScope(t.ID)
+		t.Fields, _ = d.GetTicketFields(t.ID)
 		tickets = append(tickets, t)
 	}
 	return tickets, nil
@@ -740,7 +748,7 @@ func (d *DB) ResolveIDDB(ticketsDir, partial string) (string, error) {
 
 	switch len(matches) {
 	case 0:
-		return "", fmt.Errorf("ticket '%s' not found", partial)
+		return d.resolveAlias(partial)
 	case 1:
 		return matches[0], nil
 	default:
@@ -806,6 +814,8 @@ func (d *DB) ListReadyDB(project string, limit int) ([]*Ticket, error) {
 		if tags, _ := d.GetTicketTags(t.ID); tags != nil {
 			t.Tags = tags
 		}
+		t.Scope, _ = d.GetTicketScope(t.ID)
+		t.Fields, _ = d.GetTicketFields(t.ID)
 		tickets = append(tickets, t)
 	}
 	return tickets, nil
@@ -830,7 +840,7 @@ func (d *DB) GetTicketDB(ticketID string) (*Ticket, error) {
 		&assignee, &parentTicketID, &extRef, &snooze, &triage,
 		&t.Created, &updatedAt, &t.Body)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("ticket not found: %s", ticketID)
+		return d.getAliasedTicket(ticketID)
 	}
 	if err != nil {
 		return nil, err
@@ -850,6 +860,8 @@ func (d *DB) GetTicketDB(ticketID string) (*Ticket, error) {
 	if tags, _ := d.GetTicketTags(t.ID); tags != nil {
 		t.Tags = tags
 	}
+	t.Scope, _ = d.GetTicketScope(t.ID)
+	t.Fields, _ = d.GetTicketFields(t.ID)
 	t.PlanQuestions, _ = d.GetPlanQuestions(t.ID)
 
 	return t, nil
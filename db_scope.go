@@ -0,0 +1,24 @@
+package main
+
+// GetTicketScope returns the allowed write scope globs for a ticket.
+func (d *DB) GetTicketScope(ticketID string) ([]string, error) {
+	q := `SELECT s.glob FROM ticket_scopes s
+		  JOIN tickets t ON s.ticket_id = t.id
+		  WHERE t.ticket_id = ?
+		  ORDER BY s.rowid`
+	rows, err := d.db.Query(q, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var globs []string
+	for rows.Next() {
+		var glob string
+		if err := rows.Scan(&glob); err != nil {
+			return nil, err
+		}
+		globs = append(globs, glob)
+	}
+	return globs, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRunMigrationRollsBack(t *testing.T) {
+	db, err := OpenDBAt(filepath.Join(t.TempDir(), "ko.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.runMigration(99, false, `CREATE TABLE half_done (x TEXT)`, `NOT SQL`)
+	if err == nil {
+		t.Fatal("a failing statement should fail the migration")
+	}
+	var n int
+	db.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_done'").Scan(&n)
+	if n != 0 {
+		t.Error("the statements before the failure should be rolled back")
+	}
+	db.db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = 99").Scan(&n)
+	if n != 0 {
+		t.Error("a failed migration should not be recorded")
+	}
+}
+
+func TestMigrationRebuildsReferencedTable(t *testing.T) {
+	db, err := OpenDBAt(filepath.Join(t.TempDir(), "ko.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dir := filepath.Join(t.TempDir(), ".ko", "tickets")
+	if err := db.UpsertTicket(&Ticket{ID: "ko-a001", Title: "Kept", Status: "open", Type: "task"}, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rebuilding projects drops a table tickets references; with foreign
+	// keys left on, the DROP would fail.
+	if err := db.migrateV2(); err != nil {
+		t.Fatalf("migrateV2: %v", err)
+	}
+	if ts, _ := db.ListTicketsByDir(dir, false); len(ts) != 1 {
+		t.Errorf("tickets after the rebuild = %+v", ts)
+	}
+	var fk int
+	db.db.QueryRow("PRAGMA foreign_keys").Scan(&fk)
+	if fk != 1 {
+		t.Error("foreign keys should be back on after the migration")
+	}
+}
@@ -49,11 +49,33 @@ func (d *DB) ensureProject(ticketsDir string) (int64, error) {
 // UpsertTicket writes a ticket and its relations to the shadow database.
 // All writes happen in a single transaction.
 func (d *DB) UpsertTicket(t *Ticket, ticketsDir string) error {
+	return d.UpsertTickets([]*Ticket{t}, ticketsDir)
+}
+
+// UpsertTickets writes several tickets in one transaction: either all of
+// them are saved or none is. Parents must come before their children for
+// the parent link to be set.
+func (d *DB) UpsertTickets(tickets []*Ticket, ticketsDir string) error {
 	projectID, err := d.ensureProject(ticketsDir)
 	if err != nil {
 		return fmt.Errorf("ensure project: %w", err)
 	}
 
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, t := range tickets {
+		if err := upsertTicketTx(tx, projectID, t); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// upsertTicketTx writes one ticket and its relations within tx.
+func upsertTicketTx(tx *sql.Tx, projectID int64, t *Ticket) error {
 	prefix := extractPrefix(t.ID)
 	uuid := ticketUUID(prefix, t.ID)
 	now := time.Now().UTC().Format(time.RFC3339Nano)
@@ -64,19 +86,13 @@ func (d *DB) UpsertTicket(t *Ticket, ticketsDir string) error {
 	if t.Parent != "" {
 		p := ticketUUID(extractPrefix(t.Parent), t.Parent)
 		var exists int
-		if d.db.QueryRow("SELECT 1 FROM tickets WHERE id = ?", p).Scan(&exists) == nil {
+		if tx.QueryRow("SELECT 1 FROM tickets WHERE id = ?", p).Scan(&exists) == nil {
 			parentUUID = &p
 		}
 	}
 
-	tx, err := d.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
 	// Upsert ticket row.
-	_, err = tx.Exec(`
+	_, err := tx.Exec(`
 		INSERT INTO tickets (id, ticket_id, project_id, title, body, status, type, priority,
 			assignee, parent_id, external_ref, snooze, triage, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
@@ -84,7 +100,8 @@ func (d *DB) UpsertTicket(t *Ticket, ticketsDir string) error {
 			title=excluded.title, body=excluded.body, status=excluded.status,
 			type=excluded.type, priority=excluded.priority, assignee=excluded.assignee,
 			parent_id=excluded.parent_id, external_ref=excluded.external_ref,
-			snooze=excluded.snooze, triage=excluded.triage, updated_at=excluded.updated_at`,
+			snooze=excluded.snooze, triage=excluded.triage, updated_at=excluded.updated_at,
+			archived_at=CASE WHEN excluded.status = 'closed' THEN tickets.archived_at END`,
 		uuid, t.ID, projectID, t.Title, t.Body, t.Status, t.Type, t.Priority,
 		nullStr(t.Assignee), parentUUID, nullStr(t.ExternalRef),
 		nullStr(t.Snooze), nullStr(t.Triage),
@@ -104,6 +121,27 @@ func (d *DB) UpsertTicket(t *Ticket, ticketsDir string) error {
 		}
 	}
 
+	// Replace scope.
+	if _, err := tx.Exec("DELETE FROM ticket_scopes WHERE ticket_id = ?", uuid); err != nil {
+		return err
+	}
+	for _, glob := range t.Scope {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO ticket_scopes (ticket_id, glob) VALUES (?, ?)", uuid, glob); err != nil {
+			return err
+		}
+	}
+
+	// Replace custom fields.
+	if _, err := tx.Exec("DELETE FROM ticket_fields WHERE ticket_id = ?", uuid); err != nil {
+		return err
+	}
+	for _, f := range t.Fields {
+		if _, err := tx.Exec("INSERT OR REPLACE INTO ticket_fields (ticket_id, name, type, value) VALUES (?, ?, ?, ?)",
+			uuid, f.Name, coalesceStr(f.Type, "string"), f.Value); err != nil {
+			return err
+		}
+	}
+
 	// Replace deps.
 	if _, err := tx.Exec("DELETE FROM ticket_deps WHERE ticket_id = ?", uuid); err != nil {
 		return err
@@ -154,8 +192,7 @@ func (d *DB) UpsertTicket(t *Ticket, ticketsDir string) error {
 			return err
 		}
 	}
-
-	return tx.Commit()
+	return nil
 }
 
 // UpsertProject writes a project row to the shadow database.
@@ -198,10 +235,51 @@ func (d *DB) InsertMutationEvent(e MutationEvent) error {
 }
 
 // InsertBuildEvent writes a raw build event to the shadow database.
-func (d *DB) InsertBuildEvent(ticketUUID, eventType, occurredAt, payload string) error {
+// buildID links the event to its builds row; 0 leaves it unlinked.
+func (d *DB) InsertBuildEvent(buildID int64, ticketUUID, eventType, occurredAt, payload string) error {
+	_, err := d.db.Exec(
+		"INSERT INTO build_events (build_id, ticket_id, event_type, occurred_at, payload) VALUES (?, ?, ?, ?, ?)",
+		nullID(buildID), ticketUUID, eventType, occurredAt, payload,
+	)
+	return err
+}
+
+// InsertBuild opens a builds row for a ticket and returns its ID.
+func (d *DB) InsertBuild(ticketUUID, workflow, startedAt string) (int64, error) {
+	res, err := d.db.Exec(
+		"INSERT INTO builds (ticket_id, workflow, started_at) VALUES (?, ?, ?)",
+		ticketUUID, workflow, startedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// CompleteBuild records the terminal outcome of a builds row.
+func (d *DB) CompleteBuild(buildID int64, outcome, completedAt string) error {
+	_, err := d.db.Exec(
+		"UPDATE builds SET outcome = ?, completed_at = ? WHERE id = ?",
+		outcome, completedAt, buildID,
+	)
+	return err
+}
+
+// InsertBuildArtifact indexes a file captured during a build.
+func (d *DB) InsertBuildArtifact(buildID int64, node, path, content, createdAt string) error {
 	_, err := d.db.Exec(
-		"INSERT INTO build_events (ticket_id, event_type, occurred_at, payload) VALUES (?, ?, ?, ?)",
-		ticketUUID, eventType, occurredAt, payload,
+		"INSERT INTO build_artifacts (build_id, node_name, artifact_path, content, created_at) VALUES (?, ?, ?, ?, ?)",
+		buildID, nullStr(node), path, content, createdAt,
+	)
+	return err
+}
+
+// DeleteBuildArtifactsUnder drops the index rows for artifacts stored
+// under dir (used when gc removes a build output directory).
+func (d *DB) DeleteBuildArtifactsUnder(dir string) error {
+	_, err := d.db.Exec(
+		"DELETE FROM build_artifacts WHERE artifact_path = ? OR artifact_path LIKE ?",
+		dir, dir+string(filepath.Separator)+"%",
 	)
 	return err
 }
@@ -266,6 +344,14 @@ func nullStr(s string) interface{} {
 	return s
 }
 
+// nullID returns nil for a zero row ID, otherwise the ID.
+func nullID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
 // coalesceStr returns a if non-empty, otherwise b.
 func coalesceStr(a, b string) string {
 	if a != "" {
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ko edit renders tickets with FormatTicket, lets the user change them in
+// $EDITOR and reads them back with ParseTicket. The functions here decide
+// what an edit means; cmd_edit.go does the I/O.
+
+// fieldChange is one field changed by an edit, with its values before and
+// after. Custom fields are named fields.<name>.
+type fieldChange struct {
+	Field  string
+	Before interface{}
+	After  interface{}
+}
+
+// ticketChanges lists the fields that differ between before and after, in
+// frontmatter order. Trailing newlines of the body do not count.
+func ticketChanges(before, after *Ticket) []fieldChange {
+	var out []fieldChange
+	str := func(field, a, b string) {
+		if a != b {
+			out = append(out, fieldChange{field, a, b})
+		}
+	}
+	list := func(field string, a, b []string) {
+		if strings.Join(a, "\x00") != strings.Join(b, "\x00") {
+			out = append(out, fieldChange{field, nonNilList(a), nonNilList(b)})
+		}
+	}
+	str("title", before.Title, after.Title)
+	str("status", before.Status, after.Status)
+	list("deps", before.Deps, after.Deps)
+	str("type", before.Type, after.Type)
+	if before.Priority != after.Priority {
+		out = append(out, fieldChange{"priority", before.Priority, after.Priority})
+	}
+	str("assignee", before.Assignee, after.Assignee)
+	str("parent", before.Parent, after.Parent)
+	str("external-ref", before.ExternalRef, after.ExternalRef)
+	str("snooze", before.Snooze, after.Snooze)
+	str("triage", before.Triage, after.Triage)
+	list("tags", before.Tags, after.Tags)
+	list("scope", before.Scope, after.Scope)
+	for _, name := range fieldNames(before.Fields, after.Fields) {
+		str("fields."+name, before.FieldValue(name), after.FieldValue(name))
+	}
+	if !samePlanQuestions(before.PlanQuestions, after.PlanQuestions) {
+		out = append(out, fieldChange{"plan-questions", nonNilQuestions(before.PlanQuestions), nonNilQuestions(after.PlanQuestions)})
+	}
+	str("body", strings.TrimRight(before.Body, "\n"), strings.TrimRight(after.Body, "\n"))
+	return out
+}
+
+func nonNilList(l []string) []string {
+	if l == nil {
+		return []string{}
+	}
+	return l
+}
+
+func nonNilQuestions(qs []PlanQuestion) []PlanQuestion {
+	if qs == nil {
+		return []PlanQuestion{}
+	}
+	return qs
+}
+
+func samePlanQuestions(a, b []PlanQuestion) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
+
+// fieldNames returns the custom field names set in either list, in order.
+func fieldNames(a, b []TicketField) []string {
+	var names []string
+	for _, f := range append(append([]TicketField(nil), a...), b...) {
+		if !contains(names, f.Name) {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// applyEditedFields checks the custom fields an edit changed against the
+// schema and stores them typed and in canonical form. Fields the edit left
+// alone are kept as they were.
+func applyEditedFields(orig, t *Ticket, schema []FieldDef) error {
+	var assignments []string
+	for _, name := range fieldNames(orig.Fields, t.Fields) {
+		if v := t.FieldValue(name); v != orig.FieldValue(name) {
+			assignments = append(assignments, name+"="+v)
+		}
+	}
+	edited := t.Fields
+	t.Fields = orig.Fields
+	if err := applyFieldAssignments(t, schema, assignments); err != nil {
+		t.Fields = edited
+		return fmt.Errorf("fields: %v", err)
+	}
+	return nil
+}
+
+// validateEdit checks an edited ticket against its original. Deps and the
+// parent are only checked where the edit changed them, so an edit never
+// fails over a dangling reference it did not make. lookup returns the
+// current version of a ticket, with the other edits of a batch applied,
+// or nil if there is none.
+func validateEdit(orig, t *Ticket, lookup func(id string) *Ticket) error {
+	if t.ID != orig.ID {
+		return fmt.Errorf("id cannot be changed (was %s)", orig.ID)
+	}
+	if t.Created != orig.Created {
+		return fmt.Errorf("created cannot be changed (was %s)", orig.Created)
+	}
+	if strings.TrimSpace(t.Title) == "" {
+		return fmt.Errorf("title is missing (the body must start with a # heading)")
+	}
+	if !ValidStatus(t.Status) {
+		return fmt.Errorf("invalid status %q (valid statuses: %s)", t.Status, strings.Join(Statuses, " "))
+	}
+	if t.Priority < 0 || t.Priority > 4 {
+		return fmt.Errorf("priority must be 0-4, got %d", t.Priority)
+	}
+	if t.Snooze != "" && t.Snooze != orig.Snooze {
+		if _, err := time.Parse("2006-01-02", t.Snooze); err != nil {
+			return fmt.Errorf("invalid snooze date %q: must be ISO 8601 format (e.g. 2026-05-01)", t.Snooze)
+		}
+	}
+	for _, dep := range t.Deps {
+		if contains(orig.Deps, dep) {
+			continue
+		}
+		if dep == t.ID {
+			return fmt.Errorf("deps: ticket cannot depend on itself")
+		}
+		if lookup(dep) == nil {
+			return fmt.Errorf("deps: ticket '%s' not found", dep)
+		}
+	}
+	if cycle := depCycle(t, lookup); cycle != nil {
+		return fmt.Errorf("deps: dependency cycle %s", strings.Join(cycle, " -> "))
+	}
+	if t.Parent != "" && t.Parent != orig.Parent {
+		for id := t.Parent; id != ""; {
+			if id == t.ID {
+				return fmt.Errorf("parent: %s is %s itself or one of its descendants", t.Parent, t.ID)
+			}
+			p := lookup(id)
+			if p == nil {
+				if id == t.Parent {
+					return fmt.Errorf("parent: ticket '%s' not found", id)
+				}
+				break
+			}
+			id = p.Parent
+		}
+	}
+	if err := ValidatePlanQuestions(t.PlanQuestions); err != nil {
+		return fmt.Errorf("plan-questions: %v", err)
+	}
+	return nil
+}
+
+// depCycle returns a dependency path from t back to itself, or nil.
+func depCycle(t *Ticket, lookup func(id string) *Ticket) []string {
+	seen := map[string]bool{}
+	path := []string{t.ID}
+	var walk func(deps []string) bool
+	walk = func(deps []string) bool {
+		for _, dep := range deps {
+			if dep == t.ID {
+				path = append(path, dep)
+				return true
+			}
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			if next := lookup(dep); next != nil {
+				path = append(path, dep)
+				if walk(next.Deps) {
+					return true
+				}
+				path = path[:len(path)-1]
+			}
+		}
+		return false
+	}
+	if walk(t.Deps) {
+		return path
+	}
+	return nil
+}
+
+// editMarkerPattern matches the line that starts each ticket of a batch
+// edit buffer.
+var editMarkerPattern = regexp.MustCompile(`^<!-- ko edit: (\S+) -->$`)
+
+// formatEditBuffer renders tickets for ko edit --batch: a header, then
+// each ticket under a marker line naming it.
+func formatEditBuffer(tickets []*Ticket) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!-- ko edit --batch: %d tickets. Each one starts at its marker line.\n", len(tickets))
+	b.WriteString("     Keep the markers; removing a ticket's section leaves it unchanged. -->\n")
+	for _, t := range tickets {
+		fmt.Fprintf(&b, "\n<!-- ko edit: %s -->\n", t.ID)
+		b.WriteString(strings.TrimRight(FormatTicket(t), "\n") + "\n")
+	}
+	return b.String()
+}
+
+// parseEditBuffer splits a batch edit buffer at its markers and parses
+// each ticket, returning them by the ID of their marker, in order.
+func parseEditBuffer(content string) ([]string, map[string]*Ticket, error) {
+	var ids []string
+	sections := map[string][]string{}
+	var cur string
+	for _, line := range strings.Split(content, "\n") {
+		if m := editMarkerPattern.FindStringSubmatch(line); m != nil {
+			cur = m[1]
+			if _, dup := sections[cur]; dup {
+				return nil, nil, fmt.Errorf("%s: marker appears twice", cur)
+			}
+			ids = append(ids, cur)
+			sections[cur] = nil
+			continue
+		}
+		if cur != "" {
+			sections[cur] = append(sections[cur], line)
+		}
+	}
+	tickets := map[string]*Ticket{}
+	for _, id := range ids {
+		text := strings.TrimSpace(strings.Join(sections[id], "\n")) + "\n"
+		t, err := ParseTicket(text)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", id, err)
+		}
+		tickets[id] = t
+	}
+	return ids, tickets, nil
+}
+
+// editFilterKeys are the ko edit --batch filter terms handled like the
+// REST API's query parameters.
+var editFilterKeys = []string{"status", "type", "tag", "assignee", "parent", "all"}
+
+// parseEditFilter reads a ko edit --batch filter: whitespace-separated
+// terms such as "status=open tag=ui". Terms naming a custom field are
+// field filters, which may also compare (estimate>3).
+func parseEditFilter(filter string, schema []FieldDef) (url.Values, []FieldFilter, error) {
+	q := url.Values{}
+	var fields []FieldFilter
+	terms := strings.Fields(filter)
+	if len(terms) == 0 {
+		return nil, nil, fmt.Errorf("empty filter (want e.g. status=open tag=ui)")
+	}
+	for _, term := range terms {
+		if key, val, ok := strings.Cut(term, "="); ok && contains(editFilterKeys, key) {
+			q.Add(key, val)
+			continue
+		}
+		f, err := parseFieldFilter(term, schema)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v (filter terms: %s or a custom field)", err, strings.Join(editFilterKeys, ", "))
+		}
+		fields = append(fields, f)
+	}
+	return q, fields, nil
+}
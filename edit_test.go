@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func editTicket(id, title string, deps ...string) *Ticket {
+	return &Ticket{ID: id, Status: "open", Created: "2026-01-01T00:00:00Z", Type: "task", Priority: 2, Title: title, Deps: deps, Body: "\nBody.\n"}
+}
+
+func TestTicketChanges(t *testing.T) {
+	before := editTicket("ko-a001", "Old")
+	after := *before
+	after.Title = "New"
+	after.Tags = []string{"ui"}
+	after.Body = "\nBody.\n\n"
+	after.Fields = []TicketField{{Name: "estimate", Type: "int", Value: "3"}}
+
+	var got []string
+	for _, c := range ticketChanges(before, &after) {
+		got = append(got, c.Field)
+	}
+	if s := strings.Join(got, " "); s != "title tags fields.estimate" {
+		t.Errorf("changes = %s", s)
+	}
+
+	// Nil and empty lists are the same
+	after = *before
+	after.Deps = []string{}
+	after.PlanQuestions = []PlanQuestion{}
+	if c := ticketChanges(before, &after); len(c) != 0 {
+		t.Errorf("changes = %+v, want none", c)
+	}
+}
+
+func TestValidateEdit(t *testing.T) {
+	tickets := map[string]*Ticket{
+		"ko-a001": editTicket("ko-a001", "A"),
+		"ko-a002": editTicket("ko-a002", "B", "ko-a001"),
+		"ko-a003": editTicket("ko-a003", "C"),
+	}
+	tickets["ko-a003"].Parent = "ko-a001"
+	lookup := func(id string) *Ticket { return tickets[id] }
+
+	for _, tc := range []struct {
+		name string
+		edit func(t *Ticket)
+		want string
+	}{
+		{"ok", func(t *Ticket) { t.Title = "Renamed"; t.Deps = []string{"ko-a003"} }, ""},
+		{"id", func(t *Ticket) { t.ID = "ko-zzzz" }, "id cannot be changed"},
+		{"title", func(t *Ticket) { t.Title = " " }, "title is missing"},
+		{"status", func(t *Ticket) { t.Status = "done" }, `invalid status "done"`},
+		{"priority", func(t *Ticket) { t.Priority = 7 }, "priority must be 0-4"},
+		{"snooze", func(t *Ticket) { t.Snooze = "tomorrow" }, "invalid snooze date"},
+		{"self dep", func(t *Ticket) { t.Deps = []string{"ko-a001"} }, "cannot depend on itself"},
+		{"missing dep", func(t *Ticket) { t.Deps = []string{"ko-nope"} }, "ticket 'ko-nope' not found"},
+		{"cycle", func(t *Ticket) { t.Deps = []string{"ko-a002"} }, "dependency cycle ko-a001 -> ko-a002 -> ko-a001"},
+		{"parent", func(t *Ticket) { t.Parent = "ko-a003" }, "ko-a003 is ko-a001 itself or one of its descendants"},
+		{"missing parent", func(t *Ticket) { t.Parent = "ko-nope" }, "parent: ticket 'ko-nope' not found"},
+		{"questions", func(t *Ticket) { t.PlanQuestions = []PlanQuestion{{ID: "q1"}} }, "plan-questions: "},
+	} {
+		orig := tickets["ko-a001"]
+		edited := *orig
+		tc.edit(&edited)
+		err := validateEdit(orig, &edited, lookup)
+		if tc.want == "" && err != nil || tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+
+	// A dangling dep the edit did not add is left alone
+	orig := editTicket("ko-a004", "D", "ko-gone")
+	edited := *orig
+	edited.Title = "Renamed"
+	if err := validateEdit(orig, &edited, lookup); err != nil {
+		t.Errorf("untouched dangling dep: %v", err)
+	}
+}
+
+func TestEditBuffer(t *testing.T) {
+	a, b := editTicket("ko-a001", "A"), editTicket("ko-a002", "B", "ko-a001")
+	buf := formatEditBuffer([]*Ticket{a, b})
+	ids, tickets, err := parseEditBuffer(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, " ") != "ko-a001 ko-a002" {
+		t.Fatalf("ids = %v", ids)
+	}
+	for _, orig := range []*Ticket{a, b} {
+		if c := ticketChanges(orig, tickets[orig.ID]); len(c) != 0 {
+			t.Errorf("%s round trip changed %+v", orig.ID, c)
+		}
+	}
+
+	if _, _, err := parseEditBuffer(buf + "\n<!-- ko edit: ko-a001 -->\n"); err == nil || !strings.Contains(err.Error(), "marker appears twice") {
+		t.Errorf("duplicate marker: err = %v", err)
+	}
+	if _, _, err := parseEditBuffer("<!-- ko edit: ko-a001 -->\n# no frontmatter\n"); err == nil || !strings.HasPrefix(err.Error(), "ko-a001: ") {
+		t.Errorf("bad section: err = %v", err)
+	}
+}
+
+func TestParseEditFilter(t *testing.T) {
+	schema := []FieldDef{{Name: "estimate", Type: "int"}}
+	q, fields, err := parseEditFilter("status=open tag=ui estimate>=3", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Get("status") != "open" || q.Get("tag") != "ui" || len(fields) != 1 || fields[0].Op != ">=" {
+		t.Errorf("filter = %v %+v", q, fields)
+	}
+	if _, _, err := parseEditFilter("  ", schema); err == nil {
+		t.Error("empty filter accepted")
+	}
+	if _, _, err := parseEditFilter("owner=sam", schema); err == nil || !strings.Contains(err.Error(), `unknown field "owner"`) {
+		t.Errorf("unknown term: err = %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// secretsFile is the untracked dotenv file that env values can reference
+// with ${secrets.NAME}. Its contents are never exported wholesale.
+const secretsFile = "secrets.env"
+
+// secretRefPrefix marks an interpolation that reads from the secrets file.
+const secretRefPrefix = "secrets."
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// nodeEnvConfig is the env configuration that applies to one node after
+// override resolution.
+type nodeEnvConfig struct {
+	Env     map[string]string
+	EnvFile []string
+}
+
+// resolveNodeEnvConfig picks the most specific env and env_file, following
+// the same override semantics as allowed_tools (node > workflow > pipeline).
+// env and env_file are resolved independently.
+func resolveNodeEnvConfig(p *Pipeline, wf *Workflow, node *Node) nodeEnvConfig {
+	var c nodeEnvConfig
+	switch {
+	case node.Env != nil:
+		c.Env = node.Env
+	case wf.Env != nil:
+		c.Env = wf.Env
+	default:
+		c.Env = p.Env
+	}
+	switch {
+	case node.EnvFile != nil:
+		c.EnvFile = node.EnvFile
+	case wf.EnvFile != nil:
+		c.EnvFile = wf.EnvFile
+	default:
+		c.EnvFile = p.EnvFile
+	}
+	return c
+}
+
+// resolveNodeEnv returns the extra env entries for a node, reading
+// .ko/secrets.env for ${secrets.NAME} references.
+func resolveNodeEnv(ticketsDir string, p *Pipeline, wf *Workflow, node *Node) ([]string, error) {
+	c := resolveNodeEnvConfig(p, wf, node)
+	if len(c.Env) == 0 && len(c.EnvFile) == 0 {
+		return nil, nil
+	}
+	secrets, err := loadSecrets(ticketsDir)
+	if err != nil {
+		return nil, err
+	}
+	return buildNodeEnv(ProjectRoot(ticketsDir), c, secrets)
+}
+
+// buildNodeEnv returns the KEY=VALUE entries a node adds to its inherited
+// environment. env_file entries (paths relative to the project root) are
+// loaded first, then env values, which may reference ${secrets.NAME} and
+// ${VAR} from the process environment. The result is sorted by key.
+func buildNodeEnv(projectRoot string, c nodeEnvConfig, secrets map[string]string) ([]string, error) {
+	vars := map[string]string{}
+	for _, f := range c.EnvFile {
+		path := f
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectRoot, path)
+		}
+		fileVars, err := loadEnvFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("env_file %s: %v", f, err)
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+	for k, v := range c.Env {
+		expanded, err := interpolateEnv(v, secrets)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %v", k, err)
+		}
+		vars[k] = expanded
+	}
+	return sortedEnv(vars), nil
+}
+
+// interpolateEnv expands ${secrets.NAME} from the secrets file and $VAR or
+// ${VAR} from the process environment. A missing secret is an error.
+func interpolateEnv(val string, secrets map[string]string) (string, error) {
+	var missing []string
+	out := os.Expand(val, func(name string) string {
+		if key, ok := strings.CutPrefix(name, secretRefPrefix); ok {
+			v, found := secrets[key]
+			if !found {
+				missing = append(missing, key)
+			}
+			return v
+		}
+		return os.Getenv(name)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("secret %s not found in .ko/%s", strings.Join(missing, ", "), secretsFile)
+	}
+	return out, nil
+}
+
+// loadSecrets reads .ko/secrets.env. A missing file yields no secrets.
+func loadSecrets(ticketsDir string) (map[string]string, error) {
+	path := filepath.Join(ProjectRoot(ticketsDir), ".ko", secretsFile)
+	secrets, err := loadEnvFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf(".ko/%s: %v", secretsFile, err)
+	}
+	return secrets, nil
+}
+
+// loadEnvFile reads a dotenv file.
+func loadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseDotenv(f)
+}
+
+// parseDotenv parses KEY=VALUE lines. Blank lines and # comments are
+// skipped, an "export " prefix is allowed, and values may be single- or
+// double-quoted (quotes are stripped, nothing inside is expanded).
+func parseDotenv(r io.Reader) (map[string]string, error) {
+	vars := map[string]string{}
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, ok := strings.Cut(line, "=")
+		k = strings.TrimSpace(k)
+		if !ok || !envNameRe.MatchString(k) {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		vars[k] = v
+	}
+	return vars, sc.Err()
+}
+
+// validateEnvNames rejects env keys that are not valid variable names at
+// any level of the pipeline.
+func validateEnvNames(p *Pipeline) error {
+	check := func(where string, env map[string]string) error {
+		for k := range env {
+			if !envNameRe.MatchString(k) {
+				return fmt.Errorf("%s: invalid env var name %q", where, k)
+			}
+		}
+		return nil
+	}
+	if err := check("pipeline", p.Env); err != nil {
+		return err
+	}
+	for name, wf := range p.Workflows {
+		if err := check("workflow '"+name+"'", wf.Env); err != nil {
+			return err
+		}
+		for _, n := range wf.Nodes {
+			if err := check("node '"+n.Name+"'", n.Env); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveNodeEnvConfigOverrides(t *testing.T) {
+	p := &Pipeline{Env: map[string]string{"LEVEL": "pipeline"}, EnvFile: []string{"p.env"}}
+	wf := &Workflow{Env: map[string]string{"LEVEL": "workflow"}}
+	node := &Node{Env: map[string]string{"LEVEL": "node"}}
+
+	if got := resolveNodeEnvConfig(p, wf, node); got.Env["LEVEL"] != "node" {
+		t.Errorf("node env should win, got %v", got.Env)
+	}
+	c := resolveNodeEnvConfig(p, wf, &Node{})
+	if c.Env["LEVEL"] != "workflow" {
+		t.Errorf("workflow env should win over pipeline, got %v", c.Env)
+	}
+	if !reflect.DeepEqual(c.EnvFile, []string{"p.env"}) {
+		t.Errorf("env_file should inherit from pipeline independently, got %v", c.EnvFile)
+	}
+	if got := resolveNodeEnvConfig(p, &Workflow{}, &Node{}); got.Env["LEVEL"] != "pipeline" {
+		t.Errorf("pipeline env should apply, got %v", got.Env)
+	}
+	// An empty node env clears inherited values.
+	if got := resolveNodeEnvConfig(p, wf, &Node{Env: map[string]string{}}); len(got.Env) != 0 {
+		t.Errorf("empty node env should override, got %v", got.Env)
+	}
+}
+
+func TestBuildNodeEnv(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "shared.env"), []byte("# shared\nREGION=eu-west-1\nexport MODE='file'\n"), 0644)
+	t.Setenv("KO_TEST_HOME_DIR", "/home/test")
+
+	c := nodeEnvConfig{
+		EnvFile: []string{"shared.env"},
+		Env: map[string]string{
+			"MODE":         "env",
+			"DEPLOY_TOKEN": "${secrets.DEPLOY_TOKEN}",
+			"CACHE":        "${KO_TEST_HOME_DIR}/.cache",
+		},
+	}
+	got, err := buildNodeEnv(root, c, map[string]string{"DEPLOY_TOKEN": "s3cr3t-value"})
+	if err != nil {
+		t.Fatalf("buildNodeEnv: %v", err)
+	}
+	want := []string{"CACHE=/home/test/.cache", "DEPLOY_TOKEN=s3cr3t-value", "MODE=env", "REGION=eu-west-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildNodeEnv = %v, want %v", got, want)
+	}
+}
+
+func TestBuildNodeEnvMissingSecret(t *testing.T) {
+	c := nodeEnvConfig{Env: map[string]string{"X": "${secrets.NOPE}"}}
+	_, err := buildNodeEnv(t.TempDir(), c, map[string]string{})
+	if err == nil || !strings.Contains(err.Error(), "secret NOPE not found") {
+		t.Errorf("err = %v, want missing secret error", err)
+	}
+}
+
+func TestBuildNodeEnvMissingFile(t *testing.T) {
+	c := nodeEnvConfig{EnvFile: []string{"missing.env"}}
+	if _, err := buildNodeEnv(t.TempDir(), c, nil); err == nil {
+		t.Error("expected error for missing env_file")
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	got, err := parseDotenv(strings.NewReader("A=1\n\n# c\nexport B=\"two words\"\nC='x=y'\n"))
+	if err != nil {
+		t.Fatalf("parseDotenv: %v", err)
+	}
+	want := map[string]string{"A": "1", "B": "two words", "C": "x=y"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDotenv = %v, want %v", got, want)
+	}
+	if _, err := parseDotenv(strings.NewReader("not a pair\n")); err == nil {
+		t.Error("expected error for malformed line")
+	}
+}
+
+func TestParsePipelineEnv(t *testing.T) {
+	config := `
+env:
+  LOG_LEVEL: debug
+  API_URL: "http://localhost:8080"
+env_file: .ko/shared.env
+workflows:
+  main:
+    env: {STAGE: build}
+    env_file:
+      - a.env
+      - b.env
+    - name: deploy
+      type: action
+      run: ./deploy.sh
+      env:
+        DEPLOY_TOKEN: ${secrets.DEPLOY_TOKEN}
+      timeout: 5m
+    - name: check
+      type: action
+      run: ./check.sh
+`
+	p, err := ParsePipeline(config)
+	if err != nil {
+		t.Fatalf("ParsePipeline failed: %v", err)
+	}
+	wantP := map[string]string{"LOG_LEVEL": "debug", "API_URL": "http://localhost:8080"}
+	if !reflect.DeepEqual(p.Env, wantP) {
+		t.Errorf("pipeline Env = %v, want %v", p.Env, wantP)
+	}
+	if !reflect.DeepEqual(p.EnvFile, []string{".ko/shared.env"}) {
+		t.Errorf("pipeline EnvFile = %v", p.EnvFile)
+	}
+	wf := p.Workflows["main"]
+	if !reflect.DeepEqual(wf.Env, map[string]string{"STAGE": "build"}) {
+		t.Errorf("workflow Env = %v", wf.Env)
+	}
+	if !reflect.DeepEqual(wf.EnvFile, []string{"a.env", "b.env"}) {
+		t.Errorf("workflow EnvFile = %v", wf.EnvFile)
+	}
+	deploy := wf.Nodes[0]
+	if !reflect.DeepEqual(deploy.Env, map[string]string{"DEPLOY_TOKEN": "${secrets.DEPLOY_TOKEN}"}) {
+		t.Errorf("deploy Env = %v", deploy.Env)
+	}
+	if deploy.Timeout != "5m" {
+		t.Errorf("property after env block lost: Timeout = %q", deploy.Timeout)
+	}
+	if wf.Nodes[1].Env != nil {
+		t.Errorf("check node should inherit env, got %v", wf.Nodes[1].Env)
+	}
+
+	if _, err := ParsePipeline("env: {BAD-NAME: x}\n" + config[strings.Index(config, "workflows:"):]); err == nil {
+		t.Error("expected error for invalid env var name")
+	}
+}
+
+func TestMergePipelineEnv(t *testing.T) {
+	base := &Pipeline{Env: map[string]string{"A": "base"}, EnvFile: []string{"base.env"}}
+	override := &Pipeline{
+		Env:       map[string]string{"A": "override"},
+		setFields: map[string]bool{"env": true},
+	}
+	merged := MergePipeline(base, override)
+	if merged.Env["A"] != "override" {
+		t.Errorf("Env = %v, want override", merged.Env)
+	}
+	if !reflect.DeepEqual(merged.EnvFile, []string{"base.env"}) {
+		t.Errorf("EnvFile = %v, want inherited base.env", merged.EnvFile)
+	}
+}
@@ -41,6 +41,7 @@ func (l *EventLogger) emit(fields map[string]interface{}) {
 	if l.file == nil {
 		return
 	}
+	redactFields(fields)
 	fields["ts"] = time.Now().UTC().Format(time.RFC3339)
 	data, err := json.Marshal(fields)
 	if err != nil {
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldDef declares a custom ticket field in the fields: section of
+// .ko/config.yaml:
+//
+//	fields:
+//	  customer: string
+//	  estimate:
+//	    type: int
+//	    default: 1
+//	  component:
+//	    type: enum
+//	    values: [api, web, cli]
+type FieldDef struct {
+	Name    string
+	Type    string   // string | int | enum | date | bool
+	Values  []string // allowed values of an enum
+	Default string   // applied by ko add; canonical form, "" for none
+}
+
+// TicketField is one custom field value on a ticket. Value is canonical
+// text (see FieldDef.normalize); Type is the field's type when it was set,
+// so readers can type the value without the project config.
+type TicketField struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+// fieldTypes is the closed set of custom field types.
+var fieldTypes = []string{"string", "int", "enum", "date", "bool"}
+
+var fieldNameRe = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// parseFieldSchema reads the top-level fields: section of a config file.
+func parseFieldSchema(content string) ([]FieldDef, error) {
+	n, err := yamlSection(content, "fields")
+	if err != nil {
+		return nil, err
+	}
+	return decodeFieldSchema(&yamlDecoder{}, n)
+}
+
+// decodeFieldSchema reads a fields: mapping. "name: type" is the short
+// form of a block with only a type.
+func decodeFieldSchema(d *yamlDecoder, n *yamlNode) ([]FieldDef, error) {
+	entries, err := d.entries(n, "fields")
+	if err != nil {
+		return nil, err
+	}
+	var defs []FieldDef
+	for _, e := range entries {
+		def := FieldDef{Name: e.Key.Value}
+		what := "fields." + def.Name
+		if e.Value.Kind == yamlScalar {
+			if def.Type, err = yamlString(e.Value, what); err != nil {
+				return nil, err
+			}
+			defs = append(defs, def)
+			continue
+		}
+		props, err := d.entries(e.Value, what)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range props {
+			switch p.Key.Value {
+			case "type":
+				def.Type, err = yamlString(p.Value, what+".type")
+			case "default":
+				def.Default, err = yamlString(p.Value, what+".default")
+			case "values":
+				def.Values, err = yamlStrings(p.Value, what+".values")
+			default:
+				return nil, yamlErrorf(p.Key, "fields: %s: unknown key %q", def.Name, p.Key.Value)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		defs = append(defs, def)
+	}
+	return defs, validateFieldSchema(defs)
+}
+
+// validateFieldSchema checks names and types and puts defaults in
+// canonical form.
+func validateFieldSchema(defs []FieldDef) error {
+	seen := map[string]bool{}
+	for i := range defs {
+		d := &defs[i]
+		if !fieldNameRe.MatchString(d.Name) {
+			return fmt.Errorf("fields: invalid name %q (want lowercase letters, digits, - and _)", d.Name)
+		}
+		if seen[d.Name] {
+			return fmt.Errorf("fields: %s declared twice", d.Name)
+		}
+		seen[d.Name] = true
+		if !contains(fieldTypes, d.Type) {
+			return fmt.Errorf("fields: %s: type must be one of %s, got %q", d.Name, strings.Join(fieldTypes, ", "), d.Type)
+		}
+		if d.Type == "enum" && len(d.Values) == 0 {
+			return fmt.Errorf("fields: %s: an enum needs values", d.Name)
+		}
+		if d.Type != "enum" && len(d.Values) > 0 {
+			return fmt.Errorf("fields: %s: values are only for enums", d.Name)
+		}
+		if d.Default != "" {
+			v, err := d.normalize(d.Default)
+			if err != nil {
+				return fmt.Errorf("fields: %s: default: %v", d.Name, err)
+			}
+			d.Default = v
+		}
+	}
+	return nil
+}
+
+// normalize checks a value against the field's type and returns it in
+// canonical form: decimal ints, YYYY-MM-DD dates, true/false.
+func (d FieldDef) normalize(val string) (string, error) {
+	switch d.Type {
+	case "int":
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return "", fmt.Errorf("%s: %q is not an integer", d.Name, val)
+		}
+		return strconv.Itoa(n), nil
+	case "date":
+		if _, err := time.Parse("2006-01-02", val); err != nil {
+			return "", fmt.Errorf("%s: %q is not a date (want YYYY-MM-DD)", d.Name, val)
+		}
+		return val, nil
+	case "bool":
+		switch strings.ToLower(val) {
+		case "yes", "y", "on":
+			return "true", nil
+		case "no", "n", "off":
+			return "false", nil
+		}
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return "", fmt.Errorf("%s: %q is not true or false", d.Name, val)
+		}
+		return strconv.FormatBool(b), nil
+	case "enum":
+		if !contains(d.Values, val) {
+			return "", fmt.Errorf("%s: %q is not one of %s", d.Name, val, strings.Join(d.Values, ", "))
+		}
+	}
+	return val, nil
+}
+
+// findFieldDef returns the declaration of name, or nil.
+func findFieldDef(schema []FieldDef, name string) *FieldDef {
+	for i := range schema {
+		if schema[i].Name == name {
+			return &schema[i]
+		}
+	}
+	return nil
+}
+
+// loadFieldSchema returns the custom fields declared for the project of
+// ticketsDir. A project without a config has none.
+func loadFieldSchema(ticketsDir string) ([]FieldDef, error) {
+	path, err := FindConfig(ticketsDir)
+	if err != nil {
+		return nil, nil
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Fields, nil
+}
+
+// FieldValue returns the value of a custom field, or "".
+func (t *Ticket) FieldValue(name string) string {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// setField sets or, with an empty value, removes a field, keeping the
+// fields sorted by name.
+func (t *Ticket) setField(name, typ, value string) {
+	fields := t.Fields[:0:0]
+	for _, f := range t.Fields {
+		if f.Name != name {
+			fields = append(fields, f)
+		}
+	}
+	if value != "" {
+		fields = append(fields, TicketField{Name: name, Type: typ, Value: value})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	t.Fields = fields
+}
+
+// applyFieldAssignments applies --field name=value flags. An empty value
+// removes the field.
+func applyFieldAssignments(t *Ticket, schema []FieldDef, assignments []string) error {
+	for _, a := range assignments {
+		name, val, ok := strings.Cut(a, "=")
+		if !ok {
+			return fmt.Errorf("--field %q: want name=value", a)
+		}
+		name, val = strings.TrimSpace(name), strings.TrimSpace(val)
+		def := findFieldDef(schema, name)
+		if def == nil {
+			return fmt.Errorf("unknown field %q (declare it under fields: in .ko/config.yaml)", name)
+		}
+		if val != "" {
+			var err error
+			if val, err = def.normalize(val); err != nil {
+				return err
+			}
+		}
+		t.setField(name, def.Type, val)
+	}
+	return nil
+}
+
+// applyFieldDefaults sets every field with a default that the ticket does
+// not have yet.
+func applyFieldDefaults(t *Ticket, schema []FieldDef) {
+	for _, d := range schema {
+		if d.Default != "" && t.FieldValue(d.Name) == "" {
+			t.setField(d.Name, d.Type, d.Default)
+		}
+	}
+}
+
+// fieldsJSON returns a ticket's fields as a JSON object with typed values:
+// numbers for ints, booleans for bools, strings otherwise.
+func fieldsJSON(fields []TicketField) map[string]interface{} {
+	if len(fields) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		out[f.Name] = f.Value
+		switch f.Type {
+		case "int":
+			if n, err := strconv.Atoi(f.Value); err == nil {
+				out[f.Name] = n
+			}
+		case "bool":
+			if b, err := strconv.ParseBool(f.Value); err == nil {
+				out[f.Name] = b
+			}
+		}
+	}
+	return out
+}
+
+// FieldFilter is a --field condition of ko ls and ko search: name, an
+// operator (= != < <= > >=) and a value.
+type FieldFilter struct {
+	Name  string
+	Op    string
+	Value string
+}
+
+// fieldFilterOps is ordered so that two-character operators match first.
+var fieldFilterOps = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+// parseFieldFilter parses "estimate>=3". When the project declares the
+// field, the value is checked and put in canonical form.
+func parseFieldFilter(s string, schema []FieldDef) (FieldFilter, error) {
+	i := strings.IndexAny(s, "!<>=")
+	if i <= 0 {
+		return FieldFilter{}, fmt.Errorf("--field %q: want name=value (or !=, <, <=, >, >=)", s)
+	}
+	f := FieldFilter{Name: strings.TrimSpace(s[:i])}
+	for _, op := range fieldFilterOps {
+		if strings.HasPrefix(s[i:], op) {
+			f.Op = op
+			f.Value = strings.TrimSpace(s[i+len(op):])
+			break
+		}
+	}
+	if f.Op == "" {
+		return FieldFilter{}, fmt.Errorf("--field %q: unknown operator", s)
+	}
+	if def := findFieldDef(schema, f.Name); def != nil {
+		if (f.Op != "=" && f.Op != "!=") && def.Type != "int" && def.Type != "date" {
+			return FieldFilter{}, fmt.Errorf("--field %q: %s is a %s; only = and != apply", s, f.Name, def.Type)
+		}
+		v, err := def.normalize(f.Value)
+		if err != nil {
+			return FieldFilter{}, err
+		}
+		f.Value = v
+	} else if len(schema) > 0 {
+		return FieldFilter{}, fmt.Errorf("--field %q: unknown field %q", s, f.Name)
+	}
+	return f, nil
+}
+
+// parseFieldFilters parses every --field flag of a listing command.
+func parseFieldFilters(flags []string, schema []FieldDef) ([]FieldFilter, error) {
+	var filters []FieldFilter
+	for _, s := range flags {
+		f, err := parseFieldFilter(s, schema)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// fieldFiltersFor parses --field filters against the custom fields of the
+// project of ticketsDir, if any.
+func fieldFiltersFor(ticketsDir string, flags []string) ([]FieldFilter, error) {
+	if len(flags) == 0 {
+		return nil, nil
+	}
+	var schema []FieldDef
+	if ticketsDir != "" {
+		var err error
+		if schema, err = loadFieldSchema(ticketsDir); err != nil {
+			return nil, err
+		}
+	}
+	return parseFieldFilters(flags, schema)
+}
+
+// numeric reports whether the filter compares integers.
+func (f FieldFilter) numeric() bool {
+	_, err := strconv.Atoi(f.Value)
+	return err == nil && f.Op != "=" && f.Op != "!="
+}
+
+// sql returns an SQL condition on the tickets row aliased t. A ticket
+// without the field matches only !=.
+func (f FieldFilter) sql() (string, []interface{}) {
+	if f.Op == "!=" {
+		return `NOT EXISTS (SELECT 1 FROM ticket_fields tf
+			WHERE tf.ticket_id = t.id AND tf.name = ? AND tf.value = ?)`, []interface{}{f.Name, f.Value}
+	}
+	if f.numeric() {
+		n, _ := strconv.Atoi(f.Value)
+		return `EXISTS (SELECT 1 FROM ticket_fields tf
+			WHERE tf.ticket_id = t.id AND tf.name = ? AND CAST(tf.value AS INTEGER) ` + f.Op + ` ?)`, []interface{}{f.Name, n}
+	}
+	return `EXISTS (SELECT 1 FROM ticket_fields tf
+		WHERE tf.ticket_id = t.id AND tf.name = ? AND tf.value ` + f.Op + ` ?)`, []interface{}{f.Name, f.Value}
+}
+
+// match applies the filter to a loaded ticket, as sql does in the database.
+func (f FieldFilter) match(t *Ticket) bool {
+	val := ""
+	found := false
+	for _, tf := range t.Fields {
+		if tf.Name == f.Name {
+			val, found = tf.Value, true
+		}
+	}
+	if f.Op == "!=" {
+		return !found || val != f.Value
+	}
+	if !found {
+		return false
+	}
+	cmp := strings.Compare(val, f.Value)
+	if f.numeric() {
+		a, err := strconv.Atoi(val)
+		if err != nil {
+			return false
+		}
+		b, _ := strconv.Atoi(f.Value)
+		cmp = 0
+		if a < b {
+			cmp = -1
+		} else if a > b {
+			cmp = 1
+		}
+	}
+	switch f.Op {
+	case "=":
+		return cmp == 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default: // >=
+		return cmp >= 0
+	}
+}
+
+// matchFieldFilters reports whether a ticket passes every filter.
+func matchFieldFilters(t *Ticket, filters []FieldFilter) bool {
+	for _, f := range filters {
+		if !f.match(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// stringList is a repeatable string flag, e.g. --field a=1 --field b=2.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
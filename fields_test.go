@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFieldSchema(t *testing.T) {
+	defs, err := parseFieldSchema(`project:
+  prefix: ko
+fields:
+  customer: string   # who asked
+  estimate:
+    type: int
+    default: "03"
+  component:
+    type: enum
+    values: [api, web]
+    default: api
+pipeline:
+  agent: claude
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 3 {
+		t.Fatalf("defs = %+v", defs)
+	}
+	if defs[0].Name != "customer" || defs[0].Type != "string" {
+		t.Errorf("short form = %+v", defs[0])
+	}
+	if defs[1].Default != "3" {
+		t.Errorf("estimate default = %q, want canonical 3", defs[1].Default)
+	}
+	if strings.Join(defs[2].Values, ",") != "api,web" || defs[2].Default != "api" {
+		t.Errorf("component = %+v", defs[2])
+	}
+
+	for _, tc := range []struct{ yaml, err string }{
+		{"fields:\n  size: huge\n", "type must be one of"},
+		{"fields:\n  kind:\n    type: enum\n", "an enum needs values"},
+		{"fields:\n  n:\n    type: int\n    values: [1]\n", "only for enums"},
+		{"fields:\n  n:\n    type: int\n    default: x\n", "not an integer"},
+		{"fields:\n  Name: string\n", "invalid name"},
+		{"fields:\n  a: string\n  a: int\n", `line 3: duplicate key "a"`},
+		{"fields:\n  a:\n    type: string\n    required: true\n", "unknown key"},
+	} {
+		if _, err := parseFieldSchema(tc.yaml); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%q: err = %v, want %q", tc.yaml, err, tc.err)
+		}
+	}
+}
+
+func TestFieldNormalize(t *testing.T) {
+	tests := []struct {
+		typ, in, want string
+		ok            bool
+	}{
+		{"int", "007", "7", true},
+		{"int", "1.5", "", false},
+		{"bool", "yes", "true", true},
+		{"bool", "0", "false", true},
+		{"bool", "maybe", "", false},
+		{"date", "2026-02-28", "2026-02-28", true},
+		{"date", "2026-02-30", "", false},
+		{"enum", "web", "web", true},
+		{"enum", "cli", "", false},
+		{"string", "anything at all", "anything at all", true},
+	}
+	for _, tt := range tests {
+		d := FieldDef{Name: "f", Type: tt.typ, Values: []string{"api", "web"}}
+		got, err := d.normalize(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("%s %q: got %q, %v", tt.typ, tt.in, got, err)
+		}
+	}
+}
+
+func TestFieldFilterMatch(t *testing.T) {
+	schema := []FieldDef{{Name: "estimate", Type: "int"}, {Name: "due", Type: "date"}, {Name: "team", Type: "string"}}
+	tk := &Ticket{}
+	tk.setField("estimate", "int", "10")
+	tk.setField("due", "date", "2026-03-01")
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"estimate=10", true},
+		{"estimate>9", true},    // numeric, not "10" < "9"
+		{"estimate<=09", false}, // normalized to 9
+		{"due<2026-04-01", true},
+		{"due>=2026-03-02", false},
+		{"team=core", false},
+		{"team!=core", true}, // absent counts as different
+	}
+	for _, tt := range tests {
+		f, err := parseFieldFilter(tt.filter, schema)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.filter, err)
+		}
+		if got := f.match(tk); got != tt.want {
+			t.Errorf("%s: match = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	if _, err := parseFieldFilter("team>core", schema); err == nil {
+		t.Error("ordering a string field should fail")
+	}
+	if _, err := parseFieldFilter("=x", schema); err == nil {
+		t.Error("a filter without a name should fail")
+	}
+}
+
+func TestTicketFieldsRoundTrip(t *testing.T) {
+	defer setupTestDB(t)()
+	ticketsDir := t.TempDir()
+
+	tk := NewTicket("ft", "Fields")
+	schema := []FieldDef{{Name: "estimate", Type: "int", Default: "1"}, {Name: "customer", Type: "string"}}
+	if err := applyFieldAssignments(tk, schema, []string{"customer=Acme"}); err != nil {
+		t.Fatal(err)
+	}
+	applyFieldDefaults(tk, schema)
+	if err := SaveTicket(ticketsDir, tk); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadTicket(ticketsDir, tk.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Fields) != 2 || got.FieldValue("customer") != "Acme" || got.Fields[1] != (TicketField{"estimate", "int", "1"}) {
+		t.Fatalf("fields = %+v", got.Fields)
+	}
+	if j := fieldsJSON(got.Fields); j["estimate"] != 1 || j["customer"] != "Acme" {
+		t.Errorf("fieldsJSON = %v", j)
+	}
+
+	// Markdown keeps the values (types come from the config again)
+	parsed, err := ParseTicket(FormatTicket(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.FieldValue("estimate") != "1" || parsed.FieldValue("customer") != "Acme" || parsed.Title != "Fields" {
+		t.Errorf("parsed = %+v", parsed)
+	}
+
+	// Clearing removes the row
+	applyFieldAssignments(got, schema, []string{"customer="})
+	SaveTicket(ticketsDir, got)
+	if got, _ = LoadTicket(ticketsDir, tk.ID); got.FieldValue("customer") != "" {
+		t.Errorf("customer not cleared: %+v", got.Fields)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultGCAge is how old an artifact must be before `ko gc` prunes it
+// when --older-than is not given.
+const defaultGCAge = 30 * 24 * time.Hour
+
+// gcCandidate is one prunable directory inside a ticket's artifact dir.
+// Only workspaces and numbered build output dirs are ever candidates;
+// plan.md, notes, and the build history JSONL are never touched.
+type gcCandidate struct {
+	TicketID string
+	Kind     string // "workspace" or "build"
+	Build    int    // build number (Kind == "build")
+	Path     string
+	Size     int64
+	ModTime  time.Time // newest mtime of anything in the directory
+}
+
+// Label describes the candidate for gc output, e.g. "ko-a001 build 3".
+func (c gcCandidate) Label() string {
+	if c.Kind == "build" {
+		return fmt.Sprintf("%s build %d", c.TicketID, c.Build)
+	}
+	return c.TicketID + " " + c.Kind
+}
+
+// gcPolicy controls which candidates are eligible for removal.
+type gcPolicy struct {
+	Cutoff     time.Time // candidates modified after this are kept
+	ClosedOnly bool
+	Orphans    bool // prune artifacts of tickets that no longer exist
+}
+
+// parseAge parses an --older-than value. It accepts Go durations
+// ("36h", "90m") plus day and week suffixes ("30d", "2w").
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty age")
+	}
+	unit := s[len(s)-1]
+	if unit == 'd' || unit == 'w' {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		day := 24 * time.Hour
+		if unit == 'w' {
+			day *= 7
+		}
+		return time.Duration(n) * day, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w, 12h)", s)
+	}
+	return d, nil
+}
+
+// selectGCCandidates decides which candidates to remove. statuses maps
+// ticket ID to status; a missing entry means the ticket no longer exists,
+// and its artifacts are only pruned, like a closed ticket's, when
+// policy.Orphans asks for it. In-progress tickets are never touched
+// (a build may be running), and the latest build of a ticket that is not
+// closed is kept so `ko logs` still has something to show.
+func selectGCCandidates(cands []gcCandidate, statuses map[string]string, policy gcPolicy) []gcCandidate {
+	latest := map[string]int{}
+	for _, c := range cands {
+		if c.Kind == "build" && c.Build > latest[c.TicketID] {
+			latest[c.TicketID] = c.Build
+		}
+	}
+
+	var out []gcCandidate
+	for _, c := range cands {
+		status, known := statuses[c.TicketID]
+		if !known && !policy.Orphans {
+			continue
+		}
+		closed := !known || status == "closed"
+		if status == "in_progress" {
+			continue
+		}
+		if policy.ClosedOnly && !closed {
+			continue
+		}
+		if c.ModTime.After(policy.Cutoff) {
+			continue
+		}
+		if !closed && c.Kind == "build" && c.Build == latest[c.TicketID] {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// scanGCCandidates walks every <id>.artifacts dir under ticketsDir and
+// returns its workspace and build output dirs, sorted by ticket then build.
+func scanGCCandidates(ticketsDir string) ([]gcCandidate, error) {
+	entries, err := os.ReadDir(ticketsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cands []gcCandidate
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasSuffix(e.Name(), ".artifacts") {
+			continue
+		}
+		id := strings.TrimSuffix(e.Name(), ".artifacts")
+		artifactDir := filepath.Join(ticketsDir, e.Name())
+
+		ws := filepath.Join(artifactDir, "workspace")
+		if info, err := os.Stat(ws); err == nil && info.IsDir() {
+			size, mod := dirUsage(ws)
+			cands = append(cands, gcCandidate{TicketID: id, Kind: "workspace", Path: ws, Size: size, ModTime: mod})
+		}
+		for _, n := range ListBuildNumbers(artifactDir) {
+			dir := BuildOutputDir(artifactDir, n)
+			size, mod := dirUsage(dir)
+			cands = append(cands, gcCandidate{TicketID: id, Kind: "build", Build: n, Path: dir, Size: size, ModTime: mod})
+		}
+	}
+
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].TicketID != cands[j].TicketID {
+			return cands[i].TicketID < cands[j].TicketID
+		}
+		return cands[i].Build < cands[j].Build
+	})
+	return cands, nil
+}
+
+// dirUsage returns the total size of regular files under dir and the
+// newest modification time of dir or anything inside it.
+func dirUsage(dir string) (int64, time.Time) {
+	var size int64
+	var newest time.Time
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	return size, newest
+}
+
+// formatBytes renders a byte count for humans ("512 B", "1.5 KB", "3.2 MB").
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// workerWorktree is a git worktree and the branch it has checked out.
+type workerWorktree struct {
+	Path   string
+	Branch string // short branch name, empty if detached
+}
+
+var workerDirPattern = regexp.MustCompile(`ko-workers-(\d+)`)
+
+// orphanWorkerBranches returns the ko-worker-* branches left behind by
+// createWorktree that no live loop owns. A branch is orphaned when no
+// worktree has it checked out, or when its worktree lives under a
+// ko-workers-<pid> dir whose process is gone. The returned worktree path
+// is set when the stale worktree itself must be removed first.
+func orphanWorkerBranches(branches []string, worktrees []workerWorktree, alive func(int) bool) []workerWorktree {
+	checkedOut := map[string]string{}
+	for _, wt := range worktrees {
+		if wt.Branch != "" {
+			checkedOut[wt.Branch] = wt.Path
+		}
+	}
+
+	var out []workerWorktree
+	for _, b := range branches {
+		if !strings.HasPrefix(b, "ko-worker-") {
+			continue
+		}
+		path, ok := checkedOut[b]
+		if !ok {
+			out = append(out, workerWorktree{Branch: b})
+			continue
+		}
+		m := workerDirPattern.FindStringSubmatch(path)
+		if m == nil {
+			continue
+		}
+		pid, _ := strconv.Atoi(m[1])
+		if !alive(pid) {
+			out = append(out, workerWorktree{Path: path, Branch: b})
+		}
+	}
+	return out
+}
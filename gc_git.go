@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Worker branches are pruned by editing the repository's files directly,
+// like the index reader in changes_hash.go reads them: loose refs under
+// refs/heads, the packed-refs file, and one admin dir per linked worktree
+// under worktrees/. No git binary is needed.
+
+// gitCommonDir returns the directory holding the refs and worktrees of the
+// repository containing projectRoot, or "" outside a repository.
+func gitCommonDir(projectRoot string) string {
+	gitDir, _ := findGitDir(projectRoot)
+	if gitDir == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	dir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// listWorkerBranches returns the ko-worker-* branches, loose or packed,
+// sorted.
+func listWorkerBranches(commonDir string) []string {
+	seen := map[string]bool{}
+	entries, _ := os.ReadDir(filepath.Join(commonDir, "refs", "heads"))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), "ko-worker-") {
+			seen[e.Name()] = true
+		}
+	}
+	packed, _ := os.ReadFile(filepath.Join(commonDir, "packed-refs"))
+	for _, line := range strings.Split(string(packed), "\n") {
+		_, ref, _ := strings.Cut(line, " ")
+		if name, ok := strings.CutPrefix(ref, "refs/heads/"); ok && strings.HasPrefix(name, "ko-worker-") {
+			seen[name] = true
+		}
+	}
+	branches := make([]string, 0, len(seen))
+	for b := range seen {
+		branches = append(branches, b)
+	}
+	sort.Strings(branches)
+	return branches
+}
+
+// readWorktrees returns the worktrees of the repository with the branch
+// each has checked out, and the admin dir of each linked worktree by path.
+func readWorktrees(commonDir string) ([]workerWorktree, map[string]string) {
+	headBranch := func(dir string) string {
+		head, _ := os.ReadFile(filepath.Join(dir, "HEAD"))
+		branch, _ := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: refs/heads/")
+		return branch
+	}
+	wts := []workerWorktree{{Path: filepath.Dir(commonDir), Branch: headBranch(commonDir)}}
+	admin := map[string]string{}
+	entries, _ := os.ReadDir(filepath.Join(commonDir, "worktrees"))
+	for _, e := range entries {
+		dir := filepath.Join(commonDir, "worktrees", e.Name())
+		gitdir, err := os.ReadFile(filepath.Join(dir, "gitdir"))
+		if err != nil {
+			continue
+		}
+		dotGit := strings.TrimSpace(string(gitdir))
+		if !filepath.IsAbs(dotGit) {
+			dotGit = filepath.Join(dir, dotGit)
+		}
+		path := filepath.Dir(filepath.Clean(dotGit))
+		wts = append(wts, workerWorktree{Path: path, Branch: headBranch(dir)})
+		admin[path] = dir
+	}
+	return wts, admin
+}
+
+// removeLinkedWorktree deletes a worktree and its admin dir.
+func removeLinkedWorktree(path, adminDir string) error {
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	if adminDir == "" {
+		return nil
+	}
+	return os.RemoveAll(adminDir)
+}
+
+// deleteGitBranch deletes a branch, packed or loose, and its reflog.
+func deleteGitBranch(commonDir, name string) error {
+	ref := "refs/heads/" + name
+	if err := removePackedRef(commonDir, ref); err != nil {
+		return err
+	}
+	loose := filepath.Join(commonDir, filepath.FromSlash(ref))
+	if err := os.Remove(loose); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	os.Remove(filepath.Join(commonDir, "logs", filepath.FromSlash(ref)))
+	return nil
+}
+
+// removePackedRef drops ref and its peeled line from packed-refs, holding
+// git's packed-refs.lock while it rewrites the file.
+func removePackedRef(commonDir, ref string) error {
+	path := filepath.Join(commonDir, "packed-refs")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("packed-refs: %v", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			lock.Close()
+			os.Remove(lock.Name())
+		}
+	}()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, found := dropPackedRef(string(data), ref)
+	if !found {
+		return nil
+	}
+	if _, err := lock.WriteString(out); err != nil {
+		return err
+	}
+	if err := lock.Close(); err != nil {
+		return err
+	}
+	committed = true
+	if err := os.Rename(lock.Name(), path); err != nil {
+		os.Remove(lock.Name())
+		return err
+	}
+	return nil
+}
+
+// dropPackedRef removes ref's line, and the "^" peeled line after it, from
+// packed-refs content.
+func dropPackedRef(content, ref string) (string, bool) {
+	var b strings.Builder
+	found, dropping := false, false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if dropping && strings.HasPrefix(line, "^") {
+			continue
+		}
+		dropping = false
+		if _, r, ok := strings.Cut(strings.TrimSuffix(line, "\n"), " "); ok && r == ref && !strings.HasPrefix(line, "#") {
+			found, dropping = true, true
+			continue
+		}
+		b.WriteString(line)
+	}
+	return b.String(), found
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGCWorkerBranches(t *testing.T) {
+	root := t.TempDir()
+	gitInit(t, root)
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("a\n"), 0644)
+	gitCommitAll(t, root)
+	git(t, root, "branch", "ko-worker-ko-a")
+	git(t, root, "branch", "ko-worker-ko-b")
+	git(t, root, "pack-refs", "--all")
+	git(t, root, "branch", "ko-worker-ko-c")
+	// A worktree of a loop that is gone, and one of a live loop
+	dead := filepath.Join(t.TempDir(), "ko-workers-999999999", "ko-d")
+	git(t, root, "worktree", "add", "-q", "-b", "ko-worker-ko-d", dead)
+	live := filepath.Join(t.TempDir(), "ko-workers-"+strconv.Itoa(os.Getpid()), "ko-e")
+	git(t, root, "worktree", "add", "-q", "-b", "ko-worker-ko-e", live)
+
+	commonDir := gitCommonDir(root)
+	want := []string{"ko-worker-ko-a", "ko-worker-ko-b", "ko-worker-ko-c", "ko-worker-ko-d", "ko-worker-ko-e"}
+	if got := listWorkerBranches(commonDir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("listWorkerBranches = %v, want %v", got, want)
+	}
+	if got := gitCommonDir(live); got != commonDir {
+		t.Errorf("gitCommonDir from a worktree = %q, want %q", got, commonDir)
+	}
+
+	if n := gcWorkerBranches(root, true); n != 4 {
+		t.Errorf("dry run counted %d branches, want 4", n)
+	}
+	if n := gcWorkerBranches(root, false); n != 4 {
+		t.Errorf("deleted %d branches, want 4", n)
+	}
+
+	out, err := exec.Command("git", "-C", root, "branch", "--list", "ko-worker-*", "--format=%(refname:short)").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(string(out)); !reflect.DeepEqual(got, []string{"ko-worker-ko-e"}) {
+		t.Errorf("branches left = %v, want [ko-worker-ko-e]", got)
+	}
+	if _, err := os.Stat(dead); !os.IsNotExist(err) {
+		t.Error("the dead loop's worktree should be removed")
+	}
+	if _, err := os.Stat(live); err != nil {
+		t.Errorf("the live loop's worktree should stay: %v", err)
+	}
+	if err := exec.Command("git", "-C", root, "fsck", "--no-progress").Run(); err != nil {
+		t.Errorf("git fsck after gc: %v", err)
+	}
+}
+
+func TestDropPackedRef(t *testing.T) {
+	content := "# pack-refs with: peeled fully-peeled sorted \n" +
+		"aaa refs/heads/ko-worker-ko-a\n" +
+		"bbb refs/tags/v1\n^ccc\n" +
+		"ddd refs/heads/main\n"
+	got, found := dropPackedRef(content, "refs/tags/v1")
+	want := "# pack-refs with: peeled fully-peeled sorted \naaa refs/heads/ko-worker-ko-a\nddd refs/heads/main\n"
+	if !found || got != want {
+		t.Errorf("dropPackedRef = %q, %v; want %q", got, found, want)
+	}
+	if _, found := dropPackedRef(content, "refs/heads/missing"); found {
+		t.Error("missing ref reported found")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"0s", 0, false},
+		{"", 0, true},
+		{"xd", 0, true},
+		{"-1d", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAge(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAge(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSelectGCCandidates(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	cands := []gcCandidate{
+		{TicketID: "ko-a", Kind: "workspace", ModTime: old},
+		{TicketID: "ko-a", Kind: "build", Build: 1, ModTime: old},
+		{TicketID: "ko-b", Kind: "build", Build: 1, ModTime: old},
+		{TicketID: "ko-b", Kind: "build", Build: 2, ModTime: old},
+		{TicketID: "ko-c", Kind: "workspace", ModTime: old},
+		{TicketID: "ko-d", Kind: "workspace", ModTime: now},
+		{TicketID: "ko-gone", Kind: "build", Build: 1, ModTime: old},
+	}
+	statuses := map[string]string{
+		"ko-a": "closed",
+		"ko-b": "open",
+		"ko-c": "in_progress",
+		"ko-d": "closed",
+	}
+
+	labels := func(cs []gcCandidate) []string {
+		var out []string
+		for _, c := range cs {
+			out = append(out, c.Label())
+		}
+		return out
+	}
+
+	policy := gcPolicy{Cutoff: now.Add(-24 * time.Hour)}
+	got := labels(selectGCCandidates(cands, statuses, policy))
+	want := []string{"ko-a workspace", "ko-a build 1", "ko-b build 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("selected = %v, want %v", got, want)
+	}
+
+	policy.ClosedOnly = true
+	got = labels(selectGCCandidates(cands, statuses, policy))
+	want = []string{"ko-a workspace", "ko-a build 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("closed-only selected = %v, want %v", got, want)
+	}
+
+	// Unknown tickets are pruned only on request
+	policy.Orphans = true
+	got = labels(selectGCCandidates(cands, statuses, policy))
+	want = []string{"ko-a workspace", "ko-a build 1", "ko-gone build 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orphans selected = %v, want %v", got, want)
+	}
+
+	// Without statuses nothing is pruned
+	if got := selectGCCandidates(cands, nil, gcPolicy{Cutoff: now, ClosedOnly: true}); len(got) != 0 {
+		t.Errorf("no statuses selected %v", labels(got))
+	}
+}
+
+func TestOrphanWorkerBranches(t *testing.T) {
+	branches := []string{"main", "ko-worker-ko-a", "ko-worker-ko-b", "ko-worker-ko-c"}
+	worktrees := []workerWorktree{
+		{Path: "/repo", Branch: "main"},
+		{Path: "/tmp/ko-workers-100/ko-b", Branch: "ko-worker-ko-b"},
+		{Path: "/tmp/ko-workers-200/ko-c", Branch: "ko-worker-ko-c"},
+	}
+	alive := func(pid int) bool { return pid == 100 }
+
+	got := orphanWorkerBranches(branches, worktrees, alive)
+	want := []workerWorktree{
+		{Branch: "ko-worker-ko-a"},
+		{Path: "/tmp/ko-workers-200/ko-c", Branch: "ko-worker-ko-c"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orphanWorkerBranches = %+v, want %+v", got, want)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:           "0 B",
+		512:         "512 B",
+		1536:        "1.5 KB",
+		5 * 1 << 20: "5.0 MB",
+	}
+	for in, want := range tests {
+		if got := formatBytes(in); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
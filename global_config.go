@@ -1,9 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 // GlobalConfig represents ~/.config/knockout/config.yaml — user-level settings
@@ -11,6 +11,13 @@ import (
 type GlobalConfig struct {
 	Summarizer string // command to summarize long titles (e.g., "ollama run qwen3:0.6b --nowordwrap")
 	Server     string // remote ko serve URL (e.g., "https://ko.gisi.network") — when set, CLI proxies commands over HTTP
+	Token      string // bearer token sent to Server; may reference $VAR or ${secrets.NAME}
+
+	Notifications []NotificationTarget // outbound webhooks for every project
+	Inbound       []InboundHook        // ko serve POST /hooks/<name> endpoints
+	Tokens        []ServeToken         // bearer tokens ko serve accepts
+
+	Warnings []string // unknown keys found while parsing
 }
 
 // GlobalConfigPath returns the path to the global config file.
@@ -41,32 +48,49 @@ func LoadGlobalConfig() (*GlobalConfig, error) {
 		}
 		return nil, err
 	}
-	return ParseGlobalConfig(string(data))
+	g, err := ParseGlobalConfig(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	warnYAML(path, g.Warnings)
+	return g, nil
 }
 
 // ParseGlobalConfig parses global config YAML content.
 func ParseGlobalConfig(content string) (*GlobalConfig, error) {
 	g := &GlobalConfig{}
-	for _, line := range strings.Split(content, "\n") {
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			continue
-		}
-		key, val, ok := parseYAMLLine(trimmed)
-		if !ok {
-			continue
-		}
-		// Strip inline comments
-		if idx := strings.Index(val, " #"); idx >= 0 {
-			val = strings.TrimSpace(val[:idx])
-		}
+	root, err := parseYAML(content)
+	if err != nil {
+		return nil, err
+	}
+	d := &yamlDecoder{}
+	entries, err := d.entries(root, "config")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		key := e.Key.Value
 		switch key {
 		case "summarizer":
-			g.Summarizer = val
+			g.Summarizer, err = yamlString(e.Value, key)
 		case "server":
-			g.Server = val
+			g.Server, err = yamlString(e.Value, key)
+		case "token":
+			g.Token, err = yamlString(e.Value, key)
+		case "notifications":
+			g.Notifications, err = decodeNotifications(d, e.Value)
+		case "inbound":
+			g.Inbound, err = decodeInboundHooks(d, e.Value)
+		case "tokens":
+			g.Tokens, err = decodeServeTokens(d, e.Value)
+		default:
+			d.unknown(e.Key, "")
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
+	g.Warnings = d.warnings
 	return g, nil
 }
 
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// defaultHookTimeout bounds a hook when neither the hook nor the pipeline's
+// hook_timeout sets a limit.
+const defaultHookTimeout = 10 * time.Minute
+
+// Hook is one command in a lifecycle hook list. The plain list form
+// ("- git add -A") sets only Run; the map form ("- run: ...") can add
+// options on the following, more-indented lines.
+type Hook struct {
+	Run             string // shell command
+	Timeout         string // max duration (overrides hook_timeout)
+	ContinueOnError bool   // a failure is recorded but later hooks still run
+	When            string // condition on the hook env; empty always runs
+}
+
+// hookKeys are the keys of the map form of a hook entry.
+var hookKeys = map[string]bool{"run": true, "timeout": true, "continue_on_error": true, "when": true}
+
+// decodeHooks reads a hook list. An entry is a command or the map form
+// (run, timeout, continue_on_error, when). A null list is empty, which
+// for a workflow disables the pipeline's hook.
+func decodeHooks(d *yamlDecoder, n *yamlNode, what string) ([]Hook, error) {
+	items, err := yamlItems(n, what)
+	if err != nil {
+		return nil, err
+	}
+	hooks := []Hook{}
+	for _, item := range items {
+		if cmd, ok := yamlCommand(item); ok {
+			hooks = append(hooks, Hook{Run: cmd})
+			continue
+		}
+		entries, err := d.entries(item, what)
+		if err != nil {
+			return nil, err
+		}
+		var h Hook
+		for _, e := range entries {
+			w := what + "." + e.Key.Value
+			switch e.Key.Value {
+			case "run":
+				h.Run, err = yamlString(e.Value, w)
+			case "timeout":
+				h.Timeout, err = yamlString(e.Value, w)
+			case "continue_on_error":
+				h.ContinueOnError, err = yamlBool(e.Value, w)
+			case "when":
+				h.When, err = yamlString(e.Value, w)
+			default:
+				d.unknown(e.Key, what)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, nil
+}
+
+// decodeCommands reads a list of shell commands.
+func decodeCommands(n *yamlNode, what string) ([]string, error) {
+	items, err := yamlItems(n, what)
+	if err != nil {
+		return nil, err
+	}
+	cmds := []string{}
+	for _, item := range items {
+		cmd, ok := yamlCommand(item)
+		if !ok {
+			return nil, yamlErrorf(item, "%s: expected a command, got %s", what, yamlKindName(item))
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+// yamlCommand returns the shell command of a list entry. An unquoted
+// command containing ": " (git commit -m "ko: fix") reads as a one-pair
+// mapping; that shape is joined back into the command as written.
+func yamlCommand(n *yamlNode) (string, bool) {
+	if n.Kind == yamlScalar && !(n.Null && n.Style == 0) {
+		return n.Value, true
+	}
+	if n.Kind != yamlMapping || n.Flow || len(n.Pairs) != 1 {
+		return "", false
+	}
+	k, v := n.Pairs[0].Key, n.Pairs[0].Value
+	if hookKeys[k.Value] || k.Style != 0 || v.Kind != yamlScalar || v.Style != 0 || v.Null {
+		return "", false
+	}
+	return k.Value + ": " + v.Value, true
+}
+
+// resolveHookTimeout returns a hook's timeout: its own, else the pipeline's
+// hook_timeout, else defaultHookTimeout.
+func resolveHookTimeout(p *Pipeline, h Hook) (time.Duration, error) {
+	s := h.Timeout
+	if s == "" && p != nil {
+		s = p.HookTimeout
+	}
+	if s == "" {
+		return defaultHookTimeout, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// hookClause is one "VAR == value" or "VAR != value" test in a when:
+// condition.
+type hookClause struct {
+	name   string
+	negate bool
+	value  string
+}
+
+// parseHookCondition parses a when: condition: clauses of the form
+// "VAR == value" or "VAR != value" joined by "&&". VAR is any variable the
+// hook receives; value is a glob and may be quoted.
+func parseHookCondition(cond string) ([]hookClause, error) {
+	var clauses []hookClause
+	for _, part := range strings.Split(cond, "&&") {
+		part = strings.TrimSpace(part)
+		op := "=="
+		name, value, ok := strings.Cut(part, "==")
+		if !ok {
+			op = "!="
+			name, value, ok = strings.Cut(part, "!=")
+		}
+		name = strings.TrimSpace(name)
+		if !ok || !envNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid when clause %q: want VAR == value or VAR != value", part)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if _, err := path.Match(value, ""); err != nil {
+			return nil, fmt.Errorf("invalid when clause %q: %v", part, err)
+		}
+		clauses = append(clauses, hookClause{name: name, negate: op == "!=", value: value})
+	}
+	return clauses, nil
+}
+
+// hookConditionMet evaluates a when: condition against the hook env. For
+// multi-line values such as CHANGED_FILES, "==" holds when any line matches
+// and "!=" when none does.
+func hookConditionMet(cond string, env map[string]string) (bool, error) {
+	if strings.TrimSpace(cond) == "" {
+		return true, nil
+	}
+	clauses, err := parseHookCondition(cond)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range clauses {
+		matched := false
+		for _, line := range strings.Split(env[c.name], "\n") {
+			if ok, _ := path.Match(c.value, line); ok {
+				matched = true
+				break
+			}
+		}
+		if matched == c.negate {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// validateHooks rejects hooks with a malformed timeout or when: condition
+// at any level of the pipeline.
+func validateHooks(p *Pipeline) error {
+	if p.HookTimeout != "" {
+		if _, err := time.ParseDuration(p.HookTimeout); err != nil {
+			return fmt.Errorf("hook_timeout: %v", err)
+		}
+	}
+	check := func(where string, hooks []Hook) error {
+		for _, h := range hooks {
+			if h.Run == "" {
+				return fmt.Errorf("%s: hook entry has no run command", where)
+			}
+			if h.Timeout != "" {
+				if _, err := time.ParseDuration(h.Timeout); err != nil {
+					return fmt.Errorf("%s: hook timeout: %v", where, err)
+				}
+			}
+			if h.When != "" {
+				if _, err := parseHookCondition(h.When); err != nil {
+					return fmt.Errorf("%s: %v", where, err)
+				}
+			}
+		}
+		return nil
+	}
+	for _, name := range workflowHookNames {
+		if err := check(name, pipelineHooks(p, name)); err != nil {
+			return err
+		}
+	}
+	for wfName, wf := range p.Workflows {
+		for name, hooks := range wf.Hooks {
+			if err := check("workflow '"+wfName+"' "+name, hooks); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
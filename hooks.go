@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Build lifecycle hook names, as written in pipeline and workflow config.
+const (
+	HookOnStart     = "on_start"     // build starts, before the first node
+	HookOnNode      = "on_node"      // each node completes
+	HookOnBlock     = "on_block"     // the build leaves the ticket blocked
+	HookOnDecompose = "on_decompose" // a decision node created child tickets
+	HookOnSucceed   = "on_succeed"   // all workflows passed
+	HookOnFail      = "on_fail"      // the build failed
+	HookOnClose     = "on_close"     // the ticket was closed by the build
+)
+
+// workflowHookNames are the hooks a workflow can override.
+var workflowHookNames = []string{
+	HookOnStart, HookOnNode, HookOnBlock, HookOnDecompose,
+	HookOnSucceed, HookOnFail, HookOnClose,
+}
+
+// isWorkflowHook reports whether key names a per-workflow hook list.
+func isWorkflowHook(key string) bool {
+	return contains(workflowHookNames, key)
+}
+
+// pipelineHooks returns the pipeline-level commands for a hook.
+func pipelineHooks(p *Pipeline, name string) []Hook {
+	switch name {
+	case HookOnStart:
+		return p.OnStart
+	case HookOnNode:
+		return p.OnNode
+	case HookOnBlock:
+		return p.OnBlock
+	case HookOnDecompose:
+		return p.OnDecompose
+	case HookOnSucceed:
+		return p.OnSucceed
+	case HookOnFail:
+		return p.OnFail
+	case HookOnClose:
+		return p.OnClose
+	}
+	return nil
+}
+
+// resolveHooks returns the commands for a hook in workflow wfName. A
+// workflow that declares the hook (even as an empty list) replaces the
+// pipeline's commands; otherwise the pipeline's apply.
+func resolveHooks(p *Pipeline, wfName, name string) []Hook {
+	if wf, ok := p.Workflows[wfName]; ok {
+		if hooks, ok := wf.Hooks[name]; ok {
+			return hooks
+		}
+	}
+	return pipelineHooks(p, name)
+}
+
+// runBuildHooks runs a lifecycle hook for workflow wfName and records a
+// failure as a build error. extra is exported to the hook alongside the
+// standard ticket variables.
+func runBuildHooks(ticketsDir string, t *Ticket, p *Pipeline, wfName, name string, changes ChangeSet, extra map[string]string, wsDir string, log *EventLogger, hist *BuildHistoryLogger) error {
+	err := runHooks(ticketsDir, t, p, name, resolveHooks(p, wfName, name), changes, extra, wsDir, hist)
+	if err != nil {
+		log.BuildError(t.ID, name+"_hook", err.Error())
+		hist.BuildError(t.ID, name+"_hook", err.Error())
+	}
+	return err
+}
+
+// runFailHooks runs on_fail after a failed build, then on_block when the
+// failure left the ticket blocked. Both are best-effort.
+func runFailHooks(ticketsDir string, t *Ticket, p *Pipeline, wfName, wsDir string, log *EventLogger, hist *BuildHistoryLogger) {
+	if wfName == "" {
+		wfName = "main"
+	}
+	runBuildHooks(ticketsDir, t, p, wfName, HookOnFail, ChangeSet{}, nil, wsDir, log, hist)
+	if t.Status == "blocked" {
+		runBuildHooks(ticketsDir, t, p, wfName, HookOnBlock, ChangeSet{},
+			blockHookEnv(t.blockReason, t.blockedOn), wsDir, log, hist)
+	}
+}
+
+// nodeHookEnv returns the on_node variables for a completed node. The
+// workspace file is only set when the node wrote one.
+func nodeHookEnv(wsDir, wfName, nodeName, result string) map[string]string {
+	env := map[string]string{
+		"WORKFLOW":    wfName,
+		"NODE_NAME":   nodeName,
+		"NODE_RESULT": result,
+		"NODE_OUTPUT": "",
+	}
+	path := filepath.Join(wsDir, WorkspaceOutputName(wfName, nodeName))
+	if _, err := os.Stat(path); err == nil {
+		env["NODE_OUTPUT"] = path
+	}
+	return env
+}
+
+// hookOutputLimit caps the hook output kept in build history; the tail is
+// kept since errors usually come last.
+const hookOutputLimit = 64 << 10
+
+// hookWaitDelay bounds how long a timed-out hook's children may hold its
+// output open after the shell is killed.
+const hookWaitDelay = 2 * time.Second
+
+// runHooks executes a hook list in order. Each hook gets the standard
+// ticket, change and build variables plus extra, which are also expanded in
+// the command like TICKET_ID. A hook whose when: condition does not hold is
+// skipped, and a failure stops the list unless the hook sets
+// continue_on_error. Every hook's result and output go to build history.
+func runHooks(ticketsDir string, t *Ticket, p *Pipeline, name string, hooks []Hook, changes ChangeSet, extra map[string]string, wsDir string, hist *BuildHistoryLogger) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	projectRoot := ProjectRoot(ticketsDir)
+	env := hookEnv(ticketsDir, t, name, changes, extra, wsDir, hist.Path())
+	environ := append(os.Environ(), sortedEnv(env)...)
+
+	for _, hook := range hooks {
+		run, err := hookConditionMet(hook.When, env)
+		if err != nil {
+			return fmt.Errorf("hook '%s': %v", hook.Run, err)
+		}
+		if !run {
+			hist.HookRun(t.ID, name, hook.Run, "skipped", 0, 0, "")
+			continue
+		}
+		timeout, err := resolveHookTimeout(p, hook)
+		if err != nil {
+			return fmt.Errorf("hook '%s': invalid timeout: %v", hook.Run, err)
+		}
+
+		expanded := os.Expand(hook.Run, func(key string) string {
+			if v, ok := env[key]; ok {
+				return v
+			}
+			return os.Getenv(key)
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		cmd := exec.CommandContext(ctx, "sh", "-c", expanded)
+		cmd.Dir = projectRoot
+		cmd.Env = environ
+		// On timeout, kill the hook's whole process group, not just sh.
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+		cmd.WaitDelay = hookWaitDelay
+
+		start := time.Now()
+		out, err := cmd.CombinedOutput()
+		elapsed := time.Since(start)
+		timedOut := ctx.Err() == context.DeadlineExceeded
+		cancel()
+
+		status, exitCode := "ok", 0
+		if err != nil {
+			status, exitCode = "failed", -1
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				exitCode = exitErr.ExitCode()
+			}
+			if timedOut {
+				status = "timeout"
+				err = fmt.Errorf("timed out after %v", timeout)
+			}
+		}
+		hist.HookRun(t.ID, name, hook.Run, status, exitCode, elapsed, tailString(out, hookOutputLimit))
+
+		if err != nil && !hook.ContinueOnError {
+			return fmt.Errorf("hook '%s' failed: %v\n%s", hook.Run, err, string(out))
+		}
+	}
+	return nil
+}
+
+// hookEnv returns the variables every hook receives: the ticket (including
+// its full `ko show --json` form), the changed files, the build's paths,
+// outcome and disposition reason, and extra.
+func hookEnv(ticketsDir string, t *Ticket, name string, changes ChangeSet, extra map[string]string, wsDir, histPath string) map[string]string {
+	env := changes.hookEnv()
+	env["TICKET_ID"] = t.ID
+	env["TICKET_TITLE"] = t.Title
+	env["TICKET_TYPE"] = t.Type
+	env["TICKET_STATUS"] = t.Status
+	env["TICKET_PRIORITY"] = strconv.Itoa(t.Priority)
+	env["TICKET_TAGS"] = strings.Join(t.Tags, "\n")
+	env["KO_HOOK"] = name
+	env["KO_TICKET_WORKSPACE"] = wsDir
+	env["KO_ARTIFACT_DIR"] = ArtifactDir(ticketsDir, t.ID)
+	env["KO_BUILD_HISTORY"] = histPath
+	env["KO_BUILD_OUTCOME"] = t.buildOutcome
+	env["KO_DISPOSITION_REASON"] = t.dispReason
+	env["KO_TICKET_JSON"] = ticketHookJSON(ticketsDir, t)
+	for k, v := range extra {
+		env[k] = v
+	}
+	return env
+}
+
+// ticketHookJSON renders the ticket as `ko show --json` would.
+func ticketHookJSON(ticketsDir string, t *Ticket) string {
+	j := newShowJSON(t, openDeps(ticketsDir, t.Deps), findBlocking(ticketsDir, t.ID), findChildren(ticketsDir, t.ID))
+	data, err := json.Marshal(j)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// tailString returns at most limit bytes from the end of b.
+func tailString(b []byte, limit int) string {
+	if len(b) > limit {
+		b = b[len(b)-limit:]
+	}
+	return string(b)
+}
+
+// sortedEnv renders a map as KEY=VALUE entries sorted by key.
+func sortedEnv(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = k + "=" + vars[k]
+	}
+	return out
+}
+
+// blockHookEnv returns the on_block variables.
+func blockHookEnv(reason, blockedOn string) map[string]string {
+	return map[string]string{
+		"BLOCK_REASON": reason,
+		"BLOCKED_ON":   blockedOn,
+	}
+}
+
+// decomposeHookEnv returns the on_decompose variables; child IDs are
+// newline-separated like CHANGED_FILES.
+func decomposeHookEnv(childIDs []string) map[string]string {
+	return map[string]string{"CHILD_IDS": strings.Join(childIDs, "\n")}
+}
+
+// finishBuild records a build's terminal outcome in the event log, build
+// history and mutation stream (as build_complete), and exposes it to the
+// hooks that follow as KO_BUILD_OUTCOME.
+func finishBuild(ticketsDir string, t *Ticket, outcome string, log *EventLogger, hist *BuildHistoryLogger) {
+	t.buildOutcome = outcome
+	log.WorkflowComplete(t.ID, outcome)
+	hist.BuildComplete(t.ID, outcome)
+	EmitMutationEvent(ticketsDir, t.ID, "build_complete", map[string]interface{}{
+		"outcome": outcome,
+		"reason":  t.dispReason,
+	})
+}
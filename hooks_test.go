@@ -0,0 +1,306 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePipelineLifecycleHooks(t *testing.T) {
+	config := `
+command: test-llm
+workflows:
+  main:
+    on_node:
+      - echo main node
+    on_succeed: []
+    nodes:
+    - name: implement
+      type: action
+      prompt: implement.md
+  docs:
+    - name: write
+      type: action
+      prompt: write.md
+on_start:
+  - echo start
+on_node:
+  - echo node
+on_block:
+  - echo block
+on_decompose:
+  - echo decompose
+on_succeed:
+  - echo ok
+`
+	p, err := ParsePipeline(config)
+	if err != nil {
+		t.Fatalf("ParsePipeline failed: %v", err)
+	}
+	for name, want := range map[string]string{
+		HookOnStart:     "echo start",
+		HookOnNode:      "echo node",
+		HookOnBlock:     "echo block",
+		HookOnDecompose: "echo decompose",
+		HookOnSucceed:   "echo ok",
+	} {
+		got := pipelineHooks(p, name)
+		if len(got) != 1 || got[0].Run != want {
+			t.Errorf("%s = %v, want [%s]", name, got, want)
+		}
+	}
+
+	main := p.Workflows["main"]
+	if got := main.Hooks[HookOnNode]; len(got) != 1 || got[0].Run != "echo main node" {
+		t.Errorf("main on_node = %v, want [echo main node]", got)
+	}
+	if got, ok := main.Hooks[HookOnSucceed]; !ok || len(got) != 0 {
+		t.Errorf("main on_succeed = %v (set=%v), want empty override", got, ok)
+	}
+	if len(main.Nodes) != 1 || main.Nodes[0].Name != "implement" {
+		t.Errorf("main nodes = %v, want [implement]", main.Nodes)
+	}
+	if p.Workflows["docs"].Hooks != nil {
+		t.Errorf("docs hooks = %v, want nil", p.Workflows["docs"].Hooks)
+	}
+}
+
+func TestResolveHooks(t *testing.T) {
+	p := &Pipeline{
+		OnNode:    []Hook{{Run: "echo pipeline"}},
+		OnSucceed: []Hook{{Run: "git commit"}},
+		Workflows: map[string]*Workflow{
+			"main": {Name: "main"},
+			"docs": {Name: "docs", Hooks: map[string][]Hook{
+				HookOnNode:    {{Run: "echo docs"}},
+				HookOnSucceed: {},
+			}},
+		},
+	}
+
+	tests := []struct {
+		wf, hook string
+		want     []string
+	}{
+		{"main", HookOnNode, []string{"echo pipeline"}},
+		{"main", HookOnSucceed, []string{"git commit"}},
+		{"docs", HookOnNode, []string{"echo docs"}},
+		{"docs", HookOnSucceed, nil},
+		{"docs", HookOnFail, nil},
+		{"missing", HookOnNode, []string{"echo pipeline"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, h := range resolveHooks(p, tt.wf, tt.hook) {
+			got = append(got, h.Run)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("resolveHooks(%s, %s) = %v, want %v", tt.wf, tt.hook, got, tt.want)
+		}
+	}
+}
+
+func TestMergePipelineLifecycleHooks(t *testing.T) {
+	base := &Pipeline{
+		OnStart: []Hook{{Run: "echo base start"}},
+		OnNode:  []Hook{{Run: "echo base node"}},
+	}
+	override := &Pipeline{
+		setFields: map[string]bool{"on_node": true},
+		OnNode:    []Hook{{Run: "echo override node"}},
+	}
+	result := MergePipeline(base, override)
+	if len(result.OnStart) != 1 || result.OnStart[0].Run != "echo base start" {
+		t.Errorf("OnStart = %v, want base preserved", result.OnStart)
+	}
+	if len(result.OnNode) != 1 || result.OnNode[0].Run != "echo override node" {
+		t.Errorf("OnNode = %v, want override", result.OnNode)
+	}
+	result.OnStart[0].Run = "modified"
+	if base.OnStart[0].Run != "echo base start" {
+		t.Error("MergePipeline aliased base on_start — mutation leaked")
+	}
+}
+
+func TestNodeHookEnv(t *testing.T) {
+	wsDir := t.TempDir()
+	env := nodeHookEnv(wsDir, "main", "triage", "decision")
+	if env["WORKFLOW"] != "main" || env["NODE_NAME"] != "triage" || env["NODE_RESULT"] != "decision" {
+		t.Errorf("env = %v", env)
+	}
+	if env["NODE_OUTPUT"] != "" {
+		t.Errorf("NODE_OUTPUT = %q, want empty without a workspace file", env["NODE_OUTPUT"])
+	}
+
+	out := filepath.Join(wsDir, WorkspaceOutputName("main", "triage"))
+	if err := os.WriteFile(out, []byte("done"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeHookEnv(wsDir, "main", "triage", "decision")["NODE_OUTPUT"]; got != out {
+		t.Errorf("NODE_OUTPUT = %q, want %q", got, out)
+	}
+}
+
+func TestDecomposeHookEnv(t *testing.T) {
+	env := decomposeHookEnv([]string{"ko-a001.1", "ko-a001.2"})
+	if env["CHILD_IDS"] != "ko-a001.1\nko-a001.2" {
+		t.Errorf("CHILD_IDS = %q", env["CHILD_IDS"])
+	}
+}
+
+func TestParsePipelineHookOptions(t *testing.T) {
+	config := `
+command: test-llm
+hook_timeout: 2m
+workflows:
+  main:
+    on_close:
+      - run: ./deploy.sh
+        timeout: 30s
+    nodes:
+    - name: implement
+      type: action
+      prompt: implement.md
+on_succeed:
+  - git add -A
+  - run: ./notify.sh
+    timeout: 5s
+    continue_on_error: true
+    when: "TICKET_TYPE == bug && CHANGED_FILES == 'src/*'"
+  - echo "run: not an option"
+`
+	p, err := ParsePipeline(config)
+	if err != nil {
+		t.Fatalf("ParsePipeline failed: %v", err)
+	}
+	if p.HookTimeout != "2m" {
+		t.Errorf("HookTimeout = %q, want 2m", p.HookTimeout)
+	}
+	want := []Hook{
+		{Run: "git add -A"},
+		{Run: "./notify.sh", Timeout: "5s", ContinueOnError: true, When: "TICKET_TYPE == bug && CHANGED_FILES == 'src/*'"},
+		{Run: `echo "run: not an option"`},
+	}
+	if len(p.OnSucceed) != len(want) {
+		t.Fatalf("OnSucceed = %+v, want %+v", p.OnSucceed, want)
+	}
+	for i := range want {
+		if p.OnSucceed[i] != want[i] {
+			t.Errorf("OnSucceed[%d] = %+v, want %+v", i, p.OnSucceed[i], want[i])
+		}
+	}
+	closeHooks := p.Workflows["main"].Hooks[HookOnClose]
+	if len(closeHooks) != 1 || closeHooks[0] != (Hook{Run: "./deploy.sh", Timeout: "30s"}) {
+		t.Errorf("main on_close = %+v", closeHooks)
+	}
+}
+
+func TestParsePipelineHookValidation(t *testing.T) {
+	tests := []struct {
+		name, config, wantErr string
+	}{
+		{"bad hook_timeout", "hook_timeout: soon\n", "hook_timeout"},
+		{"bad timeout", "on_fail:\n  - run: cleanup\n    timeout: later\n", "hook timeout"},
+		{"bad when", "on_fail:\n  - run: cleanup\n    when: TICKET_TYPE\n", "invalid when clause"},
+		{"missing run", "on_fail:\n  - timeout: 5s\n", "no run command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := "command: test-llm\nworkflows:\n  main:\n    - name: a\n      type: action\n      run: true\n" + tt.config
+			_, err := ParsePipeline(config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHookConditionMet(t *testing.T) {
+	env := map[string]string{
+		"TICKET_TYPE":      "bug",
+		"CHANGED_FILES":    "README.md\nsrc/main.go",
+		"KO_BUILD_OUTCOME": "succeed",
+		"BLOCKED_ON":       "",
+	}
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{"", true},
+		{"TICKET_TYPE == bug", true},
+		{"TICKET_TYPE != bug", false},
+		{"TICKET_TYPE == 'b*'", true},
+		{"CHANGED_FILES == src/*", true},
+		{"CHANGED_FILES != docs/*", true},
+		{"CHANGED_FILES != README.md", false},
+		{`BLOCKED_ON == ""`, true},
+		{"TICKET_TYPE == bug && KO_BUILD_OUTCOME == fail", false},
+		{"TICKET_TYPE == bug && KO_BUILD_OUTCOME == succeed", true},
+		{"UNSET == ''", true},
+	}
+	for _, tt := range tests {
+		got, err := hookConditionMet(tt.cond, env)
+		if err != nil {
+			t.Errorf("hookConditionMet(%q) error: %v", tt.cond, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("hookConditionMet(%q) = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+	if _, err := hookConditionMet("TICKET_TYPE = bug", env); err == nil {
+		t.Error("expected error for a clause without == or !=")
+	}
+}
+
+func TestResolveHookTimeout(t *testing.T) {
+	tests := []struct {
+		pipeline, hook string
+		want           time.Duration
+	}{
+		{"", "", defaultHookTimeout},
+		{"1m", "", time.Minute},
+		{"1m", "5s", 5 * time.Second},
+	}
+	for _, tt := range tests {
+		got, err := resolveHookTimeout(&Pipeline{HookTimeout: tt.pipeline}, Hook{Run: "x", Timeout: tt.hook})
+		if err != nil || got != tt.want {
+			t.Errorf("resolveHookTimeout(%q, %q) = %v, %v; want %v", tt.pipeline, tt.hook, got, err, tt.want)
+		}
+	}
+}
+
+func TestParsePipelineHookCommands(t *testing.T) {
+	config := `
+on_succeed:
+  - git add -A
+  - git commit -m "ko: implement ${TICKET_ID}"
+  - run: ./deploy.sh
+    continue_on_error: true
+on_loop_complete:
+  - echo "loop: done"
+workflows:
+  main:
+    - name: implement
+      type: action
+      run: make
+`
+	p, err := ParsePipeline(config)
+	if err != nil {
+		t.Fatalf("ParsePipeline: %v", err)
+	}
+	want := []Hook{
+		{Run: "git add -A"},
+		{Run: `git commit -m "ko: implement ${TICKET_ID}"`},
+		{Run: "./deploy.sh", ContinueOnError: true},
+	}
+	if !reflect.DeepEqual(p.OnSucceed, want) {
+		t.Errorf("OnSucceed = %+v", p.OnSucceed)
+	}
+	if !reflect.DeepEqual(p.OnLoopComplete, []string{`echo "loop: done"`}) {
+		t.Errorf("OnLoopComplete = %q", p.OnLoopComplete)
+	}
+}
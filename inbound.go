@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// InboundHook is one POST /hooks/<name> endpoint from the inbound: section
+// of the global config. Its rules turn a JSON payload into tickets.
+type InboundHook struct {
+	Name    string // URL path segment
+	Project string // registry tag of the project tickets are filed in
+	Secret  string // required bearer token or HMAC key; may reference $VAR or ${secrets.NAME}
+	Items   string // dotted path to an array; each element becomes a ticket
+
+	// Templates, rendered against the payload (or each item). Missing
+	// fields render empty.
+	When        string // the item is skipped when this renders "" or "false"
+	Title       string // required
+	Body        string
+	Tags        string // comma-separated
+	Priority    string // 0-4
+	Type        string
+	Fingerprint string // dedupe key, stored in external-ref; defaults to the title
+}
+
+// inboundMaxBody caps the payload size of an inbound hook request.
+const inboundMaxBody = 1 << 20
+
+// parseInboundHooks parses the top-level inbound: section of a config file.
+func parseInboundHooks(content string) ([]InboundHook, error) {
+	n, err := yamlSection(content, "inbound")
+	if err != nil {
+		return nil, err
+	}
+	return decodeInboundHooks(&yamlDecoder{}, n)
+}
+
+// decodeInboundHooks reads the list of an inbound: section.
+func decodeInboundHooks(d *yamlDecoder, n *yamlNode) ([]InboundHook, error) {
+	items, err := yamlItems(n, "inbound")
+	if err != nil {
+		return nil, err
+	}
+	var hooks []InboundHook
+	for _, item := range items {
+		entries, err := d.entries(item, "inbound")
+		if err != nil {
+			return nil, err
+		}
+		var h InboundHook
+		fields := map[string]*string{
+			"name": &h.Name, "project": &h.Project, "secret": &h.Secret, "items": &h.Items,
+			"when": &h.When, "title": &h.Title, "body": &h.Body, "tags": &h.Tags,
+			"priority": &h.Priority, "type": &h.Type, "fingerprint": &h.Fingerprint,
+		}
+		for _, e := range entries {
+			field, ok := fields[e.Key.Value]
+			if !ok {
+				return nil, yamlErrorf(e.Key, "inbound: unknown key %q", e.Key.Value)
+			}
+			if *field, err = yamlString(e.Value, "inbound."+e.Key.Value); err != nil {
+				return nil, err
+			}
+		}
+		h.Project = strings.TrimPrefix(h.Project, "#")
+		hooks = append(hooks, h)
+	}
+	return hooks, validateInboundHooks(hooks)
+}
+
+// validateInboundHooks checks names, required fields and that every rule
+// is a valid template.
+func validateInboundHooks(hooks []InboundHook) error {
+	seen := map[string]bool{}
+	for _, h := range hooks {
+		if h.Name == "" || strings.Contains(h.Name, "/") {
+			return fmt.Errorf("inbound: every hook needs a name without '/'")
+		}
+		if seen[h.Name] {
+			return fmt.Errorf("inbound: duplicate hook %q", h.Name)
+		}
+		seen[h.Name] = true
+		if h.Project == "" {
+			return fmt.Errorf("inbound: hook %q: project is required", h.Name)
+		}
+		if h.Title == "" {
+			return fmt.Errorf("inbound: hook %q: title is required", h.Name)
+		}
+		for field, text := range map[string]string{
+			"when": h.When, "title": h.Title, "body": h.Body, "tags": h.Tags,
+			"priority": h.Priority, "type": h.Type, "fingerprint": h.Fingerprint,
+		} {
+			if _, err := parseInboundTemplate(text); err != nil {
+				return fmt.Errorf("inbound: hook %q: %s: %v", h.Name, field, err)
+			}
+		}
+	}
+	return nil
+}
+
+// requireInboundSecrets rejects hooks without a secret. /hooks/ bypasses
+// the serve tokens, so once tokens: is set an open hook would let anyone
+// create and reopen tickets; ko serve refuses to start with one.
+func requireInboundSecrets(hooks []InboundHook) error {
+	for _, h := range hooks {
+		if h.Secret == "" {
+			return fmt.Errorf("inbound: hook %q: a secret is required when tokens: is configured", h.Name)
+		}
+	}
+	return nil
+}
+
+// parseInboundTemplate parses one rule. Besides the text/template builtins
+// it offers json, lower, upper, default and trunc.
+func parseInboundTemplate(text string) (*template.Template, error) {
+	return template.New("rule").Option("missingkey=zero").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"default": func(def string, v interface{}) string {
+			if s := inboundString(v); s != "" {
+				return s
+			}
+			return def
+		},
+		"trunc": func(n int, s string) string {
+			if len(s) <= n {
+				return s
+			}
+			return s[:n]
+		},
+	}).Parse(text)
+}
+
+// renderInbound renders one rule against data. Missing fields print as
+// nothing rather than "<no value>".
+func renderInbound(text string, data interface{}) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := parseInboundTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		// A path through a missing object ({{.a.b}} without .a) renders
+		// the rule empty rather than failing the request.
+		if strings.Contains(err.Error(), "nil pointer evaluating") {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(strings.ReplaceAll(buf.String(), "<no value>", "")), nil
+}
+
+// inboundString formats a decoded JSON value for use as a default.
+func inboundString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// inboundItems returns the payload elements that each become a ticket: the
+// array at the hook's items path, or the payload itself.
+func inboundItems(h InboundHook, payload interface{}) ([]interface{}, error) {
+	if h.Items == "" {
+		return []interface{}{payload}, nil
+	}
+	cur := payload
+	for _, part := range strings.Split(h.Items, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("items: %q is not an object path in the payload", h.Items)
+		}
+		cur = m[part]
+	}
+	list, ok := cur.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("items: %q is not an array in the payload", h.Items)
+	}
+	return list, nil
+}
+
+// InboundTicket is the rendered form of one payload item.
+type InboundTicket struct {
+	Title       string
+	Body        string
+	Tags        []string
+	Priority    int // -1 keeps the default
+	Type        string
+	ExternalRef string
+}
+
+// renderInboundTicket applies a hook's rules to one item. ok is false when
+// the when: rule skips it.
+func renderInboundTicket(h InboundHook, item interface{}) (t InboundTicket, ok bool, err error) {
+	if h.When != "" {
+		when, err := renderInbound(h.When, item)
+		if err != nil {
+			return t, false, fmt.Errorf("when: %v", err)
+		}
+		if when == "" || when == "false" {
+			return t, false, nil
+		}
+	}
+	fields := []struct {
+		name, text string
+		dst        *string
+	}{
+		{"title", h.Title, &t.Title},
+		{"body", h.Body, &t.Body},
+		{"type", h.Type, &t.Type},
+		{"fingerprint", h.Fingerprint, &t.ExternalRef},
+	}
+	for _, f := range fields {
+		if *f.dst, err = renderInbound(f.text, item); err != nil {
+			return t, false, fmt.Errorf("%s: %v", f.name, err)
+		}
+	}
+	// Title and fingerprint are single frontmatter/heading lines.
+	t.Title = strings.Join(strings.Fields(t.Title), " ")
+	t.ExternalRef = strings.Join(strings.Fields(t.ExternalRef), " ")
+	if t.Title == "" {
+		return t, false, fmt.Errorf("title rendered empty")
+	}
+	if t.ExternalRef == "" {
+		t.ExternalRef = t.Title
+	}
+	t.ExternalRef = h.Name + ":" + t.ExternalRef
+
+	tags, err := renderInbound(h.Tags, item)
+	if err != nil {
+		return t, false, fmt.Errorf("tags: %v", err)
+	}
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+
+	t.Priority = -1
+	prio, err := renderInbound(h.Priority, item)
+	if err != nil {
+		return t, false, fmt.Errorf("priority: %v", err)
+	}
+	if prio != "" {
+		p, err := strconv.Atoi(prio)
+		if err != nil || p < 0 || p > 4 {
+			return t, false, fmt.Errorf("priority: %q is not 0-4", prio)
+		}
+		t.Priority = p
+	}
+	return t, true, nil
+}
+
+// inboundMu serialises inbound deliveries so concurrent alerts with the
+// same fingerprint cannot create duplicate tickets.
+var inboundMu sync.Mutex
+
+// InboundResult reports what one payload item did.
+type InboundResult struct {
+	ID     string `json:"id"`
+	Action string `json:"action"` // created | reopened | updated
+}
+
+// applyInboundTicket files t in ticketsDir, using the registry prefix when
+// one is set. A ticket whose external-ref
+// matches is reopened if resolved or closed, or noted if still active,
+// instead of creating a duplicate.
+func applyInboundTicket(ticketsDir, prefix, hookName string, t InboundTicket) (InboundResult, error) {
+	existing, err := ListTickets(ticketsDir)
+	if err != nil {
+		return InboundResult{}, err
+	}
+	for _, match := range existing {
+		if match.ExternalRef != t.ExternalRef {
+			continue
+		}
+		old, err := LoadTicket(ticketsDir, match.ID)
+		if err != nil {
+			return InboundResult{}, err
+		}
+		action := "updated"
+		from := old.Status
+		if from == "closed" || from == "resolved" {
+			action = "reopened"
+			old.Status = "open"
+			AddNote(old, fmt.Sprintf("ko: reopened by webhook '%s'", hookName))
+		} else {
+			AddNote(old, fmt.Sprintf("ko: webhook '%s' fired again", hookName))
+		}
+		if err := SaveTicket(ticketsDir, old); err != nil {
+			return InboundResult{}, err
+		}
+		EmitMutationEvent(ticketsDir, old.ID, "note", nil)
+		if old.Status != from {
+			EmitMutationEvent(ticketsDir, old.ID, "status", map[string]interface{}{
+				"from": from,
+				"to":   old.Status,
+			})
+		}
+		return InboundResult{ID: old.ID, Action: action}, nil
+	}
+
+	if err := EnsureTicketsDir(ticketsDir); err != nil {
+		return InboundResult{}, err
+	}
+	if prefix == "" {
+		prefix = detectPrefix(ticketsDir)
+	}
+	nt := NewTicket(prefix, t.Title)
+	if t.Body != "" {
+		nt.Body += "\n" + t.Body + "\n"
+	}
+	if t.Type != "" {
+		nt.Type = t.Type
+	}
+	if t.Priority >= 0 {
+		nt.Priority = t.Priority
+	}
+	nt.Tags = t.Tags
+	nt.ExternalRef = t.ExternalRef
+	if err := SaveTicket(ticketsDir, nt); err != nil {
+		return InboundResult{}, err
+	}
+	EmitMutationEvent(ticketsDir, nt.ID, "create", map[string]interface{}{
+		"title": nt.Title,
+	})
+	maybeAutoAgent(ticketsDir)
+	return InboundResult{ID: nt.ID, Action: "created"}, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const alertmanagerHook = `
+inbound:
+  - name: alertmanager
+    project: ops
+    secret: $INBOUND_TOKEN
+    items: alerts
+    when: '{{eq .status "firing"}}'
+    title: '{{.labels.alertname}} on {{.labels.instance}}'
+    body: '{{.annotations.description}}'
+    tags: 'alert, {{.labels.severity}}'
+    priority: '{{if eq .labels.severity "critical"}}0{{else}}2{{end}}'
+    type: bug
+    fingerprint: '{{.fingerprint}}'
+  - name: generic
+    project: "#ops"
+    title: '{{.title}}'
+    body: '{{.body}}'
+`
+
+func TestParseInboundHooks(t *testing.T) {
+	hooks, err := parseInboundHooks(alertmanagerHook)
+	if err != nil {
+		t.Fatalf("parseInboundHooks: %v", err)
+	}
+	if len(hooks) != 2 {
+		t.Fatalf("got %d hooks, want 2", len(hooks))
+	}
+	am := hooks[0]
+	if am.Name != "alertmanager" || am.Project != "ops" || am.Items != "alerts" || am.Type != "bug" ||
+		am.When != `{{eq .status "firing"}}` || am.Secret != "$INBOUND_TOKEN" {
+		t.Errorf("alertmanager = %+v", am)
+	}
+	if hooks[1].Project != "ops" {
+		t.Errorf("generic project = %q, want '#' stripped", hooks[1].Project)
+	}
+
+	g, err := ParseGlobalConfig("server: http://ko\n" + alertmanagerHook)
+	if err != nil {
+		t.Fatalf("ParseGlobalConfig: %v", err)
+	}
+	if len(g.Inbound) != 2 || g.Server != "http://ko" {
+		t.Errorf("global = %+v", g)
+	}
+
+	if err := requireInboundSecrets(hooks); err == nil || !strings.Contains(err.Error(), `hook "generic": a secret is required when tokens: is configured`) {
+		t.Errorf("open hook: err = %v", err)
+	}
+
+	for _, bad := range []struct{ config, wantErr string }{
+		{"inbound:\n  - name: a\n    title: x\n", "project is required"},
+		{"inbound:\n  - name: a\n    project: p\n", "title is required"},
+		{"inbound:\n  - name: a\n    project: p\n    title: '{{.x'\n", "title:"},
+		{"inbound:\n  - name: a\n    project: p\n    title: x\n    labels: y\n", `unknown key "labels"`},
+	} {
+		if _, err := parseInboundHooks(bad.config); err == nil || !strings.Contains(err.Error(), bad.wantErr) {
+			t.Errorf("parseInboundHooks(%q) err = %v, want %q", bad.config, err, bad.wantErr)
+		}
+	}
+}
+
+func TestRenderInboundTicket(t *testing.T) {
+	hooks, _ := parseInboundHooks(alertmanagerHook)
+	var payload interface{}
+	json.Unmarshal([]byte(`{"alerts": [
+		{"status": "firing", "fingerprint": "abc123",
+		 "labels": {"alertname": "DiskFull", "instance": "db1", "severity": "critical"},
+		 "annotations": {"description": "/var is 98% full"}},
+		{"status": "resolved", "fingerprint": "def456", "labels": {"alertname": "CPU"}},
+		{"status": "firing", "fingerprint": "ghi789", "labels": {"alertname": "Latency", "instance": "web1"}}
+	]}`), &payload)
+
+	items, err := inboundItems(hooks[0], payload)
+	if err != nil || len(items) != 3 {
+		t.Fatalf("inboundItems = %v, %v", items, err)
+	}
+
+	got, ok, err := renderInboundTicket(hooks[0], items[0])
+	if err != nil || !ok {
+		t.Fatalf("render firing: ok=%v err=%v", ok, err)
+	}
+	if got.Title != "DiskFull on db1" || got.Body != "/var is 98% full" || got.Priority != 0 ||
+		got.Type != "bug" || got.ExternalRef != "alertmanager:abc123" || strings.Join(got.Tags, ",") != "alert,critical" {
+		t.Errorf("firing = %+v", got)
+	}
+
+	if _, ok, err := renderInboundTicket(hooks[0], items[1]); ok || err != nil {
+		t.Errorf("resolved alert: ok=%v err=%v, want skipped", ok, err)
+	}
+
+	got, _, err = renderInboundTicket(hooks[0], items[2])
+	if err != nil || got.Body != "" || got.Priority != 2 || strings.Join(got.Tags, ",") != "alert" {
+		t.Errorf("missing fields = %+v, %v", got, err)
+	}
+
+	// Without a fingerprint rule, the title is the dedupe key
+	got, _, _ = renderInboundTicket(hooks[1], map[string]interface{}{"title": "Build\nfailed"})
+	if got.Title != "Build failed" || got.ExternalRef != "generic:Build failed" {
+		t.Errorf("generic = %+v", got)
+	}
+	if _, _, err := renderInboundTicket(hooks[1], map[string]interface{}{}); err == nil {
+		t.Error("expected error for an empty title")
+	}
+}
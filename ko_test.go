@@ -1,12 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/rogpeppe/go-internal/testscript"
 )
@@ -73,7 +84,12 @@ func seedTicketsDir(ticketsDir, dbPath string) error {
 // After archive extraction, any .ko/tickets/*.md fixtures are seeded into
 // the DB so tests don't depend on filesystem sync.
 // A custom "seed" command is available in test scripts to import tickets
-// from a directory: `seed <ticketsDir>`.
+// from a directory: `seed <ticketsDir>`. `webhook <dir> [failFirst]` starts
+// a local receiver (see startWebhookReceiver) and `waitfile <path>` waits
+// for a file written in the background. `freeport <VAR>` and `http` let a
+// script talk to a `ko serve` started in the background (see httpRequest).
+// The "sandbox" condition reports whether namespace sandboxing works on
+// this host.
 func testParams(dir string) testscript.Params {
 	return testscript.Params{
 		Dir: dir,
@@ -95,6 +111,12 @@ func testParams(dir string) testscript.Params {
 			}
 			return nil
 		},
+		Condition: func(cond string) (bool, error) {
+			if cond == "sandbox" {
+				return sandboxSupported() == nil, nil
+			}
+			return false, fmt.Errorf("unknown condition %q", cond)
+		},
 		Cmds: map[string]func(ts *testscript.TestScript, neg bool, args []string){
 			// seed <ticketsDir> — imports .md ticket files into the DB
 			"seed": func(ts *testscript.TestScript, neg bool, args []string) {
@@ -110,10 +132,137 @@ func testParams(dir string) testscript.Params {
 					ts.Fatalf("seed: %v", err)
 				}
 			},
+			// webhook <dir> [failFirst] — starts a webhook receiver
+			"webhook": startWebhookReceiver,
+			// waitfile <path> — waits up to 10s for path to exist
+			"waitfile": func(ts *testscript.TestScript, neg bool, args []string) {
+				if neg || len(args) != 1 {
+					ts.Fatalf("usage: waitfile <path>")
+				}
+				path := ts.MkAbs(args[0])
+				for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); {
+					if _, err := os.Stat(path); err == nil {
+						return
+					}
+					time.Sleep(50 * time.Millisecond)
+				}
+				ts.Fatalf("waitfile: %s did not appear", args[0])
+			},
+			// freeport <VAR> — sets $VAR to a free localhost port
+			"freeport": func(ts *testscript.TestScript, neg bool, args []string) {
+				if neg || len(args) != 1 {
+					ts.Fatalf("usage: freeport <VAR>")
+				}
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					ts.Fatalf("freeport: %v", err)
+				}
+				ts.Setenv(args[0], strconv.Itoa(ln.Addr().(*net.TCPAddr).Port))
+				ln.Close()
+			},
+			// http [-H header]... [-d body] [-for dur] METHOD URL
+			"http": httpRequest,
 		},
 	}
 }
 
+// startWebhookReceiver serves a local HTTP endpoint for the script and sets
+// $WEBHOOK_URL to it. Each accepted request is written to <dir>/<n>.json
+// (body) and <dir>/<n>.headers (the X-Ko-* and Content-Type headers); the
+// first failFirst requests get a 500 and are not recorded.
+func startWebhookReceiver(ts *testscript.TestScript, neg bool, args []string) {
+	if neg || len(args) < 1 || len(args) > 2 {
+		ts.Fatalf("usage: webhook <dir> [failFirst]")
+	}
+	dir := ts.MkAbs(args[0])
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		ts.Fatalf("webhook: %v", err)
+	}
+	failFirst := 0
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			ts.Fatalf("webhook: failFirst: %v", err)
+		}
+		failFirst = n
+	}
+	var mu sync.Mutex
+	requests, accepted := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests <= failFirst {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		accepted++
+		body, _ := io.ReadAll(r.Body)
+		var headers strings.Builder
+		for _, name := range []string{"Content-Type", "X-Ko-Event", "X-Ko-Delivery", "X-Ko-Signature-256"} {
+			if v := r.Header.Get(name); v != "" {
+				fmt.Fprintf(&headers, "%s: %s\n", name, v)
+			}
+		}
+		base := filepath.Join(dir, strconv.Itoa(accepted))
+		os.WriteFile(base+".json", append(body, '\n'), 0644)
+		os.WriteFile(base+".headers", []byte(headers.String()), 0644)
+	}))
+	ts.Defer(srv.Close)
+	ts.Setenv("WEBHOOK_URL", srv.URL)
+}
+
+// httpRequest sends a request and writes "HTTP <status>" and the response
+// body to stdout. Refused connections are retried for up to 10s, so it can
+// follow a `ko serve &` that is still starting. With -for, the body is read
+// for that long and whatever arrived is kept, for streams that stay open.
+func httpRequest(ts *testscript.TestScript, neg bool, args []string) {
+	fs := flag.NewFlagSet("http", flag.ContinueOnError)
+	var headers []string
+	fs.Func("H", "request header", func(h string) error { headers = append(headers, h); return nil })
+	body := fs.String("d", "", "request body")
+	stream := fs.Duration("for", 0, "read the body for this long")
+	if err := fs.Parse(args); neg || err != nil || fs.NArg() != 2 {
+		ts.Fatalf("usage: http [-H header]... [-d body] [-for dur] METHOD URL")
+	}
+	method, url := fs.Arg(0), fs.Arg(1)
+
+	timeout := 10 * time.Second
+	if *stream > 0 {
+		timeout = *stream
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(*body))
+		if err != nil {
+			ts.Fatalf("http: %v", err)
+		}
+		for _, h := range headers {
+			name, value, _ := strings.Cut(h, ":")
+			req.Header.Set(name, strings.TrimSpace(value))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if errors.Is(err, syscall.ECONNREFUSED) && time.Now().Before(deadline) {
+			cancel()
+			time.Sleep(50 * time.Millisecond)
+			continue
+		}
+		if err != nil {
+			cancel()
+			ts.Fatalf("http: %v", err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		cancel()
+		if err != nil && *stream == 0 {
+			ts.Fatalf("http: %v", err)
+		}
+		fmt.Fprintf(ts.Stdout(), "HTTP %d\n%s", resp.StatusCode, data)
+		return
+	}
+}
+
 func TestTicketCreation(t *testing.T) {
 	testscript.Run(t, testParams("testdata/ticket_creation"))
 }
@@ -130,6 +279,10 @@ func TestTicketListing(t *testing.T) {
 	testscript.Run(t, testParams("testdata/ticket_listing"))
 }
 
+func TestTicketFields(t *testing.T) {
+	testscript.Run(t, testParams("testdata/ticket_fields"))
+}
+
 func TestTicketDeps(t *testing.T) {
 	testscript.Run(t, testParams("testdata/ticket_deps"))
 }
@@ -177,3 +330,27 @@ func TestTicketTriage(t *testing.T) {
 func TestAgentTriage(t *testing.T) {
 	testscript.Run(t, testParams("testdata/agent_triage"))
 }
+
+func TestNotifications(t *testing.T) {
+	testscript.Run(t, testParams("testdata/notifications"))
+}
+
+func TestYAMLConfig(t *testing.T) {
+	testscript.Run(t, testParams("testdata/yaml_config"))
+}
+
+func TestTicketTemplates(t *testing.T) {
+	testscript.Run(t, testParams("testdata/ticket_templates"))
+}
+
+func TestTicketEdit(t *testing.T) {
+	testscript.Run(t, testParams("testdata/ticket_edit"))
+}
+
+func TestTicketMove(t *testing.T) {
+	testscript.Run(t, testParams("testdata/ticket_move"))
+}
+
+func TestTicketArchive(t *testing.T) {
+	testscript.Run(t, testParams("testdata/ticket_archive"))
+}
@@ -20,6 +20,12 @@ func run(args []string) int {
 	cmd := args[0]
 	rest := args[1:]
 
+	// Sandbox helper: re-executed by ko itself inside fresh namespaces, so it
+	// must run before any store, shim or remote handling.
+	if cmd == sandboxExecCmd {
+		return runSandboxExec(rest)
+	}
+
 	// QQL shim (opt-in, inert by default): route the familiar ko surface to
 	// Questbook's QQL API instead of the local store. Nothing changes until
 	// KO_QQL is set.
@@ -41,8 +47,17 @@ func run(args []string) int {
 
 	// If a remote server is configured, proxy eligible commands over HTTP.
 	if isRemoteCommand(cmd) {
-		if cfg, err := LoadGlobalConfig(); err == nil && cfg.Server != "" {
-			return remoteExec(cfg.Server, args)
+		if server := os.Getenv(sandboxServerEnvVar); server != "" {
+			return sandboxExec(server, args)
+		}
+		cfg, err := LoadGlobalConfig()
+		if err != nil {
+			// Running locally instead would send writes to the wrong store.
+			fmt.Fprintf(os.Stderr, "ko: %v\n", err)
+			return 1
+		}
+		if cfg.Server != "" {
+			return remoteExec(cfg.Server, cfg.Token, args)
 		}
 	}
 
@@ -77,6 +92,14 @@ func run(args []string) int {
 		return cmdUndep(rest)
 	case "update":
 		return cmdUpdate(rest)
+	case "edit":
+		return cmdEdit(rest)
+	case "mv":
+		return cmdMv(rest)
+	case "rm":
+		return cmdRm(rest)
+	case "archive":
+		return cmdArchive(rest)
 	case "note":
 		return cmdAddNote(rest)
 	case "bump":
@@ -93,8 +116,18 @@ func run(args []string) int {
 		return cmdSearch(rest)
 	case "history":
 		return cmdHistory(rest)
+	case "logs":
+		return cmdLogs(rest)
+	case "gc":
+		return cmdGC(rest)
 	case "export":
 		return cmdExport(rest)
+	case "template":
+		return cmdTemplate(rest)
+	case "notify":
+		return cmdNotify(rest)
+	case "remote":
+		return cmdRemote(rest)
 	case "help", "--help", "-h":
 		return cmdHelp(rest)
 	case "version", "--version", "-v":
@@ -152,8 +185,12 @@ Usage: ko <command> [arguments]
 
 Commands:
   add [title]        Create a new ticket (routes by #tag if registered)
+  add --template <name> [title]
+                     Create a ticket from .ko/templates/<name>.md (or the global templates dir)
+  template ls [--project=tag] [--json]
+                     List the templates ko add --template can use
   show <id>          Show ticket details
-  ls                 List open tickets
+  ls [--field k=v]   List open tickets (filter by custom fields)
   ready              Show ready queue (open + deps resolved)
   triage [<id> <instructions>]   List tickets with a triage value, or set triage on a ticket
   block <id> [reason]           Block ticket with optional reason
@@ -164,15 +201,23 @@ Commands:
   close <id>         Set status to closed
   open <id>          Set status to open
   snooze <id> <date> Snooze ticket until date (ISO 8601, e.g. 2026-05-01)
-  serve [--port PORT]    Start HTTP daemon (default :9876)
+  serve [--port PORT] [--listen ADDR] [--tls-cert F --tls-key F]
+                     Start HTTP daemon (default :19876; ADDR may be unix:/path)
 
   update <id> [--title title] [-d description] [-t type] [-p priority] [-a assignee]
               [--parent id] [--external-ref ref]
               [--design notes] [--acceptance criteria]
-              [--tags tag1,tag2]
+              [--tags tag1,tag2] [--scope glob1,glob2] [--field name=value]
               [--questions '<json>'] [--answers '<json>']
               [--status status]
                     Update ticket fields (tags replace, --answers auto-unblocks)
+  edit <id>          Edit a ticket in $EDITOR (frontmatter and body)
+  edit --batch <filter>
+                     Edit matching tickets in one buffer (e.g. 'status=open tag=ui')
+  mv <id> '#project' Move a ticket and its children to another project
+  rm <id> [--rewire] Delete a ticket (--rewire hands its deps to its dependents)
+  archive [--closed-before date]
+                     Archive closed tickets, hiding them from ls/ready/stats
 
   dep <id> <dep>     Add dependency
   undep <id> <dep>   Remove dependency
@@ -195,12 +240,25 @@ Commands:
 
   stats [--project=tag] [--json]
                      Show aggregate statistics (cross-project by default)
-  search <query> [--project=tag] [--status=X] [--type=X] [--tag=X] [--limit=N] [--json]
+  search <query> [--project=tag] [--status=X] [--type=X] [--tag=X] [--field=k=v] [--limit=N] [--json]
                      Search tickets by title and body (cross-project by default)
   history [<id>] [--project=tag] [--limit=N] [--json]
                      Show build/event history (per-ticket if ID given, global otherwise)
+  logs <id> [--build N] [--node X]
+                     Show captured stdout/stderr of each node attempt (latest build by default)
+  gc [--project=tag] [--older-than 30d] [--closed-only] [--orphans] [--dry-run]
+                     Prune old workspaces, build outputs, and orphaned worker branches
   export [--out FILE] [--project=tag] [--no-history]
                      Dump all tickets across all projects as JSON (Questbook import contract)
+  notify list [--status=X] [--limit=N] [--json]
+                     Show outbound webhook deliveries (newest first)
+  notify flush [--force]
+                     Deliver due webhook notifications now (--force skips backoff)
+  remote status [--all] [--json]
+                     Show the server and commands queued while it was unreachable
+  remote sync        Replay queued commands now
+  remote retry <n>   Replay a held command, skipping the conflict check
+  remote drop <n>    Remove a command from the queue
 
   help               Show this help
   version            Show version`)
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// ko mv moves a ticket and its descendants to another project. IDs encode
+// the project prefix and the hierarchy, so every moved ticket gets a new
+// ID; the functions here work out the new IDs and the references to
+// rewrite, cmd_mv.go does the I/O.
+
+// idHash returns the last segment of a ticket ID without its prefix:
+// "a1b2" for ko-a1b2, "c3d4" for ko-a1b2.c3d4.
+func idHash(id string) string {
+	if dot := strings.LastIndex(id, "."); dot >= 0 {
+		return id[dot+1:]
+	}
+	if dash := strings.Index(id, "-"); dash >= 0 {
+		return id[dash+1:]
+	}
+	return id
+}
+
+// planMove returns the new ID of root and each of its descendants under
+// prefix, and copies of them with IDs, parents and deps rewritten, parents
+// before children. The root keeps its hash unless taken reports the new
+// ID or a descendant's as in use, in which case it gets a fresh one. A
+// root that was a child becomes a top-level ticket of the new project.
+func planMove(all []*Ticket, root *Ticket, prefix string, taken func(id string) bool) (map[string]string, []*Ticket) {
+	children := map[string][]*Ticket{}
+	for _, t := range all {
+		if t.Parent != "" {
+			children[t.Parent] = append(children[t.Parent], t)
+		}
+	}
+	order := []*Ticket{root}
+	for i := 0; i < len(order); i++ {
+		kids := children[order[i].ID]
+		sort.Slice(kids, func(a, b int) bool { return kids[a].ID < kids[b].ID })
+		order = append(order, kids...)
+	}
+
+	var ids map[string]string
+	for hash := idHash(root.ID); ; hash = GenerateHash() {
+		ids = map[string]string{root.ID: prefix + "-" + hash}
+		free := !taken(ids[root.ID])
+		for _, t := range order[1:] {
+			ids[t.ID] = ids[t.Parent] + "." + idHash(t.ID)
+			free = free && !taken(ids[t.ID])
+		}
+		if free {
+			break
+		}
+	}
+
+	moved := make([]*Ticket, len(order))
+	for i, t := range order {
+		c := *t
+		c.ID = ids[t.ID]
+		c.Parent = ids[t.Parent]
+		c.Deps = append([]string{}, t.Deps...)
+		rewriteRefs(&c, ids)
+		moved[i] = &c
+	}
+	return ids, moved
+}
+
+// rewriteRefs points the deps and parent of t at the new IDs of moved
+// tickets and returns what changed.
+func rewriteRefs(t *Ticket, ids map[string]string) []fieldChange {
+	var changes []fieldChange
+	deps := make([]string, len(t.Deps))
+	for i, dep := range t.Deps {
+		deps[i] = dep
+		if id, ok := ids[dep]; ok {
+			deps[i] = id
+		}
+	}
+	if strings.Join(deps, " ") != strings.Join(t.Deps, " ") {
+		changes = append(changes, fieldChange{"deps", t.Deps, deps})
+		t.Deps = deps
+	}
+	if id, ok := ids[t.Parent]; ok && t.Parent != "" {
+		changes = append(changes, fieldChange{"parent", t.Parent, id})
+		t.Parent = id
+	}
+	return changes
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIdHash(t *testing.T) {
+	for id, want := range map[string]string{
+		"ko-a1b2":      "a1b2",
+		"ko-a1b2.c3d4": "c3d4",
+		"a1b2":         "a1b2",
+	} {
+		if got := idHash(id); got != want {
+			t.Errorf("idHash(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestPlanMove(t *testing.T) {
+	root := &Ticket{ID: "ko-a001", Parent: "ko-0000"}
+	child := &Ticket{ID: "ko-a001.b001", Parent: "ko-a001", Deps: []string{"ko-a002"}}
+	grandchild := &Ticket{ID: "ko-a001.b001.c001", Parent: "ko-a001.b001", Deps: []string{"ko-a001.b002"}}
+	sibling := &Ticket{ID: "ko-a001.b002", Parent: "ko-a001"}
+	other := &Ticket{ID: "ko-a002"}
+	all := []*Ticket{other, grandchild, sibling, child, root}
+
+	ids, moved := planMove(all, root, "oth", func(string) bool { return false })
+	var got []string
+	for _, m := range moved {
+		got = append(got, m.ID+"<"+m.Parent+">"+strings.Join(m.Deps, ","))
+	}
+	want := "oth-a001<> oth-a001.b001<oth-a001>ko-a002 oth-a001.b002<oth-a001> oth-a001.b001.c001<oth-a001.b001>oth-a001.b002"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("moved =\n%s\nwant\n%s", s, want)
+	}
+	if len(ids) != 4 || ids["ko-a001.b001.c001"] != "oth-a001.b001.c001" {
+		t.Errorf("ids = %v", ids)
+	}
+	if child.ID != "ko-a001.b001" || child.Deps[0] != "ko-a002" {
+		t.Errorf("originals changed: %+v", child)
+	}
+
+	// A taken ID gives the whole subtree a fresh root hash
+	ids, _ = planMove(all, root, "oth", func(id string) bool { return id == "oth-a001.b002" })
+	if r := ids["ko-a001"]; r == "oth-a001" || !strings.HasPrefix(ids["ko-a001.b002"], r+".") {
+		t.Errorf("ids = %v", ids)
+	}
+}
+
+func TestRewriteRefs(t *testing.T) {
+	ids := map[string]string{"ko-a001": "oth-a001"}
+	tk := &Ticket{ID: "ko-a002", Parent: "ko-a001", Deps: []string{"ko-a003", "ko-a001"}}
+	changes := rewriteRefs(tk, ids)
+	if len(changes) != 2 || changes[0].Field != "deps" || changes[1].Field != "parent" {
+		t.Fatalf("changes = %+v", changes)
+	}
+	if tk.Parent != "oth-a001" || strings.Join(tk.Deps, ",") != "ko-a003,oth-a001" {
+		t.Errorf("ticket = %+v", tk)
+	}
+	if c := rewriteRefs(&Ticket{ID: "ko-a004", Deps: []string{"ko-a003"}}, ids); len(c) != 0 {
+		t.Errorf("unrelated ticket changed: %+v", c)
+	}
+}
@@ -64,4 +64,5 @@ func EmitMutationEvent(ticketsDir, ticketID, event string, data map[string]inter
 	f.Write(line)
 
 	shadowWriteMutation(e)
+	notifyMutation(ticketsDir, e)
 }
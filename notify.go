@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NotificationTarget is one outbound webhook from a notifications: section
+// of the global or project config.
+type NotificationTarget struct {
+	Name     string   // unique within its config file
+	URL      string   // http(s) endpoint; may reference $VAR or ${secrets.NAME}
+	Events   []string // event filters; empty matches every event
+	Projects []string // project tags; empty matches every project
+	Secret   string   // HMAC key; may reference $VAR or ${secrets.NAME}
+	Template string   // "json" (default), "slack", or a template file path
+}
+
+// notificationEvents are the event names a filter may use. A filter may
+// also be "<event>:<value>" for status (the new status) and build_complete
+// (the outcome). "block" is shorthand for status:blocked.
+var notificationEvents = map[string]bool{
+	"*": true, "create": true, "update": true, "status": true, "note": true,
+	"dep": true, "undep": true, "bump": true, "build_complete": true, "block": true,
+}
+
+// parseNotifications parses the top-level notifications: section of a
+// config file. A missing section yields no targets.
+func parseNotifications(content string) ([]NotificationTarget, error) {
+	n, err := yamlSection(content, "notifications")
+	if err != nil {
+		return nil, err
+	}
+	return decodeNotifications(&yamlDecoder{}, n)
+}
+
+// decodeNotifications reads the list of a notifications: section.
+func decodeNotifications(d *yamlDecoder, n *yamlNode) ([]NotificationTarget, error) {
+	items, err := yamlItems(n, "notifications")
+	if err != nil {
+		return nil, err
+	}
+	var targets []NotificationTarget
+	for _, item := range items {
+		entries, err := d.entries(item, "notifications")
+		if err != nil {
+			return nil, err
+		}
+		var t NotificationTarget
+		for _, e := range entries {
+			what := "notifications." + e.Key.Value
+			switch e.Key.Value {
+			case "name":
+				t.Name, err = yamlString(e.Value, what)
+			case "url":
+				t.URL, err = yamlString(e.Value, what)
+			case "secret":
+				t.Secret, err = yamlString(e.Value, what)
+			case "template":
+				t.Template, err = yamlString(e.Value, what)
+			case "events":
+				t.Events, err = yamlStrings(e.Value, what)
+			case "projects":
+				t.Projects, err = yamlStrings(e.Value, what)
+			default:
+				return nil, yamlErrorf(e.Key, "notifications: unknown key %q", e.Key.Value)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets, validateNotifications(targets)
+}
+
+// validateNotifications checks names, URLs, event filters and templates.
+func validateNotifications(targets []NotificationTarget) error {
+	seen := map[string]bool{}
+	for _, t := range targets {
+		if t.Name == "" {
+			return fmt.Errorf("notifications: every target needs a name")
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("notifications: duplicate target %q", t.Name)
+		}
+		seen[t.Name] = true
+		// A URL built from variables is checked once expanded, at send time.
+		if !strings.Contains(t.URL, "$") {
+			if err := checkNotificationURL(t); err != nil {
+				return err
+			}
+		}
+		for _, e := range t.Events {
+			name, _, _ := strings.Cut(e, ":")
+			if !notificationEvents[name] {
+				return fmt.Errorf("notifications: target %q: unknown event %q", t.Name, e)
+			}
+		}
+	}
+	return nil
+}
+
+// checkNotificationURL requires an absolute http(s) URL.
+func checkNotificationURL(t NotificationTarget) error {
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("notifications: target %q: url must be http(s)", t.Name)
+	}
+	return nil
+}
+
+// NotificationEvent is the data a webhook body is rendered from.
+type NotificationEvent struct {
+	Event     string                 `json:"event"`
+	Project   string                 `json:"project"`
+	Ticket    string                 `json:"ticket,omitempty"`
+	Title     string                 `json:"title,omitempty"`
+	Status    string                 `json:"status,omitempty"`
+	Timestamp string                 `json:"ts"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// value returns the event's filterable value: the new status for status
+// events, the outcome for build_complete.
+func (e NotificationEvent) value() string {
+	switch e.Event {
+	case "status":
+		s, _ := e.Data["to"].(string)
+		return s
+	case "build_complete":
+		s, _ := e.Data["outcome"].(string)
+		return s
+	}
+	return ""
+}
+
+// Text is a one-line human summary, used by the slack template.
+func (e NotificationEvent) Text() string {
+	var what string
+	switch e.Event {
+	case "status":
+		what = fmt.Sprintf("%v → %s", e.Data["from"], e.value())
+	case "build_complete":
+		what = "build " + e.value()
+		if r, _ := e.Data["reason"].(string); r != "" {
+			what += ": " + r
+		}
+	case "create":
+		what = "created"
+	default:
+		what = e.Event
+	}
+	s := fmt.Sprintf("[%s] %s", e.Project, e.Ticket)
+	if e.Title != "" {
+		s += " " + e.Title
+	}
+	return s + " — " + what
+}
+
+// matches reports whether target t wants event e from project tag.
+func (t NotificationTarget) matches(e NotificationEvent) bool {
+	if len(t.Projects) > 0 && !contains(t.Projects, e.Project) {
+		return false
+	}
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, f := range t.Events {
+		if f == "block" {
+			f = "status:blocked"
+		}
+		name, val, hasVal := strings.Cut(f, ":")
+		if name == "*" || (name == e.Event && (!hasVal || val == e.value())) {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinNotificationTemplates are the bodies selectable by name.
+var builtinNotificationTemplates = map[string]string{
+	"json":  `{{json .}}`,
+	"slack": `{"text": {{json .Text}}}`,
+}
+
+// renderNotification renders the body for target t. Template files are
+// resolved relative to the directory of the config that declared t. The
+// result must be valid JSON.
+func renderNotification(t NotificationTarget, configDir string, e NotificationEvent) (string, error) {
+	name := t.Template
+	if name == "" {
+		name = "json"
+	}
+	text, ok := builtinNotificationTemplates[name]
+	if !ok {
+		path, err := expandTilde(name)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(configDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("template: %v", err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, e); err != nil {
+		return "", fmt.Errorf("template: %v", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return "", fmt.Errorf("template %s did not render valid JSON", name)
+	}
+	return buf.String(), nil
+}
+
+// signNotification returns the X-Ko-Signature-256 header value for body.
+func signNotification(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notificationSource is a config file that declares targets.
+type notificationSource struct {
+	Path    string
+	Targets []NotificationTarget
+}
+
+// notificationSources returns the global config and, when ticketsDir is
+// set, the project config. Unreadable or invalid files are skipped:
+// notifications are best-effort and never fail the command.
+func notificationSources(ticketsDir string) []notificationSource {
+	var sources []notificationSource
+	add := func(path string) {
+		if path == "" {
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		targets, err := parseNotifications(string(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ko: %s: %v\n", path, err)
+			return
+		}
+		if len(targets) > 0 {
+			sources = append(sources, notificationSource{Path: path, Targets: targets})
+		}
+	}
+	add(GlobalConfigPath())
+	if ticketsDir != "" {
+		add(filepath.Join(ProjectRoot(ticketsDir), ".ko", "config.yaml"))
+	}
+	return sources
+}
+
+// notificationProjectTag returns the shadow DB tag of a project, falling
+// back to the directory name of its root.
+func notificationProjectTag(db *DB, ticketsDir string) string {
+	abs, err := filepath.Abs(ticketsDir)
+	if err != nil {
+		abs = ticketsDir
+	}
+	if tag, _ := db.ResolveProjectTag(abs); tag != "" {
+		return tag
+	}
+	return projectTagFromPath(ProjectRoot(abs))
+}
+
+// notifyMutation queues a webhook delivery for every target that wants the
+// event and hands the queue to a deliverer, so a slow target never holds
+// up the mutation. Best-effort.
+func notifyMutation(ticketsDir string, m MutationEvent) {
+	sources := notificationSources(ticketsDir)
+	if len(sources) == 0 {
+		return
+	}
+	db := getShadowDB()
+	if db == nil {
+		return
+	}
+
+	e := NotificationEvent{
+		Event:     m.Event,
+		Project:   notificationProjectTag(db, ticketsDir),
+		Ticket:    m.Ticket,
+		Timestamp: m.Timestamp,
+		Data:      m.Data,
+	}
+	if t, err := LoadTicket(ticketsDir, m.Ticket); err == nil {
+		e.Title, e.Status = t.Title, t.Status
+	}
+
+	queued := 0
+	for _, src := range sources {
+		for _, target := range src.Targets {
+			if !target.matches(e) {
+				continue
+			}
+			body, err := renderNotification(target, filepath.Dir(src.Path), e)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ko: notification %s: %v\n", target.Name, err)
+				continue
+			}
+			if err := db.EnqueueNotification(target.Name, src.Path, e.Event, e.Ticket, body, time.Now()); err != nil {
+				fmt.Fprintf(os.Stderr, "ko: notification %s: %v\n", target.Name, err)
+				continue
+			}
+			queued++
+		}
+	}
+	if queued > 0 {
+		kickNotifications()
+	}
+}
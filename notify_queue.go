@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxNotificationAttempts is how many times a delivery is tried before
+	// it is marked failed.
+	maxNotificationAttempts = 8
+	// notificationTimeout bounds a single webhook POST.
+	notificationTimeout = 5 * time.Second
+	// notificationClaim is how long a sender holds a delivery before another
+	// flush may retry it.
+	notificationClaim = time.Minute
+	// notificationFlushInterval is how often ko serve retries deliveries.
+	notificationFlushInterval = 30 * time.Second
+)
+
+// NotificationDelivery is one row of the outbound webhook queue.
+type NotificationDelivery struct {
+	ID            int64  `json:"id"`
+	Target        string `json:"target"`
+	ConfigPath    string `json:"config_path"`
+	Event         string `json:"event"`
+	Ticket        string `json:"ticket,omitempty"`
+	Body          string `json:"body"`
+	Status        string `json:"status"` // pending | delivered | failed
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt string `json:"next_attempt_at"`
+	LastError     string `json:"last_error,omitempty"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// notificationBackoff is the delay before retry n (1-based): 30s doubling
+// per attempt, capped at an hour.
+func notificationBackoff(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
+}
+
+// EnqueueNotification adds a pending delivery, due immediately. The URL and
+// secret are resolved from configPath at send time so they never reach the
+// database.
+func (d *DB) EnqueueNotification(target, configPath, event, ticketID, body string, now time.Time) error {
+	ts := now.UTC().Format(time.RFC3339Nano)
+	_, err := d.db.Exec(`INSERT INTO notification_deliveries
+		(target, config_path, event_type, ticket_id, body, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		target, configPath, event, nullStr(ticketID), body, ts, ts)
+	return err
+}
+
+// DueNotifications returns pending deliveries whose next attempt is due,
+// or every pending delivery when force is set.
+func (d *DB) DueNotifications(now time.Time, force bool) ([]NotificationDelivery, error) {
+	query := "SELECT " + notificationColumns + " FROM notification_deliveries WHERE status = 'pending'"
+	var args []interface{}
+	if !force {
+		query += " AND next_attempt_at <= ?"
+		args = append(args, now.UTC().Format(time.RFC3339Nano))
+	}
+	return d.queryNotifications(query+" ORDER BY id", args...)
+}
+
+// ListNotifications returns the most recent deliveries, newest first,
+// optionally filtered by status.
+func (d *DB) ListNotifications(status string, limit int) ([]NotificationDelivery, error) {
+	query := "SELECT " + notificationColumns + " FROM notification_deliveries"
+	var args []interface{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+	return d.queryNotifications(query, args...)
+}
+
+const notificationColumns = `id, target, config_path, event_type, COALESCE(ticket_id, ''), body,
+	status, attempts, next_attempt_at, COALESCE(last_error, ''), created_at`
+
+func (d *DB) queryNotifications(query string, args ...interface{}) ([]NotificationDelivery, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []NotificationDelivery
+	for rows.Next() {
+		var n NotificationDelivery
+		if err := rows.Scan(&n.ID, &n.Target, &n.ConfigPath, &n.Event, &n.Ticket, &n.Body,
+			&n.Status, &n.Attempts, &n.NextAttemptAt, &n.LastError, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// claimNotification pushes a pending delivery's next attempt past the claim
+// window. It reports false when another process claimed it first.
+func (d *DB) claimNotification(n NotificationDelivery, now time.Time) (bool, error) {
+	res, err := d.db.Exec(`UPDATE notification_deliveries SET next_attempt_at = ?
+		WHERE id = ? AND status = 'pending' AND next_attempt_at = ?`,
+		now.Add(notificationClaim).UTC().Format(time.RFC3339Nano), n.ID, n.NextAttemptAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// recordNotificationAttempt stores the result of one delivery attempt. A
+// failed attempt is rescheduled with backoff until maxNotificationAttempts,
+// or immediately marked failed when permanent is set.
+func (d *DB) recordNotificationAttempt(n NotificationDelivery, sendErr error, permanent bool, now time.Time) error {
+	attempts := n.Attempts + 1
+	ts := now.UTC().Format(time.RFC3339Nano)
+	if sendErr == nil {
+		_, err := d.db.Exec(`UPDATE notification_deliveries
+			SET status = 'delivered', attempts = ?, last_error = NULL, delivered_at = ? WHERE id = ?`,
+			attempts, ts, n.ID)
+		return err
+	}
+	status := "pending"
+	if permanent || attempts >= maxNotificationAttempts {
+		status = "failed"
+	}
+	next := now.Add(notificationBackoff(attempts)).UTC().Format(time.RFC3339Nano)
+	_, err := d.db.Exec(`UPDATE notification_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?`,
+		status, attempts, next, sendErr.Error(), n.ID)
+	return err
+}
+
+// flushNotifications sends every due delivery and returns how many were
+// delivered and how many attempts failed. force ignores the backoff.
+func flushNotifications(db *DB, now time.Time, force bool) (delivered, failed int) {
+	due, err := db.DueNotifications(now, force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko: notifications: %v\n", err)
+		return 0, 0
+	}
+	client := &http.Client{Timeout: notificationTimeout}
+	for _, n := range due {
+		if ok, err := db.claimNotification(n, now); err != nil || !ok {
+			continue
+		}
+		permanent, sendErr := sendNotification(client, n)
+		if err := db.recordNotificationAttempt(n, sendErr, permanent, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "ko: notifications: %v\n", err)
+		}
+		if sendErr != nil {
+			failed++
+		} else {
+			delivered++
+		}
+	}
+	return delivered, failed
+}
+
+// notifyDeferEnvVar, when set, leaves queued deliveries to ko serve or an
+// explicit ko notify flush instead of a background flush per mutation.
+const notifyDeferEnvVar = "KO_NOTIFY_DEFER"
+
+// notificationKick wakes the flusher of a ko serve running in this
+// process; nil when there is none.
+var notificationKick chan struct{}
+
+// kickNotifications gets newly queued deliveries sent without waiting for
+// them: by this process's ko serve flusher if it has one, else by a
+// detached `ko notify flush`.
+func kickNotifications() {
+	if v := os.Getenv(notifyDeferEnvVar); v != "" && v != "0" {
+		return
+	}
+	if kick := notificationKick; kick != nil {
+		select {
+		case kick <- struct{}{}:
+		default:
+		}
+		return
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return
+	}
+	cmd := exec.Command(self, "notify", "flush")
+	if cmd.Start() == nil {
+		// Reaps the flush if this process outlives it; otherwise the
+		// flush carries on on its own.
+		go cmd.Wait()
+	}
+}
+
+// startNotificationFlusher sends due deliveries every interval, and as
+// soon as kickNotifications reports new ones, until the returned stop
+// function is called.
+func startNotificationFlusher(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	kick := make(chan struct{}, 1)
+	notificationKick = kick
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if db := getShadowDB(); db != nil {
+					flushNotifications(db, now, false)
+				}
+			case <-kick:
+				if db := getShadowDB(); db != nil {
+					flushNotifications(db, time.Now(), false)
+				}
+			}
+		}
+	}()
+	return func() {
+		notificationKick = nil
+		close(done)
+	}
+}
+
+// sendNotification POSTs one delivery. permanent reports errors a retry
+// cannot fix, such as the target having been removed from the config.
+func sendNotification(client *http.Client, n NotificationDelivery) (permanent bool, err error) {
+	target, err := resolveNotificationTarget(n.ConfigPath, n.Target)
+	if err != nil {
+		return true, err
+	}
+	req, err := http.NewRequest(http.MethodPost, target.URL, strings.NewReader(n.Body))
+	if err != nil {
+		return true, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "knockout")
+	req.Header.Set("X-Ko-Event", n.Event)
+	req.Header.Set("X-Ko-Delivery", strconv.FormatInt(n.ID, 10))
+	if target.Secret != "" {
+		req.Header.Set("X-Ko-Signature-256", signNotification(target.Secret, n.Body))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, fmt.Errorf("%s returned %s", target.Name, resp.Status)
+	}
+	return false, nil
+}
+
+// resolveNotificationTarget re-reads a target from its config and expands
+// its URL and secret. ${secrets.NAME} resolves against secrets.env next to the
+// config file.
+func resolveNotificationTarget(configPath, name string) (NotificationTarget, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return NotificationTarget{}, err
+	}
+	targets, err := parseNotifications(string(data))
+	if err != nil {
+		return NotificationTarget{}, err
+	}
+	for _, t := range targets {
+		if t.Name != name {
+			continue
+		}
+		secrets, err := loadEnvFile(filepath.Join(filepath.Dir(configPath), secretsFile))
+		if err != nil && !os.IsNotExist(err) {
+			return t, fmt.Errorf("%s: %v", secretsFile, err)
+		}
+		if t.URL, err = interpolateEnv(t.URL, secrets); err != nil {
+			return t, err
+		}
+		if t.Secret, err = interpolateEnv(t.Secret, secrets); err != nil {
+			return t, err
+		}
+		return t, checkNotificationURL(t)
+	}
+	return NotificationTarget{}, fmt.Errorf("target %q no longer in %s", name, configPath)
+}
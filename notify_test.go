@@ -0,0 +1,280 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseNotifications(t *testing.T) {
+	config := `
+project:
+  prefix: ko
+notifications:
+  - name: slack
+    url: https://hooks.example.com/T1  # team channel
+    events: [block, "status:resolved"]
+    projects: [api]
+    secret: ${secrets.SLACK_SIGNING}
+    template: slack
+  - name: audit
+    url: http://localhost:9000/ko
+    events:
+      - build_complete:fail
+      - create
+summarizer: none
+`
+	targets, err := parseNotifications(config)
+	if err != nil {
+		t.Fatalf("parseNotifications: %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("got %d targets, want 2: %+v", len(targets), targets)
+	}
+	slack := targets[0]
+	if slack.Name != "slack" || slack.URL != "https://hooks.example.com/T1" || slack.Template != "slack" ||
+		slack.Secret != "${secrets.SLACK_SIGNING}" {
+		t.Errorf("slack = %+v", slack)
+	}
+	if strings.Join(slack.Events, ",") != "block,status:resolved" || strings.Join(slack.Projects, ",") != "api" {
+		t.Errorf("slack filters = %v / %v", slack.Events, slack.Projects)
+	}
+	if strings.Join(targets[1].Events, ",") != "build_complete:fail,create" {
+		t.Errorf("audit events = %v", targets[1].Events)
+	}
+
+	c, err := ParseConfig(config)
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	if len(c.Notifications) != 2 || c.Summarizer != "none" || c.Project.Prefix != "ko" {
+		t.Errorf("ParseConfig = %+v", c)
+	}
+	g, err := ParseGlobalConfig(config)
+	if err != nil {
+		t.Fatalf("ParseGlobalConfig: %v", err)
+	}
+	if len(g.Notifications) != 2 || g.Summarizer != "none" {
+		t.Errorf("ParseGlobalConfig = %+v", g)
+	}
+}
+
+func TestParseNotificationsErrors(t *testing.T) {
+	tests := []struct {
+		name, entry, wantErr string
+	}{
+		{"missing name", "  - url: https://x.example\n", "needs a name"},
+		{"bad url", "  - name: a\n    url: ftp://x.example\n", "url must be http(s)"},
+		{"unknown event", "  - name: a\n    url: https://x.example\n    events: [deploy]\n", `unknown event "deploy"`},
+		{"unknown key", "  - name: a\n    url: https://x.example\n    method: PUT\n", `unknown key "method"`},
+		{"duplicate", "  - name: a\n    url: https://x.example\n  - name: a\n    url: https://y.example\n", "duplicate target"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseNotifications("notifications:\n" + tt.entry)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := parseNotifications("notifications:\n  - name: a\n    url: ${HOOK_URL}\n"); err != nil {
+		t.Errorf("variable url rejected at parse time: %v", err)
+	}
+}
+
+func TestNotificationTargetMatches(t *testing.T) {
+	blocked := NotificationEvent{Event: "status", Project: "api", Data: map[string]interface{}{"from": "open", "to": "blocked"}}
+	resolved := NotificationEvent{Event: "status", Project: "api", Data: map[string]interface{}{"from": "in_progress", "to": "resolved"}}
+	failed := NotificationEvent{Event: "build_complete", Project: "web", Data: map[string]interface{}{"outcome": "fail"}}
+	note := NotificationEvent{Event: "note", Project: "web"}
+
+	tests := []struct {
+		target NotificationTarget
+		event  NotificationEvent
+		want   bool
+	}{
+		{NotificationTarget{}, note, true},
+		{NotificationTarget{Events: []string{"*"}}, failed, true},
+		{NotificationTarget{Events: []string{"block"}}, blocked, true},
+		{NotificationTarget{Events: []string{"block"}}, resolved, false},
+		{NotificationTarget{Events: []string{"status"}}, resolved, true},
+		{NotificationTarget{Events: []string{"status:resolved"}}, resolved, true},
+		{NotificationTarget{Events: []string{"status:resolved"}}, blocked, false},
+		{NotificationTarget{Events: []string{"build_complete"}}, failed, true},
+		{NotificationTarget{Events: []string{"build_complete:succeed"}}, failed, false},
+		{NotificationTarget{Projects: []string{"api"}}, blocked, true},
+		{NotificationTarget{Projects: []string{"api"}}, note, false},
+		{NotificationTarget{Events: []string{"note"}, Projects: []string{"api", "web"}}, note, true},
+	}
+	for i, tt := range tests {
+		if got := tt.target.matches(tt.event); got != tt.want {
+			t.Errorf("case %d: %+v matches %s %v = %v, want %v", i, tt.target, tt.event.Event, tt.event.Data, got, tt.want)
+		}
+	}
+}
+
+func TestRenderNotification(t *testing.T) {
+	e := NotificationEvent{
+		Event: "build_complete", Project: "api", Ticket: "ko-a001", Title: `Fix "quotes"`,
+		Status: "blocked", Timestamp: "2026-01-01T00:00:00Z",
+		Data: map[string]interface{}{"outcome": "fail", "reason": "tests failed"},
+	}
+
+	body, err := renderNotification(NotificationTarget{Name: "a"}, "", e)
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	want := `{"event":"build_complete","project":"api","ticket":"ko-a001","title":"Fix \"quotes\"","status":"blocked","ts":"2026-01-01T00:00:00Z","data":{"outcome":"fail","reason":"tests failed"}}`
+	if body != want {
+		t.Errorf("json body = %s\nwant %s", body, want)
+	}
+
+	body, err = renderNotification(NotificationTarget{Name: "a", Template: "slack"}, "", e)
+	if err != nil {
+		t.Fatalf("slack: %v", err)
+	}
+	if body != `{"text": "[api] ko-a001 Fix \"quotes\" — build fail: tests failed"}` {
+		t.Errorf("slack body = %s", body)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "custom.tmpl"), []byte(`{"id": {{json .Ticket}}, "outcome": {{json (index .Data "outcome")}}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte(`{"id": {{.Ticket}}}`), 0644)
+	body, err = renderNotification(NotificationTarget{Name: "a", Template: "custom.tmpl"}, dir, e)
+	if err != nil || body != `{"id": "ko-a001", "outcome": "fail"}` {
+		t.Errorf("custom body = %s, %v", body, err)
+	}
+	if _, err := renderNotification(NotificationTarget{Name: "a", Template: "broken.tmpl"}, dir, e); err == nil ||
+		!strings.Contains(err.Error(), "valid JSON") {
+		t.Errorf("broken template err = %v, want invalid JSON", err)
+	}
+}
+
+func TestNotificationBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		7:  32 * time.Minute,
+		8:  time.Hour,
+		20: time.Hour,
+	} {
+		if got := notificationBackoff(attempts); got != want {
+			t.Errorf("notificationBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestFlushNotifications(t *testing.T) {
+	db, err := OpenDBAt(filepath.Join(t.TempDir(), "ko.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var mu sync.Mutex
+	var got []*http.Request
+	var bodies []string
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		b, _ := io.ReadAll(r.Body)
+		got = append(got, r)
+		bodies = append(bodies, string(b))
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(configPath, []byte("notifications:\n  - name: ops\n    url: "+srv.URL+"\n    secret: ${secrets.KEY}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, secretsFile), []byte("KEY=topsecret\n"), 0600)
+
+	now := time.Now()
+	body := `{"event":"note"}`
+	if err := db.EnqueueNotification("ops", configPath, "note", "ko-a001", body, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if delivered, failed := flushNotifications(db, now, false); delivered != 0 || failed != 1 {
+		t.Fatalf("first flush = %d delivered, %d failed; want 0, 1", delivered, failed)
+	}
+	pending, _ := db.ListNotifications("pending", 10)
+	if len(pending) != 1 || pending[0].Attempts != 1 || !strings.Contains(pending[0].LastError, "502") {
+		t.Fatalf("pending = %+v", pending)
+	}
+
+	// Still backing off
+	if delivered, failed := flushNotifications(db, now, false); delivered+failed != 0 {
+		t.Errorf("flush during backoff sent %d", delivered+failed)
+	}
+
+	fail = false
+	if delivered, failed := flushNotifications(db, now.Add(time.Minute), false); delivered != 1 || failed != 0 {
+		t.Fatalf("retry flush = %d delivered, %d failed; want 1, 0", delivered, failed)
+	}
+	done, _ := db.ListNotifications("delivered", 10)
+	if len(done) != 1 || done[0].Attempts != 2 {
+		t.Errorf("delivered = %+v", done)
+	}
+
+	r := got[len(got)-1]
+	mac := hmac.New(sha256.New, []byte("topsecret"))
+	mac.Write([]byte(body))
+	if sig := r.Header.Get("X-Ko-Signature-256"); sig != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("signature = %q", sig)
+	}
+	if r.Header.Get("X-Ko-Event") != "note" || r.Header.Get("X-Ko-Delivery") == "" {
+		t.Errorf("headers = %v", r.Header)
+	}
+	if bodies[len(bodies)-1] != body {
+		t.Errorf("body = %q", bodies[len(bodies)-1])
+	}
+
+	// A target removed from the config fails permanently
+	if err := db.EnqueueNotification("gone", configPath, "note", "", body, now); err != nil {
+		t.Fatal(err)
+	}
+	flushNotifications(db, now, false)
+	failedRows, _ := db.ListNotifications("failed", 10)
+	if len(failedRows) != 1 || !strings.Contains(failedRows[0].LastError, "no longer in") {
+		t.Errorf("failed = %+v", failedRows)
+	}
+}
+
+func TestNotificationGiveUp(t *testing.T) {
+	db, err := OpenDBAt(filepath.Join(t.TempDir(), "ko.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(configPath, []byte("notifications:\n  - name: ops\n    url: "+srv.URL+"\n"), 0644)
+
+	now := time.Now()
+	db.EnqueueNotification("ops", configPath, "note", "ko-a001", "{}", now)
+	for i := 0; i < maxNotificationAttempts; i++ {
+		flushNotifications(db, now, true)
+	}
+	rows, _ := db.ListNotifications("", 10)
+	if len(rows) != 1 || rows[0].Status != "failed" || rows[0].Attempts != maxNotificationAttempts {
+		t.Errorf("rows = %+v", rows)
+	}
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// configSections are the top-level keys that mark a unified config.yaml;
+// a file without any of them is a legacy pipeline.yml.
+var configSections = []string{"project", "pipeline", "notifications", "fields"}
+
+// hasConfigSection reports whether a parsed file is a unified config.yaml.
+func hasConfigSection(root *yamlNode) bool {
+	for _, key := range configSections {
+		if yamlGet(root, key) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConfigFile parses a config file in either format.
+func parseConfigFile(content string) (*Config, error) {
+	root, err := parseYAML(content)
+	if err != nil {
+		return nil, err
+	}
+	d := &yamlDecoder{}
+	if hasConfigSection(root) {
+		c, err := decodeConfig(d, root)
+		if err != nil {
+			return nil, err
+		}
+		c.Warnings = d.warnings
+		return c, nil
+	}
+
+	// Legacy format: the whole file is the pipeline. Project.Prefix stays
+	// empty; callers fall back to ReadPrefix().
+	p, err := decodePipeline(d, root, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateWorkflows(p.Workflows); err != nil {
+		return nil, err
+	}
+	return &Config{Pipeline: *p, Warnings: d.warnings}, nil
+}
+
+// ParseConfig parses unified config.yaml format with project: and pipeline: sections.
+func ParseConfig(content string) (*Config, error) {
+	root, err := parseYAML(content)
+	if err != nil {
+		return nil, err
+	}
+	d := &yamlDecoder{}
+	c, err := decodeConfig(d, root)
+	if err != nil {
+		return nil, err
+	}
+	c.Warnings = d.warnings
+	return c, nil
+}
+
+// decodeConfig reads the sections of a unified config.yaml.
+func decodeConfig(d *yamlDecoder, root *yamlNode) (*Config, error) {
+	c := &Config{}
+	entries, err := d.entries(root, "config")
+	if err != nil {
+		return nil, err
+	}
+	var pipeline *yamlNode
+	for _, e := range entries {
+		key := e.Key.Value
+		switch key {
+		case "summarizer":
+			c.Summarizer, err = yamlString(e.Value, key)
+		case "project":
+			err = c.Project.decode(d, e.Value)
+		case "pipeline":
+			pipeline = e.Value
+		case "notifications":
+			c.Notifications, err = decodeNotifications(d, e.Value)
+		case "fields":
+			c.Fields, err = decodeFieldSchema(d, e.Value)
+		default:
+			d.unknown(e.Key, "")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if pipeline != nil && pipeline.Kind != yamlScalar {
+		p, err := decodeConfigPipeline(d, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		c.Pipeline = *p
+	}
+	return c, nil
+}
+
+// decode reads the project: section.
+func (pc *ProjectConfig) decode(d *yamlDecoder, n *yamlNode) error {
+	entries, err := d.entries(n, "project")
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		switch e.Key.Value {
+		case "prefix":
+			if pc.Prefix, err = yamlString(e.Value, "project.prefix"); err != nil {
+				return err
+			}
+		default:
+			d.unknown(e.Key, "project")
+		}
+	}
+	return nil
+}
+
+// decodeConfigPipeline reads the pipeline: section. With from:, the
+// section's other keys override the template's pipeline.
+func decodeConfigPipeline(d *yamlDecoder, n *yamlNode) (*Pipeline, error) {
+	from := yamlGet(n, "from")
+	if from == nil {
+		p, err := decodePipeline(d, n, "pipeline")
+		if err != nil {
+			return nil, err
+		}
+		return p, ValidateWorkflows(p.Workflows)
+	}
+
+	fromDir, err := yamlString(from, "pipeline.from")
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := expandTilde(fromDir)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline from: %v", err)
+	}
+	if !filepath.IsAbs(expanded) {
+		return nil, fmt.Errorf("pipeline from: path must be absolute or tilde-prefixed, got %q", fromDir)
+	}
+	base, err := LoadTemplatePipeline(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline from: %v", err)
+	}
+
+	overrides := *n
+	overrides.Pairs = nil
+	for _, pair := range n.Pairs {
+		if pair.Key.Value != "from" {
+			overrides.Pairs = append(overrides.Pairs, pair)
+		}
+	}
+	p := base
+	if len(overrides.Pairs) > 0 {
+		override, err := decodePipeline(d, &overrides, "pipeline")
+		if err != nil {
+			return nil, fmt.Errorf("pipeline overrides: %v", err)
+		}
+		p = MergePipeline(base, override)
+	}
+	return p, ValidateWorkflows(p.Workflows)
+}
+
+// decodePipeline reads a pipeline mapping; what prefixes its warnings. Every
+// key present is recorded in setFields so a from: override replaces only
+// what it sets.
+func decodePipeline(d *yamlDecoder, n *yamlNode, what string) (*Pipeline, error) {
+	p := &Pipeline{
+		Agent:          "muse",
+		MaxRetries:     2,
+		MaxDepth:       2,
+		Discretion:     "medium",
+		Workers:        1,
+		KeepBuilds:     defaultKeepBuilds,
+		MaxOutputBytes: defaultMaxOutputBytes,
+		ScopeViolation: ScopeViolationFail,
+		RedactDefaults: true,
+		SandboxNetwork: true,
+		Workflows:      make(map[string]*Workflow),
+		setFields:      make(map[string]bool),
+	}
+	entries, err := d.entries(n, "pipeline")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		key, v := e.Key.Value, e.Value
+		switch key {
+		case "from":
+			return nil, fmt.Errorf("from: directive is only supported in unified config.yaml format (under pipeline: section)")
+		case "agent":
+			p.Agent, err = yamlString(v, key)
+			p.agentExplicit = true
+		case "command":
+			p.Command, err = yamlString(v, key)
+		case "allow_all_tool_calls":
+			p.AllowAll, err = yamlBool(v, key)
+		case "allowed_tools":
+			p.AllowedTools, err = yamlStrings(v, key)
+		case "model":
+			p.Model, err = yamlString(v, key)
+		case "max_retries":
+			p.MaxRetries, err = yamlInt(v, key)
+		case "max_depth":
+			p.MaxDepth, err = yamlInt(v, key)
+		case "discretion":
+			p.Discretion, err = yamlString(v, key)
+		case "step_timeout":
+			p.StepTimeout, err = yamlString(v, key)
+		case "hook_timeout":
+			p.HookTimeout, err = yamlString(v, key)
+		case "require_clean_tree":
+			p.RequireCleanTree, err = yamlBool(v, key)
+		case "auto_triage":
+			p.AutoTriage, err = yamlBool(v, key)
+		case "auto_agent":
+			p.AutoAgent, err = yamlBool(v, key)
+		case "workers":
+			p.Workers, err = yamlInt(v, key)
+			p.Workers = max(p.Workers, 1)
+		case "keep_builds":
+			p.KeepBuilds, err = yamlInt(v, key)
+		case "max_output_bytes":
+			p.MaxOutputBytes, err = yamlInt(v, key)
+		case "scope_violation":
+			p.ScopeViolation, err = yamlString(v, key)
+			if err == nil && p.ScopeViolation != ScopeViolationFail && p.ScopeViolation != ScopeViolationRevert {
+				return nil, fmt.Errorf("scope_violation must be %q or %q, got %q", ScopeViolationFail, ScopeViolationRevert, p.ScopeViolation)
+			}
+		case "redact_defaults":
+			p.RedactDefaults, err = yamlBool(v, key)
+		case "redact_patterns":
+			p.RedactPatterns, err = yamlStrings(v, key)
+		case "redact_env":
+			p.RedactEnv, err = yamlStrings(v, key)
+		case "ignore":
+			p.Ignore, err = yamlStrings(v, key)
+		case "sandbox":
+			p.Sandbox, err = yamlBool(v, key)
+		case "sandbox_network":
+			p.SandboxNetwork, err = yamlBool(v, key)
+		case "sandbox_writable":
+			p.SandboxWritable, err = yamlStrings(v, key)
+		case "env":
+			p.Env, err = d.stringMap(v, key)
+		case "env_file":
+			p.EnvFile, err = yamlStrings(v, key)
+		case "workflows":
+			p.Workflows, err = decodeWorkflows(d, v)
+		case "on_loop_complete":
+			p.OnLoopComplete, err = decodeCommands(v, key)
+		default:
+			hooks := p.hookList(key)
+			if hooks == nil {
+				d.unknown(e.Key, what)
+				continue
+			}
+			*hooks, err = decodeHooks(d, v, key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.setFields[key] = true
+	}
+
+	if _, err := NewRedactor(p.RedactPatterns, nil, false, nil); err != nil {
+		return nil, err
+	}
+	if err := validateEnvNames(p); err != nil {
+		return nil, err
+	}
+	if err := validateHooks(p); err != nil {
+		return nil, err
+	}
+
+	// Validate: agent and command are mutually exclusive.
+	// If command: is set without an explicit agent:, clear the default agent.
+	if p.Command != "" && p.Agent == "muse" && !p.agentExplicit {
+		p.Agent = ""
+	}
+	if p.Agent != "" && p.Command != "" {
+		return nil, fmt.Errorf("pipeline config cannot set both 'agent' and 'command'")
+	}
+	return p, nil
+}
+
+// hookList returns the field holding the pipeline-level hook name, or nil.
+func (p *Pipeline) hookList(name string) *[]Hook {
+	switch name {
+	case HookOnStart:
+		return &p.OnStart
+	case HookOnNode:
+		return &p.OnNode
+	case HookOnBlock:
+		return &p.OnBlock
+	case HookOnDecompose:
+		return &p.OnDecompose
+	case HookOnSucceed:
+		return &p.OnSucceed
+	case HookOnFail:
+		return &p.OnFail
+	case HookOnClose:
+		return &p.OnClose
+	}
+	return nil
+}
+
+// decodeWorkflows reads the workflows: mapping.
+func decodeWorkflows(d *yamlDecoder, n *yamlNode) (map[string]*Workflow, error) {
+	entries, err := d.entries(n, "workflows")
+	if err != nil {
+		return nil, err
+	}
+	workflows := make(map[string]*Workflow, len(entries))
+	for _, e := range entries {
+		wf, err := decodeWorkflow(d, e.Key.Value, e.Value)
+		if err != nil {
+			return nil, err
+		}
+		workflows[wf.Name] = wf
+	}
+	return workflows, nil
+}
+
+// decodeWorkflow reads one workflow: either its list of nodes, or a
+// mapping of workflow settings with the nodes under nodes:. The old layout,
+// settings followed by a bare node list, still reads with a warning.
+func decodeWorkflow(d *yamlDecoder, name string, n *yamlNode) (*Workflow, error) {
+	wf := &Workflow{Name: name}
+	what := "workflows." + name
+	nodes := n
+	if n.Kind == yamlMapping {
+		nodes = nil
+		if legacy := n.Rest; legacy != nil {
+			if yamlGet(n, "nodes") != nil {
+				return nil, yamlErrorf(legacy, "%s: nodes listed both under nodes: and after the workflow settings", what)
+			}
+			d.warnings = append(d.warnings, fmt.Sprintf("line %d: %s: a node list after the workflow settings is deprecated; move the nodes under `nodes:`", legacy.Line, what))
+			settings := *n
+			settings.Rest = nil
+			n, nodes = &settings, legacy
+		}
+		entries, err := d.entries(n, what)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			key, v := e.Key.Value, e.Value
+			switch key {
+			case "nodes":
+				nodes = v
+			case "model":
+				wf.Model, err = yamlString(v, what+"."+key)
+			case "allow_all_tool_calls":
+				var allow bool
+				allow, err = yamlBool(v, what+"."+key)
+				wf.AllowAll = &allow
+			case "allowed_tools":
+				wf.AllowedTools, err = yamlStrings(v, what+"."+key)
+			case "on_success":
+				wf.OnSuccess, err = yamlString(v, what+"."+key)
+			case "env":
+				wf.Env, err = d.stringMap(v, what+"."+key)
+			case "env_file":
+				wf.EnvFile, err = yamlStrings(v, what+"."+key)
+			default:
+				if !isWorkflowHook(key) {
+					d.unknown(e.Key, what)
+					continue
+				}
+				if wf.Hooks == nil {
+					wf.Hooks = make(map[string][]Hook)
+				}
+				wf.Hooks[key], err = decodeHooks(d, v, what+"."+key)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	items, err := yamlItems(nodes, what)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		node, err := decodeNode(d, item, what)
+		if err != nil {
+			return nil, err
+		}
+		wf.Nodes = append(wf.Nodes, node)
+	}
+	return wf, nil
+}
+
+// decodeNode reads one node of a workflow.
+func decodeNode(d *yamlDecoder, n *yamlNode, what string) (Node, error) {
+	node := Node{MaxVisits: 1}
+	entries, err := d.entries(n, what)
+	if err != nil {
+		return node, err
+	}
+	for _, e := range entries {
+		key, v := e.Key.Value, e.Value
+		w := what + "." + key
+		switch key {
+		case "name":
+			node.Name, err = yamlString(v, w)
+		case "type":
+			var s string
+			s, err = yamlString(v, w)
+			node.Type = NodeType(s)
+		case "prompt":
+			node.Prompt, err = yamlString(v, w)
+		case "run":
+			node.Run, err = yamlString(v, w)
+		case "model":
+			node.Model, err = yamlString(v, w)
+		case "allow_all_tool_calls":
+			var allow bool
+			allow, err = yamlBool(v, w)
+			node.AllowAll = &allow
+		case "max_visits":
+			node.MaxVisits, err = yamlInt(v, w)
+		case "timeout":
+			node.Timeout, err = yamlString(v, w)
+		case "routes":
+			node.Routes, err = yamlStrings(v, w)
+		case "skills":
+			node.Skills, err = yamlStrings(v, w)
+		case "allowed_tools":
+			node.AllowedTools, err = yamlStrings(v, w)
+		case "scope":
+			node.Scope, err = yamlStrings(v, w)
+		case "sandbox":
+			var on bool
+			on, err = yamlBool(v, w)
+			node.Sandbox = &on
+		case "note_artifact":
+			node.NoteArtifact, err = yamlString(v, w)
+		case "skill":
+			node.Skill, err = yamlString(v, w)
+		case "env":
+			node.Env, err = d.stringMap(v, w)
+		case "env_file":
+			node.EnvFile, err = yamlStrings(v, w)
+		default:
+			d.unknown(e.Key, what)
+		}
+		if err != nil {
+			return node, err
+		}
+	}
+	return node, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePipelineWorkflowNodes(t *testing.T) {
+	config := `
+workflows:
+  main:
+    - name: triage
+      type: decision
+      prompt: triage.md
+      routes: [docs]
+  docs:
+    model: haiku
+    on_success: resolved
+    on_succeed: []
+    nodes:
+      - name: write
+        type: action
+        prompt: |
+          Write the docs.
+          Keep them short.
+`
+	p, err := ParsePipeline(config)
+	if err != nil {
+		t.Fatalf("ParsePipeline: %v", err)
+	}
+	docs := p.Workflows["docs"]
+	if docs.Model != "haiku" || docs.OnSuccess != "resolved" {
+		t.Errorf("docs settings = %q, %q", docs.Model, docs.OnSuccess)
+	}
+	if hooks, ok := docs.Hooks[HookOnSucceed]; !ok || len(hooks) != 0 {
+		t.Errorf("docs on_succeed = %v, %v; want an empty override", hooks, ok)
+	}
+	if len(docs.Nodes) != 1 || docs.Nodes[0].Prompt != "Write the docs.\nKeep them short.\n" {
+		t.Fatalf("docs nodes = %+v", docs.Nodes)
+	}
+	if docs.Nodes[0].MaxVisits != 1 {
+		t.Errorf("MaxVisits = %d, want the default 1", docs.Nodes[0].MaxVisits)
+	}
+}
+
+func TestParseWorkflowLegacyNodeList(t *testing.T) {
+	config := `pipeline:
+  workflows:
+    main:
+      - name: triage
+        type: decision
+        prompt: triage.md
+        routes: [research]
+    research:
+      on_success: resolved
+      - name: investigate
+        type: action
+        prompt: investigate.md
+`
+	c, err := ParseConfig(config)
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	wf := c.Pipeline.Workflows["research"]
+	if wf.OnSuccess != "resolved" || len(wf.Nodes) != 1 || wf.Nodes[0].Name != "investigate" {
+		t.Errorf("research = %+v", wf)
+	}
+	want := []string{"line 10: workflows.research: a node list after the workflow settings is deprecated; move the nodes under `nodes:`"}
+	if !reflect.DeepEqual(c.Warnings, want) {
+		t.Errorf("Warnings = %q\nwant %q", c.Warnings, want)
+	}
+
+	both := strings.Replace(config, "      on_success: resolved\n", "      nodes: []\n", 1)
+	if _, err := ParseConfig(both); err == nil || !strings.Contains(err.Error(), "line 10: workflows.research: nodes listed both") {
+		t.Errorf("nodes: and a node list: err = %v", err)
+	}
+}
+
+func TestParseConfigWarnings(t *testing.T) {
+	config := `project:
+  prefix: ko
+  prefx: typo
+pipeline:
+  max_retry: 1
+  workflows:
+    main:
+      - name: a
+        type: action
+        run: echo
+        tmeout: 5m
+colour: blue
+`
+	c, err := ParseConfig(config)
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	want := []string{
+		`line 3: project: unknown key "prefx"`,
+		`line 12: unknown key "colour"`,
+		`line 5: pipeline: unknown key "max_retry"`,
+		`line 11: workflows.main: unknown key "tmeout"`,
+	}
+	if !reflect.DeepEqual(c.Warnings, want) {
+		t.Errorf("Warnings = %q\nwant %q", c.Warnings, want)
+	}
+	if c.Project.Prefix != "ko" || c.Pipeline.MaxRetries != 2 {
+		t.Errorf("prefix %q, max_retries %d", c.Project.Prefix, c.Pipeline.MaxRetries)
+	}
+}
+
+func TestParseConfigFileFormat(t *testing.T) {
+	legacy := "command: echo\nworkflows:\n  main:\n    - name: a\n      type: action\n      run: echo\n"
+	c, err := parseConfigFile(legacy)
+	if err != nil {
+		t.Fatalf("legacy: %v", err)
+	}
+	if c.Pipeline.Command != "echo" || c.Project.Prefix != "" {
+		t.Errorf("legacy: command %q, prefix %q", c.Pipeline.Command, c.Project.Prefix)
+	}
+
+	c, err = parseConfigFile("project:\n  prefix: ab\n")
+	if err != nil {
+		t.Fatalf("unified: %v", err)
+	}
+	if c.Project.Prefix != "ab" {
+		t.Errorf("unified: prefix %q", c.Project.Prefix)
+	}
+
+	_, err = parseConfigFile("from: /tmp/template\n" + legacy)
+	if err == nil || !strings.Contains(err.Error(), "only supported in unified config.yaml") {
+		t.Errorf("legacy from: err = %v", err)
+	}
+	_, err = parseConfigFile("max_retries: [1]\n" + legacy)
+	if err == nil || !strings.Contains(err.Error(), "line 1: max_retries: expected a value, got a list") {
+		t.Errorf("wrong type: err = %v", err)
+	}
+}
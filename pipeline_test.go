@@ -109,13 +109,13 @@ on_close:
 	}
 
 	// Hooks
-	if len(p.OnSucceed) != 1 || p.OnSucceed[0] != "git add -A" {
+	if len(p.OnSucceed) != 1 || p.OnSucceed[0].Run != "git add -A" {
 		t.Errorf("OnSucceed = %v", p.OnSucceed)
 	}
-	if len(p.OnFail) != 1 || p.OnFail[0] != "git checkout -- ." {
+	if len(p.OnFail) != 1 || p.OnFail[0].Run != "git checkout -- ." {
 		t.Errorf("OnFail = %v", p.OnFail)
 	}
-	if len(p.OnClose) != 1 || p.OnClose[0] != "echo done" {
+	if len(p.OnClose) != 1 || p.OnClose[0].Run != "echo done" {
 		t.Errorf("OnClose = %v", p.OnClose)
 	}
 }
@@ -879,7 +879,7 @@ pipeline:
 	}
 
 	// Hooks
-	if len(c.Pipeline.OnSucceed) != 1 || c.Pipeline.OnSucceed[0] != "git commit" {
+	if len(c.Pipeline.OnSucceed) != 1 || c.Pipeline.OnSucceed[0].Run != "git commit" {
 		t.Errorf("OnSucceed = %v, want [git commit]", c.Pipeline.OnSucceed)
 	}
 }
@@ -1248,7 +1248,7 @@ workflows:
 	}
 
 	// on_succeed should be from override
-	if len(c.Pipeline.OnSucceed) != 1 || c.Pipeline.OnSucceed[0] != "git commit" {
+	if len(c.Pipeline.OnSucceed) != 1 || c.Pipeline.OnSucceed[0].Run != "git commit" {
 		t.Errorf("OnSucceed = %v, want [git commit]", c.Pipeline.OnSucceed)
 	}
 
@@ -1286,7 +1286,7 @@ on_succeed:
 	if c.Pipeline.MaxRetries != 3 {
 		t.Errorf("Pipeline.MaxRetries = %d, want 3", c.Pipeline.MaxRetries)
 	}
-	if len(c.Pipeline.OnSucceed) != 1 || c.Pipeline.OnSucceed[0] != "echo done" {
+	if len(c.Pipeline.OnSucceed) != 1 || c.Pipeline.OnSucceed[0].Run != "echo done" {
 		t.Errorf("OnSucceed = %v, want [echo done]", c.Pipeline.OnSucceed)
 	}
 }
@@ -1312,7 +1312,7 @@ on_succeed:
 	}
 
 	// Override replaces, not appends
-	if len(c.Pipeline.OnSucceed) != 1 || c.Pipeline.OnSucceed[0] != "custom commit" {
+	if len(c.Pipeline.OnSucceed) != 1 || c.Pipeline.OnSucceed[0].Run != "custom commit" {
 		t.Errorf("OnSucceed = %v, want [custom commit] (replace not append)", c.Pipeline.OnSucceed)
 	}
 }
@@ -1421,8 +1421,8 @@ func TestMergePipeline(t *testing.T) {
 		Workflows: map[string]*Workflow{
 			"main": {Name: "main", Nodes: []Node{{Name: "impl", Type: NodeAction}}},
 		},
-		OnSucceed:         []string{"git add ."},
-		OnFail:            []string{"echo fail"},
+		OnSucceed:         []Hook{{Run: "git add ."}},
+		OnFail:            []Hook{{Run: "echo fail"}},
 		TemplatePromptDir: "/template/prompts",
 	}
 
@@ -1431,7 +1431,7 @@ func TestMergePipeline(t *testing.T) {
 		Model:      "sonnet",
 		MaxRetries: 2, // default — should NOT override
 		setFields:  map[string]bool{"model": true, "on_succeed": true},
-		OnSucceed:  []string{"git commit -m 'done'"},
+		OnSucceed:  []Hook{{Run: "git commit -m 'done'"}},
 	}
 
 	result := MergePipeline(base, override)
@@ -1448,10 +1448,10 @@ func TestMergePipeline(t *testing.T) {
 	if result.Discretion != "high" {
 		t.Errorf("Discretion = %q, want %q (base preserved)", result.Discretion, "high")
 	}
-	if len(result.OnSucceed) != 1 || result.OnSucceed[0] != "git commit -m 'done'" {
+	if len(result.OnSucceed) != 1 || result.OnSucceed[0].Run != "git commit -m 'done'" {
 		t.Errorf("OnSucceed = %v, want [git commit -m 'done'] (overridden)", result.OnSucceed)
 	}
-	if len(result.OnFail) != 1 || result.OnFail[0] != "echo fail" {
+	if len(result.OnFail) != 1 || result.OnFail[0].Run != "echo fail" {
 		t.Errorf("OnFail = %v, want [echo fail] (base preserved)", result.OnFail)
 	}
 	if result.TemplatePromptDir != "/template/prompts" {
@@ -1467,8 +1467,8 @@ func TestMergePipeline(t *testing.T) {
 	}
 
 	// Mutating result's base-sourced slices shouldn't affect original
-	result.OnFail[0] = "modified"
-	if base.OnFail[0] != "echo fail" {
+	result.OnFail[0].Run = "modified"
+	if base.OnFail[0].Run != "echo fail" {
 		t.Error("MergePipeline aliased base slices — mutation leaked")
 	}
 }
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A plan file is an outline of tickets for ko add --from: markdown
+// headings and bullets, or a YAML list. Nesting makes children; inline
+// metadata such as [p1 #infra after:2] sets priority, tags, type and deps
+// on earlier or later siblings, counted from 1.
+
+// planItem is one ticket of a plan.
+type planItem struct {
+	Line        int
+	Title       string
+	Description string
+	Type        string
+	Priority    *int
+	Tags        []string
+	After       []int
+	Children    []*planItem
+}
+
+// planMetaPattern matches trailing inline metadata: "Title [p1 #infra]".
+var planMetaPattern = regexp.MustCompile(`\s*\[([^\[\]]*)\]\s*$`)
+
+// parsePlanTitle splits a title from its inline metadata.
+func parsePlanTitle(it *planItem, text string) error {
+	text = strings.TrimSpace(text)
+	if m := planMetaPattern.FindStringSubmatchIndex(text); m != nil {
+		for _, tok := range strings.Fields(text[m[2]:m[3]]) {
+			if err := it.setMeta(tok); err != nil {
+				return fmt.Errorf("line %d: %v", it.Line, err)
+			}
+		}
+		text = text[:m[0]]
+	}
+	if text == "" {
+		return fmt.Errorf("line %d: empty title", it.Line)
+	}
+	it.Title = text
+	return nil
+}
+
+// setMeta applies one metadata token.
+func (it *planItem) setMeta(tok string) error {
+	switch {
+	case len(tok) == 2 && tok[0] == 'p' && tok[1] >= '0' && tok[1] <= '4':
+		p := int(tok[1] - '0')
+		it.Priority = &p
+	case strings.HasPrefix(tok, "#") && len(tok) > 1:
+		it.Tags = append(it.Tags, tok[1:])
+	case strings.HasPrefix(tok, "type:") && len(tok) > 5:
+		it.Type = tok[5:]
+	case strings.HasPrefix(tok, "after:"):
+		for _, s := range strings.Split(tok[6:], ",") {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				return fmt.Errorf("after: %q is not a sibling number", s)
+			}
+			it.After = append(it.After, n)
+		}
+	default:
+		return fmt.Errorf("unknown metadata %q (want p0-p4, #tag, type:T or after:N)", tok)
+	}
+	return nil
+}
+
+// parsePlan parses a plan file; .yaml and .yml files are YAML, anything
+// else is markdown. YAML warnings are returned alongside the items.
+func parsePlan(path, content string) ([]*planItem, []string, error) {
+	var items []*planItem
+	var warnings []string
+	var err error
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		items, warnings, err = parsePlanYAML(content)
+	default:
+		items, err = parsePlanMarkdown(content)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil, fmt.Errorf("no tickets in plan")
+	}
+	if err := checkPlanDeps(items); err != nil {
+		return nil, nil, err
+	}
+	return items, warnings, nil
+}
+
+// planBulletPattern matches a list item: "- x", "* x", "+ x" or "1. x",
+// with an optional task checkbox.
+var planBulletPattern = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?(.*)$`)
+
+// parsePlanMarkdown reads headings and bullets as the outline. Headings
+// nest by level and bullets by indent below the heading above them; other
+// text becomes the description of the item above it.
+func parsePlanMarkdown(content string) ([]*planItem, error) {
+	type open struct {
+		level int
+		item  *planItem
+	}
+	var roots []*planItem
+	var stack []open
+	var last *planItem
+	var desc []string
+	flush := func() {
+		if last != nil {
+			last.Description = strings.TrimSpace(dedent(desc))
+		}
+		desc = nil
+	}
+	fenced := false
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			fenced = !fenced
+		}
+		level, text := 0, ""
+		if !fenced {
+			if h := strings.TrimLeft(trimmed, "#"); len(h) < len(trimmed) && len(trimmed)-len(h) <= 6 && strings.HasPrefix(h, " ") && line[0] == '#' {
+				level, text = len(trimmed)-len(h), h
+			} else if m := planBulletPattern.FindStringSubmatch(strings.ReplaceAll(line, "\t", "    ")); m != nil {
+				level, text = 10+len(m[1]), m[2]
+			}
+		}
+		if level == 0 {
+			if last != nil {
+				desc = append(desc, strings.TrimRight(line, " \t"))
+			}
+			continue
+		}
+		flush()
+		it := &planItem{Line: i + 1}
+		if err := parsePlanTitle(it, text); err != nil {
+			return nil, err
+		}
+		for len(stack) > 0 && stack[len(stack)-1].level >= level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, it)
+		} else {
+			parent := stack[len(stack)-1].item
+			parent.Children = append(parent.Children, it)
+		}
+		stack = append(stack, open{level, it})
+		last = it
+	}
+	flush()
+	if fenced {
+		return nil, fmt.Errorf("unterminated code block")
+	}
+	return roots, nil
+}
+
+// dedent joins lines, removing the indent they all share.
+func dedent(lines []string) string {
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			n := len(l) - len(strings.TrimLeft(l, " \t"))
+			if indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		if len(l) >= indent && indent > 0 {
+			l = l[indent:]
+		}
+		out[i] = l
+	}
+	return strings.Join(out, "\n")
+}
+
+// parsePlanYAML reads a list of items. An item is a title (with optional
+// inline metadata) or a mapping of title, description, type, priority,
+// tags, after and children.
+func parsePlanYAML(content string) ([]*planItem, []string, error) {
+	root, err := parseYAML(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	var d yamlDecoder
+	items, err := d.planItems(root, "plan")
+	return items, d.warnings, err
+}
+
+func (d *yamlDecoder) planItems(n *yamlNode, what string) ([]*planItem, error) {
+	nodes, err := yamlItems(n, what)
+	if err != nil {
+		return nil, err
+	}
+	var out []*planItem
+	for _, node := range nodes {
+		it, err := d.planItem(node)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, nil
+}
+
+func (d *yamlDecoder) planItem(n *yamlNode) (*planItem, error) {
+	it := &planItem{Line: n.Line}
+	if n.Kind == yamlScalar {
+		return it, parsePlanTitle(it, n.Value)
+	}
+	entries, err := d.entries(n, "item")
+	if err != nil {
+		return nil, err
+	}
+	title := ""
+	for _, e := range entries {
+		var err error
+		switch key := e.Key.Value; key {
+		case "title":
+			title, err = yamlString(e.Value, key)
+		case "description":
+			it.Description, err = yamlString(e.Value, key)
+			it.Description = strings.TrimSpace(it.Description)
+		case "type":
+			it.Type, err = yamlString(e.Value, key)
+		case "priority":
+			var p int
+			if p, err = yamlInt(e.Value, key); err == nil && (p < 0 || p > 4) {
+				err = yamlErrorf(e.Value, "priority: must be 0-4, got %d", p)
+			}
+			it.Priority = &p
+		case "tags":
+			var tags []string
+			tags, err = yamlStrings(e.Value, key)
+			it.Tags = append(it.Tags, tags...)
+		case "after":
+			var after []string
+			if after, err = yamlStrings(e.Value, key); err == nil {
+				if err = it.setMeta("after:" + strings.Join(after, ",")); err != nil {
+					err = yamlErrorf(e.Value, "%v", err)
+				}
+			}
+		case "children":
+			it.Children, err = d.planItems(e.Value, key)
+		default:
+			d.unknown(e.Key, "item")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := parsePlanTitle(it, title); err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+// checkPlanDeps checks that every after: names a sibling other than the
+// item itself and that siblings do not wait on each other in a cycle.
+func checkPlanDeps(items []*planItem) error {
+	for i, it := range items {
+		for _, n := range it.After {
+			if n > len(items) {
+				return fmt.Errorf("line %d: after:%d: there are only %d siblings", it.Line, n, len(items))
+			}
+			if n == i+1 {
+				return fmt.Errorf("line %d: after:%d: an item cannot come after itself", it.Line, n)
+			}
+		}
+	}
+	state := make([]int, len(items)) // 0 unvisited, 1 in progress, 2 done
+	var visit func(i int) error
+	visit = func(i int) error {
+		state[i] = 1
+		for _, n := range items[i].After {
+			switch state[n-1] {
+			case 1:
+				return fmt.Errorf("line %d: after:%d: dependency cycle", items[i].Line, n)
+			case 0:
+				if err := visit(n - 1); err != nil {
+					return err
+				}
+			}
+		}
+		state[i] = 2
+		return nil
+	}
+	for i := range items {
+		if state[i] == 0 {
+			if err := visit(i); err != nil {
+				return err
+			}
+		}
+	}
+	for _, it := range items {
+		if err := checkPlanDeps(it.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plannedTicket is a ticket built from a plan, with its outline number
+// ("1", "1.2", ...).
+type plannedTicket struct {
+	Ref    string
+	Ticket *Ticket
+}
+
+// buildPlan turns items into tickets in outline order, so parents come
+// before their children.
+// Top-level items become children of parentID when it is set. claim
+// reserves an ID, reporting false if it is already in use, so generated
+// IDs never collide.
+func buildPlan(items []*planItem, prefix, parentID, ref string, claim func(id string) bool) []plannedTicket {
+	siblings := make([]*Ticket, len(items))
+	for i, it := range items {
+		var t *Ticket
+		for t == nil || !claim(t.ID) {
+			if parentID != "" {
+				t = NewChildTicket(parentID, it.Title)
+			} else {
+				t = NewTicket(prefix, it.Title)
+			}
+		}
+		if it.Description != "" {
+			t.Body = "\n" + it.Description + "\n"
+		}
+		if it.Type != "" {
+			t.Type = it.Type
+		}
+		if it.Priority != nil {
+			t.Priority = *it.Priority
+		}
+		t.Tags = it.Tags
+		siblings[i] = t
+	}
+	var out []plannedTicket
+	for i, it := range items {
+		t := siblings[i]
+		for _, n := range it.After {
+			if dep := siblings[n-1].ID; !contains(t.Deps, dep) {
+				t.Deps = append(t.Deps, dep)
+			}
+		}
+		r := ref + strconv.Itoa(i+1)
+		out = append(out, plannedTicket{r, t})
+		out = append(out, buildPlan(it.Children, prefix, t.ID, r+".", claim)...)
+	}
+	return out
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// planOutline renders items one per line with their metadata, indented by
+// depth.
+func planOutline(items []*planItem, depth int) string {
+	var b strings.Builder
+	for _, it := range items {
+		p := "-"
+		if it.Priority != nil {
+			p = string(rune('0' + *it.Priority))
+		}
+		b.WriteString(strings.Repeat("  ", depth) + it.Title + " p" + p)
+		if it.Type != "" {
+			b.WriteString(" type:" + it.Type)
+		}
+		for _, tag := range it.Tags {
+			b.WriteString(" #" + tag)
+		}
+		for _, n := range it.After {
+			b.WriteString(" after:" + string(rune('0'+n)))
+		}
+		if it.Description != "" {
+			b.WriteString(" | " + strings.ReplaceAll(it.Description, "\n", `\n`))
+		}
+		b.WriteString("\n" + planOutline(it.Children, depth+1))
+	}
+	return b.String()
+}
+
+func TestParsePlanMarkdown(t *testing.T) {
+	items, _, err := parsePlan("plan.md", `Notes before the first item are ignored.
+
+# Auth overhaul [p1 #auth]
+
+Replace the session store.
+
+## Login form [type:feature]
+- [ ] Validate inputs [p2 #ui]
+- [x] Wire submit [after:1]
+    Needs the new endpoint.
+
+    `+"```"+`
+    # not a heading
+    `+"```"+`
+  * Error states
+## Sessions [after:1 #infra]
+1. Pick a store
+2. Migrate [after:1,1]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Auth overhaul p1 #auth | Replace the session store.
+  Login form p- type:feature
+    Validate inputs p2 #ui
+    Wire submit p- after:1 | Needs the new endpoint.\n\n` + "```" + `\n# not a heading\n` + "```" + `
+      Error states p-
+  Sessions p- #infra after:1
+    Pick a store p-
+    Migrate p- after:1 after:1
+`
+	if got := planOutline(items, 0); got != want {
+		t.Errorf("outline =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParsePlanYAML(t *testing.T) {
+	items, warnings, err := parsePlan("plan.yaml", `
+- title: Auth overhaul [#auth]
+  priority: 1
+  description: |
+    Replace the session store.
+  children:
+    - Login form [type:feature]
+    - title: Sessions
+      after: 1
+      tags: [infra]
+      owner: sam
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Auth overhaul p1 #auth | Replace the session store.
+  Login form p- type:feature
+  Sessions p- #infra after:1
+`
+	if got := planOutline(items, 0); got != want {
+		t.Errorf("outline =\n%s\nwant\n%s", got, want)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `line 11: item: unknown key "owner"`) {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+func TestParsePlanErrors(t *testing.T) {
+	for _, tc := range []struct{ path, content, want string }{
+		{"p.md", "", "no tickets in plan"},
+		{"p.md", "- A [p9]", `line 1: unknown metadata "p9"`},
+		{"p.md", "- A\n- B [after:3]", "line 2: after:3: there are only 2 siblings"},
+		{"p.md", "- A [after:1]", "line 1: after:1: an item cannot come after itself"},
+		{"p.md", "- A [after:2]\n- B [after:1]", "line 2: after:1: dependency cycle"},
+		{"p.md", "# [p1]", "line 1: empty title"},
+		{"p.md", "- A\n```\n- B", "unterminated code block"},
+		{"p.yaml", "- title: A\n  priority: 5", "line 2: priority: must be 0-4, got 5"},
+		{"p.yaml", "- title: A\n  after: [x]", `line 2: after: "x" is not a sibling number`},
+		{"p.yaml", "title: A", "plan: expected a list"},
+	} {
+		if _, _, err := parsePlan(tc.path, tc.content); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("parsePlan(%q) err = %v, want %q", tc.content, err, tc.want)
+		}
+	}
+}
+
+func TestBuildPlan(t *testing.T) {
+	items, _, err := parsePlan("plan.md", "# Epic [p1]\n- One\n- Two [after:1 #x]\n# Other\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken := map[string]bool{}
+	planned := buildPlan(items, "ko", "", "", func(id string) bool {
+		if taken[id] {
+			return false
+		}
+		taken[id] = true
+		return true
+	})
+	var refs []string
+	for _, p := range planned {
+		refs = append(refs, p.Ref+"="+p.Ticket.Title)
+	}
+	if got := strings.Join(refs, " "); got != "1=Epic 1.1=One 1.2=Two 2=Other" {
+		t.Fatalf("planned = %s", got)
+	}
+	epic, one, two := planned[0].Ticket, planned[1].Ticket, planned[2].Ticket
+	if !strings.HasPrefix(epic.ID, "ko-") || epic.Priority != 1 || epic.Status != "open" {
+		t.Errorf("epic = %+v", epic)
+	}
+	if one.Parent != epic.ID || !strings.HasPrefix(one.ID, epic.ID+".") {
+		t.Errorf("child = %+v", one)
+	}
+	if len(two.Deps) != 1 || two.Deps[0] != one.ID || strings.Join(two.Tags, ",") != "x" {
+		t.Errorf("sibling dep = %+v", two)
+	}
+
+	// Top-level items hang off an existing parent when one is given.
+	planned = buildPlan(items[:1], "ko", "ko-a001", "", func(string) bool { return true })
+	if planned[0].Ticket.Parent != "ko-a001" {
+		t.Errorf("parented = %+v", planned[0].Ticket)
+	}
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 // QQLMapping maps ko project tags onto Questbook realm/campaign slugs. It is a
@@ -24,6 +24,8 @@ import (
 type QQLMapping struct {
 	DefaultRealm string
 	Projects     map[string]QQLProjectMap
+
+	Warnings []string // unknown keys found while parsing
 }
 
 // QQLProjectMap is one project's realm/campaign binding. Realm is the anchor
@@ -70,75 +72,80 @@ func LoadQQLMapping() (*QQLMapping, error) {
 		}
 		return nil, err
 	}
-	return ParseQQLMapping(string(data)), nil
+	m, err := ParseQQLMapping(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	warnYAML(path, m.Warnings)
+	return m, nil
 }
 
-// ParseQQLMapping parses the minimal YAML shape above. Kept deliberately small
-// (no external YAML dep) — the file is flat and hand-maintained.
-func ParseQQLMapping(content string) *QQLMapping {
+// ParseQQLMapping parses the YAML shape above. A project may also map
+// straight to its realm ("tag: realm").
+func ParseQQLMapping(content string) (*QQLMapping, error) {
 	m := &QQLMapping{Projects: map[string]QQLProjectMap{}}
-	var inProjects bool
-	var current string
-
-	for _, line := range strings.Split(content, "\n") {
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			continue
-		}
-
-		indent := len(line) - len(strings.TrimLeft(line, " "))
-
-		if indent == 0 {
-			key, val, ok := parseYAMLLine(trimmed)
-			if !ok {
-				continue
-			}
-			switch key {
-			case "default_realm":
-				m.DefaultRealm = unquote(val)
-				inProjects = false
-			case "projects":
-				inProjects = true
-			default:
-				inProjects = false
-			}
-			current = ""
-			continue
+	root, err := parseYAML(content)
+	if err != nil {
+		return nil, err
+	}
+	d := &yamlDecoder{}
+	entries, err := d.entries(root, "mapping")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		switch e.Key.Value {
+		case "default_realm":
+			m.DefaultRealm, err = yamlString(e.Value, "default_realm")
+		case "projects":
+			err = m.parseProjects(d, e.Value)
+		default:
+			d.unknown(e.Key, "")
 		}
-
-		if !inProjects {
-			continue
+		if err != nil {
+			return nil, err
 		}
+	}
+	m.Warnings = d.warnings
+	return m, nil
+}
 
-		key, val, ok := parseYAMLLine(trimmed)
-		if !ok {
-			continue
-		}
-		if indent <= 2 {
-			// Project tag line: "fort-nix:"
-			current = key
-			pm := m.Projects[current]
-			if val != "" {
-				// Inline "tag: realm" shorthand.
-				pm.Realm = unquote(val)
+// parseProjects reads the projects: section.
+func (m *QQLMapping) parseProjects(d *yamlDecoder, n *yamlNode) error {
+	projects, err := d.entries(n, "projects")
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		tag, what := p.Key.Value, "projects."+p.Key.Value
+		var pm QQLProjectMap
+		if p.Value.Kind == yamlScalar {
+			if pm.Realm, err = yamlString(p.Value, what); err != nil {
+				return err
 			}
-			m.Projects[current] = pm
+			m.Projects[tag] = pm
 			continue
 		}
-		// Nested realm/campaign under the current project.
-		if current == "" {
-			continue
+		props, err := d.entries(p.Value, what)
+		if err != nil {
+			return err
 		}
-		pm := m.Projects[current]
-		switch key {
-		case "realm":
-			pm.Realm = unquote(val)
-		case "campaign":
-			pm.Campaign = unquote(val)
+		for _, prop := range props {
+			switch prop.Key.Value {
+			case "realm":
+				pm.Realm, err = yamlString(prop.Value, what+".realm")
+			case "campaign":
+				pm.Campaign, err = yamlString(prop.Value, what+".campaign")
+			default:
+				d.unknown(prop.Key, what)
+			}
+			if err != nil {
+				return err
+			}
 		}
-		m.Projects[current] = pm
+		m.Projects[tag] = pm
 	}
-	return m
+	return nil
 }
 
 // Resolve returns the realm and campaign slugs for a project tag. If the
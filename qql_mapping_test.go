@@ -16,7 +16,10 @@ projects:
   gee:
     realm: gee
 `
-	m := ParseQQLMapping(content)
+	m, err := ParseQQLMapping(content)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if m.DefaultRealm != "knockout-legacy" {
 		t.Errorf("default_realm = %q, want knockout-legacy", m.DefaultRealm)
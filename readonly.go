@@ -22,18 +22,22 @@ func ReadonlyEnabled() bool {
 
 // legacyWriteCommands are the ko subcommands that mutate the local ticket store.
 var legacyWriteCommands = map[string]bool{
-	"add":    true,
-	"update": true,
-	"status": true,
-	"start":  true,
-	"close":  true,
-	"open":   true,
-	"block":  true,
-	"snooze": true,
-	"dep":    true,
-	"undep":  true,
-	"note":   true,
-	"bump":   true,
+	"add":     true,
+	"update":  true,
+	"edit":    true,
+	"mv":      true,
+	"rm":      true,
+	"archive": true,
+	"status":  true,
+	"start":   true,
+	"close":   true,
+	"open":    true,
+	"block":   true,
+	"snooze":  true,
+	"dep":     true,
+	"undep":   true,
+	"note":    true,
+	"bump":    true,
 }
 
 // isLegacyWrite reports whether (cmd, rest) would write to the legacy store.
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// redactedMarker replaces every secret that is scrubbed.
+const redactedMarker = "[REDACTED]"
+
+// minSecretEnvLen is the shortest env var value that is redacted. Shorter
+// values ("1", "true") would mangle unrelated text.
+const minSecretEnvLen = 8
+
+// defaultSecretPatterns match common credential formats. They are on
+// unless the pipeline sets redact_defaults: false.
+var defaultSecretPatterns = []string{
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+	`\bAKIA[0-9A-Z]{16}\b`,                                               // AWS access key ID
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,                                     // GitHub tokens
+	`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,                                   // GitHub fine-grained PAT
+	`\bglpat-[A-Za-z0-9_-]{20,}\b`,                                       // GitLab PAT
+	`\bsk-ant-[A-Za-z0-9_-]{20,}`,                                        // Anthropic
+	`\bsk-(?:proj-)?[A-Za-z0-9_-]{32,}`,                                  // OpenAI
+	`\b[rs]k_live_[A-Za-z0-9]{20,}\b`,                                    // Stripe
+	`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`,                                  // Slack
+	`\bAIza[0-9A-Za-z_-]{35}\b`,                                          // Google API key
+	`\bBearer [A-Za-z0-9._~+/-]{20,}=*`,                                  // HTTP bearer tokens
+	`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`, // JWTs
+}
+
+// secretEnvName matches env var names whose values are redacted by default
+// (GITHUB_TOKEN, AWS_SECRET_ACCESS_KEY, OPENAI_API_KEY, DB_PASSWORD, ...).
+var secretEnvName = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|API_?KEY|ACCESS_KEY|PRIVATE_KEY)$`)
+
+// Redactor scrubs secrets from text before it is persisted or streamed.
+type Redactor struct {
+	patterns []*regexp.Regexp
+	values   []string // literal secret values, longest first
+}
+
+// NewRedactor builds a redactor from regex patterns, the names of env vars
+// whose values are secret, and whether the built-in defaults apply.
+// environ is the process environment in KEY=VALUE form.
+func NewRedactor(patterns, envNames []string, defaults bool, environ []string) (*Redactor, error) {
+	r := &Redactor{}
+	all := patterns
+	if defaults {
+		all = append(append([]string(nil), defaultSecretPatterns...), patterns...)
+	}
+	for _, p := range all {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %v", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	named := map[string]bool{}
+	for _, n := range envNames {
+		named[n] = true
+	}
+	seen := map[string]bool{}
+	for _, kv := range environ {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || len(v) < minSecretEnvLen || seen[v] {
+			continue
+		}
+		if named[k] || (defaults && secretEnvName.MatchString(k)) {
+			r.values = append(r.values, v)
+			seen[v] = true
+		}
+	}
+	// Longest first so a secret containing another is scrubbed whole.
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	return r, nil
+}
+
+// NewPipelineRedactor builds the redactor configured by a pipeline. Values
+// from .ko/secrets.env are always redacted.
+func NewPipelineRedactor(p *Pipeline, secrets map[string]string) (*Redactor, error) {
+	environ := os.Environ()
+	names := append([]string(nil), p.RedactEnv...)
+	for k, v := range secrets {
+		environ = append(environ, k+"="+v)
+		names = append(names, k)
+	}
+	return NewRedactor(p.RedactPatterns, names, p.RedactDefaults, environ)
+}
+
+// Redact replaces every secret in s with [REDACTED].
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, v := range r.values {
+		s = strings.ReplaceAll(s, v, redactedMarker)
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllLiteralString(s, redactedMarker)
+	}
+	return s
+}
+
+// activeRedactor is the redactor applied to artifacts, logs, events and
+// notes. It starts with the built-in defaults and is replaced by the
+// pipeline's configuration when a build starts.
+var activeRedactor atomic.Pointer[Redactor]
+
+func init() {
+	r, _ := NewRedactor(nil, nil, true, os.Environ())
+	activeRedactor.Store(r)
+}
+
+// SetRedactor installs r as the active redactor.
+func SetRedactor(r *Redactor) {
+	activeRedactor.Store(r)
+}
+
+// redact scrubs s with the active redactor.
+func redact(s string) string {
+	return activeRedactor.Load().Redact(s)
+}
+
+// redactFields scrubs every string value of an event before it is marshaled.
+func redactFields(fields map[string]interface{}) {
+	for k, v := range fields {
+		if s, ok := v.(string); ok {
+			fields[k] = redact(s)
+		}
+	}
+}
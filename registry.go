@@ -13,6 +13,8 @@ type Registry struct {
 	Projects map[string]string // tag -> absolute path
 	Prefixes map[string]string // tag -> ticket prefix (e.g. "fn" for fort-nix)
 	Hidden   map[string]bool   // tag -> true if project is hidden from ls
+
+	Warnings []string // unknown keys found while parsing
 }
 
 // RegistryPath returns the path to the registry file.
@@ -41,8 +43,9 @@ func LoadRegistry(path string) (*Registry, error) {
 	}
 	reg, err := ParseRegistry(string(data))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s: %v", path, err)
 	}
+	warnYAML(path, reg.Warnings)
 	// Lazy backfill: detect prefixes for projects that have tickets but no prefix.
 	backfillPrefixes(reg)
 	return reg, nil
@@ -52,81 +55,86 @@ func LoadRegistry(path string) (*Registry, error) {
 // Handles both the old flat format and the new nested format.
 func ParseRegistry(content string) (*Registry, error) {
 	r := &Registry{Projects: map[string]string{}, Prefixes: map[string]string{}, Hidden: map[string]bool{}}
-	var section string        // "", "projects", "prefixes"
-	var currentProject string // non-empty when inside a new-format nested project block
-
-	for _, line := range strings.Split(content, "\n") {
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			continue
-		}
-
-		if trimmed == "projects:" {
-			section = "projects"
-			currentProject = ""
-			continue
-		}
-		if trimmed == "prefixes:" {
-			section = "prefixes"
-			currentProject = ""
-			continue
-		}
-
-		key, val, ok := parseYAMLLine(trimmed)
-		if !ok {
-			continue
-		}
-
-		is4space := strings.HasPrefix(line, "    ")
-		is2space := strings.HasPrefix(line, "  ") && !is4space
-
-		if is4space {
-			// Properties of a nested project block (new format)
-			if section == "projects" && currentProject != "" {
-				switch key {
-				case "path":
-					r.Projects[currentProject] = val
-				case "prefix":
-					r.Prefixes[currentProject] = val
-				case "default":
-					if val == "true" {
-						r.Default = currentProject
-					}
-				case "hidden":
-					if val == "true" {
-						r.Hidden[currentProject] = true
-					}
+	root, err := parseYAML(content)
+	if err != nil {
+		return nil, err
+	}
+	d := &yamlDecoder{}
+	top, err := d.entries(root, "registry")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range top {
+		switch e.Key.Value {
+		case "default":
+			if r.Default, err = yamlString(e.Value, "default"); err != nil {
+				return nil, err
+			}
+		case "prefixes":
+			prefixes, err := d.stringMap(e.Value, "prefixes")
+			if err != nil {
+				return nil, err
+			}
+			for tag, prefix := range prefixes {
+				r.Prefixes[tag] = prefix
+			}
+		case "projects":
+			projects, err := d.entries(e.Value, "projects")
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range projects {
+				if err := r.parseProject(d, p.Key.Value, p.Value); err != nil {
+					return nil, err
 				}
 			}
-			continue
+		default:
+			d.unknown(e.Key, "")
 		}
+	}
+	r.Warnings = d.warnings
+	return r, nil
+}
 
-		if is2space {
-			switch section {
-			case "projects":
-				if val == "" {
-					// New format: bare project tag, start nested block
-					currentProject = key
-				} else {
-					// Old format: tag: path
-					r.Projects[key] = val
-					currentProject = ""
-				}
-			case "prefixes":
-				r.Prefixes[key] = val
+// parseProject reads one projects: entry: "tag: path" in the old format, a
+// block of path/prefix/default/hidden in the new one.
+func (r *Registry) parseProject(d *yamlDecoder, tag string, n *yamlNode) error {
+	what := "projects." + tag
+	if n.Kind == yamlScalar {
+		path, err := yamlString(n, what)
+		r.Projects[tag] = path
+		return err
+	}
+	props, err := d.entries(n, what)
+	if err != nil {
+		return err
+	}
+	r.Projects[tag] = ""
+	for _, p := range props {
+		key := what + "." + p.Key.Value
+		switch p.Key.Value {
+		case "path":
+			r.Projects[tag], err = yamlString(p.Value, key)
+		case "prefix":
+			r.Prefixes[tag], err = yamlString(p.Value, key)
+		case "default":
+			var isDefault bool
+			if isDefault, err = yamlBool(p.Value, key); isDefault {
+				r.Default = tag
 			}
-			continue
+		case "hidden":
+			var hidden bool
+			if hidden, err = yamlBool(p.Value, key); hidden {
+				r.Hidden[tag] = true
+			}
+		default:
+			d.unknown(p.Key, what)
 		}
-
-		// Top-level (no indent): reset section
-		section = ""
-		currentProject = ""
-		if key == "default" {
-			r.Default = val
+		if err != nil {
+			return err
 		}
 	}
-
-	return r, nil
+	return nil
 }
 
 // FormatRegistry serializes a registry to YAML using the nested format.
@@ -158,17 +166,92 @@ func FormatRegistry(r *Registry) string {
 }
 
 // SaveRegistry writes the registry to disk, then shadow-writes to SQLite.
+// An existing file in the nested format is edited in place, so comments
+// and unchanged entries keep their bytes; anything else is rewritten.
 func SaveRegistry(path string, r *Registry) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
 	}
-	if err := os.WriteFile(path, []byte(FormatRegistry(r)), 0644); err != nil {
+	content := FormatRegistry(r)
+	if data, err := os.ReadFile(path); err == nil {
+		if edited, err := editRegistry(string(data), r); err == nil {
+			content = edited
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
 		return err
 	}
 	shadowWriteRegistry(r)
 	return nil
 }
 
+// editRegistry applies the differences between the registry in src and r
+// to src. It fails for the old flat format, which SaveRegistry migrates by
+// rewriting the file.
+func editRegistry(src string, r *Registry) (string, error) {
+	root, err := parseYAML(src)
+	if err != nil {
+		return "", err
+	}
+	if root.Kind != yamlMapping || len(root.Pairs) != 1 || root.Pairs[0].Key.Value != "projects" {
+		return "", fmt.Errorf("not a nested registry")
+	}
+	for _, p := range root.Pairs[0].Value.Pairs {
+		if p.Value.Kind != yamlMapping {
+			return "", fmt.Errorf("project %s is in the flat format", p.Key.Value)
+		}
+	}
+	old, err := ParseRegistry(src)
+	if err != nil {
+		return "", err
+	}
+
+	// set writes key for tag when want differs from what is there; "" removes it.
+	set := func(tag, key, have, want string) error {
+		if have == want {
+			return nil
+		}
+		path := []string{"projects", tag, key}
+		if want == "" {
+			src, err = yamlDelete(src, path)
+		} else {
+			src, err = yamlSet(src, path, want)
+		}
+		return err
+	}
+	flag := func(b bool) string {
+		if b {
+			return "true"
+		}
+		return ""
+	}
+	for tag := range old.Projects {
+		if _, ok := r.Projects[tag]; !ok {
+			if src, err = yamlDelete(src, []string{"projects", tag}); err != nil {
+				return "", err
+			}
+		}
+	}
+	keys := make([]string, 0, len(r.Projects))
+	for k := range r.Projects {
+		keys = append(keys, k)
+	}
+	sortStrings(keys)
+	for _, tag := range keys {
+		for _, f := range []struct{ key, have, want string }{
+			{"path", old.Projects[tag], r.Projects[tag]},
+			{"prefix", old.Prefixes[tag], r.Prefixes[tag]},
+			{"default", flag(old.Default == tag), flag(r.Default == tag)},
+			{"hidden", flag(old.Hidden[tag]), flag(r.Hidden[tag])},
+		} {
+			if err := set(tag, f.key, f.have, f.want); err != nil {
+				return "", err
+			}
+		}
+	}
+	return src, nil
+}
+
 // sortStrings sorts a string slice in place.
 func sortStrings(s []string) {
 	for i := 1; i < len(s); i++ {
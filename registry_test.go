@@ -210,3 +210,44 @@ func TestCleanTag(t *testing.T) {
 		}
 	}
 }
+
+func TestEditRegistryInPlace(t *testing.T) {
+	src := `# machine registry
+projects:
+  exo:
+    path: /srv/exo   # shared
+    default: true
+  old:
+    path: /srv/old
+    hidden: true
+`
+	reg, err := ParseRegistry(src)
+	if err != nil {
+		t.Fatalf("ParseRegistry: %v", err)
+	}
+	same, err := editRegistry(src, reg)
+	if err != nil || same != src {
+		t.Fatalf("unchanged registry: %v\n%s", err, same)
+	}
+
+	delete(reg.Projects, "old")
+	delete(reg.Hidden, "old")
+	reg.Projects["new"] = "/srv/new"
+	reg.Prefixes["exo"] = "ex"
+	got, err := editRegistry(src, reg)
+	if err != nil {
+		t.Fatalf("editRegistry: %v", err)
+	}
+	want := `# machine registry
+projects:
+  exo:
+    path: /srv/exo   # shared
+    default: true
+    prefix: ex
+  new:
+    path: /srv/new
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
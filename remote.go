@@ -3,11 +3,11 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -23,6 +23,11 @@ var localOnlyCommands = map[string]bool{
 	"-v":        true,
 	"import":    true, // not in serve whitelist
 	"export":    true, // reads the local DB directly; never proxy
+	"logs":      true, // reads local build artifacts
+	"gc":        true, // prunes local build artifacts
+	"notify":    true, // delivers from the local queue
+	"remote":    true, // manages the local offline queue
+	"edit":      true, // needs this terminal for $EDITOR
 }
 
 // isRemoteCommand returns true if the command should proxy to a remote server.
@@ -30,36 +35,143 @@ func isRemoteCommand(cmd string) bool {
 	return !localOnlyCommands[cmd]
 }
 
-// remoteExec sends a command to a remote ko serve instance and prints the result.
-// Returns an exit code: 0 on success, 1 on failure.
-func remoteExec(server string, argv []string) int {
-	// Build request body
-	body, err := json.Marshal(struct {
-		Argv []string `json:"argv"`
-	}{Argv: argv})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "ko: failed to marshal request: %v\n", err)
+// remoteExec runs a command on a remote ko serve instance, streaming piped
+// stdin to it and its stdout and stderr back as they are written.
+// server is an http://, https:// or unix:// URL.
+// A non-empty token is sent as a bearer token.
+// Write commands are queued while the server is unreachable (see
+// remote_queue.go). Returns the remote command's exit code, or 1 if it
+// could not be run.
+func remoteExec(server, token string, argv []string) int {
+	if f := execFileFlag(argv); f != "" {
+		fmt.Fprintf(os.Stderr, "ko %s: --%s reads a local file, which the server at %s cannot see\n", argv[0], f, server)
 		return 1
 	}
+	stdin := remoteStdin()
+	if !offlineCommands[argv[0]] {
+		if db := getShadowDB(); db != nil && db.hasPendingRemote(server) {
+			replayRemoteQueue(db, server, token, os.Stderr)
+		}
+		code, err := remoteRun(server, token, argv, stdin, os.Stdout, os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ko: server unreachable: %v\n", err)
+		}
+		return code
+	}
+	return remoteExecQueued(server, token, argv, stdin)
+}
 
-	// POST to server
-	url := strings.TrimRight(server, "/") + "/ko"
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+// remoteRun runs argv on the server and copies its output to stdout and
+// stderr. err is set only when the server could not be reached, so the
+// command did not run; other failures are reported on stderr.
+func remoteRun(server, token string, argv []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	header, err := json.Marshal(execRequest{Argv: argv, Stdin: stdin != nil})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ko: server unreachable: %v\n", err)
-		return 1
+		fmt.Fprintf(stderr, "ko: failed to marshal request: %v\n", err)
+		return 1, nil
+	}
+	body := io.Reader(bytes.NewReader(append(header, '\n')))
+	if stdin != nil {
+		body = io.MultiReader(body, stdin)
+	}
+
+	// No overall timeout: the command runs as long as it needs to.
+	client, base, err := remoteClient(server, 0)
+	if err != nil {
+		fmt.Fprintf(stderr, "ko: %v\n", err)
+		return 1, nil
+	}
+	url := base + "/ko"
+	token, err = resolveGlobalSecret(token)
+	if err != nil {
+		fmt.Fprintf(stderr, "ko: token: %v\n", err)
+		return 1, nil
+	}
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		fmt.Fprintf(stderr, "ko: %v\n", err)
+		return 1, nil
+	}
+	req.Header.Set("Content-Type", execStreamType)
+	req.Header.Set("Accept", execStreamType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 1, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusOK && resp.Header.Get("Content-Type") == execStreamType {
+		return readExecFrames(resp.Body, stdout, stderr), nil
+	}
+	return remoteBufferedResult(resp, stdout, stderr), nil
+}
+
+// remoteStdin returns the caller's stdin if it is a pipe with data, or nil.
+// Like ko add, it gives the pipe 50ms to produce its first bytes so an
+// inherited pipe that never sends anything does not hang the command.
+func remoteStdin() io.Reader {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return nil
+	}
+	first := make(chan []byte, 1)
+	go func() {
+		buf := make([]byte, 32*1024)
+		n, _ := os.Stdin.Read(buf)
+		first <- buf[:n]
+	}()
+	select {
+	case b := <-first:
+		if len(b) == 0 {
+			return nil
+		}
+		return io.MultiReader(bytes.NewReader(b), os.Stdin)
+	case <-time.After(50 * time.Millisecond):
+		return nil
+	}
+}
+
+// readExecFrames copies a streamed /ko response to stdout and stderr and
+// returns the exit code from its final frame.
+func readExecFrames(r io.Reader, stdout, stderr io.Writer) int {
+	dec := json.NewDecoder(r)
+	for {
+		var f execFrame
+		if err := dec.Decode(&f); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("stream ended before the command finished")
+			}
+			fmt.Fprintf(stderr, "ko: server: %v\n", err)
+			return 1
+		}
+		switch {
+		case f.Exit != nil:
+			return *f.Exit
+		case f.Error != "":
+			fmt.Fprintf(stderr, "ko: server: %s\n", f.Error)
+			return 1
+		case f.Stream == "stderr":
+			stderr.Write(f.Data)
+		default:
+			stdout.Write(f.Data)
+		}
+	}
+}
+
+// remoteBufferedResult handles a response that is not streamed: errors
+// from ko serve itself, and servers that predate the streaming protocol.
+func remoteBufferedResult(resp *http.Response, stdout, stderr io.Writer) int {
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ko: failed to read response: %v\n", err)
+		fmt.Fprintf(stderr, "ko: failed to read response: %v\n", err)
 		return 1
 	}
 
 	if resp.StatusCode == http.StatusOK {
-		os.Stdout.Write(respBody)
+		stdout.Write(respBody)
 		return 0
 	}
 
@@ -68,11 +180,11 @@ func remoteExec(server string, argv []string) int {
 		Error string `json:"error"`
 	}
 	if json.Unmarshal(respBody, &errResp) == nil && errResp.Error != "" {
-		fmt.Fprint(os.Stderr, errResp.Error)
+		fmt.Fprint(stderr, errResp.Error)
 		return 1
 	}
 
 	// Fallback: print raw body
-	fmt.Fprintf(os.Stderr, "ko: server returned %d: %s\n", resp.StatusCode, string(respBody))
+	fmt.Fprintf(stderr, "ko: server returned %d: %s\n", resp.StatusCode, string(respBody))
 	return 1
 }
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// offlineCommands are the write commands that are queued, rather than
+// failed, while the remote server is unreachable.
+var offlineCommands = map[string]bool{
+	"add":    true,
+	"note":   true,
+	"close":  true,
+	"update": true,
+}
+
+// RemoteMutation is one row of the offline queue: a write command waiting
+// to be replayed on the server it was meant for.
+type RemoteMutation struct {
+	ID        int64    `json:"id"`
+	Server    string   `json:"server"`
+	Argv      []string `json:"argv"`
+	Stdin     []byte   `json:"stdin,omitempty"`
+	Ticket    string   `json:"ticket,omitempty"`
+	Status    string   `json:"status"` // pending | applied | conflict | failed
+	Force     bool     `json:"force,omitempty"`
+	LastError string   `json:"last_error,omitempty"`
+	Output    string   `json:"output,omitempty"`
+	CreatedAt string   `json:"created_at"`
+	AppliedAt string   `json:"applied_at,omitempty"`
+}
+
+// remoteMutationTicket is the ticket a queued command changes, or "" for
+// ko add: the first positional argument of close, note or update, with
+// flags and their values skipped the way the command parses them.
+func remoteMutationTicket(argv []string) string {
+	valueFlags := updateValueFlags
+	switch argv[0] {
+	case "add":
+		return ""
+	case "note":
+		valueFlags = map[string]bool{"project": true}
+	}
+	for i := 1; i < len(argv); i++ {
+		a := argv[i]
+		switch {
+		case strings.HasPrefix(a, "#"):
+			// a project tag
+		case strings.HasPrefix(a, "-"):
+			if !strings.Contains(a, "=") && valueFlags[strings.TrimLeft(a, "-")] {
+				i++
+			}
+		default:
+			return a
+		}
+	}
+	return ""
+}
+
+// EnqueueRemoteMutation adds a pending command to the offline queue and
+// returns its ID.
+func (d *DB) EnqueueRemoteMutation(server string, argv []string, stdin []byte, now time.Time) (int64, error) {
+	data, err := json.Marshal(argv)
+	if err != nil {
+		return 0, err
+	}
+	res, err := d.db.Exec(`INSERT INTO remote_mutations (server, argv, stdin, ticket_id, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		server, string(data), stdin, nullStr(remoteMutationTicket(argv)), now.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+const remoteMutationColumns = `id, server, argv, stdin, COALESCE(ticket_id, ''), status, force,
+	COALESCE(last_error, ''), COALESCE(output, ''), created_at, COALESCE(applied_at, '')`
+
+func (d *DB) queryRemoteMutations(query string, args ...interface{}) ([]RemoteMutation, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []RemoteMutation
+	for rows.Next() {
+		var m RemoteMutation
+		var argv string
+		if err := rows.Scan(&m.ID, &m.Server, &argv, &m.Stdin, &m.Ticket, &m.Status, &m.Force,
+			&m.LastError, &m.Output, &m.CreatedAt, &m.AppliedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(argv), &m.Argv); err != nil {
+			return nil, fmt.Errorf("remote mutation %d: %v", m.ID, err)
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// ListRemoteMutations returns the queue oldest first. Without all, applied
+// commands are left out.
+func (d *DB) ListRemoteMutations(all bool) ([]RemoteMutation, error) {
+	query := "SELECT " + remoteMutationColumns + " FROM remote_mutations"
+	if !all {
+		query += " WHERE status != 'applied'"
+	}
+	return d.queryRemoteMutations(query + " ORDER BY id")
+}
+
+// hasPendingRemote reports whether commands are waiting for server.
+func (d *DB) hasPendingRemote(server string) bool {
+	var n int
+	d.db.QueryRow("SELECT COUNT(*) FROM remote_mutations WHERE server = ? AND status = 'pending'",
+		server).Scan(&n)
+	return n > 0
+}
+
+// heldRemoteTickets returns the tickets that have a conflicting or failed
+// command in the queue. Later commands for them wait, so that they are
+// never applied out of order.
+func (d *DB) heldRemoteTickets(server string) (map[string]int64, error) {
+	rows, err := d.db.Query(`SELECT ticket_id, MIN(id) FROM remote_mutations
+		WHERE server = ? AND status IN ('conflict', 'failed') AND ticket_id IS NOT NULL
+		GROUP BY ticket_id`, server)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	held := map[string]int64{}
+	for rows.Next() {
+		var ticket string
+		var id int64
+		if err := rows.Scan(&ticket, &id); err != nil {
+			return nil, err
+		}
+		held[ticket] = id
+	}
+	return held, rows.Err()
+}
+
+// lastRemoteApply returns when a queued command last changed ticket on
+// server, or "".
+func (d *DB) lastRemoteApply(server, ticket string) string {
+	var ts sql.NullString
+	d.db.QueryRow(`SELECT MAX(applied_at) FROM remote_mutations
+		WHERE server = ? AND ticket_id = ? AND status = 'applied'`, server, ticket).Scan(&ts)
+	return ts.String
+}
+
+// recordRemoteResult stores the outcome of replaying a queued command.
+func (d *DB) recordRemoteResult(id int64, status, lastError, output string, now time.Time) error {
+	applied := ""
+	if status == "applied" {
+		applied = now.UTC().Format(time.RFC3339Nano)
+	}
+	_, err := d.db.Exec(`UPDATE remote_mutations
+		SET status = ?, force = 0, last_error = ?, output = ?, applied_at = ? WHERE id = ?`,
+		status, nullStr(lastError), nullStr(output), nullStr(applied), id)
+	return err
+}
+
+// RetryRemoteMutation puts a conflicting or failed command back in the
+// queue. It skips the conflict check on its next replay.
+func (d *DB) RetryRemoteMutation(id int64) error {
+	res, err := d.db.Exec(`UPDATE remote_mutations SET status = 'pending', force = 1
+		WHERE id = ? AND status IN ('conflict', 'failed')`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no conflicting or failed command #%d", id)
+	}
+	return nil
+}
+
+// DropRemoteMutation removes a command that has not been applied.
+func (d *DB) DropRemoteMutation(id int64) error {
+	res, err := d.db.Exec("DELETE FROM remote_mutations WHERE id = ? AND status != 'applied'", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no queued command #%d", id)
+	}
+	return nil
+}
+
+// remoteExecQueued runs a write command on the server, queueing it when
+// the server is unreachable. Commands queued earlier are replayed first;
+// if that fails, the new command joins the queue behind them.
+func remoteExecQueued(server, token string, argv []string, stdin io.Reader) int {
+	var input []byte
+	if stdin != nil {
+		var err error
+		if input, err = io.ReadAll(io.LimitReader(stdin, apiMaxBody)); err != nil {
+			fmt.Fprintf(os.Stderr, "ko: reading stdin: %v\n", err)
+			return 1
+		}
+	}
+	db := getShadowDB()
+	if db != nil && db.hasPendingRemote(server) {
+		if _, _, err := replayRemoteQueue(db, server, token, os.Stderr); err != nil {
+			return queueRemote(db, server, argv, input, err)
+		}
+	}
+
+	var in io.Reader
+	if input != nil {
+		in = bytes.NewReader(input)
+	}
+	code, err := remoteRun(server, token, argv, in, os.Stdout, os.Stderr)
+	if err != nil {
+		return queueRemote(db, server, argv, input, err)
+	}
+	return code
+}
+
+// queueRemote adds a command to the offline queue after the server could
+// not be reached.
+func queueRemote(db *DB, server string, argv []string, input []byte, reason error) int {
+	if db == nil {
+		fmt.Fprintf(os.Stderr, "ko: server unreachable: %v\n", reason)
+		return 1
+	}
+	id, err := db.EnqueueRemoteMutation(server, argv, input, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko: server unreachable: %v\nko: queueing failed: %v\n", reason, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "ko: server unreachable; queued as #%d (see 'ko remote status')\n", id)
+	return 0
+}
+
+// replayRemoteQueue sends the pending commands for server in order and
+// reports each result on w. A command for a ticket that changed on the
+// server since it was queued is held as a conflict, as is every later
+// command for that ticket. err is set when the server went away mid-way;
+// the remaining commands stay pending.
+func replayRemoteQueue(db *DB, server, token string, w io.Writer) (applied, held int, err error) {
+	pending, err := db.queryRemoteMutations("SELECT "+remoteMutationColumns+
+		" FROM remote_mutations WHERE server = ? AND status = 'pending' ORDER BY id", server)
+	if err != nil {
+		return 0, 0, err
+	}
+	waiting, err := db.heldRemoteTickets(server)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, m := range pending {
+		label := fmt.Sprintf("#%d %s", m.ID, strings.Join(m.Argv, " "))
+		if first, ok := waiting[m.Ticket]; ok && m.Ticket != "" {
+			fmt.Fprintf(w, "ko: %s waits on #%d\n", label, first)
+			held++
+			continue
+		}
+		// Notes only append, so they cannot clobber a change made meanwhile.
+		if m.Ticket != "" && !m.Force && m.Argv[0] != "note" {
+			reason, err := remoteConflict(db, server, token, m)
+			if err != nil {
+				return applied, held, err
+			}
+			if reason != "" {
+				db.recordRemoteResult(m.ID, "conflict", reason, "", time.Now())
+				fmt.Fprintf(w, "ko: %s held: %s\n", label, reason)
+				waiting[m.Ticket] = m.ID
+				held++
+				continue
+			}
+		}
+
+		var in io.Reader
+		if m.Stdin != nil {
+			in = bytes.NewReader(m.Stdin)
+		}
+		var stdout, stderr bytes.Buffer
+		code, err := remoteRun(server, token, m.Argv, in, &stdout, &stderr)
+		if err != nil {
+			return applied, held, err
+		}
+		if code != 0 {
+			reason := strings.TrimSpace(stderr.String())
+			if reason == "" {
+				reason = fmt.Sprintf("exit status %d", code)
+			}
+			db.recordRemoteResult(m.ID, "failed", reason, stdout.String(), time.Now())
+			fmt.Fprintf(w, "ko: %s failed: %s\n", label, reason)
+			if m.Ticket != "" {
+				waiting[m.Ticket] = m.ID
+			}
+			held++
+			continue
+		}
+		db.recordRemoteResult(m.ID, "applied", "", stdout.String(), time.Now())
+		fmt.Fprintf(w, "ko: replayed %s", label)
+		if out := strings.TrimSpace(stdout.String()); out != "" && !strings.Contains(out, "\n") {
+			fmt.Fprintf(w, ": %s", out)
+		}
+		fmt.Fprintln(w)
+		applied++
+	}
+	return applied, held, nil
+}
+
+// remoteConflict checks a queued command against the server's copy of its
+// ticket. It returns why the command should be held, or "" when the ticket
+// is unchanged since the command was queued (or since this queue last
+// changed it). err is set when the server could not be reached.
+func remoteConflict(db *DB, server, token string, m RemoteMutation) (string, error) {
+	var stdout, stderr bytes.Buffer
+	code, err := remoteRun(server, token, []string{"show", m.Ticket, "--json"}, nil, &stdout, &stderr)
+	if err != nil {
+		return "", err
+	}
+	if code != 0 {
+		return "ticket not found on the server: " + strings.TrimSpace(stderr.String()), nil
+	}
+	var t showJSON
+	if err := json.Unmarshal(stdout.Bytes(), &t); err != nil {
+		return "", fmt.Errorf("show %s: %v", m.Ticket, err)
+	}
+	modified, err := time.Parse(time.RFC3339, t.Modified)
+	if err != nil {
+		return "", nil // nothing to compare against
+	}
+	since, _ := time.Parse(time.RFC3339Nano, m.CreatedAt)
+	if last, err := time.Parse(time.RFC3339Nano, db.lastRemoteApply(server, m.Ticket)); err == nil && last.After(since) {
+		since = last
+	}
+	// modified has whole seconds
+	if modified.After(since.Truncate(time.Second)) {
+		return fmt.Sprintf("%s changed on the server at %s, after this was queued",
+			t.ID, modified.Local().Format("2006-01-02 15:04:05")), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKoServer answers streamed /ko requests like a tiny ko serve: show
+// returns the ticket's modified time, everything else is logged and
+// succeeds.
+type fakeKoServer struct {
+	mu       sync.Mutex
+	modified map[string]time.Time
+	ran      []string // argv of each request, space-joined
+	stdin    []string
+}
+
+func (f *fakeKoServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	br := bufio.NewReader(r.Body)
+	req, err := readExecHeader(br)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rest, _ := io.ReadAll(br)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ran = append(f.ran, strings.Join(req.Argv, " "))
+	f.stdin = append(f.stdin, string(rest))
+
+	w.Header().Set("Content-Type", execStreamType)
+	enc := json.NewEncoder(w)
+	code := 0
+	switch req.Argv[0] {
+	case "show":
+		mod, ok := f.modified[req.Argv[1]]
+		if !ok {
+			enc.Encode(execFrame{Stream: "stderr", Data: []byte("ticket not found\n")})
+			code = 1
+			break
+		}
+		out, _ := json.Marshal(showJSON{ID: req.Argv[1], Modified: mod.UTC().Format(time.RFC3339)})
+		enc.Encode(execFrame{Stream: "stdout", Data: out})
+	case "add":
+		enc.Encode(execFrame{Stream: "stdout", Data: []byte("ap-0009\n")})
+	default:
+		if id := remoteMutationTicket(req.Argv); id != "" {
+			f.modified[id] = time.Now()
+		}
+	}
+	enc.Encode(execFrame{Exit: &code})
+}
+
+func TestRemoteQueue(t *testing.T) {
+	defer setupTestDB(t)()
+
+	// A Unix socket makes "down" and "up" exact: no socket, no server.
+	dir, err := os.MkdirTemp("", "ko")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "ko.sock")
+	server := "unix://" + sock
+
+	// Server down: writes are queued, reads fail
+	if code, _, stderr := runRemote(t, server, "written offline\n", "add", "Offline ticket"); code != 0 || !strings.Contains(stderr, "queued as #1") {
+		t.Fatalf("add: code = %d, stderr = %q", code, stderr)
+	}
+	runRemote(t, server, "", "close", "ap-0002")
+	runRemote(t, server, "", "update", "ap-0003", "--title", "Renamed")
+	runRemote(t, server, "", "note", "ap-0003", "after the rename")
+	if code, _, stderr := runRemote(t, server, "", "ls"); code != 1 || !strings.Contains(stderr, "server unreachable") {
+		t.Fatalf("ls: code = %d, stderr = %q", code, stderr)
+	}
+
+	queue, _ := getShadowDB().ListRemoteMutations(false)
+	if len(queue) != 4 || string(queue[0].Stdin) != "written offline\n" || queue[1].Ticket != "ap-0002" {
+		t.Fatalf("queue = %+v", queue)
+	}
+
+	// Server back: ap-0003 was changed there after the update was queued
+	fake := &fakeKoServer{modified: map[string]time.Time{
+		"ap-0002": time.Now().Add(-time.Hour),
+		"ap-0003": time.Now().Add(time.Hour),
+	}}
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: fake}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	code, _, stderr := runRemote(t, server, "", "ls")
+	if code != 0 {
+		t.Fatalf("ls after reconnect: code = %d, stderr = %q", code, stderr)
+	}
+	want := []string{"add Offline ticket", "show ap-0002 --json", "close ap-0002", "show ap-0003 --json", "ls"}
+	if strings.Join(fake.ran, "|") != strings.Join(want, "|") {
+		t.Errorf("ran %q, want %q", fake.ran, want)
+	}
+	if fake.stdin[0] != "written offline\n" {
+		t.Errorf("replayed stdin = %q", fake.stdin[0])
+	}
+	for _, s := range []string{"replayed #1 add Offline ticket: ap-0009", "#3 update ap-0003 --title Renamed held: ap-0003 changed on the server", "#4 note ap-0003 after the rename waits on #3"} {
+		if !strings.Contains(stderr, s) {
+			t.Errorf("stderr missing %q:\n%s", s, stderr)
+		}
+	}
+
+	queue, _ = getShadowDB().ListRemoteMutations(false)
+	var statuses []string
+	for _, m := range queue {
+		statuses = append(statuses, fmt.Sprintf("#%d %s", m.ID, m.Status))
+	}
+	if strings.Join(statuses, ", ") != "#3 conflict, #4 pending" {
+		t.Fatalf("queue after replay: %v", statuses)
+	}
+
+	// Retrying the conflict applies it unchecked, then the note follows
+	db := getShadowDB()
+	if err := db.RetryRemoteMutation(4); err == nil {
+		t.Errorf("retry of a pending command should fail")
+	}
+	if err := db.RetryRemoteMutation(3); err != nil {
+		t.Fatal(err)
+	}
+	fake.ran = nil
+	applied, held, err := replayRemoteQueue(db, server, "", io.Discard)
+	if err != nil || applied != 2 || held != 0 {
+		t.Fatalf("replay: applied = %d, held = %d, err = %v", applied, held, err)
+	}
+	if strings.Join(fake.ran, "|") != "update ap-0003 --title Renamed|note ap-0003 after the rename" {
+		t.Errorf("ran %q", fake.ran)
+	}
+	if queue, _ = db.ListRemoteMutations(false); len(queue) != 0 {
+		t.Errorf("queue not empty: %+v", queue)
+	}
+}
+
+func TestRemoteQueue_MissingTicketAndDrop(t *testing.T) {
+	defer setupTestDB(t)()
+	db := getShadowDB()
+
+	fake := &fakeKoServer{modified: map[string]time.Time{}}
+	dir, err := os.MkdirTemp("", "ko")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "ko.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: fake}
+	go srv.Serve(ln)
+	defer srv.Close()
+	server := "unix://" + sock
+
+	db.EnqueueRemoteMutation(server, []string{"close", "ap-gone"}, nil, time.Now())
+	db.EnqueueRemoteMutation("https://other.example.com", []string{"close", "ap-0001"}, nil, time.Now())
+
+	applied, held, err := replayRemoteQueue(db, server, "", io.Discard)
+	if err != nil || applied != 0 || held != 1 {
+		t.Fatalf("replay: applied = %d, held = %d, err = %v", applied, held, err)
+	}
+	queue, _ := db.ListRemoteMutations(false)
+	if len(queue) != 2 || queue[0].Status != "conflict" || !strings.Contains(queue[0].LastError, "ticket not found") {
+		t.Fatalf("queue = %+v", queue)
+	}
+	if queue[1].Status != "pending" {
+		t.Errorf("command for another server was touched: %+v", queue[1])
+	}
+
+	if err := db.DropRemoteMutation(queue[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DropRemoteMutation(queue[0].ID); err == nil {
+		t.Errorf("second drop should fail")
+	}
+}
+
+func TestRemoteMutationTicket(t *testing.T) {
+	for _, tc := range []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"add", "Fix login"}, ""},
+		{[]string{"close", "ko-1"}, "ko-1"},
+		{[]string{"update", "--title", "foo", "ko-1"}, "ko-1"},
+		{[]string{"update", "-p", "1", "--tags=a,b", "ko-1", "--status", "open"}, "ko-1"},
+		{[]string{"update", "-p1", "ko-1"}, "ko-1"},
+		{[]string{"close", "--field", "owner=me", "ko-1"}, "ko-1"},
+		{[]string{"note", "--project", "api", "ko-1", "a note"}, "ko-1"},
+		{[]string{"note", "#api", "ko-1", "a note"}, "ko-1"},
+		{[]string{"update", "--title", "foo"}, ""},
+	} {
+		if got := remoteMutationTicket(tc.argv); got != tc.want {
+			t.Errorf("remoteMutationTicket(%q) = %q, want %q", tc.argv, got, tc.want)
+		}
+	}
+}
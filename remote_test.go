@@ -11,6 +11,8 @@ import (
 )
 
 func TestRemoteExec_Success(t *testing.T) {
+	defer setupTestDB(t)()
+
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			t.Errorf("expected POST, got %s", r.Method)
@@ -41,7 +43,7 @@ func TestRemoteExec_Success(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	code := remoteExec(srv.URL, []string{"ls"})
+	code := remoteExec(srv.URL, "", []string{"ls"})
 
 	w.Close()
 	os.Stdout = old
@@ -57,6 +59,8 @@ func TestRemoteExec_Success(t *testing.T) {
 }
 
 func TestRemoteExec_ServerError(t *testing.T) {
+	defer setupTestDB(t)()
+
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
@@ -69,7 +73,7 @@ func TestRemoteExec_ServerError(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stderr = w
 
-	code := remoteExec(srv.URL, []string{"show", "nonexistent"})
+	code := remoteExec(srv.URL, "", []string{"show", "nonexistent"})
 
 	w.Close()
 	os.Stderr = old
@@ -85,12 +89,14 @@ func TestRemoteExec_ServerError(t *testing.T) {
 }
 
 func TestRemoteExec_ConnectionFailure(t *testing.T) {
+	defer setupTestDB(t)()
+
 	// Capture stderr
 	old := os.Stderr
 	r, w, _ := os.Pipe()
 	os.Stderr = w
 
-	code := remoteExec("http://localhost:1", []string{"ls"})
+	code := remoteExec("http://localhost:1", "", []string{"ls"})
 
 	w.Close()
 	os.Stderr = old
@@ -106,6 +112,8 @@ func TestRemoteExec_ConnectionFailure(t *testing.T) {
 }
 
 func TestRemoteExec_TrailingSlash(t *testing.T) {
+	defer setupTestDB(t)()
+
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/ko" {
 			t.Errorf("expected /ko, got %s", r.URL.Path)
@@ -115,7 +123,7 @@ func TestRemoteExec_TrailingSlash(t *testing.T) {
 	defer srv.Close()
 
 	// Server URL with trailing slash should still work
-	code := remoteExec(srv.URL+"/", []string{"ls"})
+	code := remoteExec(srv.URL+"/", "", []string{"ls"})
 	if code != 0 {
 		t.Errorf("expected exit code 0, got %d", code)
 	}
@@ -166,3 +174,24 @@ func TestGlobalConfig_NoServer(t *testing.T) {
 		t.Errorf("expected empty server, got %q", cfg.Server)
 	}
 }
+
+func TestRemoteExec_SendsToken(t *testing.T) {
+	defer setupTestDB(t)()
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("KO_TEST_TOKEN", "s3cret")
+	old := os.Stdout
+	os.Stdout, _ = os.Open(os.DevNull)
+	code := remoteExec(srv.URL, "$KO_TEST_TOKEN", []string{"ls"})
+	os.Stdout = old
+
+	if code != 0 || got != "Bearer s3cret" {
+		t.Errorf("code = %d, Authorization = %q", code, got)
+	}
+}
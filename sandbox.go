@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+// sandboxExecCmd is the hidden subcommand ko re-executes itself with inside
+// the new namespaces. It sets up the mounts, then execs the real command.
+const sandboxExecCmd = "__sandbox-exec"
+
+// sandboxConfig describes how a node's process is confined.
+type sandboxConfig struct {
+	Writable []string // absolute paths that stay writable; everything else is read-only
+	Network  bool     // keep the host network (false = fresh, empty network namespace)
+}
+
+// resolveSandbox returns the sandbox for a node, or nil when the node runs
+// unconfined. A node's sandbox: setting overrides the pipeline's. The
+// project root, the artifact directory (which holds the workspace) and the
+// system temp directory are always writable.
+func resolveSandbox(p *Pipeline, node *Node, projectRoot, artifactDir string) (*sandboxConfig, error) {
+	enabled := p.Sandbox
+	if node.Sandbox != nil {
+		enabled = *node.Sandbox
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	paths := []string{projectRoot, artifactDir, os.TempDir()}
+	for _, w := range p.SandboxWritable {
+		w, err := expandTilde(w)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(w) {
+			w = filepath.Join(projectRoot, w)
+		}
+		paths = append(paths, w)
+	}
+	return &sandboxConfig{Writable: dedupPaths(paths), Network: p.SandboxNetwork}, nil
+}
+
+// dedupPaths cleans, deduplicates and sorts paths so parents are bound
+// before their children.
+func dedupPaths(paths []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, p := range paths {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		p = filepath.Clean(p)
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// sandboxArgs builds the argv for the sandbox helper:
+// ko __sandbox-exec --rw <path>... -- <program> <args>...
+func sandboxArgs(sb *sandboxConfig, program string, args []string) []string {
+	argv := []string{"ko", sandboxExecCmd}
+	for _, w := range sb.Writable {
+		argv = append(argv, "--rw", w)
+	}
+	argv = append(argv, "--", program)
+	return append(argv, args...)
+}
+
+// parseSandboxArgs is the inverse of sandboxArgs (without the leading
+// "ko __sandbox-exec").
+func parseSandboxArgs(args []string) (writable []string, command []string, err error) {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--rw":
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("--rw requires a path")
+			}
+			writable = append(writable, args[i+1])
+			i++
+		case "--":
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("missing command")
+			}
+			return writable, args[i+1:], nil
+		default:
+			return nil, nil, fmt.Errorf("unknown argument %q", args[i])
+		}
+	}
+	return nil, nil, fmt.Errorf("missing command")
+}
+
+// sandboxCommand rewrites cmd to run inside the sandbox: the ko binary is
+// re-executed as the helper in new user and mount namespaces (and a new
+// network namespace when network is off), and the helper execs the
+// original program. ko commands inside run through a socket served for
+// cmd.Dir's project (see sandbox_ko.go); call stop once cmd has finished.
+// A nil sandbox leaves cmd untouched.
+func sandboxCommand(cmd *exec.Cmd, sb *sandboxConfig) (stop func(), err error) {
+	if sb == nil {
+		return func() {}, nil
+	}
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	if err := sandboxSupported(); err != nil {
+		return nil, err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: cannot locate ko binary: %v", err)
+	}
+	env, stop, err := startSandboxKo(cmd.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, env)
+	cmd.Args = sandboxArgs(sb, cmd.Path, cmd.Args[1:])
+	cmd.Path = self
+	setSandboxAttr(cmd, sb)
+	return stop, nil
+}
+
+// runSandboxExec is the entry point of the helper subcommand.
+func runSandboxExec(args []string) int {
+	writable, command, err := parseSandboxArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko %s: %v\n", sandboxExecCmd, err)
+		return 2
+	}
+	if err := enterSandbox(writable, command); err != nil {
+		fmt.Fprintf(os.Stderr, "ko sandbox: %v\n", err)
+		return 126
+	}
+	return 0 // unreachable: enterSandbox execs on success
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ko's state directory stays read-only inside a sandbox. Instead, the
+// build serves /ko on a unix socket for each sandboxed process, and ko
+// commands run inside are proxied to it, as with a remote server. The
+// build runs them outside the sandbox, limited to the node's project.
+
+// sandboxServerEnvVar holds the unix:// URL of the build's socket in a
+// sandboxed process.
+const sandboxServerEnvVar = "KO_SANDBOX_SERVER"
+
+// execDirKey is the request context key of the directory /ko commands run
+// in; without it they run in ko serve's own.
+type execDirKey struct{}
+
+// startSandboxKo serves /ko for the project at projectRoot on a fresh
+// socket and returns the env entry that points ko at it, and a func that
+// shuts it down.
+func startSandboxKo(projectRoot string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "ko-sandbox-")
+	if err != nil {
+		return "", nil, fmt.Errorf("sandbox: %v", err)
+	}
+	sock := filepath.Join(dir, "ko.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("sandbox: %v", err)
+	}
+	server := &http.Server{Handler: sandboxKoHandler(projectRoot)}
+	go server.Serve(ln)
+	stop := func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+	return sandboxServerEnvVar + "=unix://" + sock, stop, nil
+}
+
+// sandboxKoHandler serves POST /ko for commands that touch only the
+// project at projectRoot, running them there.
+func sandboxKoHandler(projectRoot string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ko" {
+			http.NotFound(w, r)
+			return
+		}
+		reg, err := LoadRegistry(RegistryPath())
+		if err != nil {
+			writeAuthError(w, r, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		argv, err := peekExecArgv(r)
+		if err != nil {
+			writeAuthError(w, r, http.StatusRequestEntityTooLarge, "bad_request", err.Error())
+			return
+		}
+		if argv != nil {
+			local := registryTag(reg, projectRoot)
+			access := koCommandAccess(argv, reg, local)
+			own := ServeToken{Name: "sandbox", Scopes: []string{"write"}, Projects: []string{local}}
+			if !own.allows(access) {
+				writeAuthError(w, r, http.StatusForbidden, "forbidden",
+					fmt.Sprintf("a sandboxed node may not %s %s", access.Scope, describeProjects(access.Projects)))
+				return
+			}
+		}
+		handleKo(w, r.WithContext(context.WithValue(r.Context(), execDirKey{}, projectRoot)))
+	})
+}
+
+// sandboxExec runs a ko command inside a sandbox on the build's socket.
+func sandboxExec(server string, argv []string) int {
+	code, err := remoteRun(server, "", argv, remoteStdin(), os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ko: build unreachable from the sandbox: %v\n", err)
+	}
+	return code
+}
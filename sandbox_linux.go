@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// mount_setattr(2) is not wrapped by the syscall package. The syscall
+// number is shared by every Linux architecture (added in 5.12).
+const (
+	sysMountSetattr  = 442
+	atFdcwd          = -0x64
+	atRecursive      = 0x8000
+	mountAttrRdonly  = 0x1
+	mountAttrSizeVer = 32
+)
+
+// mountAttr mirrors struct mount_attr.
+type mountAttr struct {
+	attrSet     uint64
+	attrClr     uint64
+	propagation uint64
+	usernsFd    uint64
+}
+
+var (
+	sandboxProbeOnce sync.Once
+	sandboxProbeErr  error
+)
+
+// sandboxSupported reports whether this kernel allows unprivileged user
+// namespaces and recursive read-only mounts. The probe runs once.
+func sandboxSupported() error {
+	sandboxProbeOnce.Do(func() {
+		// An empty path makes mount_setattr fail with ENOENT when it
+		// exists and ENOSYS when the kernel predates it.
+		if err := mountSetattr("", 0, &mountAttr{}); err == syscall.ENOSYS {
+			sandboxProbeErr = fmt.Errorf("sandbox: requires Linux 5.12 or newer (mount_setattr)")
+			return
+		}
+		probe := exec.Command("/proc/self/exe")
+		probe.Args = []string{"ko", "version"}
+		probe.SysProcAttr = sandboxSysProcAttr(&sandboxConfig{Network: true})
+		if err := probe.Run(); err != nil {
+			sandboxProbeErr = fmt.Errorf("sandbox: user namespaces unavailable: %v", err)
+		}
+	})
+	return sandboxProbeErr
+}
+
+// setSandboxAttr makes cmd start in fresh namespaces.
+func setSandboxAttr(cmd *exec.Cmd, sb *sandboxConfig) {
+	attr := sandboxSysProcAttr(sb)
+	if cmd.SysProcAttr != nil {
+		attr.Setsid = cmd.SysProcAttr.Setsid
+		attr.Setpgid = cmd.SysProcAttr.Setpgid
+	}
+	cmd.SysProcAttr = attr
+}
+
+// sandboxSysProcAttr maps the invoking user to root inside a new user
+// namespace, which grants the helper the capabilities it needs to set up
+// its private mount namespace.
+func sandboxSysProcAttr(sb *sandboxConfig) *syscall.SysProcAttr {
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS)
+	if !sb.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	return &syscall.SysProcAttr{
+		Cloneflags:  flags,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}
+}
+
+// enterSandbox runs inside the new namespaces: it makes the whole mount
+// tree read-only except the writable paths, then execs command.
+func enterSandbox(writable, command []string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	// Keep our mount changes out of the parent namespace.
+	if err := syscall.Mount("none", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make / private: %v", err)
+	}
+	// Give each writable path its own mount so it can be flipped back to
+	// read-write after the recursive read-only pass. Missing paths are
+	// skipped.
+	var bound []string
+	for _, w := range writable {
+		if _, err := os.Stat(w); err != nil {
+			continue
+		}
+		if err := syscall.Mount(w, w, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("bind %s: %v", w, err)
+		}
+		bound = append(bound, w)
+	}
+	if err := mountSetattr("/", atRecursive, &mountAttr{attrSet: mountAttrRdonly}); err != nil {
+		return fmt.Errorf("remount / read-only: %v", err)
+	}
+	for _, w := range bound {
+		if err := mountSetattr(w, atRecursive, &mountAttr{attrClr: mountAttrRdonly}); err != nil {
+			return fmt.Errorf("remount %s read-write: %v", w, err)
+		}
+	}
+	// The old cwd still points at the mount underneath the binds.
+	if err := os.Chdir(wd); err != nil {
+		return err
+	}
+
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return err
+	}
+	if err := syscall.Exec(path, command, os.Environ()); err != nil {
+		return fmt.Errorf("exec %s: %v", command[0], err)
+	}
+	return nil
+}
+
+// mountSetattr changes mount attributes of the mount at path.
+func mountSetattr(path string, flags int, attr *mountAttr) error {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	dirfd := atFdcwd
+	_, _, errno := syscall.Syscall6(sysMountSetattr,
+		uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags),
+		uintptr(unsafe.Pointer(attr)), mountAttrSizeVer, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
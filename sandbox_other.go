@@ -0,0 +1,19 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// sandboxSupported reports that namespaces are Linux-only.
+func sandboxSupported() error {
+	return fmt.Errorf("sandbox: only supported on Linux")
+}
+
+func setSandboxAttr(cmd *exec.Cmd, sb *sandboxConfig) {}
+
+func enterSandbox(writable, command []string) error {
+	return sandboxSupported()
+}
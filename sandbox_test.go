@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveSandbox(t *testing.T) {
+	p := &Pipeline{SandboxNetwork: true}
+	if sb, err := resolveSandbox(p, &Node{}, "/proj", "/proj/.ko/tickets/a.artifacts"); err != nil || sb != nil {
+		t.Fatalf("disabled sandbox = %v, %v; want nil", sb, err)
+	}
+
+	on := true
+	sb, err := resolveSandbox(p, &Node{Sandbox: &on}, "/proj", "/proj/.ko/tickets/a.artifacts")
+	if err != nil || sb == nil {
+		t.Fatalf("node override should enable sandbox, got %v, %v", sb, err)
+	}
+	if !sb.Network {
+		t.Error("network should default to on")
+	}
+	want := dedupPaths([]string{"/proj", "/proj/.ko/tickets/a.artifacts", os.TempDir()})
+	if !reflect.DeepEqual(sb.Writable, want) {
+		t.Errorf("Writable = %v, want %v", sb.Writable, want)
+	}
+
+	off := false
+	p = &Pipeline{Sandbox: true, SandboxWritable: []string{"build/cache", "/var/cache/tool"}}
+	if sb, _ := resolveSandbox(p, &Node{Sandbox: &off}, "/proj", "/proj/.ko"); sb != nil {
+		t.Error("node sandbox: false should opt out")
+	}
+	sb, err = resolveSandbox(p, &Node{}, "/proj", "/proj/.ko")
+	if err != nil {
+		t.Fatalf("resolveSandbox: %v", err)
+	}
+	if sb.Network {
+		t.Error("Network should follow sandbox_network (false)")
+	}
+	for _, w := range []string{"/proj/build/cache", "/var/cache/tool"} {
+		if !contains(sb.Writable, w) {
+			t.Errorf("Writable %v missing %s", sb.Writable, w)
+		}
+	}
+}
+
+func TestDedupPaths(t *testing.T) {
+	got := dedupPaths([]string{"/b/", "/a", "/b", "/a/./c"})
+	want := []string{"/a", "/a/c", "/b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupPaths = %v, want %v", got, want)
+	}
+}
+
+func TestSandboxArgsRoundTrip(t *testing.T) {
+	sb := &sandboxConfig{Writable: []string{"/proj", "/tmp"}}
+	argv := sandboxArgs(sb, "/bin/sh", []string{"-c", "echo --rw"})
+	if argv[0] != "ko" || argv[1] != sandboxExecCmd {
+		t.Fatalf("argv prefix = %v", argv[:2])
+	}
+	writable, command, err := parseSandboxArgs(argv[2:])
+	if err != nil {
+		t.Fatalf("parseSandboxArgs: %v", err)
+	}
+	if !reflect.DeepEqual(writable, sb.Writable) {
+		t.Errorf("writable = %v, want %v", writable, sb.Writable)
+	}
+	if !reflect.DeepEqual(command, []string{"/bin/sh", "-c", "echo --rw"}) {
+		t.Errorf("command = %v", command)
+	}
+}
+
+func TestParseSandboxArgsErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"--rw"},
+		{"--rw", "/proj"},
+		{"--rw", "/proj", "--"},
+		{"--bogus", "--", "sh"},
+	} {
+		if _, _, err := parseSandboxArgs(args); err == nil {
+			t.Errorf("parseSandboxArgs(%v) should fail", args)
+		}
+	}
+}
+
+func TestParsePipelineSandbox(t *testing.T) {
+	config := `
+sandbox: true
+sandbox_network: false
+sandbox_writable:
+  - ~/.cache/go-build
+  - build/
+workflows:
+  main:
+    - name: impl
+      type: action
+      run: make
+      sandbox: false
+`
+	p, err := ParsePipeline(config)
+	if err != nil {
+		t.Fatalf("ParsePipeline failed: %v", err)
+	}
+	if !p.Sandbox || p.SandboxNetwork {
+		t.Errorf("Sandbox = %v, SandboxNetwork = %v; want true, false", p.Sandbox, p.SandboxNetwork)
+	}
+	if !reflect.DeepEqual(p.SandboxWritable, []string{"~/.cache/go-build", "build/"}) {
+		t.Errorf("SandboxWritable = %v", p.SandboxWritable)
+	}
+	n := p.Workflows["main"].Nodes[0]
+	if n.Sandbox == nil || *n.Sandbox {
+		t.Errorf("node Sandbox = %v, want false", n.Sandbox)
+	}
+
+	defaults, err := ParsePipeline(config[len("\nsandbox: true\nsandbox_network: false\n"):])
+	if err != nil {
+		t.Fatalf("ParsePipeline failed: %v", err)
+	}
+	if defaults.Sandbox || !defaults.SandboxNetwork {
+		t.Error("sandbox should default off with network on")
+	}
+}
+
+func TestSandboxCommandNil(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "true")
+	path, args := cmd.Path, append([]string(nil), cmd.Args...)
+	stop, err := sandboxCommand(cmd, nil)
+	if err != nil {
+		t.Fatalf("sandboxCommand(nil): %v", err)
+	}
+	stop()
+	if cmd.Path != path || !reflect.DeepEqual(cmd.Args, args) || cmd.SysProcAttr != nil {
+		t.Error("nil sandbox should leave the command untouched")
+	}
+}
+
+func TestSandboxKoHandler(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	os.MkdirAll(filepath.Join(configHome, "knockout"), 0755)
+	os.WriteFile(filepath.Join(configHome, "knockout", "projects.yml"),
+		[]byte("projects:\n  api:\n    path: /src/api\n    prefix: ap\n  web:\n    path: /src/web\n    prefix: wb\n"), 0644)
+	// Run argv[1] as a script in the directory the handler picked.
+	old := serveExecCommand
+	serveExecCommand = func(ctx context.Context, argv []string) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "sh", "-c", argv[1])
+		if dir, _ := ctx.Value(execDirKey{}).(string); dir != "/src/api" {
+			t.Errorf("dir = %q, want /src/api", dir)
+		}
+		return cmd
+	}
+	t.Cleanup(func() { serveExecCommand = old })
+
+	handler := sandboxKoHandler("/src/api")
+	tests := []struct {
+		name, body string
+		want       int
+	}{
+		{"own project", `{"argv": ["note", "true"]}`, http.StatusOK},
+		{"own ticket", `{"argv": ["close", "true", "ap-a1b2"]}`, http.StatusOK},
+		{"other project", `{"argv": ["add", "true", "--project", "web"]}`, http.StatusForbidden},
+		{"other ticket", `{"argv": ["close", "true", "wb-a1b2"]}`, http.StatusForbidden},
+		{"registry", `{"argv": ["project", "set", "#x"]}`, http.StatusForbidden},
+		{"agent", `{"argv": ["agent", "start"]}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/ko", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: code = %d, want %d (%s)", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
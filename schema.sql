@@ -37,6 +37,7 @@ CREATE TABLE IF NOT EXISTS tickets (
     triage       TEXT,
     created_at   TEXT    NOT NULL,
     updated_at   TEXT    NOT NULL,
+    archived_at  TEXT,
 
     CONSTRAINT valid_status CHECK (status IN (
         'captured', 'routed', 'open', 'in_progress',
@@ -60,6 +61,22 @@ CREATE TABLE IF NOT EXISTS ticket_tags (
 
 CREATE INDEX IF NOT EXISTS idx_ticket_tags_tag ON ticket_tags(tag);
 
+CREATE TABLE IF NOT EXISTS ticket_scopes (
+    ticket_id  TEXT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    glob       TEXT NOT NULL,
+    PRIMARY KEY (ticket_id, glob)
+);
+
+CREATE TABLE IF NOT EXISTS ticket_fields (
+    ticket_id  TEXT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    type       TEXT NOT NULL DEFAULT 'string',
+    value      TEXT NOT NULL,
+    PRIMARY KEY (ticket_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_fields_name_value ON ticket_fields(name, value);
+
 CREATE TABLE IF NOT EXISTS ticket_deps (
     ticket_id  TEXT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
     depends_on TEXT NOT NULL,
@@ -107,7 +124,7 @@ CREATE TABLE IF NOT EXISTS builds (
     completed_at TEXT,
     outcome      TEXT,
 
-    CONSTRAINT valid_outcome CHECK (outcome IN ('succeed', 'fail'))
+    CONSTRAINT valid_outcome CHECK (outcome IN ('succeed', 'fail', 'blocked', 'decompose'))
 );
 
 CREATE INDEX IF NOT EXISTS idx_builds_ticket     ON builds(ticket_id);
@@ -166,6 +183,65 @@ CREATE INDEX IF NOT EXISTS idx_mutation_events_ticket   ON mutation_events(ticke
 CREATE INDEX IF NOT EXISTS idx_mutation_events_type     ON mutation_events(event_type);
 CREATE INDEX IF NOT EXISTS idx_mutation_events_occurred ON mutation_events(occurred_at DESC);
 
+-- Outbound webhook deliveries; pending rows are retried with backoff
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id              INTEGER PRIMARY KEY,
+    target          TEXT    NOT NULL,
+    config_path     TEXT    NOT NULL,
+    event_type      TEXT    NOT NULL,
+    ticket_id       TEXT,
+    body            TEXT    NOT NULL,
+    status          TEXT    NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TEXT    NOT NULL,
+    last_error      TEXT,
+    created_at      TEXT    NOT NULL,
+    delivered_at    TEXT,
+
+    CONSTRAINT valid_delivery_status CHECK (status IN ('pending', 'delivered', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_due
+    ON notification_deliveries(next_attempt_at) WHERE status = 'pending';
+
+-- Write commands queued while the remote server was unreachable
+CREATE TABLE IF NOT EXISTS remote_mutations (
+    id          INTEGER PRIMARY KEY,
+    server      TEXT    NOT NULL,
+    argv        TEXT    NOT NULL,
+    stdin       BLOB,
+    ticket_id   TEXT,
+    status      TEXT    NOT NULL DEFAULT 'pending',
+    force       INTEGER NOT NULL DEFAULT 0,
+    last_error  TEXT,
+    output      TEXT,
+    created_at  TEXT    NOT NULL,
+    applied_at  TEXT,
+
+    CONSTRAINT valid_remote_status CHECK (status IN ('pending', 'applied', 'conflict', 'failed'))
+);
+
+-- Old IDs of tickets moved to another project (ko mv), so they still resolve
+CREATE TABLE IF NOT EXISTS ticket_aliases (
+    old_id    TEXT PRIMARY KEY,
+    new_id    TEXT NOT NULL,
+    moved_at  TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_aliases_new ON ticket_aliases(new_id);
+
+-- Content hashes (git blob IDs) of project files by mtime and size, so a
+-- build can tell a touched file from an edited one without hashing the
+-- whole tree up front
+CREATE TABLE IF NOT EXISTS file_hashes (
+    root   TEXT NOT NULL,
+    path   TEXT NOT NULL,
+    mtime  INTEGER NOT NULL,
+    size   INTEGER NOT NULL,
+    hash   TEXT NOT NULL,
+    PRIMARY KEY (root, path)
+);
+
 -- Ready queue: what the agent should pick up next
 CREATE VIEW IF NOT EXISTS ready_tickets AS
 SELECT t.*
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Scope violation policies for pipeline `scope_violation:`.
+const (
+	ScopeViolationFail   = "fail"   // fail the node, without retries (default)
+	ScopeViolationRevert = "revert" // undo out-of-scope changes and continue
+)
+
+// writeScope is the allowed write scope for one node: the ticket's
+// `scope:` frontmatter and the node's `scope:` property. A changed path
+// must match a glob in every non-empty list.
+type writeScope struct {
+	Ticket []string
+	Node   []string
+}
+
+// Empty reports whether no scope is declared (everything is allowed).
+func (s writeScope) Empty() bool {
+	return len(s.Ticket) == 0 && len(s.Node) == 0
+}
+
+// Allows reports whether a project-relative path is inside the scope.
+func (s writeScope) Allows(path string) bool {
+	for _, globs := range [][]string{s.Ticket, s.Node} {
+		if len(globs) > 0 && !matchAnyScopeGlob(globs, path) {
+			return false
+		}
+	}
+	return true
+}
+
+// scopeViolations returns the changed paths that fall outside the scope,
+// sorted.
+func scopeViolations(cs ChangeSet, s writeScope) []string {
+	if s.Empty() {
+		return nil
+	}
+	var out []string
+	for _, path := range cs.All() {
+		if !s.Allows(path) {
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+// matchAnyScopeGlob matches path against scope globs. Globs are anchored
+// at the project root; "*" stays within a directory, "**" crosses them,
+// and a trailing "/" means everything below that directory.
+func matchAnyScopeGlob(globs []string, path string) bool {
+	for _, g := range globs {
+		g = strings.TrimPrefix(strings.TrimSpace(g), "/")
+		if strings.HasSuffix(g, "/") {
+			g += "**"
+		}
+		re, err := regexp.Compile("^" + globToRegexp(g) + "$")
+		if err != nil {
+			continue
+		}
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitScopeFlag parses a comma-separated --scope value.
+func splitScopeFlag(s string) []string {
+	var globs []string
+	for _, g := range strings.Split(s, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			globs = append(globs, g)
+		}
+	}
+	return globs
+}
+
+// formatScopeList renders scope globs for frontmatter, quoted so that
+// leading "*" is not read as a YAML alias.
+func formatScopeList(globs []string) string {
+	quoted := make([]string, len(globs))
+	for i, g := range globs {
+		quoted[i] = fmt.Sprintf("%q", g)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// scopeSnapshot is the state of the project before a scoped action node
+// ran. Under the revert policy it also holds a copy of every file outside
+// the scope, which is what a revert restores.
+type scopeSnapshot struct {
+	files  fileSnapshot
+	copies string                 // directory of the copies, named by blob ID
+	modes  map[string]os.FileMode // permissions of the copied files, by path
+}
+
+// takeScopeSnapshot snapshots the project before a node runs, or returns
+// nil when the node is not an action node with a write scope. Copies go
+// in a directory under artifactDir, which snapshots skip.
+func takeScopeSnapshot(projectRoot, artifactDir string, t *Ticket, node *Node, p *Pipeline) *scopeSnapshot {
+	scope := writeScope{Ticket: t.Scope, Node: node.Scope}
+	if node.Type != NodeAction || scope.Empty() {
+		return nil
+	}
+	s := &scopeSnapshot{files: snapshotFiles(projectRoot, p.Ignore)}
+	if p.ScopeViolation == ScopeViolationRevert {
+		s.copyOutOfScope(projectRoot, artifactDir, scope)
+	}
+	return s
+}
+
+// copyOutOfScope copies the files outside scope aside and records their
+// hashes. Files it cannot copy are left unrestorable.
+func (s *scopeSnapshot) copyOutOfScope(projectRoot, artifactDir string, scope writeScope) {
+	if err := os.MkdirAll(artifactDir, 0755); err != nil {
+		return
+	}
+	dir, err := os.MkdirTemp(artifactDir, "scope-")
+	if err != nil {
+		return
+	}
+	s.copies, s.modes = dir, map[string]os.FileMode{}
+	for rel, st := range s.files {
+		if scope.Allows(rel) {
+			continue
+		}
+		abs := filepath.Join(projectRoot, filepath.FromSlash(rel))
+		info, err := os.Stat(abs)
+		if err != nil || info.ModTime().UnixNano() != st.ModTime || info.Size() != st.Size {
+			continue
+		}
+		content, err := os.ReadFile(abs)
+		if err != nil {
+			continue
+		}
+		st.Hash = blobID(content)
+		if err := os.WriteFile(filepath.Join(dir, st.Hash), content, 0600); err != nil {
+			continue
+		}
+		s.files[rel] = st
+		s.modes[rel] = info.Mode().Perm()
+	}
+}
+
+// Close removes the copies. It is a no-op on a nil snapshot.
+func (s *scopeSnapshot) Close() {
+	if s != nil && s.copies != "" {
+		os.RemoveAll(s.copies)
+	}
+}
+
+// restore writes the copy of rel back to disk, reporting whether there
+// was one.
+func (s *scopeSnapshot) restore(projectRoot, rel string) bool {
+	mode, ok := s.modes[rel]
+	if !ok {
+		return false
+	}
+	content, err := os.ReadFile(filepath.Join(s.copies, s.files[rel].Hash))
+	if err != nil {
+		return false
+	}
+	abs := filepath.Join(projectRoot, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return false
+	}
+	if err := os.WriteFile(abs, content, mode); err != nil {
+		return false
+	}
+	return os.Chmod(abs, mode) == nil
+}
+
+// revertOutOfScope undoes changes to the given paths. Added files are
+// removed; modified and deleted files are restored from the snapshot's
+// copies. Returns the paths that could not be reverted.
+func revertOutOfScope(projectRoot string, before *scopeSnapshot, cs ChangeSet, paths []string) []string {
+	added := map[string]bool{}
+	for _, p := range cs.Added {
+		added[p] = true
+	}
+
+	var failed []string
+	for _, rel := range paths {
+		abs := filepath.Join(projectRoot, filepath.FromSlash(rel))
+		if added[rel] {
+			if err := os.Remove(abs); err != nil && !os.IsNotExist(err) {
+				failed = append(failed, rel)
+			}
+			continue
+		}
+		if !before.restore(projectRoot, rel) {
+			failed = append(failed, rel)
+		}
+	}
+	return failed
+}
+
+// enforceScope checks the changes an action node made against its write
+// scope. Under the fail policy any violation is an error; under revert the
+// violating changes are undone (with a ticket note) and only paths that
+// could not be reverted are an error.
+func enforceScope(projectRoot string, t *Ticket, node *Node, p *Pipeline, before *scopeSnapshot) error {
+	scope := writeScope{Ticket: t.Scope, Node: node.Scope}
+	cs := computeChanges(projectRoot, p.Ignore, before.files)
+	violations := scopeViolations(cs, scope)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	if p.ScopeViolation == ScopeViolationRevert {
+		failed := revertOutOfScope(projectRoot, before, cs, violations)
+		var reverted []string
+		for _, v := range violations {
+			if !contains(failed, v) {
+				reverted = append(reverted, v)
+			}
+		}
+		if len(reverted) > 0 {
+			AddNote(t, fmt.Sprintf("ko: reverted out-of-scope changes at node '%s': %s",
+				node.Name, strings.Join(reverted, ", ")))
+		}
+		if len(failed) == 0 {
+			return nil
+		}
+		violations = failed
+	}
+	return &scopeViolationError{Files: violations}
+}
+
+// scopeViolationError reports files an attempt changed outside its scope.
+// The files are still on disk, so runNode fails the node instead of
+// retrying against them.
+type scopeViolationError struct {
+	Files []string
+}
+
+func (e *scopeViolationError) Error() string {
+	return "changed files outside allowed scope: " + strings.Join(e.Files, ", ")
+}
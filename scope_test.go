@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteScopeAllows(t *testing.T) {
+	s := writeScope{Ticket: []string{"src/api/**", "docs/"}}
+	tests := map[string]bool{
+		"src/api/handler.go":     true,
+		"src/api/v1/routes.go":   true,
+		"docs/guide.md":          true,
+		"docs/img/logo.png":      true,
+		"src/web/app.go":         false,
+		"README.md":              false,
+		"other/src/api/thing.go": false,
+	}
+	for path, want := range tests {
+		if got := s.Allows(path); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestWriteScopeIntersectsTicketAndNode(t *testing.T) {
+	s := writeScope{Ticket: []string{"src/**"}, Node: []string{"src/*.go", "*.md"}}
+	if !s.Allows("src/main.go") {
+		t.Error("path in both scopes should be allowed")
+	}
+	if s.Allows("src/sub/main.go") {
+		t.Error("path outside node scope should be rejected")
+	}
+	if s.Allows("README.md") {
+		t.Error("path outside ticket scope should be rejected")
+	}
+	if !(writeScope{}).Allows("anything") {
+		t.Error("empty scope should allow everything")
+	}
+}
+
+func TestScopeViolations(t *testing.T) {
+	cs := ChangeSet{
+		Added:    []string{"src/new.go", "scratch.txt"},
+		Modified: []string{"src/main.go", "go.mod"},
+		Deleted:  []string{"lib/old.go"},
+	}
+	got := scopeViolations(cs, writeScope{Ticket: []string{"src/**"}})
+	want := []string{"go.mod", "lib/old.go", "scratch.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scopeViolations = %v, want %v", got, want)
+	}
+	if got := scopeViolations(cs, writeScope{}); got != nil {
+		t.Errorf("empty scope violations = %v, want nil", got)
+	}
+}
+
+func TestSplitScopeFlag(t *testing.T) {
+	got := splitScopeFlag(" src/api/** , docs/**,,")
+	want := []string{"src/api/**", "docs/**"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitScopeFlag = %v, want %v", got, want)
+	}
+}
+
+func TestTicketScopeRoundTrip(t *testing.T) {
+	in := &Ticket{ID: "ko-a001", Status: "open", Type: "task", Priority: 2, Title: "Scoped",
+		Scope: []string{"src/api/**", "*.md"}}
+	out, err := ParseTicket(FormatTicket(in))
+	if err != nil {
+		t.Fatalf("ParseTicket: %v", err)
+	}
+	if !reflect.DeepEqual(out.Scope, in.Scope) {
+		t.Errorf("Scope round trip = %v, want %v", out.Scope, in.Scope)
+	}
+}
+
+func TestRevertOutOfScope(t *testing.T) {
+	defer setupTestDB(t)()
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "src"), 0755)
+	os.WriteFile(filepath.Join(root, "config.txt"), []byte("original\n"), 0644)
+	os.WriteFile(filepath.Join(root, "gone.txt"), []byte("keep me\n"), 0644)
+	os.WriteFile(filepath.Join(root, "run.sh"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("package main\n"), 0644)
+
+	tk := &Ticket{Scope: []string{"src/**"}}
+	node := &Node{Type: NodeAction}
+	before := takeScopeSnapshot(root, filepath.Join(root, ".ko", "a.artifacts"), tk, node, &Pipeline{ScopeViolation: ScopeViolationRevert})
+	defer before.Close()
+
+	os.WriteFile(filepath.Join(root, "config.txt"), []byte("changed\n"), 0644)
+	os.Remove(filepath.Join(root, "gone.txt"))
+	os.Remove(filepath.Join(root, "run.sh"))
+	os.WriteFile(filepath.Join(root, "added.txt"), []byte("new\n"), 0644)
+	os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("package other\n"), 0644)
+
+	cs := computeChanges(root, nil, before.files)
+	failed := revertOutOfScope(root, before, cs, cs.All())
+
+	// Files inside the scope are not copied, so they cannot be restored
+	if !reflect.DeepEqual(failed, []string{"src/main.go"}) {
+		t.Errorf("unrevertable = %v, want [src/main.go]", failed)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "config.txt")); string(data) != "original\n" {
+		t.Errorf("config.txt = %q, want restored", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "gone.txt")); string(data) != "keep me\n" {
+		t.Errorf("gone.txt = %q, want restored", data)
+	}
+	if info, err := os.Stat(filepath.Join(root, "run.sh")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("run.sh should be restored executable: %v, %v", info, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "added.txt")); !os.IsNotExist(err) {
+		t.Error("added.txt should be removed")
+	}
+
+	copies := before.copies
+	before.Close()
+	if _, err := os.Stat(copies); !os.IsNotExist(err) {
+		t.Error("Close should remove the copies")
+	}
+}
+
+func TestTakeScopeSnapshot(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("a\n"), 0644)
+	tk := &Ticket{Scope: []string{"src/**"}}
+
+	if s := takeScopeSnapshot(root, t.TempDir(), &Ticket{}, &Node{Type: NodeAction}, &Pipeline{}); s != nil {
+		t.Error("unscoped node should get no snapshot")
+	}
+	if s := takeScopeSnapshot(root, t.TempDir(), tk, &Node{Type: NodeDecision}, &Pipeline{}); s != nil {
+		t.Error("decision node should get no snapshot")
+	}
+	s := takeScopeSnapshot(root, t.TempDir(), tk, &Node{Type: NodeAction}, &Pipeline{})
+	if s == nil || s.copies != "" || s.files["a.txt"].Hash != "" {
+		t.Errorf("fail policy should not copy files: %+v", s)
+	}
+}
+
+func TestParsePipelineScope(t *testing.T) {
+	config := `
+scope_violation: revert
+workflows:
+  main:
+    - name: impl
+      type: action
+      prompt: impl.md
+      scope: ["src/**", docs/]
+`
+	p, err := ParsePipeline(config)
+	if err != nil {
+		t.Fatalf("ParsePipeline failed: %v", err)
+	}
+	if p.ScopeViolation != ScopeViolationRevert {
+		t.Errorf("ScopeViolation = %q, want revert", p.ScopeViolation)
+	}
+	scope := p.Workflows["main"].Nodes[0].Scope
+	if len(scope) != 2 || scope[0] != "src/**" || scope[1] != "docs/" {
+		t.Errorf("node Scope = %v, want [src/** docs/]", scope)
+	}
+
+	if _, err := ParsePipeline("scope_violation: ignore\n" + config[strings.Index(config, "workflows:"):]); err == nil {
+		t.Error("expected error for invalid scope_violation")
+	}
+}
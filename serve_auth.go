@@ -0,0 +1,470 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ServeToken is one bearer token from the tokens: section of the global
+// config. ko serve requires one as soon as any are configured.
+type ServeToken struct {
+	Name     string   // shown in logs; unique
+	Token    string   // the secret; may reference $VAR or ${secrets.NAME}
+	Scopes   []string // read, write (implies read), agent
+	Projects []string // registry tags the token may touch; empty means all
+}
+
+// serveScopes are the scopes a token may grant.
+var serveScopes = map[string]bool{"read": true, "write": true, "agent": true}
+
+// parseServeTokens parses the top-level tokens: section of a config file.
+func parseServeTokens(content string) ([]ServeToken, error) {
+	n, err := yamlSection(content, "tokens")
+	if err != nil {
+		return nil, err
+	}
+	return decodeServeTokens(&yamlDecoder{}, n)
+}
+
+// decodeServeTokens reads the list of a tokens: section.
+func decodeServeTokens(d *yamlDecoder, n *yamlNode) ([]ServeToken, error) {
+	items, err := yamlItems(n, "tokens")
+	if err != nil {
+		return nil, err
+	}
+	var tokens []ServeToken
+	for _, item := range items {
+		entries, err := d.entries(item, "tokens")
+		if err != nil {
+			return nil, err
+		}
+		var t ServeToken
+		for _, e := range entries {
+			what := "tokens." + e.Key.Value
+			switch e.Key.Value {
+			case "name":
+				t.Name, err = yamlString(e.Value, what)
+			case "token":
+				t.Token, err = yamlString(e.Value, what)
+			case "scopes":
+				t.Scopes, err = yamlStrings(e.Value, what)
+			case "projects":
+				t.Projects, err = yamlStrings(e.Value, what)
+			default:
+				return nil, yamlErrorf(e.Key, "tokens: unknown key %q", e.Key.Value)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		for i, p := range t.Projects {
+			t.Projects[i] = strings.TrimPrefix(p, "#")
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, validateServeTokens(tokens)
+}
+
+// validateServeTokens checks names, secrets and scopes.
+func validateServeTokens(tokens []ServeToken) error {
+	seen := map[string]bool{}
+	for _, t := range tokens {
+		if t.Name == "" {
+			return fmt.Errorf("tokens: every token needs a name")
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("tokens: duplicate token %q", t.Name)
+		}
+		seen[t.Name] = true
+		if t.Token == "" {
+			return fmt.Errorf("tokens: %s: token is required", t.Name)
+		}
+		if len(t.Scopes) == 0 {
+			return fmt.Errorf("tokens: %s: at least one scope is required", t.Name)
+		}
+		for _, s := range t.Scopes {
+			if !serveScopes[s] {
+				return fmt.Errorf("tokens: %s: unknown scope %q (want read, write or agent)", t.Name, s)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveGlobalSecret expands $VAR and ${secrets.NAME} in a global config
+// value, reading secrets from secrets.env next to the global config.
+func resolveGlobalSecret(val string) (string, error) {
+	if !strings.Contains(val, "$") {
+		return val, nil
+	}
+	secrets, err := loadEnvFile(filepath.Join(filepath.Dir(GlobalConfigPath()), secretsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("%s: %v", secretsFile, err)
+	}
+	return interpolateEnv(val, secrets)
+}
+
+// serveAccess is what a request needs from a token.
+type serveAccess struct {
+	Scope    string   // "" when the endpoint needs no token
+	Projects []string // registry tags touched; "" for an unregistered project
+	Global   bool     // not tied to a project (e.g. listing projects)
+}
+
+// allows reports whether t grants access.
+func (t ServeToken) allows(a serveAccess) bool {
+	granted := false
+	for _, s := range t.Scopes {
+		if s == a.Scope || (s == "write" && a.Scope == "read") {
+			granted = true
+		}
+	}
+	if !granted {
+		return false
+	}
+	if len(t.Projects) == 0 || a.Global {
+		return true
+	}
+	if len(a.Projects) == 0 {
+		return false
+	}
+	for _, p := range a.Projects {
+		if !contains(t.Projects, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// koReadCommands are the /ko subcommands that only need the read scope.
+var koReadCommands = map[string]bool{"show": true, "ls": true, "ready": true}
+
+// koCommandAccess works out the scope and projects of a /ko argv. Projects
+// come from #tag and project flag arguments and from ticket ID prefixes;
+// without any, the command runs in localTag, the server's own project.
+// The project flag is matched in every form Go's flag package accepts
+// (-project, --project, with = or a separate value), as commands parsing
+// it with a FlagSet would honour any of them.
+func koCommandAccess(argv []string, reg *Registry, localTag string) serveAccess {
+	cmd, args := argv[0], argv[1:]
+	var positional []string
+	a := serveAccess{Scope: "write"}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "#"):
+			a.Projects = append(a.Projects, CleanTag(arg))
+		case strings.HasPrefix(arg, "-"):
+			name, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+			if name != "project" {
+				continue
+			}
+			if !hasValue && i+1 < len(args) {
+				value = args[i+1]
+				i++
+			}
+			a.Projects = append(a.Projects, CleanTag(value))
+		default:
+			positional = append(positional, arg)
+			a.Projects = append(a.Projects, idTags(reg, arg)...)
+		}
+	}
+
+	switch {
+	case cmd == "agent":
+		a.Scope = "agent"
+	case koReadCommands[cmd], cmd == "triage" && len(positional) < 2:
+		a.Scope = "read"
+	case cmd == "project":
+		// The registry is shared: listing it is harmless, changing it
+		// needs a token without project restrictions.
+		if len(positional) > 0 && positional[0] == "ls" {
+			return serveAccess{Scope: "read", Global: true}
+		}
+		return serveAccess{Scope: "write"}
+	}
+	if len(a.Projects) == 0 {
+		a.Projects = []string{localTag}
+	}
+	return a
+}
+
+// prefixTags returns the registry tags whose prefix matches a ticket ID.
+func prefixTags(reg *Registry, id string) []string {
+	prefix := extractPrefix(id)
+	if prefix == "" {
+		return nil
+	}
+	var tags []string
+	for tag, p := range reg.Prefixes {
+		if p == prefix {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// idTags returns the registry tags a ticket ID touches: those of its
+// prefix and, when it is the old ID of a ticket moved by ko mv, those of
+// the ID it now resolves to.
+func idTags(reg *Registry, id string) []string {
+	tags := prefixTags(reg, id)
+	if db := getShadowDB(); db != nil {
+		if newID, ok := db.aliasOf(id); ok {
+			tags = append(tags, prefixTags(reg, newID)...)
+		}
+	}
+	return tags
+}
+
+// registryTag maps a #tag, bare tag or project path to its registry tag,
+// or "" when the project is not registered.
+func registryTag(reg *Registry, project string) string {
+	if project == "" {
+		return ""
+	}
+	if tag := CleanTag(project); reg.Projects[tag] != "" {
+		return tag
+	}
+	clean := filepath.Clean(project)
+	for tag, path := range reg.Projects {
+		if filepath.Clean(path) == clean {
+			return tag
+		}
+	}
+	return ""
+}
+
+// localRegistryTag is the registry tag of the project ko serve runs in.
+func localRegistryTag(reg *Registry) string {
+	if ticketsDir, err := FindTicketsDir(); err == nil {
+		return registryTag(reg, ProjectRoot(ticketsDir))
+	}
+	return ""
+}
+
+// peekExecArgv returns the argv of a /ko request, or nil when the request
+// is malformed, and restores the body for the handler.
+func peekExecArgv(r *http.Request) ([]string, error) {
+	var body []byte
+	var err error
+	if r.Header.Get("Content-Type") == execStreamType {
+		// Only the header line: stdin may still be streaming in.
+		br := bufio.NewReader(r.Body)
+		body, err = readExecHeaderLine(br)
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), br), r.Body}
+	} else {
+		body, err = readLimited(r, apiMaxBody)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var req struct {
+		Argv []string `json:"argv"`
+	}
+	if json.Unmarshal(body, &req) != nil || len(req.Argv) == 0 {
+		return nil, nil
+	}
+	return req.Argv, nil
+}
+
+// requestAccess works out what a ko serve request needs. Bodies it has to
+// look into are restored for the handler.
+func requestAccess(r *http.Request, reg *Registry) (serveAccess, error) {
+	path := r.URL.Path
+	peekBody := func() ([]byte, error) {
+		body, err := readLimited(r, apiMaxBody)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		return body, err
+	}
+	switch {
+	case path == "/ko":
+		argv, err := peekExecArgv(r)
+		if err != nil {
+			return serveAccess{}, err
+		}
+		if argv == nil {
+			// Malformed; the handler reports it to authenticated callers.
+			return serveAccess{Scope: "read", Global: true}, nil
+		}
+		return koCommandAccess(argv, reg, localRegistryTag(reg)), nil
+
+	case strings.HasPrefix(path, "/builds/"):
+		id, _, _ := strings.Cut(strings.TrimPrefix(path, "/builds/"), "/")
+		projects := idTags(reg, id)
+		if len(projects) == 0 {
+			projects = []string{localRegistryTag(reg)}
+		}
+		return serveAccess{Scope: "read", Projects: projects}, nil
+
+	case strings.HasPrefix(path, "/agent/"):
+		project := r.URL.Query().Get("project")
+		if r.Method == http.MethodPost {
+			body, err := peekBody()
+			if err != nil {
+				return serveAccess{}, err
+			}
+			var req struct {
+				Project string `json:"project"`
+			}
+			json.Unmarshal(body, &req)
+			project = req.Project
+		}
+		return serveAccess{Scope: "agent", Projects: []string{registryTag(reg, project)}}, nil
+
+	case strings.HasPrefix(path, "/subscribe/"), strings.HasPrefix(path, "/status/"):
+		project := r.URL.Query().Get("project")
+		if project == "" {
+			_, project, _ = strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		}
+		return serveAccess{Scope: "read", Projects: []string{registryTag(reg, project)}}, nil
+
+	case strings.HasPrefix(path, apiPrefix+"/"):
+		a := serveAccess{Scope: "write"}
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			a.Scope = "read"
+		}
+		parts := strings.Split(strings.TrimPrefix(path, apiPrefix+"/"), "/")
+		if parts[0] == "projects" && len(parts) > 1 {
+			a.Projects = []string{registryTag(reg, parts[1])}
+		} else {
+			// /api/v1/projects is filtered to the token's projects.
+			a.Global = true
+		}
+		return a, nil
+	}
+	return serveAccess{}, nil
+}
+
+// bearerToken returns the token a request presents: an Authorization
+// header, or ?access_token= for EventSource clients that cannot set one.
+func bearerToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+// matchServeToken returns the configured token equal to presented.
+func matchServeToken(tokens []ServeToken, presented string) *ServeToken {
+	if presented == "" {
+		return nil
+	}
+	for i := range tokens {
+		if subtle.ConstantTimeCompare([]byte(tokens[i].Token), []byte(presented)) == 1 {
+			return &tokens[i]
+		}
+	}
+	return nil
+}
+
+// serveTokenKey is the context key of the token that authorised a request.
+type serveTokenKey struct{}
+
+// requestToken returns the token that authorised r, or nil when ko serve
+// runs without tokens.
+func requestToken(r *http.Request) *ServeToken {
+	t, _ := r.Context().Value(serveTokenKey{}).(*ServeToken)
+	return t
+}
+
+// loadServeTokens reads the tokens from the global config with their
+// secrets expanded.
+func loadServeTokens() ([]ServeToken, error) {
+	global, err := LoadGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
+	tokens := global.Tokens
+	for i := range tokens {
+		if tokens[i].Token, err = resolveGlobalSecret(tokens[i].Token); err != nil {
+			return nil, fmt.Errorf("token %s: %v", tokens[i].Name, err)
+		}
+	}
+	return tokens, nil
+}
+
+// requireToken guards next with the tokens from the global config. The
+// config is re-read on every request, so tokens can be rotated without
+// restarting ko serve. Without tokens every request is let through.
+func requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens, err := loadServeTokens()
+		if err != nil {
+			writeAuthError(w, r, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		if len(tokens) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		reg, err := LoadRegistry(RegistryPath())
+		if err != nil {
+			writeAuthError(w, r, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		access, err := requestAccess(r, reg)
+		if err != nil {
+			writeAuthError(w, r, http.StatusRequestEntityTooLarge, "bad_request", err.Error())
+			return
+		}
+		if access.Scope == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tok := matchServeToken(tokens, bearerToken(r))
+		if tok == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ko"`)
+			writeAuthError(w, r, http.StatusUnauthorized, "unauthorized", "missing or invalid token")
+			return
+		}
+		if !tok.allows(access) {
+			writeAuthError(w, r, http.StatusForbidden, "forbidden",
+				fmt.Sprintf("token %s does not grant %s on %s", tok.Name, access.Scope, describeProjects(access.Projects)))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), serveTokenKey{}, tok)))
+	})
+}
+
+// describeProjects names the projects of a denied request.
+func describeProjects(projects []string) string {
+	var names []string
+	for _, p := range projects {
+		if p == "" {
+			p = "an unregistered project"
+		} else {
+			p = "#" + p
+		}
+		names = append(names, p)
+	}
+	if len(names) == 0 {
+		return "every project"
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeAuthError answers API requests with the API error envelope and
+// everything else with {"error": message}, which remote clients print.
+func writeAuthError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+		writeAPIError(w, &apiError{status, code, message})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": "ko serve: " + message + "\n"})
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const serveTokensConfig = `
+server: https://ko.example.com
+token: $KO_TOKEN
+tokens:
+  - name: admin
+    token: ${secrets.ADMIN_TOKEN}
+    scopes: [read, write, agent]
+  - name: ci
+    token: ci-token  # read-only dashboards
+    scopes:
+      - read
+    projects: ["#api"]
+  - name: bot
+    token: bot-token
+    scopes: [write]
+    projects: [api]
+`
+
+func TestParseServeTokens(t *testing.T) {
+	g, err := ParseGlobalConfig(serveTokensConfig)
+	if err != nil {
+		t.Fatalf("ParseGlobalConfig: %v", err)
+	}
+	if g.Server != "https://ko.example.com" || g.Token != "$KO_TOKEN" || len(g.Tokens) != 3 {
+		t.Fatalf("global = %+v", g)
+	}
+	ci := g.Tokens[1]
+	if ci.Token != "ci-token" || strings.Join(ci.Scopes, ",") != "read" || strings.Join(ci.Projects, ",") != "api" {
+		t.Errorf("ci = %+v", ci)
+	}
+
+	for _, bad := range []struct{ config, wantErr string }{
+		{"tokens:\n  - token: x\n    scopes: [read]\n", "needs a name"},
+		{"tokens:\n  - name: a\n    scopes: [read]\n", "token is required"},
+		{"tokens:\n  - name: a\n    token: x\n", "at least one scope"},
+		{"tokens:\n  - name: a\n    token: x\n    scopes: [admin]\n", `unknown scope "admin"`},
+		{"tokens:\n  - name: a\n    token: x\n    scopes: [read]\n    expires: never\n", `unknown key "expires"`},
+	} {
+		if _, err := parseServeTokens(bad.config); err == nil || !strings.Contains(err.Error(), bad.wantErr) {
+			t.Errorf("parseServeTokens(%q) err = %v, want %q", bad.config, err, bad.wantErr)
+		}
+	}
+}
+
+func TestServeTokenAllows(t *testing.T) {
+	admin := ServeToken{Scopes: []string{"read", "write", "agent"}}
+	bot := ServeToken{Scopes: []string{"write"}, Projects: []string{"api"}}
+	tests := []struct {
+		token  ServeToken
+		access serveAccess
+		want   bool
+	}{
+		{admin, serveAccess{Scope: "agent", Projects: []string{""}}, true},
+		{bot, serveAccess{Scope: "read", Projects: []string{"api"}}, true},
+		{bot, serveAccess{Scope: "write", Projects: []string{"api", "web"}}, false},
+		{bot, serveAccess{Scope: "agent", Projects: []string{"api"}}, false},
+		{bot, serveAccess{Scope: "write", Projects: []string{""}}, false},
+		{bot, serveAccess{Scope: "write"}, false},
+		{bot, serveAccess{Scope: "read", Global: true}, true},
+	}
+	for i, tt := range tests {
+		if got := tt.token.allows(tt.access); got != tt.want {
+			t.Errorf("case %d: allows(%+v) = %v, want %v", i, tt.access, got, tt.want)
+		}
+	}
+}
+
+func TestKoCommandAccess(t *testing.T) {
+	reg := &Registry{
+		Projects: map[string]string{"api": "/src/api", "web": "/src/web"},
+		Prefixes: map[string]string{"api": "ap", "web": "wb"},
+	}
+	tests := []struct {
+		argv         []string
+		wantScope    string
+		wantProjects string
+	}{
+		{[]string{"ls", "#api"}, "read", "api"},
+		{[]string{"show", "wb-a1b2"}, "read", "web"},
+		{[]string{"ls"}, "read", "local"},
+		{[]string{"add", "Fix login", "--project", "web"}, "write", "web"},
+		{[]string{"dep", "ap-a1b2", "wb-c3d4"}, "write", "api,web"},
+		{[]string{"triage", "--json"}, "read", "local"},
+		{[]string{"triage", "ap-a1b2", "check", "logs"}, "write", "api"},
+		{[]string{"agent", "start", "--project=#api"}, "agent", "api"},
+		{[]string{"add", "-project=web", "Fix login"}, "write", "web"},
+		{[]string{"add", "-project", "web", "Fix login"}, "write", "web"},
+		{[]string{"project", "set", "#x"}, "write", ""},
+	}
+	for _, tt := range tests {
+		a := koCommandAccess(tt.argv, reg, "local")
+		if a.Scope != tt.wantScope || strings.Join(a.Projects, ",") != tt.wantProjects {
+			t.Errorf("koCommandAccess(%v) = %+v, want %s on %s", tt.argv, a, tt.wantScope, tt.wantProjects)
+		}
+	}
+	if a := koCommandAccess([]string{"project", "ls", "--json"}, reg, ""); !a.Global || a.Scope != "read" {
+		t.Errorf("project ls = %+v", a)
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	os.MkdirAll(filepath.Join(configHome, "knockout"), 0755)
+	os.WriteFile(filepath.Join(configHome, "knockout", "projects.yml"),
+		[]byte("projects:\n  api:\n    path: /src/api\n    prefix: ap\n  web:\n    path: /src/web\n    prefix: wb\n"), 0644)
+
+	var reached *http.Request
+	handler := requireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = r
+	}))
+	send := func(method, target, token, body string) int {
+		reached = nil
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Without tokens, everything is open
+	if code := send(http.MethodPost, "/agent/spawn", "", `{"project": "#api"}`); code != http.StatusOK || reached == nil {
+		t.Fatalf("open server: code = %d", code)
+	}
+
+	os.WriteFile(filepath.Join(configHome, "knockout", "config.yaml"), []byte(serveTokensConfig), 0644)
+	os.WriteFile(filepath.Join(configHome, "knockout", secretsFile), []byte("ADMIN_TOKEN=root\n"), 0600)
+
+	// ap-c3d4 was moved to web and still resolves there
+	if _, err := getShadowDB().db.Exec("INSERT INTO ticket_aliases (old_id, new_id, moved_at) VALUES ('ap-c3d4', 'wb-c3d4', '2026-01-01T00:00:00Z')"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, method, target, token, body string
+		want                              int
+	}{
+		{"no token", http.MethodPost, "/ko", "", `{"argv": ["ls", "#api"]}`, http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "/ko", "nope", `{"argv": ["ls", "#api"]}`, http.StatusUnauthorized},
+		{"admin from secrets", http.MethodPost, "/agent/spawn", "root", `{"project": "#web"}`, http.StatusOK},
+		{"read token reads", http.MethodPost, "/ko", "ci-token", `{"argv": ["show", "ap-a1b2"]}`, http.StatusOK},
+		{"read token cannot write", http.MethodPost, "/ko", "ci-token", `{"argv": ["close", "ap-a1b2"]}`, http.StatusForbidden},
+		{"other project", http.MethodPost, "/ko", "ci-token", `{"argv": ["ls", "#web"]}`, http.StatusForbidden},
+		{"write cannot spawn", http.MethodPost, "/agent/spawn", "bot-token", `{"project": "#api"}`, http.StatusForbidden},
+		{"subscribe by tag", http.MethodGet, "/subscribe/%23api", "ci-token", "", http.StatusOK},
+		{"status by path", http.MethodGet, "/status/?project=/src/web&access_token=ci-token", "", "", http.StatusForbidden},
+		{"status query token", http.MethodGet, "/status/%23api?access_token=ci-token", "", "", http.StatusOK},
+		{"api write", http.MethodPatch, "/api/v1/projects/api/tickets/ap-a1b2", "bot-token", "{}", http.StatusOK},
+		{"api read-only", http.MethodPost, "/api/v1/projects/api/tickets", "ci-token", "{}", http.StatusForbidden},
+		{"build stream", http.MethodGet, "/builds/ap-a1b2/stream", "ci-token", "", http.StatusOK},
+		{"build stream of other project", http.MethodGet, "/builds/wb-a1b2/stream?access_token=ci-token", "", "", http.StatusForbidden},
+		{"moved ticket by old ID", http.MethodPost, "/ko", "bot-token", `{"argv": ["close", "ap-c3d4"]}`, http.StatusForbidden},
+		{"build stream of moved ticket", http.MethodGet, "/builds/ap-c3d4/stream", "ci-token", "", http.StatusForbidden},
+		{"web UI is open", http.MethodGet, "/", "", "", http.StatusOK},
+		{"hooks use their own secret", http.MethodPost, "/hooks/alertmanager", "", "{}", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := send(tt.method, tt.target, tt.token, tt.body); code != tt.want {
+			t.Errorf("%s: code = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+
+	// The handler still sees the body and the token
+	send(http.MethodPost, "/agent/spawn", "root", `{"project": "#api"}`)
+	if reached == nil || requestToken(reached) == nil || requestToken(reached).Name != "admin" {
+		t.Fatalf("token not passed to handler")
+	}
+	if body, _ := readLimited(reached, 1024); string(body) != `{"project": "#api"}` {
+		t.Errorf("body = %q", body)
+	}
+
+	// Streamed /ko requests are judged by their header line alone
+	streamed := "{\"argv\": [\"note\", \"ap-a1b2\"], \"stdin\": true}\nnote text\n"
+	r := httptest.NewRequest(http.MethodPost, "/ko", strings.NewReader(streamed))
+	r.Header.Set("Content-Type", execStreamType)
+	r.Header.Set("Authorization", "Bearer ci-token")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("streamed note with read token: code = %d", w.Code)
+	}
+	r = httptest.NewRequest(http.MethodPost, "/ko", strings.NewReader(streamed))
+	r.Header.Set("Content-Type", execStreamType)
+	r.Header.Set("Authorization", "Bearer bot-token")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if reached == nil {
+		t.Fatalf("streamed note with write token was rejected")
+	}
+	if body, _ := readLimited(reached, 1024); string(body) != streamed {
+		t.Errorf("streamed body = %q", body)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// buildStreamPoll is how often /builds/<id>/stream checks for new output,
+// matching the event tailer.
+const buildStreamPoll = 200 * time.Millisecond
+
+// lineTail reads the complete lines appended to a file since the last
+// call. A file that shrank was truncated and is read from the start.
+type lineTail struct {
+	path    string
+	offset  int64
+	partial []byte
+}
+
+func (t *lineTail) next() []string {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() < t.offset {
+		t.offset, t.partial = 0, nil
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil
+	}
+	data, _ := io.ReadAll(f)
+	t.offset += int64(len(data))
+	t.partial = append(t.partial, data...)
+
+	var lines []string
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if i > 0 {
+			lines = append(lines, string(t.partial[:i]))
+		}
+		t.partial = t.partial[i+1:]
+	}
+	return lines
+}
+
+// buildStream is one /builds/<id>/stream client.
+type buildStream struct {
+	w       io.Writer
+	flusher http.Flusher
+	eventID int64
+}
+
+// send writes one SSE event. Multi-line data is split per the SSE spec.
+func (s *buildStream) send(event, data string) {
+	s.eventID++
+	fmt.Fprintf(s.w, "id: %d\nevent: %s\n", s.eventID, event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(s.w, "data: %s\n", line)
+	}
+	fmt.Fprint(s.w, "\n")
+	s.flusher.Flush()
+}
+
+// historyEventName returns the event field of a build history line.
+func historyEventName(line string) string {
+	var evt struct {
+		Event string `json:"event"`
+	}
+	json.Unmarshal([]byte(line), &evt)
+	return evt.Event
+}
+
+// currentBuildHistory returns the history lines of the ticket's latest
+// build, up to and including build_complete if it has finished. It
+// returns nil when the ticket has never been built.
+func currentBuildHistory(lines []string) []string {
+	start := -1
+	for i, line := range lines {
+		if historyEventName(line) == "build_start" {
+			start = i
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+	build := lines[start:]
+	for i, line := range build {
+		if historyEventName(line) == "build_complete" {
+			return build[:i+1]
+		}
+	}
+	return build
+}
+
+// handleBuildStream serves GET /builds/<id>/stream: the running build's
+// output as "output" events, one per "[node] " prefixed line, interleaved
+// with "history" events (node_start, node_complete, ...) from its build
+// history. A client that connects mid-build first gets everything so far.
+// The stream ends with a "done" event carrying the build_complete record.
+// Between builds it waits for the next one to start.
+func handleBuildStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/builds/"), "/stream")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		http.Error(w, "want /builds/<ticket>/stream", http.StatusNotFound)
+		return
+	}
+	localDir, _ := FindTicketsDir()
+	ticketsDir, id, err := ResolveTicket(localDir, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	s := &buildStream{w: w, flusher: flusher}
+	history := &lineTail{path: BuildHistoryPath(ticketsDir, id)}
+	live := &lineTail{path: LiveLogPath(ArtifactDir(ticketsDir, id))}
+
+	// Catch up on the latest build; a finished one is replayed and closed.
+	running := false
+	if build := currentBuildHistory(history.next()); build != nil {
+		running = true
+		for _, line := range build {
+			if historyEventName(line) == "build_complete" {
+				s.sendOutput(live)
+				s.send("done", line)
+				return
+			}
+			s.send("history", line)
+		}
+		s.sendOutput(live)
+	}
+
+	ticker := time.NewTicker(buildStreamPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		if running {
+			s.sendOutput(live)
+		}
+		for _, line := range history.next() {
+			switch historyEventName(line) {
+			case "build_start":
+				// The live log was truncated before build_start was written.
+				running = true
+				live.offset, live.partial = 0, nil
+			case "build_complete":
+				if running {
+					s.sendOutput(live)
+					s.send("done", line)
+					return
+				}
+			}
+			if running {
+				s.send("history", line)
+			}
+		}
+	}
+}
+
+// sendOutput sends the lines added to the live log since the last call.
+func (s *buildStream) sendOutput(live *lineTail) {
+	for _, line := range live.next() {
+		s.send("output", line)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readSSE collects "event: data" pairs from an SSE response until it ends
+// or n events have arrived.
+func readSSE(t *testing.T, resp *http.Response, n int) []string {
+	t.Helper()
+	var events []string
+	var event string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() && len(events) < n {
+		line := sc.Text()
+		if v, ok := strings.CutPrefix(line, "event: "); ok {
+			event = v
+		} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+			events = append(events, event+": "+v)
+		}
+	}
+	return events
+}
+
+func TestHandleBuildStream(t *testing.T) {
+	defer setupTestDB(t)()
+
+	ticketsDir := filepath.Join(t.TempDir(), ".ko", "tickets")
+	os.MkdirAll(ticketsDir, 0755)
+	t.Setenv("TICKETS_DIR", ticketsDir)
+	ticket := &Ticket{ID: "ko-b001", Title: "Streamed", Status: "in_progress", Type: "task", Priority: 2}
+	if err := SaveTicket(ticketsDir, ticket); err != nil {
+		t.Fatalf("SaveTicket: %v", err)
+	}
+	artifactDir, _ := EnsureArtifactDir(ticketsDir, ticket.ID)
+
+	// A build is under way when the client connects
+	outs, _ := StartBuildOutputs(artifactDir, &Pipeline{}, &BuildHistoryLogger{})
+	defer outs.Close()
+	hist, _ := OpenBuildHistory(ticketsDir, ticket.ID)
+	defer hist.Close()
+	hist.BuildStart(ticket.ID)
+	hist.NodeStart(ticket.ID, "main", "implement")
+	out := outs.NewAttempt("implement")
+	stdout, _ := out.writers()
+	stdout.Write([]byte("step one\n"))
+	out.live.close()
+
+	srv := httptest.NewServer(http.HandlerFunc(handleBuildStream))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/builds/b001/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	// More output and the end of the build arrive while it is connected
+	go func() {
+		time.Sleep(2 * buildStreamPoll)
+		out := outs.NewAttempt("verify")
+		stdout, _ := out.writers()
+		hist.NodeComplete(ticket.ID, "main", "implement", "ok")
+		hist.NodeStart(ticket.ID, "main", "verify")
+		// Output and history seen in the same poll come output first
+		time.Sleep(2 * buildStreamPoll)
+		stdout.Write([]byte("step two\n"))
+		outs.Record("verify", out)
+		hist.BuildComplete(ticket.ID, "succeed")
+	}()
+
+	got := readSSE(t, resp, 10)
+	want := []string{
+		"history: build_start",
+		"history: node_start implement",
+		"output: [implement] step one",
+		"history: node_complete implement",
+		"history: node_start verify",
+		"output: [verify] step two",
+		"done: build_complete",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events:\n%s", strings.Join(got, "\n"))
+	}
+	for i, w := range want {
+		kind, text, _ := strings.Cut(w, " ")
+		if !strings.HasPrefix(got[i], kind) || !containsAll(got[i], strings.Fields(text)) {
+			t.Errorf("event %d = %q, want %q", i, got[i], w)
+		}
+	}
+
+	// A finished build is replayed and the stream ends
+	resp2, err := http.Get(srv.URL + "/builds/ko-b001/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp2.Body.Close()
+	if got := readSSE(t, resp2, 100); len(got) != len(want) || !strings.HasPrefix(got[len(got)-1], "done:") {
+		t.Errorf("replay:\n%s", strings.Join(got, "\n"))
+	}
+
+	resp3, _ := http.Get(srv.URL + "/builds/ko-zzzz/stream")
+	if resp3.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown ticket: status %d", resp3.StatusCode)
+	}
+	resp3.Body.Close()
+}
+
+func containsAll(s string, subs []string) bool {
+	for _, sub := range subs {
+		if !strings.Contains(s, sub) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// serveWhitelist is the set of subcommands POST /ko may run.
+var serveWhitelist = map[string]bool{
+	"add":      true,
+	"show":     true,
+	"ls":       true,
+	"ready":    true,
+	"triage":   true,
+	"update":   true,
+	"status":   true,
+	"start":    true,
+	"close":    true,
+	"open":     true,
+	"dep":      true,
+	"undep":    true,
+	"mv":       true,
+	"rm":       true,
+	"archive":  true,
+	"note":     true,
+	"bump":     true,
+	"agent":    true,
+	"project":  true,
+	"template": true,
+}
+
+// serveFileFlags are, by subcommand, the flags naming a file to read. Over
+// /ko the file would be read on the server, so they are refused there.
+var serveFileFlags = map[string][]string{
+	"add": {"from"},
+}
+
+// execFileFlag returns the first flag of argv that names a file to read,
+// in any form Go's flag package accepts, or "".
+func execFileFlag(argv []string) string {
+	for _, arg := range argv[1:] {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		for _, f := range serveFileFlags[argv[0]] {
+			if name == f {
+				return f
+			}
+		}
+	}
+	return ""
+}
+
+// execStreamType is the content type of the streaming /ko protocol. The
+// request body is one JSON line ({"argv": [...], "stdin": true}) followed
+// by the raw stdin bytes; the response is one JSON execFrame per line.
+const execStreamType = "application/x-ko-stream"
+
+// execFrame is one line of a streamed /ko response: a chunk of stdout or
+// stderr, the exit code of the finished command, or an error that kept
+// it from running.
+type execFrame struct {
+	Stream string `json:"stream,omitempty"` // stdout | stderr
+	Data   []byte `json:"data,omitempty"`
+	Exit   *int   `json:"exit,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// execRequest is the header line of a /ko request.
+type execRequest struct {
+	Argv  []string `json:"argv"`
+	Stdin bool     `json:"stdin,omitempty"` // stdin bytes follow the header line
+}
+
+// serveExecCommand builds the process that runs a /ko request, in the
+// directory ctx carries under execDirKey if any; tests replace it.
+var serveExecCommand = func(ctx context.Context, argv []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, os.Args[0], argv...)
+	cmd.Dir, _ = ctx.Value(execDirKey{}).(string)
+	return cmd
+}
+
+// handleKo serves POST /ko. Streaming clients get stdout, stderr and the
+// exit code as they happen; others get the buffered output.
+func handleKo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("Content-Type") == execStreamType {
+		handleKoStream(w, r)
+		return
+	}
+
+	// Parse JSON body
+	var req execRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := checkExecArgv(req.Argv); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Execute command
+	output, err := serveExecCommand(r.Context(), req.Argv).CombinedOutput()
+
+	if err != nil {
+		// Non-zero exit code: return 400 with stderr
+		errResp := map[string]string{
+			"error": string(output),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errResp)
+		return
+	}
+
+	// Success: return 200 with stdout
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	w.Write(output)
+}
+
+// checkExecArgv rejects empty and non-whitelisted commands, and flags
+// that would read a file on the server.
+func checkExecArgv(argv []string) error {
+	if len(argv) == 0 {
+		return errors.New("argv must have at least one element")
+	}
+	if !serveWhitelist[argv[0]] {
+		return fmt.Errorf("subcommand '%s' not allowed", argv[0])
+	}
+	if f := execFileFlag(argv); f != "" {
+		return fmt.Errorf("%s --%s not allowed: the file would be read on the server", argv[0], f)
+	}
+	return nil
+}
+
+// readExecHeader reads the JSON header line of a streamed request.
+func readExecHeader(br *bufio.Reader) (execRequest, error) {
+	var req execRequest
+	line, err := readExecHeaderLine(br)
+	if err != nil {
+		return req, err
+	}
+	if err := json.Unmarshal(line, &req); err != nil {
+		return req, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return req, nil
+}
+
+// readExecHeaderLine reads up to and including the first newline,
+// without reading into the stdin bytes that follow.
+func readExecHeaderLine(br *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := br.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > apiMaxBody {
+			return nil, errors.New("request header line too long")
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return line, nil
+	}
+}
+
+// frameWriter sends everything written to it as frames of one stream.
+type frameWriter struct {
+	stream string
+	send   func(execFrame) error
+}
+
+func (fw frameWriter) Write(p []byte) (int, error) {
+	if err := fw.send(execFrame{Stream: fw.stream, Data: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// handleKoStream runs a /ko request with the streaming protocol. The
+// command is killed if the client goes away.
+func handleKoStream(w http.ResponseWriter, r *http.Request) {
+	br := bufio.NewReader(r.Body)
+	req, err := readExecHeader(br)
+	if err == nil {
+		err = checkExecArgv(req.Argv)
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Read stdin from the request while the response streams back.
+	rc := http.NewResponseController(w)
+	rc.EnableFullDuplex()
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	send := func(f execFrame) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	cmd := serveExecCommand(r.Context(), req.Argv)
+	cmd.Stdout = frameWriter{"stdout", send}
+	cmd.Stderr = frameWriter{"stderr", send}
+	if req.Stdin {
+		// Wait for the first stdin bytes before starting the command, so
+		// ko add's short stdin probe does not miss them.
+		br.Peek(1)
+		cmd.Stdin = br
+	}
+
+	w.Header().Set("Content-Type", execStreamType)
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	code := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() < 0 {
+			// Never started, or killed by a signal: no exit code to pass on.
+			send(execFrame{Error: err.Error()})
+			return
+		}
+		code = exitErr.ExitCode()
+	}
+	send(execFrame{Exit: &code})
+}
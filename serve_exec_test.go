@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// fakeServeExec makes /ko run argv[1] as a shell script instead of ko.
+func fakeServeExec(t *testing.T) {
+	old := serveExecCommand
+	serveExecCommand = func(ctx context.Context, argv []string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", argv[1])
+	}
+	t.Cleanup(func() { serveExecCommand = old })
+}
+
+// runRemote calls remoteExec with the given stdin and returns the exit
+// code and what was written to stdout and stderr.
+// Callers set up the test DB, which holds the offline queue.
+func runRemote(t *testing.T, server, stdin string, argv ...string) (int, string, string) {
+	t.Helper()
+	oldIn, oldOut, oldErr := os.Stdin, os.Stdout, os.Stderr
+	defer func() { os.Stdin, os.Stdout, os.Stderr = oldIn, oldOut, oldErr }()
+
+	inR, inW, _ := os.Pipe()
+	outR, outW, _ := os.Pipe()
+	errR, errW, _ := os.Pipe()
+	os.Stdin, os.Stdout, os.Stderr = inR, outW, errW
+	io.WriteString(inW, stdin)
+	inW.Close()
+
+	outC, errC := make(chan string), make(chan string)
+	go func() { b, _ := io.ReadAll(outR); outC <- string(b) }()
+	go func() { b, _ := io.ReadAll(errR); errC <- string(b) }()
+
+	code := remoteExec(server, "", argv)
+	outW.Close()
+	errW.Close()
+	inR.Close()
+	return code, <-outC, <-errC
+}
+
+func TestRemoteExec_Streaming(t *testing.T) {
+	defer setupTestDB(t)()
+	fakeServeExec(t)
+	srv := httptest.NewServer(http.HandlerFunc(handleKo))
+	defer srv.Close()
+
+	code, stdout, stderr := runRemote(t, srv.URL, "piped description\n",
+		"add", "cat; echo oops >&2; exit 3")
+	if code != 3 {
+		t.Errorf("exit code = %d, want 3", code)
+	}
+	if stdout != "piped description\n" {
+		t.Errorf("stdout = %q", stdout)
+	}
+	if stderr != "oops\n" {
+		t.Errorf("stderr = %q", stderr)
+	}
+
+	// Without piped stdin the command sees none
+	code, stdout, _ = runRemote(t, srv.URL, "", "ls", "cat; echo done")
+	if code != 0 || stdout != "done\n" {
+		t.Errorf("no stdin: code = %d, stdout = %q", code, stdout)
+	}
+
+	// Commands outside the whitelist never run
+	code, _, stderr = runRemote(t, srv.URL, "", "serve", "echo ran")
+	if code != 1 || !strings.Contains(stderr, "not allowed") {
+		t.Errorf("serve: code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestRemoteExec_StreamCutOff(t *testing.T) {
+	defer setupTestDB(t)()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", execStreamType)
+		json.NewEncoder(w).Encode(execFrame{Stream: "stdout", Data: []byte("partial\n")})
+	}))
+	defer srv.Close()
+
+	code, stdout, stderr := runRemote(t, srv.URL, "", "ls")
+	if code != 1 || stdout != "partial\n" || !strings.Contains(stderr, "stream ended") {
+		t.Errorf("code = %d, stdout = %q, stderr = %q", code, stdout, stderr)
+	}
+}
+
+func TestHandleKo_Buffered(t *testing.T) {
+	fakeServeExec(t)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/ko", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handleKo(w, r)
+		return w
+	}
+
+	w := post(`{"argv": ["ls", "echo listed"]}`)
+	if w.Code != http.StatusOK || w.Body.String() != "listed\n" {
+		t.Errorf("success: code = %d, body = %q", w.Code, w.Body.String())
+	}
+
+	w = post(`{"argv": ["show", "echo missing >&2; exit 2"]}`)
+	var resp map[string]string
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusBadRequest || resp["error"] != "missing\n" {
+		t.Errorf("failure: code = %d, body = %q", w.Code, w.Body.String())
+	}
+
+	w = post(`{"argv": ["import"]}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "not allowed") {
+		t.Errorf("not whitelisted: code = %d, body = %q", w.Code, w.Body.String())
+	}
+
+	w = post(`{"argv": ["add", "-from=/etc/passwd"]}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "read on the server") {
+		t.Errorf("file flag: code = %d, body = %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// parseListenAddr splits a --listen value into a network and address:
+// "unix:/path/ko.sock" (or "unix:///path/ko.sock") for a Unix socket,
+// anything else a TCP "host:port" or ":port".
+func parseListenAddr(listen string) (network, address string, err error) {
+	if path, ok := strings.CutPrefix(listen, "unix:"); ok {
+		path = strings.TrimPrefix(path, "//")
+		if !strings.HasPrefix(path, "/") {
+			return "", "", fmt.Errorf("--listen %s: socket path must be absolute", listen)
+		}
+		return "unix", path, nil
+	}
+	if _, _, err := net.SplitHostPort(listen); err != nil {
+		return "", "", fmt.Errorf("--listen %s: want host:port, :port or unix:/path", listen)
+	}
+	return "tcp", listen, nil
+}
+
+// listenServe opens the listener for ko serve. A Unix socket is created
+// readable and writable by the user only; a stale socket left by a
+// crashed server is replaced, a live one is an error.
+func listenServe(network, address string) (net.Listener, error) {
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+	if info, err := os.Lstat(address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", address)
+		}
+		if conn, err := net.DialTimeout("unix", address, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s: another ko serve is listening", address)
+		}
+		if err := os.Remove(address); err != nil {
+			return nil, err
+		}
+	}
+	// Create the socket under a umask so it is never briefly world-writable.
+	old := syscall.Umask(0177)
+	ln, err := net.Listen("unix", address)
+	syscall.Umask(old)
+	return ln, err
+}
+
+// serveTLSConfig loads the certificate for --tls-cert/--tls-key. Both or
+// neither must be given.
+func serveTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("--tls-cert and --tls-key must be given together")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: %v", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// listenURL describes where ko serve listens, in the form a client's
+// server: setting takes.
+func listenURL(network, address string, useTLS bool) string {
+	if network == "unix" {
+		return "unix://" + address
+	}
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	if strings.HasPrefix(address, ":") {
+		address = "localhost" + address
+	}
+	return scheme + "://" + address
+}
+
+// remoteClient returns the HTTP client and base URL for a server: setting.
+// http:// and https:// URLs are used as they are; unix:///path/ko.sock
+// dials the socket.
+func remoteClient(server string, timeout time.Duration) (*http.Client, string, error) {
+	if path, ok := strings.CutPrefix(server, "unix:"); ok {
+		path = strings.TrimPrefix(path, "//")
+		if !strings.HasPrefix(path, "/") {
+			return nil, "", fmt.Errorf("server %s: socket path must be absolute", server)
+		}
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+		return &http.Client{Timeout: timeout, Transport: transport}, "http://ko", nil
+	}
+	if !strings.HasPrefix(server, "http://") && !strings.HasPrefix(server, "https://") {
+		return nil, "", fmt.Errorf("server %s: want an http://, https:// or unix:// URL", server)
+	}
+	return &http.Client{Timeout: timeout}, strings.TrimRight(server, "/"), nil
+}
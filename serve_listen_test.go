@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseListenAddr(t *testing.T) {
+	tests := []struct {
+		in, network, address, wantErr string
+	}{
+		{":19876", "tcp", ":19876", ""},
+		{"192.168.1.5:443", "tcp", "192.168.1.5:443", ""},
+		{"unix:/run/user/1000/ko.sock", "unix", "/run/user/1000/ko.sock", ""},
+		{"unix:///run/ko.sock", "unix", "/run/ko.sock", ""},
+		{"unix:ko.sock", "", "", "must be absolute"},
+		{"localhost", "", "", "want host:port"},
+	}
+	for _, tt := range tests {
+		network, address, err := parseListenAddr(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseListenAddr(%q) err = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || network != tt.network || address != tt.address {
+			t.Errorf("parseListenAddr(%q) = %s %s %v", tt.in, network, address, err)
+		}
+	}
+	if got := listenURL("tcp", ":8443", true); got != "https://localhost:8443" {
+		t.Errorf("listenURL = %s", got)
+	}
+}
+
+func TestListenServeUnix(t *testing.T) {
+	// Keep the path short: Unix socket paths are limited to ~100 bytes.
+	dir, err := os.MkdirTemp("", "ko")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "ko.sock")
+
+	ln, err := listenServe("unix", sock)
+	if err != nil {
+		t.Fatalf("listenServe: %v", err)
+	}
+	if info, _ := os.Stat(sock); info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, want 0600", info.Mode().Perm())
+	}
+	if _, err := listenServe("unix", sock); err == nil || !strings.Contains(err.Error(), "another ko serve") {
+		t.Errorf("second listener err = %v", err)
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ko" && r.Header.Get("Authorization") == "Bearer tok" {
+			io.WriteString(w, "over the socket\n")
+		}
+	})}
+	go srv.Serve(ln)
+
+	client, base, err := remoteClient("unix://"+sock, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, base+"/ko", nil)
+	req.Header.Set("Authorization", "Bearer tok")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request over socket: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "over the socket\n" {
+		t.Errorf("body = %q", body)
+	}
+	srv.Close()
+
+	// A socket left behind by a dead server is replaced
+	ln2, err := net.Listen("unix", sock)
+	if err == nil {
+		ln2.(*net.UnixListener).SetUnlinkOnClose(false)
+		ln2.Close()
+	}
+	ln, err = listenServe("unix", sock)
+	if err != nil {
+		t.Fatalf("stale socket not replaced: %v", err)
+	}
+	ln.Close()
+
+	os.WriteFile(filepath.Join(dir, "file"), nil, 0644)
+	if _, err := listenServe("unix", filepath.Join(dir, "file")); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("regular file err = %v", err)
+	}
+}
+
+func TestRemoteClientSchemes(t *testing.T) {
+	if _, base, err := remoteClient("https://ko.example.com/", time.Second); err != nil || base != "https://ko.example.com" {
+		t.Errorf("https = %s, %v", base, err)
+	}
+	if _, _, err := remoteClient("ftp://ko.example.com", time.Second); err == nil {
+		t.Error("ftp scheme accepted")
+	}
+	if _, _, err := remoteClient("unix://ko.sock", time.Second); err == nil {
+		t.Error("relative socket path accepted")
+	}
+}
+
+func TestServeTLSConfig(t *testing.T) {
+	if cfg, err := serveTLSConfig("", ""); cfg != nil || err != nil {
+		t.Errorf("no TLS = %v, %v", cfg, err)
+	}
+	if _, err := serveTLSConfig("cert.pem", ""); err == nil {
+		t.Error("cert without key accepted")
+	}
+
+	dir := t.TempDir()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ko"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	cfg, err := serveTLSConfig(certPath, keyPath)
+	if err != nil || len(cfg.Certificates) != 1 {
+		t.Errorf("serveTLSConfig = %v, %v", cfg, err)
+	}
+	if _, err := serveTLSConfig(keyPath, certPath); err == nil {
+		t.Error("swapped cert and key accepted")
+	}
+}
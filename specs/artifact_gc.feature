@@ -0,0 +1,64 @@
+Feature: Artifact garbage collection
+  Artifact directories persist across builds and after close, so they grow
+  with every retry. `ko gc` prunes workspaces and build output directories
+  that have not been touched for a while, keeps the notes and build history
+  that make up a ticket's record, and cleans up worker branches left behind
+  by parallel loops that crashed.
+
+  Scenario: Only old artifacts are pruned by default
+    Given ticket ko-a001 has a workspace written today
+    When I run `ko gc`
+    Then nothing is removed
+    And the output is "gc: nothing to collect"
+
+  Scenario: --older-than sets the age threshold
+    Given ticket ko-a001 is closed with a workspace and two builds
+    When I run `ko gc --older-than 0s`
+    Then the workspace and both build directories are removed
+    And the output reports the bytes reclaimed
+
+  Scenario: Notes and build history are kept
+    Given ticket ko-a001 is closed with plan.md and a build history file
+    When I run `ko gc --older-than 0s`
+    Then `ko-a001.artifacts/plan.md` still exists
+    And `ko-a001.jsonl` still exists
+
+  Scenario: --closed-only skips tickets that are not closed
+    Given ticket ko-a001 is closed and ticket ko-b002 is open
+    When I run `ko gc --older-than 0s --closed-only`
+    Then only ko-a001's artifacts are removed
+
+  Scenario: The latest build of an open ticket is kept
+    Given open ticket ko-b002 has builds 1 and 2
+    When I run `ko gc --older-than 0s`
+    Then build 1 is removed
+    And build 2 is kept so `ko logs` still works
+
+  Scenario: In-progress tickets are never touched
+    Given ticket ko-c003 is in_progress with a workspace
+    When I run `ko gc --older-than 0s`
+    Then its workspace is kept
+
+  Scenario: --dry-run removes nothing
+    When I run `ko gc --older-than 0s --dry-run`
+    Then each candidate is listed as "would remove"
+    And every directory still exists
+
+  Scenario: Orphaned worker branches are deleted
+    Given the project is a git repo with a ko-worker-ko-z999 branch
+    And no worktree has that branch checked out
+    When I run `ko gc`
+    Then the branch is deleted
+    And other branches are kept
+
+  Scenario: Worker branches of a dead loop are deleted with their worktree
+    Given a ko-worker-* branch is checked out in a ko-workers-<pid> worktree
+    And process <pid> is not running
+    When I run `ko gc`
+    Then the worktree is removed and the branch is deleted
+
+  Scenario: Branch pruning needs no git binary
+    Given a ko-worker-* branch that is packed in packed-refs
+    When I run `ko gc` without git on PATH
+    Then the branch is removed from packed-refs under packed-refs.lock
+    And git fsck still passes
@@ -0,0 +1,55 @@
+Feature: Build output capture
+  Every node attempt of every build has its stdout and stderr captured under
+  `.ko/tickets/<id>.artifacts/builds/<n>/<node>.<attempt>.{out,err}`. Failed
+  attempts are kept alongside the successful one, so a retry never erases the
+  evidence of why the earlier attempt failed. `ko logs` reads them back.
+
+  Scenario: Each node attempt captures stdout and stderr separately
+    Given a pipeline with a run node that writes to stdout and stderr
+    When I run `ko agent build ko-a001`
+    Then `builds/1/<node>.1.out` contains the stdout text
+    And `builds/1/<node>.1.err` contains the stderr text
+
+  Scenario: Prompt node stderr is captured in non-verbose mode
+    Given an agent command that writes diagnostics to stderr
+    When I run `ko agent build ko-a001` without --verbose
+    Then the node's `.err` capture contains the diagnostics
+
+  Scenario: Failed attempts are kept alongside the retry
+    Given a pipeline with max_retries: 1
+    And a run node that fails on the first attempt and succeeds on the second
+    When I run `ko agent build ko-a001`
+    Then `<node>.1.err` holds the first attempt's error output
+    And `<node>.2.out` holds the second attempt's output
+
+  Scenario: Each build gets its own numbered directory
+    Given ticket ko-a001 was built once and reset to open
+    When I run `ko agent build ko-a001` again
+    Then both `builds/1/` and `builds/2/` exist
+
+  Scenario: keep_builds prunes the oldest build directories
+    Given a pipeline with keep_builds: 1
+    And ticket ko-a001 was built once and reset to open
+    When I run `ko agent build ko-a001` again
+    Then `builds/2/` exists
+    And `builds/1/` has been removed
+
+  Scenario: max_output_bytes truncates oversized captures
+    Given a pipeline with max_output_bytes: 16
+    And a run node that prints more than 16 bytes
+    When the build runs
+    Then the capture holds the first 16 bytes followed by a truncation marker
+
+  Scenario: ko logs shows the latest build by default
+    Given ticket ko-a001 has been built
+    When I run `ko logs ko-a001`
+    Then the output names the build number
+    And each non-empty capture is printed under a `==> <file> <==` header
+
+  Scenario: ko logs filters by build and node
+    When I run `ko logs ko-a001 --build 1 --node verify`
+    Then only captures for node verify in build 1 are printed
+
+  Scenario: ko logs fails for a ticket that was never built
+    When I run `ko logs ko-b002`
+    Then the command fails with "no build output recorded"
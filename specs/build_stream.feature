@@ -0,0 +1,40 @@
+Feature: Live build output
+  ko serve streams a running build's output and node events as
+  server-sent events from /builds/<id>/stream.
+
+  Background:
+    Given ko serve is running
+    And ticket ko-a001 is being built
+
+  Scenario: Output lines stream as the node writes them
+    When a client connects to /builds/ko-a001/stream
+    And the implement node prints "running tests"
+    Then the client receives an "output" event "[implement] running tests"
+
+  Scenario: Node events come from the build history
+    When a client connects to /builds/ko-a001/stream
+    And the verify node starts
+    Then the client receives a "history" event with "node_start" for "verify"
+
+  Scenario: Connecting mid-build catches up first
+    Given the implement node has already printed 3 lines
+    When a client connects to /builds/ko-a001/stream
+    Then it first receives build_start, the node events and those 3 lines
+
+  Scenario: The stream ends with the build
+    When the build completes with outcome "succeed"
+    Then the client receives a "done" event with the build_complete record
+    And the stream closes
+
+  Scenario: Secrets are redacted
+    Given a node prints a configured secret
+    Then the "output" event shows it redacted, as in verbose mode
+
+  Scenario: The live log is per build
+    When the next build of ko-a001 starts
+    Then .ko/tickets/ko-a001.artifacts/live.log is emptied first
+
+  Scenario: Streams need the read scope
+    Given tokens are configured
+    When a client without a token connects to /builds/ko-a001/stream
+    Then it gets 401
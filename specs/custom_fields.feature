@@ -0,0 +1,64 @@
+Feature: Custom fields
+  Projects declare typed custom fields under fields: in .ko/config.yaml.
+  Tickets carry values for them, stored in the ticket_fields table.
+
+  Background:
+    Given .ko/config.yaml declares:
+      """
+      fields:
+        customer: string
+        estimate:
+          type: int
+          default: 1
+        component:
+          type: enum
+          values: [api, web, cli]
+        due: date
+        billable:
+          type: bool
+      """
+
+  Scenario: Values are set on add and defaults fill the rest
+    When I run "ko add 'Rate limiter' --field component=api"
+    Then the ticket has component "api" and estimate 1
+
+  Scenario: Values are checked against their type
+    When I run "ko add 'Bad' --field estimate=lots"
+    Then the command fails with "estimate: \"lots\" is not an integer"
+    And no ticket is created
+
+  Scenario: Enum values are restricted
+    When I run "ko add 'Bad' --field component=mobile"
+    Then the command fails with "not one of api, web, cli"
+
+  Scenario: Undeclared fields are rejected
+    When I run "ko update ko-a001 --field color=red"
+    Then the command fails with "unknown field \"color\""
+
+  Scenario: An empty value clears a field
+    Given ticket ko-a001 has customer "Acme"
+    When I run "ko update ko-a001 --field customer="
+    Then ko-a001 has no customer field
+
+  Scenario: JSON output types the values
+    Given ticket ko-a001 has estimate 5 and billable "yes"
+    When I run "ko show ko-a001 --json"
+    Then "fields" is {"billable": true, "estimate": 5}
+
+  Scenario: Listing filters on fields
+    Given ko-a001 has estimate 5 and ko-a002 has estimate 2
+    When I run "ko ls --field estimate>=3"
+    Then the output lists ko-a001 but not ko-a002
+
+  Scenario: A missing field only matches !=
+    Given ko-a002 has no component
+    When I run "ko ls --field component!=api"
+    Then the output lists ko-a002
+
+  Scenario: Search filters on fields
+    When I run "ko search limiter --field component=api"
+    Then only tickets with component api are searched
+
+  Scenario: Export includes fields
+    When I run "ko export"
+    Then each ticket with custom fields has a "fields" object
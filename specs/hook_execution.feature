@@ -0,0 +1,56 @@
+Feature: Hook execution
+  Each hook command runs with a timeout, sees the ticket and the build's
+  outcome, can be made conditional or allowed to fail, and leaves a
+  record in build history.
+
+  Scenario: Hooks receive the ticket as JSON
+    Given a pipeline with an on_succeed hook
+    When I run `ko agent build ko-a001`
+    Then the hook sees KO_TICKET_JSON in the `ko show --json` shape
+    And KO_BUILD_OUTCOME is "succeed"
+
+  Scenario: on_fail sees the outcome and the failure reason
+    Given an on_succeed hook that exits non-zero
+    When I run `ko agent build ko-a001`
+    Then on_fail sees KO_BUILD_OUTCOME=fail
+    And KO_DISPOSITION_REASON starts with "on_succeed failed"
+
+  Scenario: A hook that outlives its timeout is killed
+    Given an on_succeed hook `sleep 5` with timeout: 200ms
+    When I run `ko agent build ko-a001`
+    Then the hook is killed after 200ms
+    And build history records it with status "timeout"
+
+  Scenario: continue_on_error keeps the list running
+    Given a failing hook with continue_on_error: true
+    And another hook after it
+    When the hooks run
+    Then the later hook still runs
+
+  Scenario: A failing hook stops the list
+    Given a failing hook without continue_on_error
+    And another hook after it
+    When the hooks run
+    Then the later hook does not run
+
+  Scenario: when: skips a hook whose condition does not hold
+    Given an on_succeed hook with when: TICKET_TYPE == bug
+    When I build a ticket of type task
+    Then the hook is skipped
+    And build history records it with status "skipped"
+
+  Scenario: when: globs match any line of a multi-line variable
+    Given an on_succeed hook with when: CHANGED_FILES == "src/*"
+    And the build changed src/main.go
+    When the hooks run
+    Then the hook runs
+
+  Scenario: Hook output is kept in build history
+    Given an on_succeed hook that prints "giving up" and exits 3
+    When I run `ko agent build ko-a001`
+    Then .ko/tickets/ko-a001.jsonl has a hook_run event with exit_code 3 and output "giving up"
+
+  Scenario: Invalid hook options are rejected when the pipeline loads
+    Given a hook with when: TICKET_TYPE
+    When I run `ko agent build ko-a001`
+    Then the command fails with "invalid when clause"
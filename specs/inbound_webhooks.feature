@@ -0,0 +1,55 @@
+Feature: Inbound webhooks
+  `ko serve` exposes POST /hooks/<name> for each entry in the inbound:
+  section of the global config. Mapping rules template a ticket from the
+  JSON payload and dedupe on a fingerprint stored in external-ref.
+
+  Scenario: A generic payload creates a ticket
+    Given an inbound hook "generic" for project "ops" with title '{{.title}}'
+    When I POST '{"title": "Nightly build failed"}' to /hooks/generic
+    Then a ticket "Nightly build failed" is created in project "ops"
+    And the response lists it with action "created"
+
+  Scenario: Each alert in an Alertmanager payload becomes a ticket
+    Given a hook with items: alerts and when: '{{eq .status "firing"}}'
+    When Alertmanager posts two firing alerts and one resolved alert
+    Then two tickets are created
+    And their title, body, tags, priority and type come from the rules
+
+  Scenario: A repeated fingerprint does not create a duplicate
+    Given a ticket created by the hook with fingerprint "abc123"
+    When the same alert is posted again
+    Then no new ticket is created
+    And the existing ticket gets a note that the webhook fired again
+
+  Scenario: A closed ticket is reopened
+    Given a closed ticket with external-ref "alertmanager:abc123"
+    When the alert is posted again
+    Then the ticket is reopened with a note
+    And the response action is "reopened"
+
+  Scenario: A hook with a secret requires credentials
+    Given a hook with secret: $INBOUND_TOKEN
+    When I POST without an Authorization header or signature
+    Then the response status is 401
+    And a request with "Authorization: Bearer <token>" or a valid X-Hub-Signature-256 is accepted
+
+  Scenario: A hook secret can come from secrets.env
+    Given a hook with secret: ${secrets.HOOK_TOKEN}
+    And secrets.env next to the global config sets HOOK_TOKEN
+    When I POST with "Authorization: Bearer <HOOK_TOKEN>"
+    Then the response status is 200
+
+  Scenario: Serve tokens require every hook to have a secret
+    Given a global config with tokens: and a hook without secret:
+    When I run ko serve
+    Then it fails to start with "a secret is required when tokens: is configured"
+    And other ko commands still load the global config
+    When the hook is added while ko serve is running
+    And I POST to it
+    Then the response status is 403
+
+  Scenario: Unknown hooks and invalid payloads are rejected
+    When I POST to /hooks/missing
+    Then the response status is 404
+    When I POST invalid JSON to a configured hook
+    Then the response status is 400
@@ -0,0 +1,46 @@
+Feature: Build lifecycle hooks
+  Pipelines can run shell commands at each point of a build: when it
+  starts, after every node, and when the ticket ends up blocked,
+  decomposed, succeeded, failed, or closed. Workflows can replace or
+  disable any of them.
+
+  Scenario: on_start runs before the first node
+    Given a pipeline with on_start: ["echo start $TICKET_ID >> hooks.log"]
+    When I run `ko agent build ko-a001`
+    Then hooks.log contains "start ko-a001" before any node runs
+
+  Scenario: A failing on_start fails the build
+    Given a pipeline whose on_start hook exits non-zero
+    When I run `ko agent build ko-a001`
+    Then the build fails without running any node
+    And on_fail hooks run
+
+  Scenario: on_node runs after each node
+    Given a workflow main with nodes triage and implement
+    And a pipeline with on_node: ["echo $WORKFLOW $NODE_NAME $NODE_RESULT >> nodes.log"]
+    When I run `ko agent build ko-a001`
+    Then nodes.log contains one line per node with its disposition
+
+  Scenario: on_block receives the reason and the blocking ticket
+    Given a decision node that returns a blocked disposition on ko-c003
+    When I run `ko agent build ko-b002`
+    Then on_block runs with BLOCKED_ON=ko-c003
+    And BLOCK_REASON contains the disposition's reason
+
+  Scenario: A failure that blocks runs on_fail then on_block
+    Given a node that fails
+    When I run `ko agent build ko-d004`
+    Then on_fail runs
+    And on_block runs with an empty BLOCKED_ON
+
+  Scenario: on_decompose receives the child IDs
+    Given a decision node that decomposes the ticket into two children
+    When I run `ko agent build ko-a001`
+    Then on_decompose runs with CHILD_IDS listing both children
+
+  Scenario: A workflow can override or disable a hook
+    Given a pipeline with on_succeed and on_node hooks
+    And workflow docs declares on_succeed: [] and its own on_node list
+    When a build routes to docs and succeeds
+    Then the pipeline's on_succeed does not run
+    And docs nodes run docs' on_node instead of the pipeline's
@@ -0,0 +1,46 @@
+Feature: Per-node environment and secrets
+  Nodes inherit the ko process environment. Pipelines can add env vars and
+  dotenv files at pipeline, workflow, and node level, and pull credentials
+  from an untracked .ko/secrets.env so only the nodes that need a secret
+  receive it.
+
+  Scenario: The most specific env level wins
+    Given a pipeline with env: {LEVEL: pipeline}
+    And workflow main with env: {LEVEL: workflow}
+    And node deploy with env: {LEVEL: node}
+    When I run `ko agent build ko-a001`
+    Then node deploy sees LEVEL=node
+    And the other nodes in main see LEVEL=workflow
+
+  Scenario: env_file loads a dotenv file
+    Given a pipeline with env_file: shared.env
+    And shared.env contains "REGION=eu-west-1"
+    When I run `ko agent build ko-a001`
+    Then every node sees REGION=eu-west-1
+
+  Scenario: Only nodes that reference a secret receive it
+    Given .ko/secrets.env contains "DEPLOY_TOKEN=live-deploy-credential"
+    And node deploy has env: {DEPLOY_TOKEN: ${secrets.DEPLOY_TOKEN}}
+    When I run `ko agent build ko-a001`
+    Then node deploy sees DEPLOY_TOKEN
+    And node check does not see DEPLOY_TOKEN
+
+  Scenario: Secret values are redacted
+    Given node deploy prints its DEPLOY_TOKEN
+    When I run `ko agent build ko-a001`
+    Then the captured output contains "[REDACTED]"
+    And no build artifact contains "live-deploy-credential"
+
+  Scenario: A missing secret fails the node
+    Given node deploy has env: {DEPLOY_TOKEN: ${secrets.MISSING}}
+    When I run `ko agent build ko-a001`
+    Then the build fails
+    And the ticket has a note containing "secret MISSING not found in .ko/secrets.env"
+
+  Scenario: agent init keeps secrets out of git
+    When I run `ko agent init`
+    Then .ko/.gitignore contains "secrets.env"
+
+  Scenario: Invalid env names are rejected
+    Given a pipeline with env: {BAD-NAME: x}
+    Then loading the pipeline fails with "invalid env var name"
@@ -0,0 +1,65 @@
+Feature: Outbound notifications
+  A notifications: section in the global or project config lists webhook
+  targets. Matching ticket and build events are queued in the shadow DB,
+  delivered as signed, templated JSON, and retried with backoff.
+
+  Scenario: A blocked ticket is posted to a block target
+    Given a project config with a target filtered on "block" and template slack
+    When I run `ko block ko-a001 waiting on upstream`
+    Then the target receives a POST whose "text" summarises the status change
+    And the request carries X-Ko-Event "status"
+
+  Scenario: Mutations do not wait for delivery
+    Given a target that takes a long time to respond
+    When I run `ko close ko-a001`
+    Then the command returns once the delivery is queued
+    And a detached `ko notify flush` sends it
+
+  Scenario: Status filters match only the named status
+    Given a target filtered on "status:resolved"
+    When a ticket moves to blocked
+    Then nothing is sent to that target
+
+  Scenario: Build outcomes are delivered as build_complete
+    Given a target filtered on "build_complete:fail"
+    When `ko agent build ko-a001` fails
+    Then the target receives a build_complete event with the outcome and reason
+
+  Scenario: Project filters use registry tags
+    Given a target with projects: [api]
+    When a ticket in project "web" changes
+    Then nothing is sent to that target
+
+  Scenario: Bodies are signed with the target secret
+    Given a target with secret: $HOOK_SECRET
+    When an event is delivered
+    Then X-Ko-Signature-256 is "sha256=" followed by the hex HMAC-SHA256 of the body
+
+  Scenario: A custom template must render valid JSON
+    Given a target whose template file renders invalid JSON
+    When an event matches it
+    Then no delivery is queued and the error is reported on stderr
+
+  Scenario: Failed deliveries are retried with backoff
+    Given a target that returns 500 on the first request
+    When I run `ko close ko-a001` and the delivery is flushed
+    Then `ko notify list` shows the delivery as pending with 1 attempt
+    And `ko notify flush` sends nothing until the backoff has passed
+    And `ko notify flush --force` delivers it
+
+  Scenario: Deliveries give up after 8 attempts
+    Given a target that always fails
+    When a delivery has been attempted 8 times
+    Then it is marked failed and no longer retried
+
+  Scenario: A target removed from the config fails permanently
+    Given a queued delivery for target "ops"
+    And "ops" is no longer in its config file
+    When the queue is flushed
+    Then the delivery is marked failed
+
+  Scenario: Notifications never fail the command
+    Given a target whose URL is unreachable
+    When I run `ko note ko-a001 hello`
+    Then the command succeeds
+    And the delivery is queued for retry
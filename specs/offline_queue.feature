@@ -0,0 +1,40 @@
+Feature: Offline mutation queue
+  With server: set, write commands are queued locally while the server is
+  unreachable and replayed in order once it is back.
+
+  Background:
+    Given "server: https://ko.example.com" in the global config
+
+  Scenario: Writes are queued while the server is down
+    Given the server is unreachable
+    When I run "echo 'Found on the plane' | ko add 'Seat map crash'"
+    Then the command succeeds
+    And it prints "server unreachable; queued as #1"
+    And "ko remote status" lists "#1" as "pending"
+
+  Scenario: Reads still fail while the server is down
+    Given the server is unreachable
+    When I run "ko ls"
+    Then it fails with "server unreachable"
+
+  Scenario: The queue is replayed in order when the server is back
+    Given "add", "note" and "close" commands were queued
+    When the server is reachable again
+    And I run "ko ls"
+    Then the queued commands run on the server in the order they were queued
+    And the ticket listing is printed
+
+  Scenario: A ticket changed on the server holds the queued command
+    Given "ko update ko-a001 --title Renamed" was queued
+    And ko-a001 was changed on the server after that
+    When I run "ko remote sync"
+    Then the update is held as "conflict"
+    And a queued note for ko-a001 waits behind it
+
+  Scenario: Resolve a conflict
+    Given queued command #3 is held as "conflict"
+    When I run "ko remote retry 3"
+    Then #3 is applied without the conflict check
+    And the commands waiting behind it are replayed
+    When I run "ko remote drop 4"
+    Then #4 is removed from the queue
@@ -319,6 +319,41 @@ Feature: Build Pipeline
     When the build completes
     Then CHANGED_FILES should contain "foo.go" and "bar.go"
 
+  Scenario: Added, modified and deleted files are reported separately
+    Given a project containing "keep.txt" and "gone.txt"
+    And a pipeline where the implement node edits "keep.txt", deletes "gone.txt", and creates "new.txt"
+    When the build completes
+    Then ADDED_FILES should be "new.txt"
+    And MODIFIED_FILES should be "keep.txt"
+    And DELETED_FILES should be "gone.txt"
+    And CHANGED_FILES should contain all three
+
+  Scenario: Touched but unchanged files are not reported
+    Given a git project with "same.txt" committed
+    And a pipeline where the implement node rewrites "same.txt" with identical content
+    When the build completes
+    Then CHANGED_FILES should not contain "same.txt"
+    And no file was hashed before the build started
+
+  Scenario: Untracked files are known once hashed
+    Given an untracked "scratch.txt" that no build has hashed
+    When a build rewrites it with identical content
+    Then it is reported as modified and its hash is cached
+    And the next build that rewrites it identically does not report it
+
+  Scenario: Gitignored paths are skipped
+    Given a .gitignore listing "node_modules/"
+    And a pipeline where the implement node writes "node_modules/dep.js"
+    When the build completes
+    Then CHANGED_FILES should not contain "node_modules/dep.js"
+
+  Scenario: pipeline ignore globs are skipped
+    Given a pipeline with ignore: ["*.log"]
+    And the implement node writes "build.log" and "main.go"
+    When the build completes
+    Then CHANGED_FILES should contain "main.go"
+    And CHANGED_FILES should not contain "build.log"
+
   # Decomposition depth guard
 
   Scenario: Decomposition is denied at max depth
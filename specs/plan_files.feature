@@ -0,0 +1,69 @@
+Feature: Plan files
+  ko add --from creates a ticket hierarchy from a markdown or YAML outline,
+  so planning a project is one command instead of dozens of ko add --parent
+  and ko dep calls.
+
+  Background:
+    Given plan.md contains:
+      """
+      # Auth overhaul [p1 #auth]
+
+      Replace the session store.
+
+      ## Login form [type:feature]
+      - Validate inputs
+      ## Sessions [after:1 #infra]
+      """
+
+  Scenario: Nesting makes children
+    When I run "ko add --from plan.md"
+    Then "Auth overhaul" is a root ticket
+    And "Login form" and "Sessions" are its children, created with NewChildTicket
+    And "Validate inputs" is a child of "Login form"
+
+  Scenario: Inline metadata
+    When I run "ko add --from plan.md"
+    Then "Auth overhaul" has priority 1 and tag "auth"
+    And "Login form" has type "feature"
+    And "Sessions" depends on "Login form", its first sibling
+    And text under an item is its description
+
+  Scenario: The ID map is printed
+    When I run "ko add --from plan.md"
+    Then each line of output is an outline number, a ticket ID and a title
+      """
+      1      ko-a1b2            Auth overhaul
+      1.1    ko-a1b2.c3d4       Login form
+      1.1.1  ko-a1b2.c3d4.e5f6  Validate inputs
+      1.2    ko-a1b2.0f9e       Sessions
+      """
+
+  Scenario: YAML plans
+    Given plan.yaml is a list of titles or mappings with title, description, type, priority, tags, after and children
+    When I run "ko add --from plan.yaml"
+    Then the same hierarchy is created
+    And unknown keys warn with their line
+
+  Scenario: Plans under an existing ticket
+    When I run "ko add --from plan.md --parent ko-a001"
+    Then the top-level items are children of ko-a001
+
+  Scenario: A bad plan writes nothing
+    Given a plan where two siblings are each after the other
+    When I run "ko add --from plan.md"
+    Then the command fails with "dependency cycle" and the line number
+    And no tickets are created
+
+  Scenario: Plan errors
+    Then unknown metadata, an after: beyond the last sibling and an item after itself are errors
+    And the batch is saved in a single transaction
+
+  Scenario: Ticket flags are refused with --from
+    When I run "ko add --from plan.md -t bug -p 1"
+    Then the command fails with "--from takes only --parent and --project, not -p, -t"
+
+  Scenario: Plan files are never read on the server
+    Given the CLI has server: set
+    When I run "ko add --from plan.md"
+    Then the command fails without contacting the server
+    And POST /ko with argv ["add", "--from", "/etc/passwd"] is a 400
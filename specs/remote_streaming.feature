@@ -0,0 +1,33 @@
+Feature: Streaming remote commands
+  With server: set, the CLI runs commands on ko serve and streams their
+  output back. Piped stdin is forwarded and the real exit code is kept.
+
+  Background:
+    Given "server: https://ko.example.com" in the global config
+
+  Scenario: Piped stdin reaches the remote command
+    When I run "echo 'Steps to reproduce' | ko add 'Crash on save'"
+    Then the remote ticket has the description "Steps to reproduce"
+
+  Scenario: Output streams as it is written
+    When a remote command writes output over several minutes
+    Then each chunk is printed as it arrives
+    And the command is not cut off by a timeout
+
+  Scenario: stdout and stderr stay separate
+    When I run "ko show ko-a001 2>err.txt"
+    Then the ticket is printed to stdout
+    And warnings from the server land in err.txt
+
+  Scenario: The remote exit code is propagated
+    Given the remote command exits with code 3
+    When I run it through the CLI
+    Then the CLI exits with code 3
+
+  Scenario: A dropped connection is an error
+    When the stream ends before the exit frame
+    Then it fails with "stream ended before the command finished"
+
+  Scenario: Older clients keep working
+    When a client posts {"argv": ["ls"]} as application/json
+    Then it gets the buffered output with status 200
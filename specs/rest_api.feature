@@ -0,0 +1,54 @@
+Feature: REST API
+  `ko serve` exposes a typed JSON API over the ticket store under /api/v1.
+  Responses are JSON, errors carry a code, and list endpoints paginate
+  with an opaque cursor.
+
+  Scenario: Create and fetch a ticket
+    Given a registered project "api"
+    When I POST '{"title": "Fix login", "priority": 1}' to /api/v1/projects/api/tickets
+    Then the response status is 201
+    And the response is the new ticket in the ko show --json shape
+    When I GET /api/v1/projects/api/tickets/<id>
+    Then the response has title "Fix login"
+
+  Scenario: Patch a ticket
+    Given an open ticket
+    When I PATCH it with '{"status": "closed"}'
+    Then the ticket is closed
+    And "update" and "status" events are emitted as with ko update
+
+  Scenario: List endpoints paginate
+    Given two tickets in the project
+    When I GET /api/v1/projects/api/tickets?limit=1
+    Then the response has one item and a next_cursor
+    When I GET the same path with ?cursor=<next_cursor>
+    Then the response has the other item and no next_cursor
+
+  Scenario: Closed tickets are hidden by default
+    Given a closed ticket and an open ticket
+    When I list tickets
+    Then only the open ticket is listed
+    And ?status=closed or ?all=true lists the closed one
+
+  Scenario: Notes and deps
+    When I POST '{"text": "first look"}' to .../tickets/<id>/notes
+    Then GET .../notes lists it with its timestamp
+    When I POST '{"id": "<other>"}' to .../tickets/<id>/deps
+    Then GET .../deps lists the dependency with its title and status
+    And posting the same dependency again fails with code "conflict"
+    And DELETE .../deps/<other> removes it
+
+  Scenario: Builds and history
+    When I GET .../tickets/<id>/history
+    Then the ticket's mutation events are listed newest first
+    And GET .../builds lists its builds newest first
+
+  Scenario: Errors have codes
+    When I GET a ticket that does not exist
+    Then the response status is 404 with code "not_found"
+    When I POST a ticket with priority 7
+    Then the response status is 422 with code "invalid_field"
+    When I POST a body with an unknown field
+    Then the response status is 400 with code "bad_request"
+    When I DELETE /api/v1/projects/api/tickets
+    Then the response status is 405 with code "method_not_allowed"
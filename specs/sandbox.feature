@@ -0,0 +1,53 @@
+Feature: Namespace sandbox for run nodes and harnesses
+  Opt-in confinement for node processes. With sandbox: true, run commands
+  and agent harnesses execute in new Linux user and mount namespaces where
+  only the project, its artifacts and temp space are writable, and network
+  can be switched off. ko sets this up natively, without bwrap.
+
+  Scenario: Sandboxed nodes can write the project
+    Given a pipeline with sandbox: true
+    And a run node that writes inside.txt in the project root
+    When I run `ko agent build ko-a001`
+    Then inside.txt exists
+
+  Scenario: ko commands work inside the sandbox
+    Given a pipeline with sandbox: true
+    And a run node that runs `ko note ko-b002 "noted from the sandbox"`
+    When I run `ko agent build ko-a001`
+    Then ko-b002 has the note
+    And ko's state directory is read-only inside the sandbox
+    And the note was written by the build, outside the sandbox
+
+  Scenario: ko commands inside the sandbox stay in their project
+    Given a pipeline with sandbox: true
+    And a run node that runs `ko add --project elsewhere "Escaped ticket"`
+    When I run `ko agent build ko-a001`
+    Then the command fails with "a sandboxed node may not write #elsewhere"
+
+  Scenario: The rest of the filesystem is read-only
+    Given a pipeline with sandbox: true
+    And a run node that writes to a directory outside the project
+    When I run `ko agent build ko-a001`
+    Then the write fails inside the node
+    And the file does not exist afterwards
+
+  Scenario: Network can be disabled
+    Given a pipeline with sandbox: true and sandbox_network: false
+    When a run node lists its network interfaces
+    Then only the loopback interface is present
+
+  Scenario: A node can opt out
+    Given a pipeline with sandbox: true
+    And a node with sandbox: false
+    When the node writes outside the project
+    Then the write succeeds
+
+  Scenario: Extra writable paths
+    Given a pipeline with sandbox_writable: [~/.cache/go-build]
+    Then sandboxed nodes can write under ~/.cache/go-build
+
+  Scenario: Unsupported hosts fail closed
+    Given a host without unprivileged user namespaces
+    And a pipeline with sandbox: true
+    When I run `ko agent build ko-a001`
+    Then the sandboxed node fails instead of running unconfined
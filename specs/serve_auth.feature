@@ -0,0 +1,49 @@
+Feature: Serve authentication
+  Bearer tokens in the tokens: section of the global config guard
+  `ko serve`. Each token has scopes (read, write, agent) and may be
+  restricted to registry projects.
+
+  Scenario: Without tokens the server stays open
+    Given no tokens: section in the global config
+    When I start ko serve
+    Then it warns that every endpoint is unauthenticated
+    And requests succeed without a token
+
+  Scenario: A request without a valid token is rejected
+    Given a token "admin" with scopes read, write and agent
+    When I POST to /ko without an Authorization header
+    Then the response status is 401
+    When I POST with "Authorization: Bearer <wrong>"
+    Then the response status is 401
+
+  Scenario: Scopes gate commands
+    Given a token "dashboard" with scope read
+    When it runs ko ls or ko show through /ko
+    Then the command runs
+    When it runs ko close through /ko
+    Then the response status is 403
+    When it calls /agent/spawn
+    Then the response status is 403
+
+  Scenario: Project restrictions
+    Given a token restricted to project "api"
+    When it runs "ls #api"
+    Then the command runs
+    When it runs "ls #web" or shows a ticket with web's prefix
+    Then the response status is 403
+    And GET /api/v1/projects lists only "api"
+
+  Scenario: SSE clients pass the token as a query parameter
+    Given a token with scope read
+    When I open /status/#api?access_token=<token>
+    Then the stream is served
+
+  Scenario: The CLI sends its configured token
+    Given server: and token: $KO_TOKEN in the global config
+    When I run a command that proxies to the server
+    Then the request carries "Authorization: Bearer <value of KO_TOKEN>"
+
+  Scenario: Tokens can reference secrets
+    Given a token "${secrets.KO_ADMIN_TOKEN}"
+    And KO_ADMIN_TOKEN in secrets.env next to the global config
+    Then requests with that value are accepted
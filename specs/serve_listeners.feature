@@ -0,0 +1,39 @@
+Feature: Serve listeners
+  `ko serve` can listen on a TCP address with TLS, or on a Unix socket.
+  The CLI's server: setting takes matching https:// and unix:// URLs.
+
+  Scenario: Serve HTTPS
+    When I run "ko serve --listen :8443 --tls-cert ko.pem --tls-key ko-key.pem"
+    Then it prints "listening on https://localhost:8443"
+    And plain HTTP requests to the port fail
+
+  Scenario: TLS needs both files
+    When I run "ko serve --tls-cert ko.pem"
+    Then it fails with "--tls-cert and --tls-key must be given together"
+
+  Scenario: Serve on a Unix socket
+    When I run "ko serve --listen unix:/run/user/1000/ko.sock"
+    Then the socket is created with mode 0600
+    And it is removed when the server shuts down
+
+  Scenario: A stale socket is replaced
+    Given a socket file left behind by a crashed server
+    When I start ko serve on that path
+    Then the server starts
+    But starting a second server on a live socket fails with "another ko serve is listening"
+
+  Scenario: Invalid listen addresses are rejected
+    When I run "ko serve --listen bogus"
+    Then it fails with "want host:port, :port or unix:/path"
+    When I run "ko serve --listen unix:relative.sock"
+    Then it fails with "socket path must be absolute"
+
+  Scenario: The CLI proxies over a Unix socket
+    Given "server: unix:///run/user/1000/ko.sock" in the global config
+    When I run "ko ls"
+    Then the request is sent over the socket
+
+  Scenario: Unsupported server schemes are rejected
+    Given "server: ftp://ko.example.com" in the global config
+    When I run "ko ls"
+    Then it fails with "want an http://, https:// or unix:// URL"
@@ -0,0 +1,34 @@
+Feature: Resuming ticket streams
+  The SSE streams at /subscribe/ and /status/ use byte offsets in
+  events.jsonl as event IDs, so a client that reconnects can replay the
+  mutations it missed.
+
+  Background:
+    Given ko serve is running
+    And project #api is registered
+
+  Scenario: Live events carry their offset
+    When a client subscribes to /subscribe/#api
+    And a ticket in #api changes
+    Then the client receives a snapshot with the offset after that event as its ID
+    And a "mutation" event with the raw event line and the same ID
+
+  Scenario: Reconnecting replays missed events
+    Given a client last saw event ID 1200
+    And two tickets in #api and one in #web changed while it was away
+    When it reconnects with Last-Event-ID 1200
+    Then it receives the two #api events as "mutation" events in order
+    And then a snapshot with the current offset as its ID
+
+  Scenario: Scripts catch up with since
+    When a script subscribes to /subscribe/#api?since=2026-10-19T09:00:00Z
+    Then it receives every #api event from 09:00 onwards before the snapshot
+
+  Scenario: An ID from before a rotation replays nothing
+    Given events.jsonl was rotated
+    When a client reconnects with an ID that is not at a line boundary
+    Then it receives only the snapshot
+
+  Scenario: A bad since is rejected
+    When a client subscribes to /subscribe/#api?since=yesterday
+    Then it gets 400
@@ -0,0 +1,57 @@
+Feature: Archiving and deleting tickets
+  ko archive moves closed tickets out of the everyday views without losing
+  them, and ko rm deletes tickets outright. Both record mutation events so
+  the history stays consistent.
+
+  Background:
+    Given ar-0001 and ar-0002 are closed and ar-0003 is open
+    And ar-0001 has an artifact directory
+
+  Scenario: Archiving closed tickets
+    When I run "ko archive"
+    Then the output shows "ar-0001 archived" and "ar-0002 archived"
+    And ar-0003 is not archived
+    And an archive event is recorded for each archived ticket
+
+  Scenario: Archiving tickets closed before a date
+    When I run "ko archive --closed-before 2000-01-01"
+    Then the output shows "No closed tickets to archive"
+    And "ko archive --closed-before yesterday" fails with an invalid date error
+
+  Scenario: Archived tickets leave the everyday views
+    Given ar-0001 is archived
+    Then "ko ls --all", "ko ready" and "ko stats" do not count ar-0001
+
+  Scenario: Artifacts are compressed
+    When I run "ko archive"
+    Then .ko/tickets/ar-0001.artifacts.tar.gz holds the artifact directory
+    And .ko/tickets/ar-0001.artifacts no longer exists
+
+  Scenario: Archived tickets stay readable
+    Given ar-0001 is archived
+    Then "ko show ar-0001" shows it with an "archived:" line
+    And "ko search --archived" finds it while "ko search" does not
+    And "ko export" includes it with its archived timestamp
+
+  Scenario: Reopening unarchives
+    Given ar-0002 is archived
+    When I run "ko open ar-0002"
+    Then ar-0002 is listed by "ko ls" again
+
+  Scenario: Deleting a ticket
+    Given rm-0004 has no dependents and no children
+    When I run "ko rm rm-0004"
+    Then the output shows "rm-0004 removed"
+    And its notes, builds and artifacts are gone
+    And an rm event is recorded
+
+  Scenario: Deleting a ticket others depend on
+    Given rm-0003 depends on rm-0002, which depends on rm-0001
+    Then "ko rm rm-0002" fails with "rm-0002 is a dependency of rm-0003"
+    When I run "ko rm rm-0002 --rewire"
+    Then rm-0003 depends on rm-0001
+    And an update event records the rewired deps of rm-0003
+
+  Scenario: Deleting a parent
+    Given rm-0004 has a child rm-0004.a001
+    Then "ko rm rm-0004" fails with "rm-0004 has children (rm-0004.a001)"
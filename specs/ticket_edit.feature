@@ -0,0 +1,52 @@
+Feature: Editing tickets in $EDITOR
+  ko edit renders a ticket as markdown with frontmatter, opens it in
+  $EDITOR and saves what changed, so long bodies are edited in an editor
+  rather than through quoted ko update flags.
+
+  Scenario: Editing one ticket
+    Given ticket ko-a001 with title "Old login page" and priority 2
+    When I run "ko edit ko-a001" and change the title and priority
+    Then the output is "ko-a001 updated (title, priority)"
+    And one update event is recorded per field with "field", "from" and "to"
+
+  Scenario: Status changes also record a status event
+    When I change "status: open" to "status: in_progress" in the editor
+    Then an update event and a status event are recorded
+
+  Scenario: Closing the editor without changes
+    When I save the buffer unchanged
+    Then the output is "No changes."
+    And no event is recorded
+
+  Scenario: Invalid edits are rejected
+    When I set the status to "done", add a dep that does not exist, add a dep
+      that closes a cycle, set a descendant as parent, change the id, or write
+      a malformed plan question or custom field value
+    Then the command fails naming the ticket and the problem
+    And nothing is saved
+    And the error gives the path of the temp file holding my changes
+
+  Scenario: Existing dangling references are not my edit's fault
+    Given ko-a001 depends on a ticket that no longer exists
+    When I only change its title
+    Then the edit is saved
+
+  Scenario: Concurrent changes
+    Given someone runs "ko update ko-a001 -p 0" while my editor is open
+    When I save
+    Then the edit is rejected as changed by someone else
+
+  Scenario: Batch edits
+    When I run "ko edit --batch 'status=open tag=ui'"
+    Then every matching ticket is in one buffer under a "<!-- ko edit: <id> -->" marker
+    And all changes are saved in one transaction
+    And one invalid ticket rejects the whole batch
+
+  Scenario: Batch filters
+    Then the filter terms are status=, type=, tag=, assignee=, parent= and all=true
+    And custom field filters such as "estimate>=3" apply
+    And an empty match fails with "no tickets match"
+
+  Scenario: Removing a section from a batch
+    When I delete a ticket's section from the buffer
+    Then that ticket is left unchanged
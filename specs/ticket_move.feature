@@ -0,0 +1,48 @@
+Feature: Moving tickets between projects
+  ko mv moves a ticket and its descendants to another registered project.
+  Ticket IDs carry the project prefix, so moved tickets are re-prefixed and
+  every reference to them is rewritten; the old IDs stay resolvable.
+
+  Background:
+    Given projects "#alpha" (prefix alp) and "#beta" (prefix bet) are registered
+    And alp-a001 has a child alp-a001.b001
+    And alp-a002 in alpha and bet-0001 in beta depend on them
+
+  Scenario: Moving a ticket with its children
+    When I run "ko mv alp-a001 '#beta'"
+    Then the output shows "alp-a001 -> bet-a001" and "alp-a001.b001 -> bet-a001.b001"
+    And bet-a001.b001 has parent bet-a001
+    And the tickets are listed in beta and no longer in alpha
+
+  Scenario: References are rewritten in every registered project
+    When I run "ko mv alp-a001 '#beta'"
+    Then alp-a002 depends on bet-a001.b001
+    And bet-0001 depends on bet-a001
+    And an update event is recorded for each rewritten reference
+
+  Scenario: Artifacts and build history move along
+    Given alp-a001 has an artifact directory and a build
+    When I run "ko mv alp-a001 '#beta'"
+    Then the artifact directory is .ko/tickets/bet-a001.artifacts in beta
+    And the build belongs to bet-a001
+
+  Scenario: Old IDs still resolve
+    When I run "ko mv alp-a001 '#beta'"
+    Then "ko show alp-a001" shows bet-a001
+    And commands taking an ID accept alp-a001.b001 for bet-a001.b001
+    And a move event records the old and new IDs
+
+  Scenario: Moving a child
+    When I run "ko mv bet-a001.b001 '#alpha'"
+    Then it becomes the top-level ticket alp-b001
+    And the output says it was a child of bet-a001
+
+  Scenario: Taken IDs
+    Given bet-a001 already exists in beta
+    When I run "ko mv alp-a001 '#beta'"
+    Then the moved tickets get a fresh hash
+
+  Scenario: Errors
+    Then "ko mv alp-a002" fails with a usage message
+    And "ko mv alp-a002 '#gamma'" fails with "unknown project 'gamma'"
+    And "ko mv alp-a002 '#alpha'" fails with "alp-a002 is already in #alpha"
@@ -0,0 +1,64 @@
+Feature: Ticket templates
+  Tickets of a recurring kind start from a template: .ko/templates/<name>.md
+  in the project, or ~/.config/knockout/templates/<name>.md for every
+  project. The frontmatter holds defaults; the body is a skeleton with
+  placeholders.
+
+  Background:
+    Given .ko/templates/bug.md contains:
+      """
+      ---
+      description: Bug report
+      type: bug
+      priority: 1
+      tags: [bug]
+      triage: confirm severity
+      ---
+      ## Steps to reproduce
+
+      {{.description}}
+
+      ## Expected
+      """
+
+  Scenario: A template fills defaults and the body
+    When I run "ko add --template bug 'Login 500s' -d 'POST /login fails'"
+    Then the ticket has type "bug", priority 1, tag "bug" and triage "confirm severity"
+    And its body is the skeleton with "POST /login fails" under "## Steps to reproduce"
+
+  Scenario: Flags override template defaults
+    When I run "ko add --template bug -p 3 --tags ui 'Typo'"
+    Then the ticket has priority 3 and tags "ui"
+    And its type is still "bug"
+
+  Scenario: A description without a placeholder goes above the skeleton
+    Given .ko/templates/spike.md has no {{.description}} placeholder
+    When I run "ko add --template spike 'Try sqlite' 'Is it fast enough?'"
+    Then the body starts with "Is it fast enough?" followed by the skeleton
+
+  Scenario: Placeholders
+    Then {{.title}}, {{.description}}, {{.id}}, {{.parent}} and {{.date}} are filled in
+    And unknown placeholders render as nothing
+
+  Scenario: Project templates shadow global ones
+    Given ~/.config/knockout/templates/bug.md and ~/.config/knockout/templates/retro.md exist
+    When I run "ko template ls"
+    Then bug is listed from "project" and retro from "global"
+
+  Scenario: Unknown templates are rejected
+    When I run "ko add --template epic 'Big thing'"
+    Then the command fails with "unknown template \"epic\" (have bug)"
+    And no ticket is created
+
+  Scenario: Broken templates
+    Given a template with "priority: 9" in its frontmatter
+    When I run "ko add --template broken 'x'"
+    Then the command fails with "frontmatter: line 2: priority: must be 0-4, got 9"
+    And ko template ls skips it with a warning
+    And unknown frontmatter keys only warn, naming the file and line
+
+  Scenario: Web UI and REST API
+    Then the web UI add bar shows a template picker when the project has templates
+    And POST /api/v1/projects/{tag}/tickets accepts "template"
+    And GET /api/v1/projects/{tag}/templates lists the templates
+    And template names containing "/" or ".." are rejected
@@ -0,0 +1,68 @@
+Feature: Path scope enforcement
+  Tickets and action nodes can declare the paths an agent may write. After
+  each action node ko compares the files it changed against that scope and
+  either fails the node or reverts the stray changes, so agents cannot
+  wander into unrelated modules.
+
+  Scenario: Ticket scope is declared in frontmatter
+    Given a ticket with frontmatter scope: ["src/api/**", "docs/**"]
+    When I run `ko show ko-a001`
+    Then the output contains "scope: [src/api/**, docs/**]"
+
+  Scenario: Scope can be set from the CLI
+    When I run `ko update ko-a001 --scope 'src/**,docs/**'`
+    Then the ticket's scope is ["src/**", "docs/**"]
+
+  Scenario: Writes outside the scope fail the node
+    Given ticket ko-a001 has scope ["src/api/**"]
+    And an action node that writes "src/api/handler.go" and "README.md"
+    When I run `ko agent build ko-a001`
+    Then the build fails
+    And ticket ko-a001 has a note containing "outside allowed scope: README.md"
+
+  Scenario: Writes inside the scope pass
+    Given ticket ko-a001 has scope ["src/**"]
+    And an action node that only writes under "src/"
+    When I run `ko agent build ko-a001`
+    Then the build succeeds
+
+  Scenario: Ticket and node scopes both apply
+    Given ticket ko-a001 has scope ["src/**"]
+    And the implement node has scope ["src/api/**", "README.md"]
+    When the node writes "README.md"
+    Then the node fails because "README.md" is outside the ticket scope
+
+  Scenario: A scope violation is not retried
+    Given a pipeline with max_retries: 2
+    And an action node that writes outside the scope
+    When I run `ko agent build ko-a001`
+    Then the node runs once and fails
+    And the out-of-scope file is left in place
+
+  Scenario: scope_violation revert undoes stray changes
+    Given a pipeline with scope_violation: revert
+    And ticket ko-a001 has scope ["src/**"]
+    And an action node that adds "stray.txt" and edits "config.txt"
+    When I run `ko agent build ko-a001`
+    Then the build succeeds
+    And "stray.txt" has been removed
+    And "config.txt" has its content from before the node, committed or not
+    And ticket ko-a001 has a note listing the reverted files
+
+  Scenario: revert copies the files outside the scope before the node runs
+    Given a pipeline with scope_violation: revert
+    When an action node with a scope starts
+    Then every file outside the scope is copied under the ticket's artifact directory
+    And the copies are removed when the node finishes
+    And no git repository is needed
+
+  Scenario: Unrevertable changes still fail
+    Given a pipeline with scope_violation: revert
+    And a file outside the scope that could not be copied before the node ran
+    When the node modifies that file
+    Then the node fails because the file cannot be restored
+
+  Scenario: Invalid scope_violation is rejected
+    Given a pipeline with scope_violation: ignore
+    When the pipeline is parsed
+    Then parsing fails with "scope_violation must be"
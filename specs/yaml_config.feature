@@ -0,0 +1,46 @@
+Feature: YAML config files
+  Every YAML file ko reads — .ko/config.yaml, legacy .ko/pipeline.yml,
+  projects.yml, the global config.yaml, the QQL mapping and ticket
+  frontmatter — goes through one built-in YAML parser. Errors carry the file
+  and line, unknown keys are warned about, and files ko rewrites keep their
+  comments and formatting.
+
+  Scenario: A syntax error names the file and line
+    Given .ko/pipeline.yml has a node key indented one space short on line 6
+    When I run "ko agent build ko-a001"
+    Then the command fails with "pipeline.yml: line 6: bad indentation"
+
+  Scenario: A value of the wrong type names its key and line
+    Given .ko/pipeline.yml sets max_retries: none on line 2
+    When I run "ko agent build ko-a001"
+    Then the command fails with "line 2: max_retries: expected an integer"
+
+  Scenario: Nodes listed straight after workflow settings are deprecated
+    Given a workflow with on_success: followed directly by "- name:" entries
+    When I run "ko agent build ko-a001"
+    Then the build runs with those nodes
+    And stderr warns "move the nodes under `nodes:`"
+
+  Scenario: Unknown keys warn but do not fail
+    Given .ko/pipeline.yml has max_retry: on line 2 and tmeout: on a node
+    When I run "ko agent build ko-a001"
+    Then the build succeeds
+    And stderr has "ko: warning: ...pipeline.yml: line 2: unknown key \"max_retry\""
+    And stderr has "line 8: workflows.main: unknown key \"tmeout\""
+
+  Scenario: Full YAML syntax is understood
+    Given a pipeline using a flow mapping with quoted keys, an anchor, a << merge,
+      a folded (>-) and a literal (|) block scalar
+    When I run "ko agent build ko-a001"
+    Then each value reads as YAML defines it
+
+  Scenario: ko project set edits files in place
+    Given .ko/config.yaml and projects.yml with comments
+    When I run "ko project set '#fort-nix' --prefix=fx"
+    Then only the prefix value in config.yaml changes
+    And the new project is appended to projects.yml with the comments intact
+
+  Scenario: Rewriting unchanged values is a no-op
+    Given the project is already registered with the same values
+    When I run "ko project set '#fort-nix' --prefix=fx" again
+    Then config.yaml and projects.yml are byte-for-byte unchanged
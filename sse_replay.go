@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSE event IDs on /subscribe/ and /status/ are byte offsets in
+// events.jsonl: the ID of an event is the offset just past its line, and
+// a snapshot's ID is the offset the stream was at when it was taken. A
+// client that resumes from an ID has seen everything before it, so the
+// lines after it are exactly what it missed.
+
+// publish sends the messages for the event ending at offset: message to
+// tickets-only subscribers of project, statusMessage to /status/ ones.
+// Moving the offset under the same lock means a new subscriber either
+// replays the event or receives it, never both.
+func (t *tailer) publish(project, message, statusMessage string, offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.offset = offset
+	for sub := range t.subscribers {
+		if sub.project != project {
+			continue
+		}
+		msg := message
+		if sub.includeAgent {
+			msg = statusMessage
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			// Channel full, drop event (best-effort delivery)
+		}
+	}
+}
+
+// setOffset records that events up to offset have been handled.
+func (t *tailer) setOffset(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.offset = offset
+}
+
+// currentOffset returns the events.jsonl offset broadcast so far. It is
+// the SSE event ID: a client resuming from it has seen every event before.
+func (t *tailer) currentOffset() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.offset
+}
+
+// resumePoint is where a subscriber asked to pick the stream up again:
+// an offset from Last-Event-ID or ?since=<id>, or a time from
+// ?since=<RFC 3339 time>.
+type resumePoint struct {
+	offset int64
+	since  time.Time
+}
+
+// parseResumePoint reads the Last-Event-ID header an EventSource sends on
+// reconnect, or the ?since= parameter scripts use. It returns nil for a
+// fresh subscription.
+func parseResumePoint(r *http.Request) (*resumePoint, error) {
+	if v := r.URL.Query().Get("since"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			return &resumePoint{offset: n}, nil
+		}
+		ts, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("since: want an event ID or an RFC 3339 time, got %q", v)
+		}
+		return &resumePoint{since: ts}, nil
+	}
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			// Not one of ours (or from before IDs were offsets): start fresh.
+			return nil, nil
+		}
+		return &resumePoint{offset: n}, nil
+	}
+	return nil, nil
+}
+
+// resumeSubscriber replays what a resuming subscriber missed and starts
+// the snapshot that follows, whose ID is the offset it subscribed at.
+func resumeSubscriber(w io.Writer, resume *resumePoint, sub *subscriber) {
+	if resume != nil {
+		replayMutations(w, mutationEventPath(), sub.project, resume, sub.offset)
+	}
+	fmt.Fprintf(w, "id: %d\n", sub.offset)
+}
+
+// mutationMessage formats one events.jsonl line as a "mutation" SSE event.
+func mutationMessage(event string, offset int64) string {
+	return fmt.Sprintf("id: %d\nevent: mutation\ndata: %s\n\n", offset, event)
+}
+
+// replayMutations writes the events for project between the resume point
+// and end as "mutation" events. An offset that is past end or not at the
+// start of a line (the file was rotated, or the ID is made up) replays
+// nothing; the snapshot that follows still brings the client up to date.
+func replayMutations(w io.Writer, path, project string, from *resumePoint, end int64) error {
+	start := from.offset
+	if !from.since.IsZero() {
+		start = 0
+	}
+	if start >= end {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	if start > 0 {
+		var prev [1]byte
+		if _, err := f.ReadAt(prev[:], start-1); err != nil || prev[0] != '\n' {
+			return nil
+		}
+	}
+
+	br := bufio.NewReader(io.NewSectionReader(f, start, end-start))
+	pos := start
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			// A partial last line is still being written; the tailer sends it.
+			return nil
+		}
+		pos += int64(len(line))
+		line = strings.TrimSuffix(line, "\n")
+		var evt MutationEvent
+		if line == "" || json.Unmarshal([]byte(line), &evt) != nil || evt.Project != project {
+			continue
+		}
+		if !from.since.IsZero() {
+			ts, err := time.Parse(time.RFC3339, evt.Timestamp)
+			if err != nil || ts.Before(from.since) {
+				continue
+			}
+		}
+		if _, err := io.WriteString(w, mutationMessage(line, pos)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseID returns the id: field of an event, or -1.
+func sseID(lines []string) int64 {
+	for _, l := range lines {
+		if v, ok := strings.CutPrefix(l, "id: "); ok {
+			n, _ := strconv.ParseInt(v, 10, 64)
+			return n
+		}
+	}
+	return -1
+}
+
+// connectSSE opens a stream and returns a reader positioned after the
+// retry directive.
+func connectSSE(t *testing.T, url, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	reader := bufio.NewReader(resp.Body)
+	if retry, err := readSSEEvent(reader, 3*time.Second); err != nil || !sseHasLine(retry, "retry:") {
+		t.Fatalf("retry directive: %v %v", retry, err)
+	}
+	return resp, reader
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	projectDir, eventsFile, testTailer := setupSSETest(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subscribe/", func(w http.ResponseWriter, r *http.Request) {
+		handleSubscribeWithTailer(w, r, testTailer)
+	})
+	mux.HandleFunc("/status/", func(w http.ResponseWriter, r *http.Request) {
+		handleStatusSubscribeWithTailer(w, r, testTailer)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close) // after the streams are closed
+	url := server.URL + "/subscribe?project=" + projectDir
+
+	// A live client sees the snapshot, then the raw event, with one ID
+	resp, reader := connectSSE(t, url, "")
+	readSSEEvent(reader, 3*time.Second)
+	writeEvent(t, eventsFile, projectDir)
+	snapshot, err := readSSEEvent(reader, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutation, err := readSSEEvent(reader, 3*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := sseID(mutation)
+	if !sseHasLine(mutation, "event: mutation") || seen <= 0 || sseID(snapshot) != seen {
+		t.Fatalf("snapshot %v, mutation %v", snapshot, mutation)
+	}
+	resp.Body.Close()
+
+	// Missed while disconnected: one event here, one elsewhere
+	writeEvent(t, eventsFile, "/some/other/project")
+	writeEvent(t, eventsFile, projectDir)
+	time.Sleep(500 * time.Millisecond)
+	info, _ := os.Stat(eventsFile)
+
+	for _, path := range []string{"/subscribe", "/status"} {
+		_, reader = connectSSE(t, server.URL+path+"?project="+projectDir, strconv.FormatInt(seen, 10))
+		replayed, err := readSSEEvent(reader, 3*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sseHasLine(replayed, "event: mutation") || sseID(replayed) != info.Size() {
+			t.Errorf("%s: replayed %v, want the last event at %d", path, replayed, info.Size())
+		}
+		var evt MutationEvent
+		for _, l := range replayed {
+			if data, ok := strings.CutPrefix(l, "data: "); ok {
+				json.Unmarshal([]byte(data), &evt)
+			}
+		}
+		if evt.Project != projectDir || evt.Ticket != "test-1234" {
+			t.Errorf("%s: replayed event = %+v", path, evt)
+		}
+		snapshot, _ = readSSEEvent(reader, 3*time.Second)
+		if sseHasLine(snapshot, "event:") || sseID(snapshot) != info.Size() {
+			t.Errorf("%s: snapshot after replay = %v", path, snapshot)
+		}
+	}
+
+	// ?since= takes an event ID or a time; both replay from the start here
+	for _, since := range []string{"0", "2000-01-01T00:00:00Z"} {
+		_, reader = connectSSE(t, url+"&since="+since, "")
+		var ids []int64
+		for {
+			ev, err := readSSEEvent(reader, 3*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sseHasLine(ev, "event: mutation") {
+				break
+			}
+			ids = append(ids, sseID(ev))
+		}
+		if len(ids) != 2 || ids[0] != seen || ids[1] != info.Size() {
+			t.Errorf("since=%s: replayed IDs %v", since, ids)
+		}
+	}
+
+	// An ID that is not a line boundary replays nothing
+	_, reader = connectSSE(t, url, "3")
+	if ev, _ := readSSEEvent(reader, 3*time.Second); sseHasLine(ev, "event:") || sseID(ev) != info.Size() {
+		t.Errorf("bogus Last-Event-ID: first event %v", ev)
+	}
+
+	bad, err := http.Get(url + "&since=yesterday")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Errorf("since=yesterday: status %d, want 400", bad.StatusCode)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TicketTemplate is a ticket skeleton for ko add --template: a markdown
+// file whose frontmatter holds defaults for the new ticket and whose body
+// is rendered with text/template. Project templates live in
+// .ko/templates/<name>.md and shadow user-global ones of the same name in
+// ~/.config/knockout/templates/.
+type TicketTemplate struct {
+	Name        string
+	Source      string // project or global
+	Path        string
+	Description string // one-line summary for listings
+	Type        string
+	Priority    *int
+	Tags        []string
+	Triage      string
+	Body        string
+
+	body *template.Template
+}
+
+// templateNamePattern keeps template names to plain file names, so a name
+// from the API cannot reach outside the templates directories.
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// templateDir is a directory templates are read from.
+type templateDir struct {
+	source, dir string
+}
+
+// templateDirs returns the directories templates are read from, highest
+// precedence first. Without a project (ticketsDir "") only the user-global
+// directory is searched.
+func templateDirs(ticketsDir string) []templateDir {
+	var dirs []templateDir
+	if ticketsDir != "" {
+		dirs = append(dirs, templateDir{"project", filepath.Join(ProjectRoot(ticketsDir), ".ko", "templates")})
+	}
+	if global := GlobalConfigPath(); global != "" {
+		dirs = append(dirs, templateDir{"global", filepath.Join(filepath.Dir(global), "templates")})
+	}
+	return dirs
+}
+
+// LoadTicketTemplate finds and parses the template called name.
+func LoadTicketTemplate(ticketsDir, name string) (*TicketTemplate, error) {
+	if !templateNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+	for _, d := range templateDirs(ticketsDir) {
+		path := filepath.Join(d.dir, name+".md")
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return loadTemplateFile(path, name, d.source, string(data))
+	}
+	var names []string
+	all, _ := ListTicketTemplates(ticketsDir)
+	for _, tt := range all {
+		names = append(names, tt.Name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("unknown template %q (no templates in .ko/templates)", name)
+	}
+	return nil, fmt.Errorf("unknown template %q (have %s)", name, strings.Join(names, ", "))
+}
+
+// ListTicketTemplates returns every template visible from ticketsDir,
+// sorted by name. A template that fails to parse is skipped with a
+// warning, so one broken file does not hide the rest.
+func ListTicketTemplates(ticketsDir string) ([]*TicketTemplate, error) {
+	seen := map[string]bool{}
+	var out []*TicketTemplate
+	for _, d := range templateDirs(ticketsDir) {
+		entries, err := os.ReadDir(d.dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := strings.TrimSuffix(e.Name(), ".md")
+			if e.IsDir() || name == e.Name() || seen[name] || !templateNamePattern.MatchString(name) {
+				continue
+			}
+			path := filepath.Join(d.dir, e.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			seen[name] = true
+			tt, err := loadTemplateFile(path, name, d.source, string(data))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ko: warning: skipping template: %v\n", err)
+				continue
+			}
+			out = append(out, tt)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// loadTemplateFile parses a template file, printing any warnings.
+func loadTemplateFile(path, name, source, content string) (*TicketTemplate, error) {
+	tt, warnings, err := ParseTicketTemplate(name, content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	warnYAML(path, warnings)
+	tt.Source = source
+	tt.Path = path
+	return tt, nil
+}
+
+// ParseTicketTemplate parses a template file. The frontmatter is optional;
+// unknown keys in it are returned as warnings.
+func ParseTicketTemplate(name, content string) (*TicketTemplate, []string, error) {
+	tt := &TicketTemplate{Name: name, Body: content}
+	var d yamlDecoder
+	if strings.HasPrefix(content, "---\n") {
+		end := strings.Index(content[4:], "\n---\n")
+		if end < 0 {
+			return nil, nil, fmt.Errorf("unterminated frontmatter")
+		}
+		root, err := parseYAML(content[:4+end+1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("frontmatter: %v", err)
+		}
+		if err := tt.decode(&d, root); err != nil {
+			return nil, nil, fmt.Errorf("frontmatter: %v", err)
+		}
+		tt.Body = content[4+end+5:]
+	}
+	body, err := template.New(name).Option("missingkey=zero").Parse(tt.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	tt.body = body
+	return tt, d.warnings, nil
+}
+
+// decode reads the frontmatter defaults.
+func (tt *TicketTemplate) decode(d *yamlDecoder, root *yamlNode) error {
+	entries, err := d.entries(root, "frontmatter")
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		var err error
+		switch key := e.Key.Value; key {
+		case "description":
+			tt.Description, err = yamlString(e.Value, key)
+		case "type":
+			tt.Type, err = yamlString(e.Value, key)
+		case "priority":
+			var p int
+			if p, err = yamlInt(e.Value, key); err == nil && (p < 0 || p > 4) {
+				err = yamlErrorf(e.Value, "priority: must be 0-4, got %d", p)
+			}
+			tt.Priority = &p
+		case "tags":
+			tt.Tags, err = yamlStrings(e.Value, key)
+		case "triage":
+			tt.Triage, err = yamlString(e.Value, key)
+		default:
+			d.unknown(e.Key, "")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply sets t's type, priority, tags and triage from the template and
+// appends the rendered body. Placeholders are {{.title}}, {{.description}},
+// {{.id}}, {{.parent}} and {{.date}}; a description the body has no
+// placeholder for goes above the skeleton. Callers override the defaults
+// afterwards with whatever was given explicitly.
+func (tt *TicketTemplate) Apply(t *Ticket, description string) error {
+	if tt.Type != "" {
+		t.Type = tt.Type
+	}
+	if tt.Priority != nil {
+		t.Priority = *tt.Priority
+	}
+	if len(tt.Tags) > 0 {
+		t.Tags = append([]string(nil), tt.Tags...)
+	}
+	if tt.Triage != "" {
+		t.Triage = tt.Triage
+	}
+
+	var b strings.Builder
+	err := tt.body.Execute(&b, map[string]string{
+		"title":       t.Title,
+		"description": description,
+		"id":          t.ID,
+		"parent":      t.Parent,
+		"date":        time.Now().Format("2006-01-02"),
+	})
+	if err != nil {
+		return fmt.Errorf("template %s: %v", tt.Name, err)
+	}
+	body := strings.TrimSpace(b.String())
+	if description != "" && !strings.Contains(tt.Body, ".description") {
+		body = strings.TrimSpace(description + "\n\n" + body)
+	}
+	if body != "" {
+		t.Body += "\n" + body + "\n"
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTicketTemplate(t *testing.T) {
+	tt, warnings, err := ParseTicketTemplate("bug", "---\ndescription: Bug report\ntype: bug\npriority: 1\ntags: [bug, triage-me]\ncolour: red\n---\n## Steps\n\n{{.description}}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tt.Description != "Bug report" || tt.Type != "bug" || tt.Priority == nil || *tt.Priority != 1 ||
+		strings.Join(tt.Tags, ",") != "bug,triage-me" || tt.Body != "## Steps\n\n{{.description}}\n" {
+		t.Errorf("template = %+v", tt)
+	}
+	if len(warnings) != 1 || warnings[0] != `line 6: unknown key "colour"` {
+		t.Errorf("warnings = %q", warnings)
+	}
+
+	plain, _, err := ParseTicketTemplate("spike", "## Question\n")
+	if err != nil || plain.Body != "## Question\n" || plain.Priority != nil {
+		t.Errorf("no frontmatter = %+v, %v", plain, err)
+	}
+
+	for content, want := range map[string]string{
+		"---\npriority: 7\n---\n": "frontmatter: line 2: priority: must be 0-4, got 7",
+		"---\ntype: bug\n":        "unterminated frontmatter",
+		"{{.title":                "unclosed action",
+	} {
+		if _, _, err := ParseTicketTemplate("x", content); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseTicketTemplate(%q) err = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestTicketTemplateApply(t *testing.T) {
+	tt, _, err := ParseTicketTemplate("bug", "---\ntype: bug\npriority: 1\ntriage: confirm severity\n---\n{{.title}}: reported {{.date}} as {{.id}}.\n\n## Steps\n\n{{.description}}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := &Ticket{ID: "ko-a001", Title: "Login 500s", Type: "task", Priority: 2}
+	if err := tt.Apply(tk, "POST /login"); err != nil {
+		t.Fatal(err)
+	}
+	if tk.Type != "bug" || tk.Priority != 1 || tk.Triage != "confirm severity" {
+		t.Errorf("defaults not applied: %+v", tk)
+	}
+	if !strings.HasPrefix(tk.Body, "\nLogin 500s: reported 2") || !strings.Contains(tk.Body, "as ko-a001.") ||
+		!strings.HasSuffix(tk.Body, "## Steps\n\nPOST /login\n") {
+		t.Errorf("body = %q", tk.Body)
+	}
+
+	// Without a {{.description}} placeholder the description leads.
+	skeleton, _, _ := ParseTicketTemplate("spike", "## Findings\n")
+	tk = &Ticket{Title: "Try sqlite"}
+	skeleton.Apply(tk, "Is it fast enough?")
+	if tk.Body != "\nIs it fast enough?\n\n## Findings\n" {
+		t.Errorf("body = %q", tk.Body)
+	}
+}
+
+func TestLoadTicketTemplate(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	ticketsDir := filepath.Join(t.TempDir(), ".ko", "tickets")
+	project := filepath.Join(filepath.Dir(ticketsDir), "templates")
+	global := filepath.Join(configHome, "knockout", "templates")
+	os.MkdirAll(project, 0755)
+	os.MkdirAll(global, 0755)
+	os.WriteFile(filepath.Join(project, "bug.md"), []byte("---\ntype: bug\n---\nproject\n"), 0644)
+	os.WriteFile(filepath.Join(global, "bug.md"), []byte("global\n"), 0644)
+	os.WriteFile(filepath.Join(global, "spike.md"), []byte("---\ntype: spike\n---\n"), 0644)
+
+	tt, err := LoadTicketTemplate(ticketsDir, "bug")
+	if err != nil || tt.Source != "project" || tt.Body != "project\n" {
+		t.Errorf("bug = %+v, %v", tt, err)
+	}
+	tt, err = LoadTicketTemplate(ticketsDir, "spike")
+	if err != nil || tt.Source != "global" || tt.Type != "spike" {
+		t.Errorf("spike = %+v, %v", tt, err)
+	}
+	if _, err := LoadTicketTemplate(ticketsDir, "epic"); err == nil || !strings.Contains(err.Error(), "have bug, spike") {
+		t.Errorf("missing template err = %v", err)
+	}
+	if _, err := LoadTicketTemplate(ticketsDir, "../bug"); err == nil || !strings.Contains(err.Error(), "invalid template name") {
+		t.Errorf("path name err = %v", err)
+	}
+
+	all, err := ListTicketTemplates(ticketsDir)
+	if err != nil || len(all) != 2 || all[0].Source != "project" || all[1].Name != "spike" {
+		t.Errorf("list = %v, %v", all, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -34,7 +35,10 @@ type Ticket struct {
 	Snooze        string         `yaml:"snooze,omitempty"`
 	Triage        string         `yaml:"triage,omitempty"`
 	Tags          []string       `yaml:"tags,omitempty"`
+	Scope         []string       `yaml:"scope,omitempty"`
 	PlanQuestions []PlanQuestion `yaml:"plan-questions,omitempty"`
+	// Fields are the custom fields declared in .ko/config.yaml, by name.
+	Fields []TicketField `yaml:"fields,omitempty"`
 
 	// Title is extracted from the first markdown heading.
 	Title string `yaml:"-"`
@@ -42,6 +46,15 @@ type Ticket struct {
 	Body string `yaml:"-"`
 	// ModTime is the file modification time, populated by ListTickets/LoadTicket.
 	ModTime time.Time `yaml:"-"`
+
+	// Transient state of the running build, exported to lifecycle hooks.
+	// Never persisted. blockReason and blockedOn record why the build left
+	// the ticket blocked; buildOutcome is set once the build has an outcome;
+	// dispReason is the reason of the last disposition or failure.
+	blockReason  string
+	blockedOn    string
+	buildOutcome string
+	dispReason   string
 }
 
 // PlanQuestion represents a question that needs to be answered before implementing a ticket.
@@ -114,42 +127,51 @@ func FormatTicket(t *Ticket) string {
 	b.WriteString("---\n")
 	b.WriteString(fmt.Sprintf("id: %s\n", t.ID))
 	b.WriteString(fmt.Sprintf("status: %s\n", t.Status))
-	b.WriteString(fmt.Sprintf("deps: [%s]\n", strings.Join(t.Deps, ", ")))
+	b.WriteString(fmt.Sprintf("deps: %s\n", yamlFlowList(t.Deps)))
 	b.WriteString(fmt.Sprintf("created: %s\n", t.Created))
 	b.WriteString(fmt.Sprintf("type: %s\n", t.Type))
 	b.WriteString(fmt.Sprintf("priority: %d\n", t.Priority))
 	if t.Assignee != "" {
-		b.WriteString(fmt.Sprintf("assignee: %s\n", t.Assignee))
+		b.WriteString(fmt.Sprintf("assignee: %s\n", yamlQuote(t.Assignee)))
 	}
 	if t.Parent != "" {
-		b.WriteString(fmt.Sprintf("parent: %s\n", t.Parent))
+		b.WriteString(fmt.Sprintf("parent: %s\n", yamlQuote(t.Parent)))
 	}
 	if t.ExternalRef != "" {
-		b.WriteString(fmt.Sprintf("external-ref: %s\n", t.ExternalRef))
+		b.WriteString(fmt.Sprintf("external-ref: %s\n", yamlQuote(t.ExternalRef)))
 	}
 	if t.Snooze != "" {
-		b.WriteString(fmt.Sprintf("snooze: %s\n", t.Snooze))
+		b.WriteString(fmt.Sprintf("snooze: %s\n", yamlQuote(t.Snooze)))
 	}
 	if t.Triage != "" {
-		b.WriteString(fmt.Sprintf("triage: %s\n", t.Triage))
+		b.WriteString(fmt.Sprintf("triage: %s\n", yamlQuote(t.Triage)))
 	}
 	if len(t.Tags) > 0 {
-		b.WriteString(fmt.Sprintf("tags: [%s]\n", strings.Join(t.Tags, ", ")))
+		b.WriteString(fmt.Sprintf("tags: %s\n", yamlFlowList(t.Tags)))
+	}
+	if len(t.Scope) > 0 {
+		b.WriteString(fmt.Sprintf("scope: [%s]\n", formatScopeList(t.Scope)))
+	}
+	if len(t.Fields) > 0 {
+		b.WriteString("fields:\n")
+		for _, f := range t.Fields {
+			b.WriteString(fmt.Sprintf("  %s: %s\n", yamlQuote(f.Name), yamlQuote(f.Value)))
+		}
 	}
 	if len(t.PlanQuestions) > 0 {
 		b.WriteString("plan-questions:\n")
 		for _, q := range t.PlanQuestions {
-			b.WriteString(fmt.Sprintf("  - id: %s\n", q.ID))
-			b.WriteString(fmt.Sprintf("    question: \"%s\"\n", q.Question))
+			b.WriteString(fmt.Sprintf("  - id: %s\n", yamlQuote(q.ID)))
+			b.WriteString(fmt.Sprintf("    question: %s\n", strconv.Quote(q.Question)))
 			if q.Context != "" {
-				b.WriteString(fmt.Sprintf("    context: \"%s\"\n", q.Context))
+				b.WriteString(fmt.Sprintf("    context: %s\n", strconv.Quote(q.Context)))
 			}
 			b.WriteString("    options:\n")
 			for _, opt := range q.Options {
-				b.WriteString(fmt.Sprintf("      - label: \"%s\"\n", opt.Label))
-				b.WriteString(fmt.Sprintf("        value: %s\n", opt.Value))
+				b.WriteString(fmt.Sprintf("      - label: %s\n", strconv.Quote(opt.Label)))
+				b.WriteString(fmt.Sprintf("        value: %s\n", yamlQuote(opt.Value)))
 				if opt.Description != "" {
-					b.WriteString(fmt.Sprintf("        description: \"%s\"\n", opt.Description))
+					b.WriteString(fmt.Sprintf("        description: %s\n", strconv.Quote(opt.Description)))
 				}
 			}
 		}
@@ -178,209 +200,159 @@ func ParseTicket(content string) (*Ticket, error) {
 	t := &Ticket{
 		Deps: []string{},
 	}
+	// Parse from the opening "---" so error lines match the file's.
+	root, err := parseYAML(content[:4+len(frontmatter)+1])
+	if err != nil {
+		return nil, fmt.Errorf("frontmatter: %v", err)
+	}
+	if err := t.decodeFrontmatter(root); err != nil {
+		return nil, fmt.Errorf("frontmatter: %v", err)
+	}
 
-	lines := strings.Split(frontmatter, "\n")
-	var inPlanQuestions bool
-	var currentQuestion *PlanQuestion
-	var currentOption *QuestionOption
-	var inOptions bool
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" {
-			continue
-		}
-
-		indent := countIndent(line)
-
-		// Detect plan-questions section
-		if indent == 0 && trimmed == "plan-questions:" {
-			inPlanQuestions = true
-			continue
-		}
-
-		// Exit plan-questions when we hit another top-level key
-		if indent == 0 && inPlanQuestions && !strings.HasPrefix(trimmed, "-") {
-			inPlanQuestions = false
-			currentQuestion = nil
-			currentOption = nil
-			inOptions = false
+	// Extract title from first heading
+	bodyLines := strings.SplitN(body, "\n", 2)
+	if len(bodyLines) > 0 && strings.HasPrefix(bodyLines[0], "# ") {
+		t.Title = strings.TrimPrefix(bodyLines[0], "# ")
+		if len(bodyLines) > 1 {
+			t.Body = bodyLines[1]
 		}
+	} else {
+		t.Body = body
+	}
 
-		if inPlanQuestions {
-			switch {
-			case indent == 2 && strings.HasPrefix(trimmed, "- "):
-				// New question item
-				if currentQuestion != nil {
-					if currentOption != nil {
-						currentQuestion.Options = append(currentQuestion.Options, *currentOption)
-						currentOption = nil
-					}
-					t.PlanQuestions = append(t.PlanQuestions, *currentQuestion)
-				}
-				currentQuestion = &PlanQuestion{}
-				inOptions = false
-				// Parse inline key-value if present (e.g., "- id: q1")
-				rest := strings.TrimPrefix(trimmed, "- ")
-				if key, val, ok := parseYAMLLine(rest); ok {
-					switch key {
-					case "id":
-						currentQuestion.ID = unquote(val)
-					case "question":
-						currentQuestion.Question = unquote(val)
-					case "context":
-						currentQuestion.Context = unquote(val)
-					}
-				}
-
-			case indent == 4 && currentQuestion != nil && !inOptions:
-				// Question properties
-				key, val, ok := parseYAMLLine(trimmed)
-				if !ok {
-					continue
-				}
-				switch key {
-				case "id":
-					currentQuestion.ID = unquote(val)
-				case "question":
-					currentQuestion.Question = unquote(val)
-				case "context":
-					currentQuestion.Context = unquote(val)
-				case "options":
-					inOptions = true
-				}
-
-			case indent == 6 && inOptions && strings.HasPrefix(trimmed, "- "):
-				// New option item
-				if currentOption != nil {
-					currentQuestion.Options = append(currentQuestion.Options, *currentOption)
-				}
-				currentOption = &QuestionOption{}
-				// Parse inline key-value if present
-				rest := strings.TrimPrefix(trimmed, "- ")
-				if key, val, ok := parseYAMLLine(rest); ok {
-					switch key {
-					case "label":
-						currentOption.Label = unquote(val)
-					case "value":
-						currentOption.Value = unquote(val)
-					case "description":
-						currentOption.Description = unquote(val)
-					}
-				}
-
-			case indent == 8 && currentOption != nil:
-				// Option properties
-				key, val, ok := parseYAMLLine(trimmed)
-				if !ok {
-					continue
-				}
-				switch key {
-				case "label":
-					currentOption.Label = unquote(val)
-				case "value":
-					currentOption.Value = unquote(val)
-				case "description":
-					currentOption.Description = unquote(val)
-				}
-			}
-			continue
-		}
+	return t, nil
+}
 
-		// Standard frontmatter parsing
-		key, val, ok := parseYAMLLine(line)
-		if !ok {
-			continue
-		}
+// decodeFrontmatter reads the frontmatter keys. Keys ko does not know are
+// ignored: the file may carry metadata for other tools.
+func (t *Ticket) decodeFrontmatter(root *yamlNode) error {
+	d := &yamlDecoder{}
+	entries, err := d.entries(root, "frontmatter")
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		key, v := e.Key.Value, e.Value
 		switch key {
 		case "id":
-			t.ID = val
+			t.ID, err = yamlString(v, key)
 		case "status":
-			t.Status = val
+			t.Status, err = yamlString(v, key)
 		case "deps":
-			t.Deps = parseYAMLList(val)
+			t.Deps, err = yamlStrings(v, key)
 		case "created":
-			t.Created = val
+			t.Created, err = yamlString(v, key)
 		case "type":
-			t.Type = val
+			t.Type, err = yamlString(v, key)
 		case "priority":
-			fmt.Sscanf(val, "%d", &t.Priority)
+			t.Priority, err = yamlInt(v, key)
 		case "assignee":
-			t.Assignee = val
+			t.Assignee, err = yamlString(v, key)
 		case "parent":
-			t.Parent = val
+			t.Parent, err = yamlString(v, key)
 		case "external-ref":
-			t.ExternalRef = val
+			t.ExternalRef, err = yamlString(v, key)
 		case "snooze":
-			t.Snooze = val
+			t.Snooze, err = yamlString(v, key)
 		case "triage":
-			t.Triage = val
+			t.Triage, err = yamlString(v, key)
 		case "tags":
-			t.Tags = parseYAMLList(val)
+			t.Tags, err = yamlStrings(v, key)
+		case "scope":
+			t.Scope, err = yamlStrings(v, key)
+		case "fields":
+			t.Fields, err = decodeTicketFields(d, v)
+		case "plan-questions":
+			t.PlanQuestions, err = decodePlanQuestions(d, v)
 		}
-	}
-
-	// Flush any pending question/option
-	if currentOption != nil && currentQuestion != nil {
-		currentQuestion.Options = append(currentQuestion.Options, *currentOption)
-	}
-	if currentQuestion != nil {
-		t.PlanQuestions = append(t.PlanQuestions, *currentQuestion)
-	}
-
-	// Extract title from first heading
-	bodyLines := strings.SplitN(body, "\n", 2)
-	if len(bodyLines) > 0 && strings.HasPrefix(bodyLines[0], "# ") {
-		t.Title = strings.TrimPrefix(bodyLines[0], "# ")
-		if len(bodyLines) > 1 {
-			t.Body = bodyLines[1]
+		if err != nil {
+			return err
 		}
-	} else {
-		t.Body = body
 	}
-
-	return t, nil
+	return nil
 }
 
-// parseYAMLLine does minimal YAML parsing for "key: value" lines.
-func parseYAMLLine(line string) (key, val string, ok bool) {
-	idx := strings.Index(line, ": ")
-	if idx < 0 {
-		// Handle "key:" with no value
-		if strings.HasSuffix(line, ":") {
-			return strings.TrimSpace(line[:len(line)-1]), "", true
+// decodeTicketFields reads the fields: mapping in file order.
+func decodeTicketFields(d *yamlDecoder, n *yamlNode) ([]TicketField, error) {
+	entries, err := d.entries(n, "fields")
+	if err != nil {
+		return nil, err
+	}
+	var fields []TicketField
+	for _, e := range entries {
+		val, err := yamlString(e.Value, "fields."+e.Key.Value)
+		if err != nil {
+			return nil, err
 		}
-		return "", "", false
+		fields = append(fields, TicketField{Name: e.Key.Value, Value: val})
 	}
-	return strings.TrimSpace(line[:idx]), strings.TrimSpace(line[idx+2:]), true
+	return fields, nil
 }
 
-// parseYAMLList parses "[a, b, c]" into a string slice. Returns nil for "[]".
-func parseYAMLList(s string) []string {
-	s = strings.TrimSpace(s)
-	if s == "[]" || s == "" {
-		return []string{}
+// decodePlanQuestions reads the plan-questions: list.
+func decodePlanQuestions(d *yamlDecoder, n *yamlNode) ([]PlanQuestion, error) {
+	items, err := yamlItems(n, "plan-questions")
+	if err != nil {
+		return nil, err
 	}
-	s = strings.TrimPrefix(s, "[")
-	s = strings.TrimSuffix(s, "]")
-	parts := strings.Split(s, ",")
-	result := make([]string, 0, len(parts))
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p != "" {
-			result = append(result, p)
+	var questions []PlanQuestion
+	for _, item := range items {
+		entries, err := d.entries(item, "plan-questions")
+		if err != nil {
+			return nil, err
 		}
+		var q PlanQuestion
+		for _, e := range entries {
+			what := "plan-questions." + e.Key.Value
+			switch e.Key.Value {
+			case "id":
+				q.ID, err = yamlString(e.Value, what)
+			case "question":
+				q.Question, err = yamlString(e.Value, what)
+			case "context":
+				q.Context, err = yamlString(e.Value, what)
+			case "options":
+				q.Options, err = decodeQuestionOptions(d, e.Value)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		questions = append(questions, q)
 	}
-	return result
+	return questions, nil
 }
 
-// unquote removes surrounding quotes from a string if present.
-func unquote(s string) string {
-	s = strings.TrimSpace(s)
-	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
-		return s[1 : len(s)-1]
+// decodeQuestionOptions reads the options: list of a plan question.
+func decodeQuestionOptions(d *yamlDecoder, n *yamlNode) ([]QuestionOption, error) {
+	items, err := yamlItems(n, "plan-questions.options")
+	if err != nil {
+		return nil, err
+	}
+	var options []QuestionOption
+	for _, item := range items {
+		entries, err := d.entries(item, "plan-questions.options")
+		if err != nil {
+			return nil, err
+		}
+		var o QuestionOption
+		for _, e := range entries {
+			what := "plan-questions.options." + e.Key.Value
+			switch e.Key.Value {
+			case "label":
+				o.Label, err = yamlString(e.Value, what)
+			case "value":
+				o.Value, err = yamlString(e.Value, what)
+			case "description":
+				o.Description, err = yamlString(e.Value, what)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		options = append(options, o)
 	}
-	return s
+	return options, nil
 }
 
 // TicketPath returns the file path for a ticket given the tickets directory.
@@ -435,7 +407,7 @@ func ListTickets(ticketsDir string) ([]*Ticket, error) {
 	if err != nil {
 		abs = ticketsDir
 	}
-	return db.ListTicketsByDir(abs)
+	return db.ListTicketsByDir(abs, false)
 }
 
 // ResolveID finds a ticket by exact match, then by substring match, using the database.
@@ -635,6 +607,7 @@ func AllDepsResolvedWith(deps []string, lookup func(id string) (string, bool)) b
 
 // AddNote appends a timestamped note to the ticket body.
 func AddNote(t *Ticket, note string) {
+	note = redact(note)
 	ts := time.Now().UTC().Format("2006-01-02 15:04:05 UTC")
 	section := fmt.Sprintf("\n## Notes\n\n**%s:** %s\n", ts, note)
 	if strings.Contains(t.Body, "## Notes") {
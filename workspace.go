@@ -26,7 +26,7 @@ func WorkspaceOutputName(workflowName, nodeName string) string {
 // TeeOutput writes stage output to the workspace with a predictable filename.
 func TeeOutput(workspaceDir, workflowName, nodeName, output string) error {
 	name := WorkspaceOutputName(workflowName, nodeName)
-	return os.WriteFile(filepath.Join(workspaceDir, name), []byte(output), 0644)
+	return os.WriteFile(filepath.Join(workspaceDir, name), []byte(redact(output)), 0644)
 }
 
 // InjectPriorContext scans the artifact directory for plan.md and workspace files
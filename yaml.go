@@ -0,0 +1,469 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Every YAML file ko reads — .ko/config.yaml, the registry, the global
+// config, the QQL mapping and ticket frontmatter — goes through this
+// parser. It covers the parts of YAML 1.2 a config file uses: block and
+// flow collections, plain, quoted and block scalars, anchors, aliases and
+// << merge keys, comments and a single document. Tags are accepted and
+// ignored; complex (?) keys are rejected. Errors carry the line number.
+
+// yamlKind is the kind of a parsed YAML node.
+type yamlKind int
+
+const (
+	yamlScalar yamlKind = iota
+	yamlMapping
+	yamlSequence
+)
+
+// yamlNode is one node of a parsed document. An alias resolves to the node
+// it names, so a node may appear in the tree more than once.
+type yamlNode struct {
+	Kind  yamlKind
+	Value string      // scalar content, unquoted and folded
+	Style byte        // scalar style: 0 (plain), '\'', '"', '|' or '>'
+	Null  bool        // an empty value, ~ or null
+	Flow  bool        // a [...] or {...} collection
+	Pairs []yamlPair  // mapping entries, in source order
+	Items []*yamlNode // sequence entries
+	Rest  *yamlNode   // a block list after a mapping's entries (see parseMapping)
+
+	Line, Col  int // 1-based line and 0-based column of the first character
+	Start, End int // byte span in the source
+}
+
+// yamlPair is one mapping entry. Colon is the offset of the ':' after the
+// key, where an edit that replaces the value starts.
+type yamlPair struct {
+	Key, Value *yamlNode
+	Colon      int
+}
+
+// yamlParser is a recursive descent parser over the raw source, so node
+// spans index the original bytes.
+type yamlParser struct {
+	src       string
+	pos       int
+	line      int // 1-based line of pos
+	lineStart int // offset of the first byte of the current line
+	anchors   map[string]*yamlNode
+}
+
+// parseYAML parses a single-document YAML source. An empty document is a
+// null node.
+func parseYAML(src string) (*yamlNode, error) {
+	p := &yamlParser{src: src, line: 1, anchors: map[string]*yamlNode{}}
+	if strings.HasPrefix(src, "\ufeff") {
+		p.pos, p.lineStart = 3, 3
+	}
+	if err := p.skipToContent(); err != nil {
+		return nil, err
+	}
+	for p.col() == 0 && p.at(0) == '%' {
+		p.skipToLineEnd() // %YAML and %TAG directives
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isDocMarker("---") {
+		p.pos += 3
+	}
+	root, err := p.parseValue(-1, true, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skipToContent(); err != nil {
+		return nil, err
+	}
+	if p.isDocMarker("...") {
+		p.pos += 3
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case p.eof():
+		return root, nil
+	case p.isDocMarker("---"):
+		return nil, p.errorf("only one document is supported")
+	case p.col() > 0 && root.Kind != yamlScalar:
+		return nil, p.errorf("bad indentation")
+	}
+	return nil, p.errorf("unexpected %s", p.describe())
+}
+
+// errorf returns an error at the current line.
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// yamlErrorf returns an error at a node's line.
+func yamlErrorf(n *yamlNode, format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", n.Line, fmt.Sprintf(format, args...))
+}
+
+func (p *yamlParser) eof() bool { return p.pos >= len(p.src) }
+
+// at returns the byte i past pos, or 0 at the end of the source.
+func (p *yamlParser) at(i int) byte {
+	if p.pos+i < len(p.src) {
+		return p.src[p.pos+i]
+	}
+	return 0
+}
+
+func (p *yamlParser) col() int { return p.pos - p.lineStart }
+
+func isBlank(c byte) bool      { return c == ' ' || c == '\t' }
+func isBreak(c byte) bool      { return c == '\n' || c == '\r' }
+func isSpaceOrEnd(c byte) bool { return c == 0 || isBlank(c) || isBreak(c) }
+func isFlowIndicator(c byte) bool {
+	return c == ',' || c == '[' || c == ']' || c == '{' || c == '}'
+}
+
+// describe names the character at pos for an error message.
+func (p *yamlParser) describe() string {
+	if p.eof() {
+		return "end of file"
+	}
+	return fmt.Sprintf("%q", p.at(0))
+}
+
+// newline consumes a line break.
+func (p *yamlParser) newline() {
+	if p.at(0) == '\r' {
+		p.pos++
+	}
+	if p.at(0) == '\n' {
+		p.pos++
+	}
+	p.line++
+	p.lineStart = p.pos
+}
+
+// skipBlanks skips spaces and tabs within the line.
+func (p *yamlParser) skipBlanks() {
+	for isBlank(p.at(0)) {
+		p.pos++
+	}
+}
+
+// skipToLineEnd moves to the line break (or the end of the source).
+func (p *yamlParser) skipToLineEnd() {
+	for !p.eof() && !isBreak(p.at(0)) {
+		p.pos++
+	}
+}
+
+// atComment reports whether pos starts a comment: a # at the start of a
+// line or after whitespace.
+func (p *yamlParser) atComment() bool {
+	return p.at(0) == '#' && (p.pos == p.lineStart || isBlank(p.src[p.pos-1]))
+}
+
+// atLineEnd reports whether only a comment is left on the line.
+func (p *yamlParser) atLineEnd() bool {
+	return p.eof() || isBreak(p.at(0)) || p.atComment()
+}
+
+// skipToContent moves to the next character that is not whitespace, a
+// line break or part of a comment. Tabs may not indent content.
+func (p *yamlParser) skipToContent() error {
+	for !p.eof() {
+		switch c := p.at(0); {
+		case c == ' ':
+			p.pos++
+		case c == '\t':
+			if strings.Trim(p.src[p.lineStart:p.pos], " \t") == "" {
+				rest := p.pos
+				for rest < len(p.src) && isBlank(p.src[rest]) {
+					rest++
+				}
+				if rest < len(p.src) && !isBreak(p.src[rest]) && p.src[rest] != '#' {
+					return p.errorf("tabs are not allowed in indentation")
+				}
+			}
+			p.pos++
+		case isBreak(c):
+			p.newline()
+		case p.atComment():
+			p.skipToLineEnd()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// isDocMarker reports whether pos starts a "---" or "..." line.
+func (p *yamlParser) isDocMarker(marker string) bool {
+	return p.col() == 0 && strings.HasPrefix(p.src[p.pos:], marker) && isSpaceOrEnd(p.at(3))
+}
+
+// atDocBoundary reports whether pos starts either document marker.
+func (p *yamlParser) atDocBoundary() bool {
+	return p.isDocMarker("---") || p.isDocMarker("...")
+}
+
+// atSeqEntry reports whether pos starts a block sequence entry.
+func (p *yamlParser) atSeqEntry() bool {
+	return p.at(0) == '-' && isSpaceOrEnd(p.at(1))
+}
+
+// parseValue parses the node after "key:", "- " or the document start.
+// Content on following lines must be indented past indent. compact allows
+// a block collection to start on the same line (after "- "); mapValue
+// allows a block sequence at the key's own indentation.
+func (p *yamlParser) parseValue(indent int, compact, mapValue bool) (*yamlNode, error) {
+	here, line := p.pos, p.line
+	p.skipBlanks()
+	anchor := ""
+	for p.at(0) == '&' || p.at(0) == '!' {
+		kind := p.at(0)
+		name, err := p.scanName()
+		if err != nil {
+			return nil, err
+		}
+		if kind == '&' {
+			anchor = name
+		}
+		p.skipBlanks()
+	}
+
+	var n *yamlNode
+	var err error
+	if p.atLineEnd() {
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		c := p.col()
+		switch {
+		case p.eof() || p.atDocBoundary():
+		case c > indent:
+			n, err = p.parseContent(indent, true)
+		case c == indent && mapValue && p.atSeqEntry():
+			n, err = p.parseSequence(c)
+		}
+		if n == nil && err == nil {
+			n = &yamlNode{Kind: yamlScalar, Null: true, Line: line, Start: here, End: here}
+		}
+	} else {
+		n, err = p.parseContent(indent, compact)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		p.anchors[anchor] = n
+	}
+	return n, nil
+}
+
+// parseContent parses the node that starts at pos. Scalars may continue on
+// lines indented past indent; collections says whether a block collection
+// may start here.
+func (p *yamlParser) parseContent(indent int, collections bool) (*yamlNode, error) {
+	c := p.col()
+	switch ch := p.at(0); {
+	case p.atSeqEntry():
+		if !collections {
+			return nil, p.errorf("a block sequence cannot start on the same line as its key")
+		}
+		return p.parseSequence(c)
+	case ch == '|' || ch == '>':
+		return p.scanBlockScalar(indent)
+	case ch == '?' && isSpaceOrEnd(p.at(1)):
+		return nil, p.errorf("complex mapping keys are not supported")
+	}
+	if p.isMappingKey() {
+		if !collections {
+			return nil, p.errorf("mapping values are not allowed here")
+		}
+		return p.parseMapping(c)
+	}
+
+	var n *yamlNode
+	var err error
+	switch p.at(0) {
+	case '[', '{':
+		n, err = p.parseFlow()
+	case '*':
+		n, err = p.parseAlias()
+	case '"', '\'':
+		n, err = p.scanQuoted()
+	default:
+		n, err = p.scanPlain(indent, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlanks()
+	if !p.atLineEnd() {
+		if p.at(0) == ':' {
+			return nil, p.errorf("mapping values are not allowed here")
+		}
+		return nil, p.errorf("unexpected %s after a value", p.describe())
+	}
+	return n, nil
+}
+
+// isMappingKey reports whether the line at pos is a "key: value" entry.
+// Implicit keys are a single-line plain or quoted scalar.
+func (p *yamlParser) isMappingKey() bool {
+	saved := *p
+	defer func() { *p = saved }()
+	var err error
+	switch p.at(0) {
+	case '"', '\'':
+		_, err = p.scanQuoted()
+	case '[', '{', '*', '&', '!', '|', '>', '#':
+		return false
+	default:
+		_, err = p.scanPlain(-1, false)
+	}
+	if err != nil || p.line != saved.line {
+		return false
+	}
+	p.skipBlanks()
+	return p.at(0) == ':' && isSpaceOrEnd(p.at(1))
+}
+
+// parseKey parses an implicit key and the ':' after it.
+func (p *yamlParser) parseKey() (*yamlNode, int, error) {
+	var key *yamlNode
+	var err error
+	if p.at(0) == '"' || p.at(0) == '\'' {
+		key, err = p.scanQuoted()
+	} else {
+		key, err = p.scanPlain(-1, false)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	p.skipBlanks()
+	colon := p.pos
+	p.pos++
+	return key, colon, nil
+}
+
+// parseMapping parses a block mapping whose keys sit at column c. A block
+// list at the same column after nested entries is not YAML, but old pipeline
+// files put a workflow's nodes there, so it is kept as Rest for
+// decodeWorkflow; every other decoder rejects it (see entries).
+func (p *yamlParser) parseMapping(c int) (*yamlNode, error) {
+	n := &yamlNode{Kind: yamlMapping, Line: p.line, Col: c, Start: p.pos}
+	seen := map[string]bool{}
+	for {
+		if p.atSeqEntry() && c == 0 {
+			return nil, p.errorf("expected a mapping key, found a sequence entry; a key holds a mapping or a list, not both")
+		}
+		if p.atSeqEntry() {
+			rest, err := p.parseSequence(c)
+			if err != nil {
+				return nil, err
+			}
+			n.Rest, n.End = rest, rest.End
+			if !p.eof() && !p.atDocBoundary() && p.col() == c {
+				return nil, p.errorf("expected a sequence entry; a key holds a mapping or a list, not both")
+			}
+			return n, nil
+		}
+		if !p.isMappingKey() {
+			return nil, p.errorf("expected a mapping key")
+		}
+		key, colon, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		if !isMergeKey(key) {
+			if seen[key.Value] {
+				return nil, yamlErrorf(key, "duplicate key %q", key.Value)
+			}
+			seen[key.Value] = true
+		}
+		val, err := p.parseValue(c, false, true)
+		if err != nil {
+			return nil, err
+		}
+		n.Pairs = append(n.Pairs, yamlPair{Key: key, Value: val, Colon: colon})
+		n.End = max(colon+1, val.End)
+
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		if p.eof() || p.atDocBoundary() || p.col() < c {
+			return n, nil
+		}
+		if p.col() > c {
+			return nil, p.errorf("bad indentation of a mapping entry")
+		}
+	}
+}
+
+// parseSequence parses a block sequence whose "-" indicators sit at
+// column c.
+func (p *yamlParser) parseSequence(c int) (*yamlNode, error) {
+	n := &yamlNode{Kind: yamlSequence, Line: p.line, Col: c, Start: p.pos}
+	for {
+		dash := p.pos
+		p.pos++
+		item, err := p.parseValue(c, true, false)
+		if err != nil {
+			return nil, err
+		}
+		n.Items = append(n.Items, item)
+		n.End = max(dash+1, item.End)
+
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		if p.eof() || p.atDocBoundary() || p.col() < c {
+			return n, nil
+		}
+		if p.col() > c {
+			return nil, p.errorf("bad indentation of a sequence entry")
+		}
+		if !p.atSeqEntry() {
+			return n, nil
+		}
+	}
+}
+
+// scanName reads the name after an anchor (&), alias (*) or tag (!)
+// indicator.
+func (p *yamlParser) scanName() (string, error) {
+	indicator := p.at(0)
+	p.pos++
+	start := p.pos
+	for !isSpaceOrEnd(p.at(0)) && !isFlowIndicator(p.at(0)) {
+		p.pos++
+	}
+	if p.pos == start && indicator != '!' {
+		return "", p.errorf("missing name after '%c'", indicator)
+	}
+	return p.src[start:p.pos], nil
+}
+
+// parseAlias resolves *name to the node anchored as &name.
+func (p *yamlParser) parseAlias() (*yamlNode, error) {
+	line := p.line
+	name, err := p.scanName()
+	if err != nil {
+		return nil, err
+	}
+	n, ok := p.anchors[name]
+	if !ok {
+		return nil, fmt.Errorf("line %d: unknown anchor %q", line, name)
+	}
+	return n, nil
+}
+
+// isMergeKey reports whether a key is the << merge key.
+func isMergeKey(key *yamlNode) bool {
+	return key.Style == 0 && key.Value == "<<"
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// yamlDecoder turns parsed nodes into config values. A value of the wrong
+// shape is an error; a key the caller does not know is collected as a
+// warning instead, so a typo shows up without an older ko rejecting a
+// newer file.
+type yamlDecoder struct {
+	warnings []string
+}
+
+// entries returns the pairs of a mapping with << merges applied: merged
+// keys follow the mapping's own and never override them. A null node has
+// no entries.
+func (d *yamlDecoder) entries(n *yamlNode, what string) ([]yamlPair, error) {
+	if n == nil || n.Kind == yamlScalar && n.Null {
+		return nil, nil
+	}
+	if n.Kind != yamlMapping {
+		return nil, yamlErrorf(n, "%s: expected a mapping, got %s", what, yamlKindName(n))
+	}
+	if n.Rest != nil {
+		return nil, yamlErrorf(n.Rest, "%s: expected a mapping key, found a sequence entry; a key holds a mapping or a list, not both", what)
+	}
+	var out []yamlPair
+	have := map[string]bool{}
+	for _, p := range n.Pairs {
+		if !isMergeKey(p.Key) {
+			out = append(out, p)
+			have[p.Key.Value] = true
+		}
+	}
+	for _, p := range n.Pairs {
+		if !isMergeKey(p.Key) {
+			continue
+		}
+		sources := []*yamlNode{p.Value}
+		if p.Value.Kind == yamlSequence {
+			sources = p.Value.Items
+		}
+		for _, src := range sources {
+			if src.Kind != yamlMapping {
+				return nil, yamlErrorf(p.Key, "%s: << must merge a mapping or a list of mappings", what)
+			}
+			merged, err := d.entries(src, what)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range merged {
+				if !have[m.Key.Value] {
+					out = append(out, m)
+					have[m.Key.Value] = true
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// unknown records a warning for a key the caller does not handle.
+func (d *yamlDecoder) unknown(key *yamlNode, what string) {
+	if what != "" {
+		what += ": "
+	}
+	d.warnings = append(d.warnings, fmt.Sprintf("line %d: %sunknown key %q", key.Line, what, key.Value))
+}
+
+// yamlKindName describes a node's kind for an error message.
+func yamlKindName(n *yamlNode) string {
+	switch n.Kind {
+	case yamlMapping:
+		return "a mapping"
+	case yamlSequence:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", n.Value)
+}
+
+// yamlString returns a scalar's text; null is "".
+func yamlString(n *yamlNode, what string) (string, error) {
+	if n.Kind != yamlScalar {
+		return "", yamlErrorf(n, "%s: expected a value, got %s", what, yamlKindName(n))
+	}
+	if n.Null && n.Style == 0 {
+		return "", nil
+	}
+	return n.Value, nil
+}
+
+// yamlBool parses true or false (any case YAML 1.2 allows); null is false.
+func yamlBool(n *yamlNode, what string) (bool, error) {
+	s, err := yamlString(n, what)
+	if err != nil {
+		return false, err
+	}
+	switch s {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE", "":
+		return false, nil
+	}
+	return false, yamlErrorf(n, "%s: expected true or false, got %q", what, s)
+}
+
+// yamlInt parses a decimal integer.
+func yamlInt(n *yamlNode, what string) (int, error) {
+	s, err := yamlString(n, what)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, yamlErrorf(n, "%s: expected an integer, got %q", what, s)
+	}
+	return v, nil
+}
+
+// yamlItems returns the entries of a list; null is an empty list.
+func yamlItems(n *yamlNode, what string) ([]*yamlNode, error) {
+	if n == nil || n.Kind == yamlScalar && n.Null && n.Style == 0 {
+		return nil, nil
+	}
+	if n.Kind != yamlSequence {
+		return nil, yamlErrorf(n, "%s: expected a list, got %s", what, yamlKindName(n))
+	}
+	return n.Items, nil
+}
+
+// yamlStrings returns the entries of a list of values; null is an empty
+// list and a single value is a list of one.
+func yamlStrings(n *yamlNode, what string) ([]string, error) {
+	if n.Kind == yamlScalar && n.Null && n.Style == 0 {
+		return []string{}, nil
+	}
+	if n.Kind == yamlScalar {
+		return []string{n.Value}, nil
+	}
+	if n.Kind != yamlSequence {
+		return nil, yamlErrorf(n, "%s: expected a list, got %s", what, yamlKindName(n))
+	}
+	out := make([]string, 0, len(n.Items))
+	for _, it := range n.Items {
+		s, err := yamlString(it, what)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// stringMap returns a mapping of values; null is an empty map.
+func (d *yamlDecoder) stringMap(n *yamlNode, what string) (map[string]string, error) {
+	entries, err := d.entries(n, what)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(entries))
+	for _, e := range entries {
+		v, err := yamlString(e.Value, what+"."+e.Key.Value)
+		if err != nil {
+			return nil, err
+		}
+		m[e.Key.Value] = v
+	}
+	return m, nil
+}
+
+// yamlSection parses content and returns the value of one of its top-level
+// keys, or nil.
+func yamlSection(content, key string) (*yamlNode, error) {
+	root, err := parseYAML(content)
+	if err != nil {
+		return nil, err
+	}
+	return yamlGet(root, key), nil
+}
+
+// yamlGet returns the value of key in a mapping, or nil.
+func yamlGet(n *yamlNode, key string) *yamlNode {
+	if n == nil || n.Kind != yamlMapping {
+		return nil
+	}
+	for _, p := range n.Pairs {
+		if p.Key.Value == key {
+			return p.Value
+		}
+	}
+	return nil
+}
+
+// reportedYAMLWarnings dedupes warnings across the loads of one process.
+var reportedYAMLWarnings sync.Map
+
+// warnYAML prints the warnings from decoding path to stderr, each once.
+func warnYAML(path string, warnings []string) {
+	for _, w := range warnings {
+		msg := path + ": " + w
+		if _, seen := reportedYAMLWarnings.LoadOrStore(msg, true); !seen {
+			fmt.Fprintf(os.Stderr, "ko: warning: %s\n", msg)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYAMLDecoderEntriesMerge(t *testing.T) {
+	root, err := parseYAML("base: &b {a: 1, b: 2}\nmore: &m {c: 3}\nuse:\n  <<: [*b, *m]\n  b: own\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &yamlDecoder{}
+	m, err := d.stringMap(yamlGet(root, "use"), "use")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "1", "b": "own", "c": "3"}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("merged = %v, want %v", m, want)
+	}
+
+	root, _ = parseYAML("use:\n  <<: plain\n")
+	if _, err := d.entries(yamlGet(root, "use"), "use"); err == nil || !strings.Contains(err.Error(), "line 2: use: << must merge a mapping") {
+		t.Errorf("merge of a scalar: err = %v", err)
+	}
+}
+
+func TestYAMLDecoderEntriesRest(t *testing.T) {
+	root, err := parseYAML("a:\n  b: 1\n  - c\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &yamlDecoder{}
+	if _, err := d.entries(yamlGet(root, "a"), "a"); err == nil || !strings.Contains(err.Error(), "line 3: a: expected a mapping key, found a sequence entry") {
+		t.Errorf("list after entries: err = %v", err)
+	}
+}
+
+func TestYAMLDecoderUnknown(t *testing.T) {
+	root, _ := parseYAML("a: 1\nb:\n  typo: 2\n")
+	d := &yamlDecoder{}
+	d.unknown(root.Pairs[0].Key, "")
+	d.unknown(yamlGet(root, "b").Pairs[0].Key, "b")
+	want := []string{`line 1: unknown key "a"`, `line 3: b: unknown key "typo"`}
+	if !reflect.DeepEqual(d.warnings, want) {
+		t.Errorf("warnings = %q, want %q", d.warnings, want)
+	}
+}
+
+func TestYAMLScalars(t *testing.T) {
+	root, err := parseYAML("t: True\nf: false\nn:\nnum: 42\nbad: yes\nlist: [a, b]\none: x\nempty: []\nq: ''\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(k string) *yamlNode { return yamlGet(root, k) }
+
+	for k, want := range map[string]bool{"t": true, "f": false, "n": false} {
+		if got, err := yamlBool(get(k), k); err != nil || got != want {
+			t.Errorf("yamlBool(%s) = %v, %v", k, got, err)
+		}
+	}
+	if _, err := yamlBool(get("bad"), "bad"); err == nil || !strings.Contains(err.Error(), `line 5: bad: expected true or false, got "yes"`) {
+		t.Errorf("yamlBool(bad): err = %v", err)
+	}
+	if n, err := yamlInt(get("num"), "num"); err != nil || n != 42 {
+		t.Errorf("yamlInt(num) = %d, %v", n, err)
+	}
+	if _, err := yamlInt(get("one"), "one"); err == nil || !strings.Contains(err.Error(), "expected an integer") {
+		t.Errorf("yamlInt(one): err = %v", err)
+	}
+	if s, err := yamlString(get("q"), "q"); err != nil || s != "" {
+		t.Errorf("yamlString(q) = %q, %v", s, err)
+	}
+	if _, err := yamlString(get("list"), "list"); err == nil || !strings.Contains(err.Error(), "list: expected a value, got a list") {
+		t.Errorf("yamlString(list): err = %v", err)
+	}
+
+	for k, want := range map[string][]string{"list": {"a", "b"}, "one": {"x"}, "n": {}, "empty": {}} {
+		if got, err := yamlStrings(get(k), k); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("yamlStrings(%s) = %q, %v", k, got, err)
+		}
+	}
+	if _, err := yamlItems(get("one"), "one"); err == nil || !strings.Contains(err.Error(), `one: expected a list, got "x"`) {
+		t.Errorf("yamlItems(one): err = %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Files ko rewrites (the registry, the project prefix) are edited in place:
+// yamlSet and yamlDelete splice the span of one entry and leave every other
+// byte, comments and formatting included, as it was. Setting a value that
+// is already there returns the source unchanged.
+
+// yamlQuote formats s as a scalar that reads back as s: plain when that is
+// unambiguous in block context, double-quoted otherwise.
+func yamlQuote(s string) string {
+	if yamlPlainSafe(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// yamlFlowList formats items as a flow sequence: [a, b, "c, d"].
+func yamlFlowList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, s := range items {
+		if strings.ContainsAny(s, ",[]{}") {
+			quoted[i] = strconv.Quote(s)
+		} else {
+			quoted[i] = yamlQuote(s)
+		}
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// yamlPlainSafe reports whether s can be written as a plain scalar.
+func yamlPlainSafe(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, "\n\r\t") {
+		return false
+	}
+	switch s {
+	case "~", "null", "Null", "NULL":
+		return false
+	}
+	if strings.IndexByte(",[]{}#&*!|>'\"%@`", s[0]) >= 0 {
+		return false
+	}
+	if strings.IndexByte("-?:", s[0]) >= 0 && (len(s) == 1 || s[1] == ' ') {
+		return false
+	}
+	return !strings.Contains(s, ": ") && !strings.Contains(s, " #") && !strings.HasSuffix(s, ":")
+}
+
+// yamlFind returns the pair for key in a mapping, or nil.
+func yamlFind(n *yamlNode, key string) *yamlPair {
+	for i := range n.Pairs {
+		if n.Pairs[i].Key.Value == key {
+			return &n.Pairs[i]
+		}
+	}
+	return nil
+}
+
+// yamlSet returns src with the scalar at path (a chain of mapping keys)
+// set to value. Mappings missing along the path are created.
+func yamlSet(src string, path []string, value string) (string, error) {
+	root, err := parseYAML(src)
+	if err != nil {
+		return "", err
+	}
+	n := root
+	at := 0 // offset the missing part of the path is inserted at
+	indent := 0
+	for i, key := range path {
+		if n.Kind == yamlScalar && n.Null {
+			if i == 0 {
+				return yamlInsert(src, len(src), 0, path, value, false), nil
+			}
+			return yamlInsert(src, at, indent, path[i:], value, true), nil
+		}
+		if n.Kind != yamlMapping || n.Flow {
+			return "", yamlErrorf(n, "%s is not a block mapping", strings.Join(path[:i], "."))
+		}
+		pair := yamlFind(n, key)
+		if pair == nil {
+			return yamlInsert(src, yamlLineEnd(src, n.End), n.Col, path[i:], value, false), nil
+		}
+		if i == len(path)-1 {
+			v := pair.Value
+			if v.Kind == yamlScalar && !v.Null && v.Value == value {
+				return src, nil
+			}
+			return src[:pair.Colon+1] + " " + yamlQuote(value) + src[v.End:], nil
+		}
+		n, at, indent = pair.Value, pair.Colon+1, n.Col+2
+	}
+	return src, nil
+}
+
+// yamlInsert inserts the entries for path at offset at: whole lines when
+// at starts a line (or ends the file), a nested block after "key:" when
+// nested is set.
+func yamlInsert(src string, at, indent int, path []string, value string, nested bool) string {
+	var b strings.Builder
+	if nested || at > 0 && src[at-1] != '\n' {
+		b.WriteByte('\n')
+	}
+	for i, key := range path {
+		b.WriteString(strings.Repeat(" ", indent+2*i) + yamlQuote(key) + ":")
+		if i == len(path)-1 {
+			b.WriteString(" " + yamlQuote(value))
+		}
+		if !nested || i < len(path)-1 {
+			b.WriteByte('\n')
+		}
+	}
+	return src[:at] + b.String() + src[at:]
+}
+
+// yamlLineEnd returns the offset just past the line break of the line
+// containing off, or the end of a last line without one.
+func yamlLineEnd(src string, off int) int {
+	if i := strings.IndexByte(src[off:], '\n'); i >= 0 {
+		return off + i + 1
+	}
+	return len(src)
+}
+
+// yamlDelete returns src without the entry at path, removing its lines.
+// A missing entry leaves src unchanged.
+func yamlDelete(src string, path []string) (string, error) {
+	root, err := parseYAML(src)
+	if err != nil {
+		return "", err
+	}
+	n := root
+	for i, key := range path {
+		if n.Kind != yamlMapping {
+			return src, nil
+		}
+		pair := yamlFind(n, key)
+		if pair == nil {
+			return src, nil
+		}
+		if i < len(path)-1 {
+			n = pair.Value
+			continue
+		}
+		start := strings.LastIndexByte(src[:pair.Key.Start], '\n') + 1
+		if n.Flow || strings.TrimLeft(src[start:pair.Key.Start], " ") != "" {
+			return "", yamlErrorf(pair.Key, "cannot remove %s: it does not start its own line", strings.Join(path, "."))
+		}
+		return src[:start] + src[yamlLineEnd(src, max(pair.Value.End, pair.Colon+1)):], nil
+	}
+	return src, nil
+}
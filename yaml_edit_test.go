@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYAMLSet(t *testing.T) {
+	tests := []struct {
+		name, src string
+		path      []string
+		value     string
+		want      string
+	}{
+		{"replace keeps comment", "# top\na:\n  b: old   # note\nc: 1\n", []string{"a", "b"}, "new",
+			"# top\na:\n  b: new   # note\nc: 1\n"},
+		{"same value unchanged", "a:\n  b:   same # x\n", []string{"a", "b"}, "same",
+			"a:\n  b:   same # x\n"},
+		{"append to mapping", "a:\n  b: 1\n\nc: 2\n", []string{"a", "d"}, "x",
+			"a:\n  b: 1\n  d: x\n\nc: 2\n"},
+		{"missing section", "c: 2\n", []string{"a", "b"}, "x",
+			"c: 2\na:\n  b: x\n"},
+		{"no final newline", "c: 2", []string{"a"}, "x",
+			"c: 2\na: x\n"},
+		{"under a null key", "a:\nc: 2\n", []string{"a", "b"}, "x",
+			"a:\n  b: x\nc: 2\n"},
+		{"empty file", "# nothing yet\n", []string{"a", "b"}, "x",
+			"# nothing yet\na:\n  b: x\n"},
+		{"quotes when needed", "a: 1\n", []string{"a"}, "x: y",
+			"a: \"x: y\"\n"},
+		{"replace block value", "a: |\n  text\nb: 1\n", []string{"a"}, "x",
+			"a: x\nb: 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := yamlSet(tt.src, tt.path, tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := yamlSet("a: {b: 1}\n", []string{"a", "b"}, "2"); err == nil || !strings.Contains(err.Error(), "a is not a block mapping") {
+		t.Errorf("flow mapping: err = %v", err)
+	}
+}
+
+func TestYAMLDelete(t *testing.T) {
+	src := "a:\n  b: 1   # gone\n  c:\n    - x\n  d: 2\n"
+	got, err := yamlDelete(src, []string{"a", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a:\n  b: 1   # gone\n  d: 2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, _ := yamlDelete(src, []string{"a", "missing"}); got != src {
+		t.Errorf("missing key changed src: %q", got)
+	}
+	if _, err := yamlDelete("a: {b: 1}\n", []string{"a", "b"}); err == nil {
+		t.Error("deleting from a flow mapping: want an error")
+	}
+}
+
+func TestYAMLQuoteRoundTrip(t *testing.T) {
+	values := []string{"plain", "two words", "", "null", "~", "@alice", "a: b", "x #y",
+		"- item", "ends:", "'single'", `"double"`, " padded ", "line\nbreak", "tab\there", "100%", "a:b"}
+	for _, v := range values {
+		root, err := parseYAML("k: " + yamlQuote(v) + "\n")
+		if err != nil {
+			t.Errorf("%q: %v", v, err)
+			continue
+		}
+		if got, _ := yamlString(yamlGet(root, "k"), "k"); got != v {
+			t.Errorf("yamlQuote(%q) = %s reads back as %q", v, yamlQuote(v), got)
+		}
+	}
+
+	list := []string{"a", "b, c", "[x]", "@d"}
+	root, err := parseYAML("k: " + yamlFlowList(list) + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := yamlStrings(yamlGet(root, "k"), "k"); !reflect.DeepEqual(got, list) {
+		t.Errorf("yamlFlowList round trip = %q", got)
+	}
+}
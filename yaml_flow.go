@@ -0,0 +1,119 @@
+package main
+
+// parseFlow parses a [...] or {...} collection, which may span lines.
+func (p *yamlParser) parseFlow() (*yamlNode, error) {
+	open := p.at(0)
+	n := &yamlNode{Kind: yamlSequence, Flow: true, Line: p.line, Col: p.col(), Start: p.pos}
+	closer := byte(']')
+	if open == '{' {
+		n.Kind, closer = yamlMapping, '}'
+	}
+	seen := map[string]bool{}
+	p.pos++
+	for {
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		if p.eof() || p.atDocBoundary() {
+			return nil, yamlErrorf(n, "unterminated flow collection")
+		}
+		if p.at(0) == closer {
+			p.pos++
+			n.End = p.pos
+			return n, nil
+		}
+		if p.at(0) == '?' && isSpaceOrEnd(p.at(1)) {
+			p.pos++
+			if err := p.skipToContent(); err != nil {
+				return nil, err
+			}
+		}
+		entry, err := p.parseFlowNode()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		if p.at(0) == ':' {
+			colon := p.pos
+			p.pos++
+			if err := p.skipToContent(); err != nil {
+				return nil, err
+			}
+			val := &yamlNode{Kind: yamlScalar, Null: true, Line: p.line, Start: colon + 1, End: colon + 1}
+			if p.at(0) != ',' && p.at(0) != closer {
+				if val, err = p.parseFlowNode(); err != nil {
+					return nil, err
+				}
+			}
+			pair := yamlPair{Key: entry, Value: val, Colon: colon}
+			if open == '[' {
+				// [a: b] is a sequence holding the mapping {a: b}
+				n.Items = append(n.Items, &yamlNode{Kind: yamlMapping, Flow: true, Pairs: []yamlPair{pair},
+					Line: entry.Line, Col: entry.Col, Start: entry.Start, End: val.End})
+			} else {
+				if seen[entry.Value] {
+					return nil, yamlErrorf(entry, "duplicate key %q", entry.Value)
+				}
+				seen[entry.Value] = true
+				n.Pairs = append(n.Pairs, pair)
+			}
+		} else if open == '[' {
+			n.Items = append(n.Items, entry)
+		} else {
+			null := &yamlNode{Kind: yamlScalar, Null: true, Line: entry.Line, Start: entry.End, End: entry.End}
+			n.Pairs = append(n.Pairs, yamlPair{Key: entry, Value: null, Colon: entry.End})
+		}
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		switch {
+		case p.eof() || p.atDocBoundary():
+			return nil, yamlErrorf(n, "unterminated flow collection")
+		case p.at(0) == ',':
+			p.pos++
+		case p.at(0) == closer:
+		default:
+			return nil, p.errorf("expected ',' or '%c' in a flow collection, found %s", closer, p.describe())
+		}
+	}
+}
+
+// parseFlowNode parses one entry (or key, or value) inside a flow
+// collection.
+func (p *yamlParser) parseFlowNode() (*yamlNode, error) {
+	anchor := ""
+	for p.at(0) == '&' || p.at(0) == '!' {
+		kind := p.at(0)
+		name, err := p.scanName()
+		if err != nil {
+			return nil, err
+		}
+		if kind == '&' {
+			anchor = name
+		}
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+	}
+	var n *yamlNode
+	var err error
+	switch p.at(0) {
+	case '[', '{':
+		n, err = p.parseFlow()
+	case '*':
+		n, err = p.parseAlias()
+	case '"', '\'':
+		n, err = p.scanQuoted()
+	default:
+		n, err = p.scanPlain(-1, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		p.anchors[anchor] = n
+	}
+	return n, nil
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// yamlEscapes maps the single-character escapes of a double-quoted scalar.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+	'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"",
+	'/': "/", '\\': "\\", 'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// newScalar starts a scalar node at pos.
+func (p *yamlParser) newScalar(style byte) *yamlNode {
+	return &yamlNode{Kind: yamlScalar, Style: style, Line: p.line, Col: p.col(), Start: p.pos}
+}
+
+// scanPlain scans an unquoted scalar. In block context it may continue on
+// lines indented past indent; inside a flow collection it also stops at
+// , [ ] { }. Line breaks fold to spaces, blank lines to newlines.
+func (p *yamlParser) scanPlain(indent int, flow bool) (*yamlNode, error) {
+	n := p.newScalar(0)
+	switch c := p.at(0); {
+	case c == '@' || c == '`' || c == '%' || isFlowIndicator(c):
+		return nil, p.errorf("a plain value cannot start with %q; quote it", c)
+	case (c == '-' || c == '?' || c == ':') && isSpaceOrEnd(p.at(1)):
+		return nil, p.errorf("a plain value cannot start with %q; quote it", c)
+	}
+	var b strings.Builder
+	for {
+		start := p.pos
+		for !p.eof() && !isBreak(p.at(0)) && !p.atComment() {
+			c := p.at(0)
+			if c == ':' && (isSpaceOrEnd(p.at(1)) || flow && isFlowIndicator(p.at(1))) {
+				break
+			}
+			if flow && isFlowIndicator(c) {
+				break
+			}
+			p.pos++
+		}
+		seg := strings.TrimRight(p.src[start:p.pos], " \t")
+		b.WriteString(seg)
+		if seg != "" {
+			n.End = start + len(seg)
+		}
+		if !isBreak(p.at(0)) {
+			break
+		}
+
+		// Look past the break for a continuation line.
+		saved := *p
+		empty := 0
+		p.newline()
+		for {
+			p.skipBlanks()
+			if !isBreak(p.at(0)) {
+				break
+			}
+			empty++
+			p.newline()
+		}
+		c := p.at(0)
+		stop := p.eof() || p.atComment() || p.atDocBoundary() ||
+			(!flow && p.col() <= indent) ||
+			(c == ':' && isSpaceOrEnd(p.at(1))) ||
+			(flow && isFlowIndicator(c))
+		if stop || b.Len() == 0 {
+			*p = saved
+			break
+		}
+		if empty == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteString(strings.Repeat("\n", empty))
+		}
+	}
+	if n.End == 0 {
+		n.End = n.Start
+	}
+	n.Value = b.String()
+	switch n.Value {
+	case "", "~", "null", "Null", "NULL":
+		n.Null = true
+	}
+	return n, nil
+}
+
+// scanQuoted scans a single- or double-quoted scalar, which may span
+// lines.
+func (p *yamlParser) scanQuoted() (*yamlNode, error) {
+	q := p.at(0)
+	n := p.newScalar(q)
+	p.pos++
+	var buf []byte
+	blanks := -1 // start of trailing unescaped blanks in buf
+	for {
+		if p.eof() {
+			return nil, yamlErrorf(n, "unterminated quoted string")
+		}
+		c := p.at(0)
+		switch {
+		case c == q && q == '\'' && p.at(1) == '\'':
+			buf = append(buf, '\'')
+			p.pos += 2
+			blanks = -1
+		case c == q:
+			p.pos++
+			n.End = p.pos
+			n.Value = string(buf)
+			return n, nil
+		case c == '\\' && q == '"' && isBreak(p.at(1)):
+			// An escaped line break joins the lines without a space.
+			p.pos++
+			p.newline()
+			p.skipBlanks()
+			blanks = -1
+		case c == '\\' && q == '"':
+			s, err := p.scanEscape()
+			if err != nil {
+				return nil, err
+			}
+			buf = append(buf, s...)
+			blanks = -1
+		case isBreak(c):
+			if blanks >= 0 {
+				buf = buf[:blanks]
+			}
+			blanks = -1
+			p.newline()
+			empty := 0
+			for {
+				p.skipBlanks()
+				if !isBreak(p.at(0)) {
+					break
+				}
+				empty++
+				p.newline()
+			}
+			if p.atDocBoundary() {
+				return nil, yamlErrorf(n, "unterminated quoted string")
+			}
+			if empty == 0 {
+				buf = append(buf, ' ')
+			} else {
+				buf = append(buf, strings.Repeat("\n", empty)...)
+			}
+		default:
+			if isBlank(c) && blanks < 0 {
+				blanks = len(buf)
+			} else if !isBlank(c) {
+				blanks = -1
+			}
+			buf = append(buf, c)
+			p.pos++
+		}
+	}
+}
+
+// scanEscape decodes the escape sequence at pos.
+func (p *yamlParser) scanEscape() (string, error) {
+	c := p.at(1)
+	if s, ok := yamlEscapes[c]; ok {
+		p.pos += 2
+		return s, nil
+	}
+	width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+	if width == 0 || p.pos+2+width > len(p.src) {
+		return "", p.errorf("invalid escape \\%c in a double-quoted string", c)
+	}
+	code, err := strconv.ParseUint(p.src[p.pos+2:p.pos+2+width], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return "", p.errorf("invalid escape \\%s in a double-quoted string", p.src[p.pos+1:p.pos+2+width])
+	}
+	p.pos += 2 + width
+	return string(rune(code)), nil
+}
+
+// scanBlockScalar scans a literal (|) or folded (>) block scalar whose
+// content is indented past indent. The header may set the chomping (+ or
+// -) and an explicit indentation.
+func (p *yamlParser) scanBlockScalar(indent int) (*yamlNode, error) {
+	n := p.newScalar(p.at(0))
+	p.pos++
+	var chomp byte
+	explicit := 0
+	for i := 0; i < 2; i++ {
+		switch c := p.at(0); {
+		case (c == '+' || c == '-') && chomp == 0:
+			chomp = c
+			p.pos++
+		case c >= '1' && c <= '9' && explicit == 0:
+			explicit = int(c - '0')
+			p.pos++
+		}
+	}
+	p.skipBlanks()
+	if !p.atLineEnd() {
+		return nil, p.errorf("unexpected %s after a block scalar indicator", p.describe())
+	}
+	p.skipToLineEnd()
+	n.End = p.pos
+
+	contentIndent := -1
+	if explicit > 0 {
+		contentIndent = max(indent, 0) + explicit
+	}
+	var lines []string // content with the indentation removed; "" for blank lines
+	last := -1         // index of the last non-blank line
+	for isBreak(p.at(0)) {
+		saved := *p
+		p.newline()
+		spaces := 0
+		for p.at(spaces) == ' ' {
+			spaces++
+		}
+		lineEnd := p.pos
+		for lineEnd < len(p.src) && !isBreak(p.src[lineEnd]) {
+			lineEnd++
+		}
+		text := p.src[p.pos+spaces : lineEnd]
+		if strings.Trim(text, " \t") == "" && (contentIndent < 0 || spaces <= contentIndent) {
+			if contentIndent >= 0 && spaces+len(text) > contentIndent {
+				text = p.src[p.pos+contentIndent : lineEnd]
+			} else {
+				text = ""
+			}
+			lines = append(lines, text)
+			p.pos = lineEnd
+			continue
+		}
+		if contentIndent < 0 {
+			if spaces <= indent {
+				*p = saved
+				break
+			}
+			contentIndent = spaces
+		}
+		if spaces < contentIndent || p.isDocMarker("---") || p.isDocMarker("...") {
+			*p = saved
+			break
+		}
+		lines = append(lines, p.src[p.pos+contentIndent:lineEnd])
+		last = len(lines) - 1
+		p.pos = lineEnd
+		n.End = lineEnd
+	}
+	if last < len(lines)-1 {
+		// Trailing blank lines belong to the parent; leave pos after the
+		// last content line so spans stop there.
+		p.pos = n.End
+		p.line = n.Line + 1 + last
+		p.lineStart = strings.LastIndexByte(p.src[:p.pos], '\n') + 1
+	}
+
+	content := lines[:last+1]
+	var text string
+	if n.Style == '|' {
+		text = strings.Join(content, "\n")
+	} else {
+		text = foldBlockLines(content)
+	}
+	trailing := len(lines) - len(content)
+	switch {
+	case chomp == '-' || last < 0 && chomp != '+':
+	case chomp == '+':
+		if last >= 0 {
+			text += "\n"
+		}
+		text += strings.Repeat("\n", trailing)
+	default:
+		text += "\n"
+	}
+	n.Value = text
+	return n, nil
+}
+
+// foldBlockLines joins the lines of a folded scalar: lines of text fold
+// into one with spaces, blank lines become newlines, and more-indented
+// lines keep their line breaks.
+func foldBlockLines(lines []string) string {
+	var b strings.Builder
+	started, prevText := false, false
+	empty := 0
+	for _, l := range lines {
+		if l == "" {
+			empty++
+			continue
+		}
+		moreIndented := isBlank(l[0])
+		switch {
+		case !started:
+			b.WriteString(strings.Repeat("\n", empty))
+		case prevText && !moreIndented:
+			if empty == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteString(strings.Repeat("\n", empty))
+			}
+		default:
+			b.WriteString(strings.Repeat("\n", empty+1))
+		}
+		b.WriteString(l)
+		started, prevText, empty = true, !moreIndented, 0
+	}
+	return b.String()
+}
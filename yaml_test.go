@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// dumpYAML renders a node compactly: scalars quoted, ~ for null.
+func dumpYAML(n *yamlNode) string {
+	switch n.Kind {
+	case yamlMapping:
+		var parts []string
+		for _, p := range n.Pairs {
+			parts = append(parts, dumpYAML(p.Key)+": "+dumpYAML(p.Value))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case yamlSequence:
+		var parts []string
+		for _, it := range n.Items {
+			parts = append(parts, dumpYAML(it))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	if n.Null && n.Style == 0 {
+		return "~"
+	}
+	return fmt.Sprintf("%q", n.Value)
+}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct{ name, src, want string }{
+		{"empty", "# nothing\n", `~`},
+		{"scalar", "hello world\n", `"hello world"`},
+		{"mapping", "a: 1\nb: two  # comment\nc:\n", `{"a": "1", "b": "two", "c": ~}`},
+		{"nested", "project:\n  prefix: ko\npipeline:\n  agent: claude\n", `{"project": {"prefix": "ko"}, "pipeline": {"agent": "claude"}}`},
+		{"sequence", "- a\n- b c\n-\n", `["a", "b c", ~]`},
+		{"seq at key indent", "list:\n- a\n- b\nnext: x\n", `{"list": ["a", "b"], "next": "x"}`},
+		{"compact mappings", "- name: a\n  run: x\n- name: b\n", `[{"name": "a", "run": "x"}, {"name": "b"}]`},
+		{"nested seq", "- - a\n  - b\n- c\n", `[["a", "b"], "c"]`},
+		{"colons in values", "url: http://x:8080/a\ntime: 10:30\n", `{"url": "http://x:8080/a", "time": "10:30"}`},
+		{"hash in value", "a: b#c\nd: e # f\n", `{"a": "b#c", "d": "e"}`},
+		{"multiline plain", "a: one\n  two\n\n  three\nb: x\n", `{"a": "one two\nthree", "b": "x"}`},
+		{"single quotes", `a: 'it''s # not a comment'`, `{"a": "it's # not a comment"}`},
+		{"double quotes", `a: "tab\there \"q\" \u00e9 \x41"`, `{"a": "tab\there \"q\" é A"}`},
+		{"quoted folding", "a: \"one\n  two\n\n  three\"\n", `{"a": "one two\nthree"}`},
+		{"escaped break", "a: \"one\\\n  two\"\n", `{"a": "onetwo"}`},
+		{"quoted keys", `"a b": 1` + "\n'c': 2\n", `{"a b": "1", "c": "2"}`},
+		{"literal", "a: |\n  line 1\n\n    indented\n  line 3\nb: x\n", `{"a": "line 1\n\n  indented\nline 3\n", "b": "x"}`},
+		{"literal strip", "a: |-\n  x\n\n\nb: y\n", `{"a": "x", "b": "y"}`},
+		{"literal keep", "a: |+\n  x\n\n\nb: y\n", `{"a": "x\n\n\n", "b": "y"}`},
+		{"literal explicit indent", "a: |2\n    x\n  y\n", `{"a": "  x\ny\n"}`},
+		{"folded", "a: >\n  one\n  two\n\n  three\n    more\n  four\n", `{"a": "one two\nthree\n  more\nfour\n"}`},
+		{"block scalar at end", "a: |\n  x", `{"a": "x\n"}`},
+		{"flow sequence", "a: [x, 'y z', \"w\", [1, 2], ]\n", `{"a": ["x", "y z", "w", ["1", "2"]]}`},
+		{"flow mapping", "a: {k: v, n: [1], e: , bare}\n", `{"a": {"k": "v", "n": ["1"], "e": ~, "bare": ~}}`},
+		{"multiline flow", "a: [\n  x,   # one\n  y\n]\n", `{"a": ["x", "y"]}`},
+		{"flow pair", "a: [k: v]\n", `{"a": [{"k": "v"}]}`},
+		{"anchors", "base: &b {x: 1}\nuse: *b\nlist: [&i item, *i]\n", `{"base": {"x": "1"}, "use": {"x": "1"}, "list": ["item", "item"]}`},
+		{"block anchor", "base: &b\n  x: 1\nother: *b\n", `{"base": {"x": "1"}, "other": {"x": "1"}}`},
+		{"tags ignored", "a: !!str 1\nb: !custom\n  c: d\n", `{"a": "1", "b": {"c": "d"}}`},
+		{"document markers", "%YAML 1.2\n---\na: 1\n...\n", `{"a": "1"}`},
+		{"null forms", "a: ~\nb: null\nc: 'null'\n", `{"a": ~, "b": ~, "c": "null"}`},
+		{"crlf", "a: 1\r\nb:\r\n  - x\r\n", `{"a": "1", "b": ["x"]}`},
+		{"tab after value", "a: 1\t# c\n", `{"a": "1"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := parseYAML(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dumpYAML(n); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"a: b: c\n", "line 1: mapping values are not allowed here"},
+		{"a: 1\na: 2\n", `line 2: duplicate key "a"`},
+		{"a:\n  b:\n    c: 1\n   d: 2\n", "line 4: bad indentation"},
+		{"a:\n\tb: 1\n", "line 2: tabs are not allowed"},
+		{"a: \"open\n", "line 1: unterminated quoted string"},
+		{"a: [1, 2\n", "line 1: unterminated flow collection"},
+		{"a: [1 2] x\n", "line 1: unexpected"},
+		{"a: *nope\n", `line 1: unknown anchor "nope"`},
+		{"a: \"\\q\"\n", `line 1: invalid escape \q`},
+		{"a: 1\n---\nb: 2\n", "line 2: only one document"},
+		{"a: - x\n", "line 1: a block sequence cannot start"},
+		{"- a\nb: c\n", "line 2: unexpected"},
+		{"a: 1\n- b\n", "line 2: expected a mapping key"},
+		{"? a\n: b\n", "complex mapping keys"},
+		{"a: @x\n", "cannot start with '@'"},
+		{"a:\n  b: 1\n  - c\n  d: 2\n", "line 4: expected a sequence entry"},
+	}
+	for _, tt := range tests {
+		_, err := parseYAML(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestParseYAMLSpans(t *testing.T) {
+	src := "a: one  # c\nb:\n  - x\nc:\nd: |\n  text\n\ne: [1, 2]\n"
+	n, err := parseYAML(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "one", "b": "- x", "c": "", "d": "|\n  text", "e": "[1, 2]"}
+	for _, p := range n.Pairs {
+		if got := src[p.Value.Start:p.Value.End]; got != want[p.Key.Value] {
+			t.Errorf("%s: span %q, want %q", p.Key.Value, got, want[p.Key.Value])
+		}
+		if src[p.Colon] != ':' {
+			t.Errorf("%s: colon at %d is %q", p.Key.Value, p.Colon, src[p.Colon])
+		}
+	}
+	if e := n.Pairs[4]; e.Key.Line != 8 || e.Value.Col != 3 {
+		t.Errorf("e: line %d col %d", e.Key.Line, e.Value.Col)
+	}
+	if x := n.Pairs[1].Value.Items[0]; x.Line != 3 || x.Col != 4 {
+		t.Errorf("b[0]: line %d col %d", x.Line, x.Col)
+	}
+}